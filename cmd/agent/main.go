@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"os"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	containerd "github.com/containerd/containerd/v2/client"
@@ -64,6 +65,7 @@ func main() {
 			provideConfig,
 			boot.ProvideRuntimeConfig,
 			provideLogger,
+			provideReadiness,
 			provideContainerdClient,
 			provideDBConn,
 			provideDBQueries,
@@ -140,6 +142,8 @@ func main() {
 		),
 		fx.Invoke(
 			startMemoryWarmup,
+			startPendingEmbeddings,
+			startIdleReaper,
 			startScheduleService,
 			startChannelManager,
 			startContainerReconciliation,
@@ -178,10 +182,52 @@ func provideConfig() (config.Config, error) {
 
 func provideLogger(cfg config.Config) *slog.Logger {
 	logger.Init(cfg.Log.Level, cfg.Log.Format)
+	logger.SetRequestIDPropagation(cfg.Log.PropagateRequestID)
 	return logger.L
 }
 
-func provideContainerdClient(lc fx.Lifecycle, rc *boot.RuntimeConfig) (*containerd.Client, error) {
+// readinessProbeInterval is how often dependencies are re-probed after startup.
+const readinessProbeInterval = 10 * time.Second
+
+// provideReadiness tracks dependency availability for /readyz and route
+// gating, and keeps re-probing dependencies while the agent runs.
+func provideReadiness(lc fx.Lifecycle, log *slog.Logger) *server.Readiness {
+	readiness := server.NewReadiness(log)
+	ctx, cancel := context.WithCancel(context.Background())
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			if degraded := readiness.Degraded(); len(degraded) > 0 {
+				log.Warn("starting in degraded mode", slog.Any("unavailable", degraded))
+			}
+			go readiness.Watch(ctx, readinessProbeInterval)
+			return nil
+		},
+		OnStop: func(context.Context) error {
+			cancel()
+			return nil
+		},
+	})
+	return readiness
+}
+
+// awaitDependency probes a registered dependency with the configured startup
+// retries. With optional set and degraded startup enabled, a dependency that
+// stays down is logged and left to the readiness watcher instead of failing.
+func awaitDependency(log *slog.Logger, readiness *server.Readiness, cfg config.ServerConfig, name string, optional bool) error {
+	interval := time.Duration(cfg.StartupRetryIntervalSeconds) * time.Second
+	err := readiness.Await(context.Background(), name, cfg.StartupRetries, interval)
+	if err == nil {
+		return nil
+	}
+	if optional && cfg.DegradedStartup {
+		log.Warn("dependency unavailable, continuing in degraded mode",
+			slog.String("dependency", name), slog.Any("error", err))
+		return nil
+	}
+	return fmt.Errorf("%s unavailable: %w", name, err)
+}
+
+func provideContainerdClient(lc fx.Lifecycle, rc *boot.RuntimeConfig, cfg config.Config, log *slog.Logger, readiness *server.Readiness) (*containerd.Client, error) {
 	factory := ctr.DefaultClientFactory{SocketPath: rc.ContainerdSocketPath}
 	client, err := factory.New(context.Background())
 	if err != nil {
@@ -192,10 +238,23 @@ func provideContainerdClient(lc fx.Lifecycle, rc *boot.RuntimeConfig) (*containe
 			return client.Close()
 		},
 	})
+	readiness.Register("containerd", func(ctx context.Context) error {
+		serving, err := client.IsServing(ctx)
+		if err != nil {
+			return err
+		}
+		if !serving {
+			return errors.New("containerd is not serving")
+		}
+		return nil
+	}, "/bots/:bot_id/container", "/bots/:bot_id/mcp-stdio", "/bots/:bot_id/tools")
+	if err := awaitDependency(log, readiness, cfg.Server, "containerd", true); err != nil {
+		return nil, err
+	}
 	return client, nil
 }
 
-func provideDBConn(lc fx.Lifecycle, cfg config.Config) (*pgxpool.Pool, error) {
+func provideDBConn(lc fx.Lifecycle, cfg config.Config, log *slog.Logger, readiness *server.Readiness) (*pgxpool.Pool, error) {
 	conn, err := db.Open(context.Background(), cfg.Postgres)
 	if err != nil {
 		return nil, fmt.Errorf("db connect: %w", err)
@@ -206,6 +265,10 @@ func provideDBConn(lc fx.Lifecycle, cfg config.Config) (*pgxpool.Pool, error) {
 			return nil
 		},
 	})
+	readiness.Register("postgres", conn.Ping)
+	if err := awaitDependency(log, readiness, cfg.Server, "postgres", false); err != nil {
+		return nil, err
+	}
 	return conn, nil
 }
 
@@ -221,13 +284,21 @@ func provideMCPManager(log *slog.Logger, service ctr.Service, cfg config.Config,
 // memory providers
 // ---------------------------------------------------------------------------
 
-func provideMemoryLLM(modelsService *models.Service, queries *dbsqlc.Queries, log *slog.Logger) memory.LLM {
+func provideMemoryLLM(modelsService *models.Service, queries *dbsqlc.Queries, cfg config.Config, log *slog.Logger) (memory.LLM, error) {
+	prompts, err := memory.NewPrompts(memory.PromptTemplates{
+		Extract: cfg.Memory.ExtractPrompt,
+		Decide:  cfg.Memory.DecidePrompt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("memory prompts: %w", err)
+	}
 	return &lazyLLMClient{
 		modelsService: modelsService,
 		queries:       queries,
 		timeout:       30 * time.Second,
 		logger:        log,
-	}
+		prompts:       prompts,
+	}, nil
 }
 
 func provideEmbeddingsResolver(log *slog.Logger, modelsService *models.Service, queries *dbsqlc.Queries) *embeddings.Resolver {
@@ -260,29 +331,90 @@ func provideEmbeddingSetup(log *slog.Logger, modelsService *models.Service) (emb
 	}, nil
 }
 
-func provideTextEmbedderForMemory(resolver *embeddings.Resolver, setup embeddingSetup, log *slog.Logger) embeddings.Embedder {
-	return buildTextEmbedder(resolver, setup.TextModel, setup.HasEmbeddingModels, log)
+func provideTextEmbedderForMemory(resolver *embeddings.Resolver, setup embeddingSetup, cfg config.Config, log *slog.Logger) (embeddings.Embedder, error) {
+	embedder := buildTextEmbedder(resolver, setup.TextModel, setup.HasEmbeddingModels, log)
+	if embedder == nil {
+		return nil, nil
+	}
+	embedder = memory.NewCachedEmbedder(embedder, cfg.Memory.EmbeddingCacheSize)
+	fallbackModel := strings.TrimSpace(cfg.Memory.FallbackEmbeddingModel)
+	if fallbackModel == "" {
+		return embedder, nil
+	}
+	dims, ok := setup.Vectors[fallbackModel]
+	if !ok {
+		return nil, fmt.Errorf("fallback embedding model %q is not a configured embedding model", fallbackModel)
+	}
+	// Each side gets its own cache so vectors of one model are never served
+	// for the other.
+	fallback := memory.NewCachedEmbedder(&embeddings.ResolverTextEmbedder{
+		Resolver: resolver,
+		ModelID:  fallbackModel,
+		Dims:     dims,
+	}, cfg.Memory.EmbeddingCacheSize)
+	return memory.NewFailoverEmbedder(embedder, fallback, memory.FailoverOptions{
+		FailureThreshold: cfg.Memory.EmbeddingFailureThreshold,
+		Cooldown:         time.Duration(cfg.Memory.EmbeddingCooldownSeconds) * time.Second,
+		Logger:           log.With(slog.String("component", "embedder")),
+	})
 }
 
-func provideQdrantStore(log *slog.Logger, cfg config.Config, setup embeddingSetup) (*memory.QdrantStore, error) {
+func provideQdrantStore(log *slog.Logger, cfg config.Config, setup embeddingSetup, readiness *server.Readiness) (*memory.QdrantStore, error) {
 	qcfg := cfg.Qdrant
 	timeout := time.Duration(qcfg.TimeoutSeconds) * time.Second
+	var vectors map[string]int
 	if setup.HasEmbeddingModels && len(setup.Vectors) > 0 {
-		store, err := memory.NewQdrantStoreWithVectors(log, qcfg.BaseURL, qcfg.APIKey, qcfg.Collection, setup.Vectors, "sparse_hash", timeout)
-		if err != nil {
-			return nil, fmt.Errorf("qdrant named vectors init: %w", err)
-		}
-		return store, nil
+		vectors = setup.Vectors
 	}
-	store, err := memory.NewQdrantStore(log, qcfg.BaseURL, qcfg.APIKey, qcfg.Collection, setup.TextModel.Dimensions, "sparse_hash", timeout)
+	store, err := memory.NewQdrantStoreDeferred(log, qcfg.BaseURL, qcfg.APIKey, qcfg.Collection, vectors, setup.TextModel.Dimensions, "sparse_hash", timeout)
 	if err != nil {
 		return nil, fmt.Errorf("qdrant init: %w", err)
 	}
+	// The collection is set up by the first successful probe; after that the
+	// probe only checks that Qdrant is reachable.
+	var collectionReady atomic.Bool
+	readiness.Register("qdrant", func(ctx context.Context) error {
+		if collectionReady.Load() {
+			return store.HealthCheck(ctx)
+		}
+		if err := store.EnsureCollection(ctx); err != nil {
+			return err
+		}
+		collectionReady.Store(true)
+		return nil
+	}, "/bots/:bot_id/memory")
+	if err := awaitDependency(log, readiness, cfg.Server, "qdrant", true); err != nil {
+		return nil, err
+	}
 	return store, nil
 }
 
-func provideMemoryService(log *slog.Logger, llm memory.LLM, embedder embeddings.Embedder, store *memory.QdrantStore, resolver *embeddings.Resolver, bm25 *memory.BM25Indexer, setup embeddingSetup) *memory.Service {
-	return memory.NewService(log, llm, embedder, store, resolver, bm25, setup.TextModel.ModelID, setup.MultimodalModel.ModelID)
+// memoryPingInterval bounds how often the end-to-end memory probe, which
+// calls the embedding provider, actually runs.
+const memoryPingInterval = time.Minute
+
+func provideMemoryService(log *slog.Logger, cfg config.Config, queries *dbsqlc.Queries, llm memory.LLM, embedder embeddings.Embedder, store *memory.QdrantStore, resolver *embeddings.Resolver, bm25 *memory.BM25Indexer, setup embeddingSetup, readiness *server.Readiness) (*memory.Service, error) {
+	fallback, err := memory.ParseEmbeddingFallback(cfg.Memory.EmbeddingFallback)
+	if err != nil {
+		return nil, fmt.Errorf("memory config: %w", err)
+	}
+	svc := memory.NewService(log, llm, embedder, store, resolver, bm25, setup.TextModel.ModelID, setup.MultimodalModel.ModelID)
+	svc.SetEmbeddingFallback(fallback)
+	svc.SetExtractMaxTokens(cfg.Memory.ExtractMaxTokens)
+	svc.SetAddActionRetries(cfg.Memory.AddActionRetries)
+	if cfg.Memory.DefaultInfer != nil {
+		svc.SetDefaultInfer(*cfg.Memory.DefaultInfer)
+	}
+	svc.SetHistoryStore(memory.NewPostgresHistoryStore(queries))
+	// Reported on /readyz only: memory routes are already gated by the qdrant
+	// probe, and an embedder outage should not block keyword search.
+	readiness.Register("memory", server.ThrottledProbe(svc.Ping, memoryPingInterval))
+	pingCtx, cancel := context.WithTimeout(context.Background(), readinessProbeInterval)
+	defer cancel()
+	if err := readiness.Check(pingCtx, "memory"); err != nil {
+		log.Warn("memory round trip probe failed", slog.Any("error", err))
+	}
+	return svc, nil
 }
 
 // ---------------------------------------------------------------------------
@@ -308,6 +440,7 @@ func provideScheduleTriggerer(resolver *flow.Resolver) schedule.Triggerer {
 func provideChatResolver(log *slog.Logger, cfg config.Config, modelsService *models.Service, queries *dbsqlc.Queries, memoryService *memory.Service, chatService *conversation.Service, msgService *message.DBService, settingsService *settings.Service, containerdHandler *handlers.ContainerdHandler) *flow.Resolver {
 	resolver := flow.NewResolver(log, modelsService, queries, memoryService, chatService, msgService, settingsService, cfg.AgentGateway.BaseURL(), 120*time.Second)
 	resolver.SetSkillLoader(&skillLoaderAdapter{handler: containerdHandler})
+	resolver.SetStreamTerminalEvents(cfg.AgentGateway.StreamTerminalEvents)
 	return resolver
 }
 
@@ -340,11 +473,13 @@ func provideChannelManager(log *slog.Logger, registry *channel.Registry, channel
 // containerd handler & tool gateway
 // ---------------------------------------------------------------------------
 
-func provideContainerdHandler(log *slog.Logger, service ctr.Service, cfg config.Config, botService *bots.Service, accountService *accounts.Service, policyService *policy.Service, queries *dbsqlc.Queries) *handlers.ContainerdHandler {
-	return handlers.NewContainerdHandler(log, service, cfg.MCP, cfg.Containerd.Namespace, botService, accountService, policyService, queries)
+func provideContainerdHandler(log *slog.Logger, service ctr.Service, cfg config.Config, botService *bots.Service, accountService *accounts.Service, policyService *policy.Service, queries *dbsqlc.Queries, manager *mcp.Manager) *handlers.ContainerdHandler {
+	h := handlers.NewContainerdHandler(log, service, cfg.MCP, cfg.Containerd.Namespace, botService, accountService, policyService, queries)
+	h.SetVersionManager(manager)
+	return h
 }
 
-func provideToolGatewayService(log *slog.Logger, cfg config.Config, channelManager *channel.Manager, registry *channel.Registry, channelService *channel.Service, scheduleService *schedule.Service, memoryService *memory.Service, chatService *conversation.Service, accountService *accounts.Service, manager *mcp.Manager, containerdHandler *handlers.ContainerdHandler, mcpConnService *mcp.ConnectionService) *mcp.ToolGatewayService {
+func provideToolGatewayService(log *slog.Logger, cfg config.Config, channelManager *channel.Manager, registry *channel.Registry, channelService *channel.Service, scheduleService *schedule.Service, memoryService *memory.Service, chatService *conversation.Service, accountService *accounts.Service, manager *mcp.Manager, containerdHandler *handlers.ContainerdHandler, mcpConnService *mcp.ConnectionService, queries *dbsqlc.Queries) *mcp.ToolGatewayService {
 	messageExec := mcpmessage.NewExecutor(log, channelManager, channelManager, registry)
 	directoryExec := mcpdirectory.NewExecutor(log, registry, channelService, registry)
 	scheduleExec := mcpschedule.NewExecutor(log, scheduleService)
@@ -354,6 +489,28 @@ func provideToolGatewayService(log *slog.Logger, cfg config.Config, channelManag
 		execWorkDir = config.DefaultDataMount
 	}
 	fsExec := mcpcontainer.NewExecutor(log, manager, execWorkDir)
+	if manager != nil {
+		fsExec.SetWorkDirFunc(manager.DataMount)
+	}
+	if cfg.MCP.MaxFileSize > 0 {
+		fsExec.SetMaxFileSize(cfg.MCP.MaxFileSize)
+	}
+	if cfg.MCP.ListMaxEntries > 0 || cfg.MCP.ListTimeoutSeconds > 0 {
+		maxEntries := mcpcontainer.DefaultListMaxEntries
+		if cfg.MCP.ListMaxEntries > 0 {
+			maxEntries = cfg.MCP.ListMaxEntries
+		}
+		listTimeout := mcpcontainer.DefaultListTimeout
+		if cfg.MCP.ListTimeoutSeconds > 0 {
+			listTimeout = time.Duration(cfg.MCP.ListTimeoutSeconds) * time.Second
+		}
+		fsExec.SetListLimits(maxEntries, listTimeout)
+	}
+	fsExec.SetWritePolicy(mcp.NewWritePolicy(cfg.MCP.WritePolicy))
+	fsExec.SetReadOnly(cfg.MCP.ReadOnlyFS)
+	if auditLog := mcp.NewWriteAuditLog(queries); auditLog != nil {
+		fsExec.SetWriteAuditor(auditLog)
+	}
 
 	fedGateway := handlers.NewMCPFederationGateway(log, containerdHandler)
 	fedSource := mcpfederation.NewSource(log, fedGateway, mcpConnService)
@@ -378,7 +535,9 @@ func provideMemoryHandler(log *slog.Logger, service *memory.Service, chatService
 		if strings.TrimSpace(execWorkDir) == "" {
 			execWorkDir = config.DefaultDataMount
 		}
-		h.SetMemoryFS(memory.NewMemoryFS(log, manager, execWorkDir))
+		memoryFS := memory.NewMemoryFS(log, manager, execWorkDir)
+		memoryFS.SetWorkDirFunc(manager.DataMount)
+		h.SetMemoryFS(memoryFS)
 	}
 	return h
 }
@@ -415,13 +574,20 @@ type serverParams struct {
 	Config            config.Config
 	ServerHandlers    []server.Handler `group:"server_handlers"`
 	ContainerdHandler *handlers.ContainerdHandler
+	Readiness         *server.Readiness
 }
 
 func provideServer(params serverParams) *server.Server {
 	allHandlers := make([]server.Handler, 0, len(params.ServerHandlers)+1)
 	allHandlers = append(allHandlers, params.ServerHandlers...)
 	allHandlers = append(allHandlers, params.ContainerdHandler)
-	return server.NewServer(params.Logger, params.RuntimeConfig.ServerAddr, params.Config.Auth.JWTSecret, allHandlers...)
+	switch keepAlive := params.Config.Server.SSEKeepAliveSeconds; {
+	case keepAlive < 0:
+		handlers.SetSSEKeepAlive(0)
+	case keepAlive > 0:
+		handlers.SetSSEKeepAlive(time.Duration(keepAlive) * time.Second)
+	}
+	return server.NewServer(params.Logger, params.RuntimeConfig.ServerAddr, params.Config.Auth.JWTSecret, params.Config.Server, params.Readiness, allHandlers...)
 }
 
 // ---------------------------------------------------------------------------
@@ -441,6 +607,45 @@ func startMemoryWarmup(lc fx.Lifecycle, memoryService *memory.Service, logger *s
 	})
 }
 
+func startPendingEmbeddings(lc fx.Lifecycle, cfg config.Config, memoryService *memory.Service) {
+	if fallback, _ := memory.ParseEmbeddingFallback(cfg.Memory.EmbeddingFallback); fallback != memory.EmbeddingFallbackDegrade {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			go memoryService.RunPendingEmbeddings(ctx, time.Minute)
+			return nil
+		},
+		OnStop: func(context.Context) error {
+			cancel()
+			return nil
+		},
+	})
+}
+
+func startIdleReaper(lc fx.Lifecycle, manager *mcp.Manager) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			go func() {
+				defer close(done)
+				manager.RunIdleReaper(ctx)
+			}()
+			return nil
+		},
+		OnStop: func(stopCtx context.Context) error {
+			cancel()
+			select {
+			case <-done:
+			case <-stopCtx.Done():
+			}
+			return nil
+		},
+	})
+}
+
 func startScheduleService(lc fx.Lifecycle, scheduleService *schedule.Service) {
 	lc.Append(fx.Hook{
 		OnStart: func(ctx context.Context) error {
@@ -588,6 +793,7 @@ type lazyLLMClient struct {
 	queries       *dbsqlc.Queries
 	timeout       time.Duration
 	logger        *slog.Logger
+	prompts       *memory.Prompts
 }
 
 func (c *lazyLLMClient) Extract(ctx context.Context, req memory.ExtractRequest) (memory.ExtractResponse, error) {
@@ -637,7 +843,12 @@ func (c *lazyLLMClient) resolve(ctx context.Context) (memory.LLM, error) {
 	default:
 		return nil, fmt.Errorf("memory provider client type not supported: %s", memoryProvider.ClientType)
 	}
-	return memory.NewLLMClient(c.logger, memoryProvider.BaseUrl, memoryProvider.ApiKey, memoryModel.ModelID, c.timeout)
+	client, err := memory.NewLLMClient(c.logger, memoryProvider.BaseUrl, memoryProvider.ApiKey, memoryModel.ModelID, c.timeout)
+	if err != nil {
+		return nil, err
+	}
+	client.SetPrompts(c.prompts)
+	return client, nil
 }
 
 // skillLoaderAdapter bridges handlers.ContainerdHandler to flow.SkillLoader.
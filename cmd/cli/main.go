@@ -1,18 +1,24 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
+	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
 	"runtime"
 	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	gocni "github.com/containerd/go-cni"
@@ -33,6 +39,20 @@ func main() {
 			os.Exit(runCNICheck(flag.Args()[1:]))
 		case "cni-status":
 			os.Exit(runCNIStatus(flag.Args()[1:]))
+		case "clone":
+			os.Exit(runClone(flag.Args()[1:]))
+		case "version-create":
+			os.Exit(runVersionCreate(flag.Args()[1:]))
+		case "version-delete":
+			os.Exit(runVersionDelete(flag.Args()[1:]))
+		case "version-prune":
+			os.Exit(runVersionPrune(flag.Args()[1:]))
+		case "logs":
+			os.Exit(runLogs(flag.Args()[1:]))
+		case "stats":
+			os.Exit(runStats(flag.Args()[1:]))
+		case "pull":
+			os.Exit(runPull(flag.Args()[1:]))
 		}
 	}
 
@@ -251,6 +271,363 @@ func newCNIFromArgs(args []string) (gocni.CNI, error) {
 	return gocni.New(opts...)
 }
 
+// runClone creates the container of the destination bot from a version of
+// the source bot's container through the server API, and copies the source's
+// data mount with its workspace files over the destination's:
+//
+//	clone <src-bot-id> <dst-bot-id> [--version=N | --label=L]
+//
+// Without --version or --label the source's current state is cloned.
+func runClone(args []string) int {
+	fs := flag.NewFlagSet("clone", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	server := fs.String("server", "http://127.0.0.1:8080", "")
+	token := fs.String("token", os.Getenv("MEMOH_TOKEN"), "")
+	version := fs.Int("version", 0, "")
+	label := fs.String("label", "", "")
+	// Flags may come before, between or after the two bot IDs.
+	var botIDs []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return exitWithError(err)
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		botIDs = append(botIDs, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	if len(botIDs) != 2 {
+		return exitWithError(fmt.Errorf("usage: clone <src-bot-id> <dst-bot-id>"))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	resp, err := callContainerAPI(ctx, http.MethodPost, *server, *token, botIDs[1], "/clone", map[string]any{
+		"source_bot_id": botIDs[0],
+		"version":       *version,
+		"label":         strings.TrimSpace(*label),
+	})
+	if err != nil {
+		return exitWithError(fmt.Errorf("clone failed: %w", err))
+	}
+	defer resp.Body.Close()
+	if _, err := io.Copy(os.Stdout, resp.Body); err != nil {
+		return exitWithError(err)
+	}
+	return 0
+}
+
+// runVersionCreate commits a bot's container as a new version, optionally
+// named with --label, through the server API and prints the version. The
+// bot ID may be given with --bot-id or as the first argument.
+func runVersionCreate(args []string) int {
+	fs := flag.NewFlagSet("version-create", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	server := fs.String("server", "http://127.0.0.1:8080", "")
+	token := fs.String("token", os.Getenv("MEMOH_TOKEN"), "")
+	botID := fs.String("bot-id", "", "")
+	label := fs.String("label", "", "")
+	if err := parseBotArgs(fs, botID, args); err != nil {
+		return exitWithError(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	resp, err := callContainerAPI(ctx, http.MethodPost, *server, *token, *botID, "/versions", map[string]string{"label": strings.TrimSpace(*label)})
+	if err != nil {
+		return exitWithError(fmt.Errorf("create version failed: %w", err))
+	}
+	defer resp.Body.Close()
+	if _, err := io.Copy(os.Stdout, resp.Body); err != nil {
+		return exitWithError(err)
+	}
+	return 0
+}
+
+// runVersionDelete deletes a bot container version, given by --version or
+// --label, through the server API, which owns the containerd and database
+// connections.
+func runVersionDelete(args []string) int {
+	fs := flag.NewFlagSet("version-delete", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	server := fs.String("server", "http://127.0.0.1:8080", "")
+	token := fs.String("token", os.Getenv("MEMOH_TOKEN"), "")
+	botID := fs.String("bot-id", "", "")
+	version := fs.Int("version", 0, "")
+	label := fs.String("label", "", "")
+	if err := fs.Parse(args); err != nil {
+		return exitWithError(err)
+	}
+	if strings.TrimSpace(*botID) == "" {
+		return exitWithError(fmt.Errorf("missing --bot-id"))
+	}
+	ref := strconv.Itoa(*version)
+	if strings.TrimSpace(*label) != "" {
+		ref = url.PathEscape(strings.TrimSpace(*label))
+	} else if *version <= 0 {
+		return exitWithError(fmt.Errorf("missing --version or --label"))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	resp, err := callContainerAPI(ctx, http.MethodDelete, *server, *token, *botID, "/versions/"+ref, nil)
+	if err != nil {
+		return exitWithError(fmt.Errorf("delete version failed: %w", err))
+	}
+	resp.Body.Close()
+	return 0
+}
+
+// runVersionPrune deletes all but the newest --keep versions of a bot's
+// container through the server API and prints how many went, and which
+// older versions were kept because they are in use. The bot ID may be given
+// with --bot-id or as the first argument.
+func runVersionPrune(args []string) int {
+	fs := flag.NewFlagSet("version-prune", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	server := fs.String("server", "http://127.0.0.1:8080", "")
+	token := fs.String("token", os.Getenv("MEMOH_TOKEN"), "")
+	botID := fs.String("bot-id", "", "")
+	keep := fs.Int("keep", -1, "")
+	if err := parseBotArgs(fs, botID, args); err != nil {
+		return exitWithError(err)
+	}
+	if *keep < 0 {
+		return exitWithError(fmt.Errorf("missing --keep"))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	resp, err := callContainerAPI(ctx, http.MethodPost, *server, *token, *botID, "/versions/prune?keep="+strconv.Itoa(*keep), nil)
+	if err != nil {
+		return exitWithError(fmt.Errorf("prune versions failed: %w", err))
+	}
+	defer resp.Body.Close()
+	var result struct {
+		Deleted int   `json:"deleted"`
+		InUse   []int `json:"in_use"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return exitWithError(err)
+	}
+	fmt.Printf("deleted %d versions\n", result.Deleted)
+	if len(result.InUse) > 0 {
+		fmt.Printf("kept versions %v: the container's filesystem still builds on them\n", result.InUse)
+	}
+	return 0
+}
+
+// runLogs prints the output of a bot's container task through the server
+// API. The bot ID may be given with --bot-id or as the first argument.
+func runLogs(args []string) int {
+	fs := flag.NewFlagSet("logs", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	server := fs.String("server", "http://127.0.0.1:8080", "")
+	token := fs.String("token", os.Getenv("MEMOH_TOKEN"), "")
+	botID := fs.String("bot-id", "", "")
+	follow := fs.Bool("follow", false, "")
+	if err := parseBotArgs(fs, botID, args); err != nil {
+		return exitWithError(err)
+	}
+
+	suffix := "/logs"
+	if *follow {
+		suffix += "?follow=true"
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	resp, err := callContainerAPI(ctx, http.MethodGet, *server, *token, *botID, suffix, nil)
+	if err != nil {
+		return exitWithError(fmt.Errorf("get logs failed: %w", err))
+	}
+	defer resp.Body.Close()
+	if _, err := io.Copy(os.Stdout, resp.Body); err != nil && ctx.Err() == nil {
+		return exitWithError(err)
+	}
+	return 0
+}
+
+// runStats prints the CPU, memory and pids usage of a bot's container task
+// as JSON. The bot ID may be given with --bot-id or as the first argument.
+func runStats(args []string) int {
+	fs := flag.NewFlagSet("stats", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	server := fs.String("server", "http://127.0.0.1:8080", "")
+	token := fs.String("token", os.Getenv("MEMOH_TOKEN"), "")
+	botID := fs.String("bot-id", "", "")
+	if err := parseBotArgs(fs, botID, args); err != nil {
+		return exitWithError(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	resp, err := callContainerAPI(ctx, http.MethodGet, *server, *token, *botID, "/stats", nil)
+	if err != nil {
+		return exitWithError(fmt.Errorf("get stats failed: %w", err))
+	}
+	defer resp.Body.Close()
+	if _, err := io.Copy(os.Stdout, resp.Body); err != nil {
+		return exitWithError(err)
+	}
+	return 0
+}
+
+// runPull pulls a bot's container image through the server API, drawing a
+// progress line on stderr. Pulling before creating the container shows the
+// progress the create call does not.
+func runPull(args []string) int {
+	fs := flag.NewFlagSet("pull", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	server := fs.String("server", "http://127.0.0.1:8080", "")
+	token := fs.String("token", os.Getenv("MEMOH_TOKEN"), "")
+	botID := fs.String("bot-id", "", "")
+	if err := parseBotArgs(fs, botID, args); err != nil {
+		return exitWithError(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	resp, err := callContainerAPI(ctx, http.MethodPost, *server, *token, *botID, "/pull", nil)
+	if err != nil {
+		return exitWithError(fmt.Errorf("pull failed: %w", err))
+	}
+	defer resp.Body.Close()
+
+	type blob struct {
+		Digest string `json:"digest"`
+		Offset int64  `json:"offset"`
+		Total  int64  `json:"total"`
+		Done   bool   `json:"done"`
+	}
+	type result struct {
+		Image string `json:"image"`
+		Error string `json:"error"`
+	}
+	blobs := map[string]blob{}
+	finished := false
+	err = readSSE(resp.Body, func(event, data string) error {
+		switch event {
+		case "progress":
+			var b blob
+			if err := json.Unmarshal([]byte(data), &b); err != nil {
+				return err
+			}
+			blobs[b.Digest] = b
+			var done int
+			var offset, total int64
+			for _, b := range blobs {
+				if b.Done {
+					done++
+				}
+				offset += b.Offset
+				total += b.Total
+			}
+			_, _ = fmt.Fprintf(os.Stderr, "\r%d/%d blobs, %.1f/%.1f MiB", done, len(blobs), float64(offset)/(1<<20), float64(total)/(1<<20))
+		case "done", "error":
+			finished = true
+			if len(blobs) > 0 {
+				_, _ = fmt.Fprintln(os.Stderr)
+			}
+			var r result
+			if err := json.Unmarshal([]byte(data), &r); err != nil {
+				return err
+			}
+			if r.Error != "" {
+				return fmt.Errorf("pull %s failed: %s", r.Image, r.Error)
+			}
+			_, _ = fmt.Fprintf(os.Stderr, "pulled %s\n", r.Image)
+		}
+		return nil
+	})
+	if err == nil && !finished {
+		err = fmt.Errorf("pull stream ended early")
+	}
+	if err != nil {
+		return exitWithError(err)
+	}
+	return 0
+}
+
+// readSSE calls fn for every event of a server-sent event stream.
+func readSSE(r io.Reader, fn func(event, data string) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	var event string
+	var data []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if len(data) > 0 {
+				if err := fn(event, strings.Join(data, "\n")); err != nil {
+					return err
+				}
+			}
+			event, data = "", nil
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	return scanner.Err()
+}
+
+// parseBotArgs parses args into fs, taking the bot ID from the first
+// positional argument when --bot-id is not given. Flags may follow it, as in
+// "logs <bot-id> --follow".
+func parseBotArgs(fs *flag.FlagSet, botID *string, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if strings.TrimSpace(*botID) == "" && fs.NArg() > 0 {
+		*botID = fs.Arg(0)
+		if err := fs.Parse(fs.Args()[1:]); err != nil {
+			return err
+		}
+	}
+	if strings.TrimSpace(*botID) == "" {
+		return fmt.Errorf("missing --bot-id")
+	}
+	return nil
+}
+
+// callContainerAPI sends a request to the bot container endpoint at suffix,
+// with payload as its JSON body unless it is nil, and returns the response
+// when it succeeded; the caller closes its body.
+func callContainerAPI(ctx context.Context, method, server, token, botID, suffix string, payload any) (*http.Response, error) {
+	endpoint := strings.TrimRight(server, "/") + "/bots/" + url.PathEscape(botID) + "/container" + suffix
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if strings.TrimSpace(token) != "" {
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(token))
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return resp, nil
+}
+
 func exitWithError(err error) int {
 	_, _ = fmt.Fprintln(os.Stderr, err.Error())
 	return 1
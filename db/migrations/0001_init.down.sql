@@ -1,3 +1,5 @@
+DROP TABLE IF EXISTS fs_write_audit;
+DROP TABLE IF EXISTS memory_history;
 DROP TABLE IF EXISTS subagents;
 DROP TABLE IF EXISTS schedule;
 DROP TABLE IF EXISTS lifecycle_events;
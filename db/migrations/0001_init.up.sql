@@ -256,6 +256,7 @@ CREATE TABLE IF NOT EXISTS containers (
   updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
   last_started_at TIMESTAMPTZ,
   last_stopped_at TIMESTAMPTZ,
+  last_active_at TIMESTAMPTZ,
   CONSTRAINT containers_container_id_unique UNIQUE (container_id),
   CONSTRAINT containers_container_name_unique UNIQUE (container_name)
 );
@@ -280,10 +281,13 @@ CREATE TABLE IF NOT EXISTS container_versions (
   snapshot_id TEXT NOT NULL REFERENCES snapshots(id) ON DELETE RESTRICT,
   version INTEGER NOT NULL,
   created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  changed_paths JSONB NOT NULL DEFAULT '[]'::jsonb,
+  label TEXT,
   UNIQUE (container_id, version)
 );
 
 CREATE INDEX IF NOT EXISTS idx_container_versions_container_id ON container_versions(container_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_container_versions_label ON container_versions(container_id, label) WHERE label IS NOT NULL;
 
 CREATE TABLE IF NOT EXISTS lifecycle_events (
   id TEXT PRIMARY KEY,
@@ -331,3 +335,25 @@ CREATE TABLE IF NOT EXISTS subagents (
 CREATE INDEX IF NOT EXISTS idx_subagents_bot_id ON subagents(bot_id);
 CREATE INDEX IF NOT EXISTS idx_subagents_deleted ON subagents(deleted);
 
+
+CREATE TABLE IF NOT EXISTS memory_history (
+  id TEXT PRIMARY KEY,
+  memory_id TEXT NOT NULL,
+  event TEXT NOT NULL,
+  memory TEXT NOT NULL,
+  hash TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_memory_history_memory_id ON memory_history(memory_id, created_at);
+
+CREATE TABLE IF NOT EXISTS fs_write_audit (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  bot_id UUID NOT NULL REFERENCES bots(id) ON DELETE CASCADE,
+  path TEXT NOT NULL,
+  action TEXT NOT NULL,
+  metadata JSONB NOT NULL DEFAULT '{}'::jsonb,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_fs_write_audit_bot_path ON fs_write_audit(bot_id, path, created_at);
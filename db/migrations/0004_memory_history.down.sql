@@ -0,0 +1,2 @@
+-- 0004_memory_history (down)
+DROP TABLE IF EXISTS memory_history;
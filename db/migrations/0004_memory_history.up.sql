@@ -0,0 +1,12 @@
+-- 0004_memory_history
+-- Keep a revision trail for memories stored in Qdrant.
+CREATE TABLE IF NOT EXISTS memory_history (
+  id TEXT PRIMARY KEY,
+  memory_id TEXT NOT NULL,
+  event TEXT NOT NULL,
+  memory TEXT NOT NULL,
+  hash TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_memory_history_memory_id ON memory_history(memory_id, created_at);
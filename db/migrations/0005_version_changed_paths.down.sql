@@ -0,0 +1,2 @@
+-- 0005_version_changed_paths (down)
+ALTER TABLE container_versions DROP COLUMN IF EXISTS changed_paths;
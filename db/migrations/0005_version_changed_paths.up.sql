@@ -0,0 +1,3 @@
+-- 0005_version_changed_paths
+-- Record which paths changed in each container version relative to its parent.
+ALTER TABLE container_versions ADD COLUMN IF NOT EXISTS changed_paths JSONB NOT NULL DEFAULT '[]'::jsonb;
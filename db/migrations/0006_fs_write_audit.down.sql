@@ -0,0 +1,2 @@
+-- 0006_fs_write_audit (down)
+DROP TABLE IF EXISTS fs_write_audit;
@@ -0,0 +1,12 @@
+-- 0006_fs_write_audit
+-- Record who changed which file in a bot's data mount, with caller-supplied metadata.
+CREATE TABLE IF NOT EXISTS fs_write_audit (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  bot_id UUID NOT NULL REFERENCES bots(id) ON DELETE CASCADE,
+  path TEXT NOT NULL,
+  action TEXT NOT NULL,
+  metadata JSONB NOT NULL DEFAULT '{}'::jsonb,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_fs_write_audit_bot_path ON fs_write_audit(bot_id, path, created_at);
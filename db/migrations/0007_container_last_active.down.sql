@@ -0,0 +1,2 @@
+-- 0007_container_last_active (down)
+ALTER TABLE containers DROP COLUMN IF EXISTS last_active_at;
@@ -0,0 +1,3 @@
+-- 0007_container_last_active
+-- Remember when each bot last used its container, so idle containers can be stopped across restarts.
+ALTER TABLE containers ADD COLUMN IF NOT EXISTS last_active_at TIMESTAMPTZ;
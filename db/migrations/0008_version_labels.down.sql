@@ -0,0 +1,3 @@
+-- 0008_version_labels (down)
+DROP INDEX IF EXISTS idx_container_versions_label;
+ALTER TABLE container_versions DROP COLUMN IF EXISTS label;
@@ -0,0 +1,5 @@
+-- 0008_version_labels
+-- Let users name container versions; a label identifies one version of a container.
+ALTER TABLE container_versions ADD COLUMN IF NOT EXISTS label TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_container_versions_label ON container_versions(container_id, label) WHERE label IS NOT NULL;
@@ -42,7 +42,12 @@ WHERE bot_id = sqlc.arg(bot_id);
 
 -- name: UpdateContainerStarted :exec
 UPDATE containers
-SET status = 'running', last_started_at = now(), updated_at = now()
+SET status = 'running', last_started_at = now(), last_active_at = now(), updated_at = now()
+WHERE bot_id = sqlc.arg(bot_id);
+
+-- name: UpdateContainerIdleStopped :exec
+UPDATE containers
+SET status = 'idle_stopped', last_stopped_at = now(), updated_at = now()
 WHERE bot_id = sqlc.arg(bot_id);
 
 -- name: UpdateContainerStopped :exec
@@ -52,3 +57,11 @@ WHERE bot_id = sqlc.arg(bot_id);
 
 -- name: ListAutoStartContainers :many
 SELECT * FROM containers WHERE auto_start = true ORDER BY updated_at DESC;
+
+-- name: ListContainersByBotIDs :many
+SELECT * FROM containers WHERE bot_id = ANY(sqlc.arg(bot_ids)::uuid[]) ORDER BY updated_at DESC;
+
+-- name: UpdateContainerActivity :exec
+UPDATE containers
+SET last_active_at = GREATEST(last_active_at, sqlc.arg(last_active_at)::timestamptz)
+WHERE bot_id = sqlc.arg(bot_id);
@@ -6,3 +6,10 @@ VALUES (
   sqlc.arg(event_type),
   sqlc.arg(payload)
 );
+
+-- name: ListLifecycleEventsSince :many
+SELECT * FROM lifecycle_events
+WHERE container_id = sqlc.arg(container_id)
+  AND event_type = sqlc.arg(event_type)
+  AND created_at > sqlc.arg(since)
+ORDER BY created_at ASC;
@@ -0,0 +1,16 @@
+-- name: InsertFSWriteAudit :exec
+INSERT INTO fs_write_audit (bot_id, path, action, metadata)
+VALUES (
+  sqlc.arg(bot_id),
+  sqlc.arg(path),
+  sqlc.arg(action),
+  sqlc.arg(metadata)
+);
+
+-- name: ListFSWriteAudit :many
+SELECT id, bot_id, path, action, metadata, created_at
+FROM fs_write_audit
+WHERE bot_id = sqlc.arg(bot_id)
+  AND (sqlc.arg(path)::text = '' OR path = sqlc.arg(path)::text)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(max_results);
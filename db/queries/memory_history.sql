@@ -0,0 +1,15 @@
+-- name: InsertMemoryHistory :exec
+INSERT INTO memory_history (id, memory_id, event, memory, hash)
+VALUES (
+  sqlc.arg(id),
+  sqlc.arg(memory_id),
+  sqlc.arg(event),
+  sqlc.arg(memory),
+  sqlc.arg(hash)
+);
+
+-- name: ListMemoryHistory :many
+SELECT id, memory_id, event, memory, hash, created_at
+FROM memory_history
+WHERE memory_id = $1
+ORDER BY created_at ASC, id ASC;
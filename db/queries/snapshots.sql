@@ -8,3 +8,6 @@ VALUES (
   sqlc.arg(digest)
 )
 ON CONFLICT (id) DO NOTHING;
+
+-- name: DeleteSnapshot :exec
+DELETE FROM snapshots WHERE id = sqlc.arg(id);
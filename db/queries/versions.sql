@@ -5,14 +5,24 @@ SELECT * FROM container_versions WHERE container_id = sqlc.arg(container_id) ORD
 SELECT COALESCE(MAX(version), 0) + 1 FROM container_versions WHERE container_id = sqlc.arg(container_id);
 
 -- name: InsertVersion :one
-INSERT INTO container_versions (id, container_id, snapshot_id, version)
+INSERT INTO container_versions (id, container_id, snapshot_id, version, label)
 VALUES (
   sqlc.arg(id),
   sqlc.arg(container_id),
   sqlc.arg(snapshot_id),
-  sqlc.arg(version)
+  sqlc.arg(version),
+  sqlc.arg(label)
 )
 RETURNING *;
 
 -- name: GetVersionSnapshotID :one
 SELECT snapshot_id FROM container_versions WHERE container_id = sqlc.arg(container_id) AND version = sqlc.arg(version);
+
+-- name: GetVersionByLabel :one
+SELECT version FROM container_versions WHERE container_id = sqlc.arg(container_id) AND label = sqlc.arg(label);
+
+-- name: SetVersionChangedPaths :exec
+UPDATE container_versions SET changed_paths = sqlc.arg(changed_paths) WHERE id = sqlc.arg(id);
+
+-- name: DeleteVersion :exec
+DELETE FROM container_versions WHERE container_id = sqlc.arg(container_id) AND version = sqlc.arg(version);
@@ -3,6 +3,7 @@ package auth
 import (
 	"fmt"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
 
@@ -20,9 +21,13 @@ const (
 	claimBotID             = "bot_id"
 	claimChatID            = "chat_id"
 	claimRouteID           = "route_id"
+	claimScope             = "scope"
 	chatTokenType          = "chat_route"
 )
 
+// ScopeFSReadOnly limits a token to the read-only container FS endpoints.
+const ScopeFSReadOnly = "fs:readonly"
+
 // JWTMiddleware returns a JWT auth middleware configured for HS256 tokens.
 func JWTMiddleware(secret string, skipper middleware.Skipper) echo.MiddlewareFunc {
 	return echojwt.WithConfig(echojwt.Config{
@@ -55,8 +60,9 @@ func UserIDFromContext(c echo.Context) (string, error) {
 	return "", echo.NewHTTPError(http.StatusUnauthorized, "user id missing")
 }
 
-// GenerateToken creates a signed JWT for the user.
-func GenerateToken(userID, secret string, expiresIn time.Duration) (string, time.Time, error) {
+// GenerateToken creates a signed JWT for the user. Scopes, if any, are stored
+// space-separated in the scope claim.
+func GenerateToken(userID, secret string, expiresIn time.Duration, scopes ...string) (string, time.Time, error) {
 	if strings.TrimSpace(userID) == "" {
 		return "", time.Time{}, fmt.Errorf("user id is required")
 	}
@@ -75,6 +81,9 @@ func GenerateToken(userID, secret string, expiresIn time.Duration) (string, time
 		"iat":        now.Unix(),
 		"exp":        expiresAt.Unix(),
 	}
+	if len(scopes) > 0 {
+		claims[claimScope] = strings.Join(scopes, " ")
+	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	signed, err := token.SignedString([]byte(secret))
 	if err != nil {
@@ -83,6 +92,35 @@ func GenerateToken(userID, secret string, expiresIn time.Duration) (string, time
 	return signed, expiresAt, nil
 }
 
+// Scopes returns the scopes of the request token.
+func Scopes(c echo.Context) []string {
+	token, ok := c.Get("user").(*jwt.Token)
+	if !ok || token == nil {
+		return nil
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil
+	}
+	return strings.Fields(claimString(claims, claimScope))
+}
+
+// IsChatToken reports whether the request token is a chat route token.
+func IsChatToken(c echo.Context) bool {
+	token, ok := c.Get("user").(*jwt.Token)
+	if !ok || token == nil {
+		return false
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	return ok && claimString(claims, claimType) == chatTokenType
+}
+
+// HasScope reports whether the request token carries scope in its
+// space-separated scope claim.
+func HasScope(c echo.Context, scope string) bool {
+	return slices.Contains(Scopes(c), scope)
+}
+
 // ChatToken holds the claims for a chat-based JWT used for route-based reply.
 type ChatToken struct {
 	BotID             string
@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/BurntSushi/toml"
 )
@@ -23,6 +24,9 @@ const (
 	DefaultPGSSLMode        = "disable"
 	DefaultQdrantURL        = "http://127.0.0.1:6334"
 	DefaultQdrantCollection = "memory"
+
+	DefaultStartupRetries              = 5
+	DefaultStartupRetryIntervalSeconds = 2
 )
 
 type Config struct {
@@ -34,16 +38,52 @@ type Config struct {
 	MCP          MCPConfig          `toml:"mcp"`
 	Postgres     PostgresConfig     `toml:"postgres"`
 	Qdrant       QdrantConfig       `toml:"qdrant"`
+	Memory       MemoryConfig       `toml:"memory"`
 	AgentGateway AgentGatewayConfig `toml:"agent_gateway"`
 }
 
 type LogConfig struct {
 	Level  string `toml:"level"`
 	Format string `toml:"format"`
+	// PropagateRequestID forwards the X-Request-ID of each API request to the
+	// LLM and embedding providers and to Qdrant, so their logs can be matched
+	// with ours.
+	PropagateRequestID bool `toml:"propagate_request_id"`
 }
 
 type ServerConfig struct {
 	Addr string `toml:"addr"`
+	// MetricsEnabled exposes Prometheus metrics on /metrics. The endpoint is
+	// not authenticated, so it is off by default; enable it only where the
+	// listen address is not public.
+	MetricsEnabled bool `toml:"metrics_enabled"`
+	// RateLimitPerMinute caps authenticated requests per user; 0 disables it.
+	RateLimitPerMinute int `toml:"rate_limit_per_minute"`
+	// RateLimitRoutes overrides the per-user limit for specific route
+	// templates (e.g. "/bots/:bot_id/chat"). A value of 0 exempts the route.
+	RateLimitRoutes map[string]int `toml:"rate_limit_routes"`
+	// StartupRetries is how many times each dependency (postgres, containerd,
+	// qdrant) is probed at startup before giving up.
+	StartupRetries int `toml:"startup_retries"`
+	// StartupRetryIntervalSeconds is the wait between startup probes.
+	StartupRetryIntervalSeconds int `toml:"startup_retry_interval_seconds"`
+	// DegradedStartup lets the server start when containerd or qdrant are
+	// still unavailable after the retries; their routes answer 503 until the
+	// dependency comes up. Postgres is always required.
+	DegradedStartup bool `toml:"degraded_startup"`
+	// CORSAllowedOrigins lists browser origins allowed to call the API
+	// (e.g. "https://app.example.com"). Empty denies all cross-origin requests.
+	CORSAllowedOrigins []string `toml:"cors_allowed_origins"`
+	// CORSAllowedMethods overrides the allowed methods; empty allows
+	// GET, HEAD, POST, PUT, PATCH and DELETE.
+	CORSAllowedMethods []string `toml:"cors_allowed_methods"`
+	// CORSAllowCredentials allows cookies and Authorization on cross-origin
+	// requests. It cannot be combined with the "*" origin.
+	CORSAllowCredentials bool `toml:"cors_allow_credentials"`
+	// SSEKeepAliveSeconds is how long a streaming response may stay quiet
+	// before a ping comment is sent. 0 uses the default of 15 seconds; a
+	// negative value disables the pings.
+	SSEKeepAliveSeconds int `toml:"sse_keepalive_seconds"`
 }
 
 type AdminConfig struct {
@@ -60,6 +100,10 @@ type AuthConfig struct {
 type ContainerdConfig struct {
 	SocketPath string `toml:"socket_path"`
 	Namespace  string `toml:"namespace"`
+	// TaskLogDir is where the stdout and stderr of bot tasks are written, one
+	// file per container, truncated when the task starts. Task logs are off
+	// when it is empty, the default.
+	TaskLogDir string `toml:"task_log_dir"`
 }
 
 type MCPConfig struct {
@@ -67,6 +111,96 @@ type MCPConfig struct {
 	Snapshotter string `toml:"snapshotter"`
 	DataRoot    string `toml:"data_root"`
 	DataMount   string `toml:"data_mount"`
+	// DataMounts overrides DataMount per image reference, for base images that
+	// expect bot data at a different path.
+	DataMounts map[string]string `toml:"data_mounts"`
+	// MaxFileSize limits the bytes the file tools load at once; 0 uses the default.
+	MaxFileSize int64 `toml:"max_file_size"`
+	// ListMaxEntries caps entries returned by one list call; 0 uses the default.
+	ListMaxEntries int `toml:"list_max_entries"`
+	// ListTimeoutSeconds bounds how long one list call may walk; 0 uses the default.
+	ListTimeoutSeconds int `toml:"list_timeout_seconds"`
+	// SearchMaxMatches caps the matches one search call may ask for; 0 uses
+	// the default of 1000.
+	SearchMaxMatches int `toml:"search_max_matches"`
+	// WritePolicy restricts what files may be written into bot containers.
+	WritePolicy WritePolicyConfig `toml:"write_policy"`
+	// MaxRestarts is how many times in a row a crashed task is restarted
+	// before giving up; 0 disables automatic restarts.
+	MaxRestarts int `toml:"max_restarts"`
+	// RestartBackoffSeconds is the delay before the first restart, doubled
+	// for each further one; 0 uses the default of 1s.
+	RestartBackoffSeconds int `toml:"restart_backoff_seconds"`
+	// KeepVersions prunes a bot's container versions down to the newest
+	// KeepVersions after each new version, keeping labeled versions and
+	// those still in use, which without rollbacks are all older ones; 0
+	// keeps every version.
+	KeepVersions int `toml:"keep_versions"`
+	// IdleTimeoutSeconds stops a bot's container task once the bot has not
+	// executed a command or touched its files for this long; 0 never stops
+	// idle containers.
+	IdleTimeoutSeconds int `toml:"idle_timeout_seconds"`
+	// MaxArchiveBytes caps the file content of one archive download; 0 uses
+	// the default of 1 GiB.
+	MaxArchiveBytes int64 `toml:"max_archive_bytes"`
+	// ReadOnlyFS rejects every mutating container FS and version endpoint
+	// and MCP stdio session with 403, and refuses the write, edit and exec
+	// container tools, leaving read, list and diff available.
+	ReadOnlyFS bool `toml:"read_only_fs"`
+	// CPUQuota is the CFS quota in microseconds per CPUPeriod given to each
+	// new bot container; 0 means unlimited.
+	CPUQuota int64 `toml:"cpu_quota"`
+	// CPUPeriod is the CFS period in microseconds; 0 uses 100000 (100ms).
+	CPUPeriod uint64 `toml:"cpu_period"`
+	// MemoryLimitBytes caps the memory of each new bot container; 0 means
+	// unlimited.
+	MemoryLimitBytes uint64 `toml:"memory_limit_bytes"`
+}
+
+// WritePolicyConfig limits writes into the data mount. The zero value allows
+// everything.
+type WritePolicyConfig struct {
+	// AllowedExtensions, when set, is the only set of extensions that may be
+	// written (e.g. ".md", ".txt"). Use "." for files without an extension.
+	AllowedExtensions []string `toml:"allowed_extensions"`
+	// DeniedExtensions are always refused, even if also allowed.
+	DeniedExtensions []string `toml:"denied_extensions"`
+	// DeniedContentTypes refuses content sniffed as one of these MIME types,
+	// or any type under a prefix ending in "/" (e.g. "video/").
+	DeniedContentTypes []string `toml:"denied_content_types"`
+	// MaxSizeByExtension caps the bytes written per extension; the "*" key
+	// applies to extensions without their own entry.
+	MaxSizeByExtension map[string]int64 `toml:"max_size_by_extension"`
+}
+
+// DataMountFor returns the in-container data mount path for image. An exact
+// match in DataMounts wins, then a match on the image name without its tag or
+// digest; otherwise DataMount (or DefaultDataMount) is used.
+func (c MCPConfig) DataMountFor(image string) string {
+	image = strings.TrimSpace(image)
+	if image != "" && len(c.DataMounts) > 0 {
+		if mount := strings.TrimSpace(c.DataMounts[image]); mount != "" {
+			return mount
+		}
+		if mount := strings.TrimSpace(c.DataMounts[imageName(image)]); mount != "" {
+			return mount
+		}
+	}
+	if mount := strings.TrimSpace(c.DataMount); mount != "" {
+		return mount
+	}
+	return DefaultDataMount
+}
+
+// imageName strips the tag and digest from an image reference.
+func imageName(ref string) string {
+	if idx := strings.Index(ref, "@"); idx >= 0 {
+		ref = ref[:idx]
+	}
+	if idx := strings.LastIndex(ref, ":"); idx > strings.LastIndex(ref, "/") {
+		ref = ref[:idx]
+	}
+	return ref
 }
 
 type PostgresConfig struct {
@@ -85,9 +219,44 @@ type QdrantConfig struct {
 	TimeoutSeconds int    `toml:"timeout_seconds"`
 }
 
+type MemoryConfig struct {
+	ExtractMaxTokens int `toml:"extract_max_tokens"`
+	// AddActionRetries is how often a failed add/update/delete action is retried,
+	// with a doubling backoff from 200ms between attempts.
+	AddActionRetries int `toml:"add_action_retries"`
+	// EmbeddingCacheSize enables an LRU cache of text embeddings; 0 disables it.
+	EmbeddingCacheSize int `toml:"embedding_cache_size"`
+	// DefaultInfer controls whether memory adds run LLM fact extraction when
+	// the caller does not set infer. Unset means true; false stores raw messages.
+	DefaultInfer *bool `toml:"default_infer"`
+	// ExtractPrompt overrides the fact-extraction system prompt. It is a Go
+	// text/template; see memory.PromptTemplates for the placeholders.
+	ExtractPrompt string `toml:"extract_prompt"`
+	// DecidePrompt overrides the add/update/delete decision prompt.
+	DecidePrompt string `toml:"decide_prompt"`
+	// EmbeddingFallback is "fail" (default) or "degrade". With "degrade",
+	// searches fall back to keyword results and adds are stored without a
+	// dense vector and re-embedded once the provider recovers.
+	EmbeddingFallback string `toml:"embedding_fallback"`
+	// FallbackEmbeddingModel is the model id of a second text embedding
+	// model used when the primary one fails. It must have the same
+	// dimensions; empty disables failover.
+	FallbackEmbeddingModel string `toml:"fallback_embedding_model"`
+	// EmbeddingFailureThreshold is how many primary failures in a row send
+	// embeddings straight to the fallback model; 0 uses 3.
+	EmbeddingFailureThreshold int `toml:"embedding_failure_threshold"`
+	// EmbeddingCooldownSeconds is how long the primary is skipped after
+	// that before it is tried again; 0 uses 30.
+	EmbeddingCooldownSeconds int `toml:"embedding_cooldown_seconds"`
+}
+
 type AgentGatewayConfig struct {
 	Host string `toml:"host"`
 	Port int    `toml:"port"`
+	// StreamTerminalEvents lists, per provider client type, the stream event
+	// types that carry the final round messages. "*" applies to providers
+	// without an entry; unset defaults to "done" and "agent_end".
+	StreamTerminalEvents map[string][]string `toml:"stream_terminal_events"`
 }
 
 func (c AgentGatewayConfig) BaseURL() string {
@@ -109,7 +278,9 @@ func Load(path string) (Config, error) {
 			Format: "text",
 		},
 		Server: ServerConfig{
-			Addr: DefaultHTTPAddr,
+			Addr:                        DefaultHTTPAddr,
+			StartupRetries:              DefaultStartupRetries,
+			StartupRetryIntervalSeconds: DefaultStartupRetryIntervalSeconds,
 		},
 		Admin: AdminConfig{
 			Username: "admin",
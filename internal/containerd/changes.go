@@ -0,0 +1,175 @@
+package containerd
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/containerd/continuity/fs"
+)
+
+// PathChangeRenamed is the Kind of a change that moved From to Path.
+const PathChangeRenamed = "renamed"
+
+var (
+	pathChangeAdded   = fs.ChangeKind(fs.ChangeKindAdd).String()
+	pathChangeDeleted = fs.ChangeKind(fs.ChangeKindDelete).String()
+)
+
+// PathChange is a single filesystem change between a snapshot and its parent.
+type PathChange struct {
+	Kind string `json:"kind"`
+	Path string `json:"path"`
+	From string `json:"from,omitempty"`
+}
+
+// errChangeLimit stops the walk once enough changes were collected.
+var errChangeLimit = errors.New("change limit reached")
+
+// SnapshotChanges lists the paths that differ between the committed snapshot
+// key and its parent. Both are mounted read-only through temporary views.
+// When limit > 0 at most limit changes are returned and truncated is set if
+// more exist.
+func SnapshotChanges(ctx context.Context, service Service, snapshotter, key string, limit int) ([]PathChange, bool, error) {
+	if snapshotter == "" || key == "" {
+		return nil, false, ErrInvalidArgument
+	}
+	info, err := service.StatSnapshot(ctx, snapshotter, key)
+	if err != nil {
+		return nil, false, err
+	}
+
+	upperDir, cleanupUpper, err := MountSnapshotView(ctx, service, snapshotter, key)
+	if err != nil {
+		return nil, false, err
+	}
+	defer cleanupUpper()
+
+	lowerDir := ""
+	if info.Parent != "" {
+		dir, cleanupLower, err := MountSnapshotView(ctx, service, snapshotter, info.Parent)
+		if err != nil {
+			return nil, false, err
+		}
+		defer cleanupLower()
+		lowerDir = dir
+	} else {
+		dir, err := os.MkdirTemp("", "memoh-snapshot-empty-*")
+		if err != nil {
+			return nil, false, err
+		}
+		defer os.RemoveAll(dir)
+		lowerDir = dir
+	}
+
+	return DirChanges(ctx, lowerDir, upperDir, limit)
+}
+
+// DirChanges lists the changes that turn the tree at lowerDir into the one at
+// upperDir, with renames detected. limit works as in SnapshotChanges.
+func DirChanges(ctx context.Context, lowerDir, upperDir string, limit int) ([]PathChange, bool, error) {
+	changes := []PathChange{}
+	truncated := false
+	err := fs.Changes(ctx, lowerDir, upperDir, func(kind fs.ChangeKind, path string, _ os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if kind == fs.ChangeKindUnmodified {
+			return nil
+		}
+		if limit > 0 && len(changes) >= limit {
+			truncated = true
+			return errChangeLimit
+		}
+		changes = append(changes, PathChange{Kind: kind.String(), Path: path})
+		return nil
+	})
+	if err != nil && !errors.Is(err, errChangeLimit) {
+		return nil, false, err
+	}
+	return DetectRenames(changes, lowerDir, upperDir), truncated, nil
+}
+
+// DetectRenames folds delete+add pairs of regular files with identical
+// content into a single rename. Deleted paths are read from lowerDir and
+// added paths from upperDir; pairs that cannot be read are left as they are.
+func DetectRenames(changes []PathChange, lowerDir, upperDir string) []PathChange {
+	deleted := map[string]string{} // content hash -> deleted path
+	for _, ch := range changes {
+		if ch.Kind != pathChangeDeleted {
+			continue
+		}
+		if sum, ok := regularFileHash(filepath.Join(lowerDir, ch.Path)); ok {
+			if _, dup := deleted[sum]; !dup {
+				deleted[sum] = ch.Path
+			}
+		}
+	}
+	if len(deleted) == 0 {
+		return changes
+	}
+	renamedFrom := map[string]bool{}
+	out := make([]PathChange, 0, len(changes))
+	for _, ch := range changes {
+		if ch.Kind == pathChangeAdded {
+			if sum, ok := regularFileHash(filepath.Join(upperDir, ch.Path)); ok {
+				if from, ok := deleted[sum]; ok {
+					delete(deleted, sum)
+					renamedFrom[from] = true
+					ch = PathChange{Kind: PathChangeRenamed, Path: ch.Path, From: from}
+				}
+			}
+		}
+		out = append(out, ch)
+	}
+	kept := out[:0]
+	for _, ch := range out {
+		if ch.Kind == pathChangeDeleted && renamedFrom[ch.Path] {
+			continue
+		}
+		kept = append(kept, ch)
+	}
+	return kept
+}
+
+// regularFileHash returns the sha256 of a regular file, or false if p is
+// missing, not a regular file or empty (empty files carry no identity).
+func regularFileHash(p string) (string, bool) {
+	info, err := os.Lstat(p)
+	if err != nil || !info.Mode().IsRegular() || info.Size() == 0 {
+		return "", false
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", false
+	}
+	return fmt.Sprintf("%d:%x", info.Size(), h.Sum(nil)), true
+}
+
+// MountSnapshotView mounts a read-only view of a committed snapshot and
+// returns the mount dir plus a cleanup that unmounts and removes the view.
+func MountSnapshotView(ctx context.Context, service Service, snapshotter, parent string) (string, func(), error) {
+	viewKey := fmt.Sprintf("%s-view-%d", parent, time.Now().UnixNano())
+	if err := service.ViewSnapshot(ctx, snapshotter, viewKey, parent); err != nil {
+		return "", nil, err
+	}
+	dir, unmount, err := MountSnapshot(ctx, service, snapshotter, viewKey)
+	if err != nil {
+		_ = service.RemoveSnapshot(context.WithoutCancel(ctx), snapshotter, viewKey)
+		return "", nil, err
+	}
+	return dir, func() {
+		_ = unmount()
+		_ = service.RemoveSnapshot(context.WithoutCancel(ctx), snapshotter, viewKey)
+	}, nil
+}
@@ -0,0 +1,38 @@
+package containerd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDetectRenames(t *testing.T) {
+	lower, upper := t.TempDir(), t.TempDir()
+	write := func(dir, name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(lower, "notes.md", "same content")
+	write(upper, "renamed.md", "same content")
+	write(lower, "gone.md", "old")
+	write(upper, "fresh.md", "new")
+
+	changes := []PathChange{
+		{Kind: "delete", Path: "/gone.md"},
+		{Kind: "delete", Path: "/notes.md"},
+		{Kind: "add", Path: "/fresh.md"},
+		{Kind: "add", Path: "/renamed.md"},
+	}
+	got := DetectRenames(changes, lower, upper)
+	want := []PathChange{
+		{Kind: "delete", Path: "/gone.md"},
+		{Kind: "add", Path: "/fresh.md"},
+		{Kind: PathChangeRenamed, Path: "/renamed.md", From: "/notes.md"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("DetectRenames = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,153 @@
+package containerd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"syscall"
+	"time"
+
+	containerd "github.com/containerd/containerd/v2/client"
+	"github.com/containerd/containerd/v2/pkg/cio"
+	"github.com/containerd/containerd/v2/pkg/oci"
+	"github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// execProcessSpec loads the container's running task and builds the process
+// spec for an exec from the container spec and req.
+func (s *DefaultService) execProcessSpec(ctx context.Context, containerID string, req ExecTaskRequest) (containerd.Task, *specs.Process, error) {
+	container, err := s.client.LoadContainer(ctx, containerID)
+	if err != nil {
+		return nil, nil, err
+	}
+	spec, err := container.Spec(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if spec.Process == nil {
+		spec.Process = &specs.Process{}
+	}
+	if len(req.Env) > 0 {
+		if err := oci.WithEnv(req.Env)(ctx, nil, nil, spec); err != nil {
+			return nil, nil, err
+		}
+	}
+	spec.Process.Args = req.Args
+	if req.WorkDir != "" {
+		spec.Process.Cwd = req.WorkDir
+	}
+	if req.Terminal {
+		spec.Process.Terminal = true
+	}
+	task, err := container.Task(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return task, spec.Process, nil
+}
+
+// ExecTaskStream runs req.Args in the container with req.Stdin piped to the
+// process and its output copied to req.Stdout and req.Stderr (nil writers
+// discard). The process's stdin is closed once req.Stdin reaches EOF, so
+// commands such as `git apply` that read until EOF finish. It returns after
+// the process exited and all output was copied.
+func (s *DefaultService) ExecTaskStream(ctx context.Context, containerID string, req ExecTaskRequest) (ExecTaskResult, error) {
+	if containerID == "" || len(req.Args) == 0 {
+		return ExecTaskResult{}, ErrInvalidArgument
+	}
+	req.Terminal = false
+
+	ctx = s.withNamespace(ctx)
+	task, procSpec, err := s.execProcessSpec(ctx, containerID, req)
+	if err != nil {
+		return ExecTaskResult{}, err
+	}
+	fifoDir, err := resolveExecFIFODir(req.FIFODir)
+	if err != nil {
+		return ExecTaskResult{}, err
+	}
+
+	stdout, stderr := req.Stdout, req.Stderr
+	if stdout == nil {
+		stdout = io.Discard
+	}
+	if stderr == nil {
+		stderr = io.Discard
+	}
+	var stdin *eofNotifyReader
+	var stdinReader io.Reader
+	if req.Stdin != nil {
+		stdin = newEOFNotifyReader(req.Stdin)
+		stdinReader = stdin
+	}
+	ioCreator := cio.NewCreator(cio.WithStreams(stdinReader, stdout, stderr), cio.WithFIFODir(fifoDir))
+
+	execID := fmt.Sprintf("exec-%d", time.Now().UnixNano())
+	process, err := task.Exec(ctx, execID, procSpec, ioCreator)
+	if err != nil {
+		return ExecTaskResult{}, err
+	}
+	defer process.Delete(context.WithoutCancel(ctx))
+
+	statusC, err := process.Wait(ctx)
+	if err != nil {
+		return ExecTaskResult{}, err
+	}
+	if err := process.Start(ctx); err != nil {
+		return ExecTaskResult{}, err
+	}
+	if stdin != nil {
+		go func() {
+			select {
+			case <-stdin.eof:
+				if err := process.CloseIO(ctx, containerd.WithStdinCloser); err != nil {
+					s.logger.WarnContext(ctx, "close exec stdin failed", slog.String("container_id", containerID), slog.Any("error", err))
+				}
+			case <-ctx.Done():
+			}
+		}()
+	}
+
+	var status containerd.ExitStatus
+	select {
+	case status = <-statusC:
+	case <-ctx.Done():
+		_ = process.Kill(context.WithoutCancel(ctx), syscall.SIGKILL)
+		status = <-statusC
+	}
+	// Let the copy goroutines drain the FIFOs before the caller reads its
+	// writers.
+	if pio := process.IO(); pio != nil {
+		pio.Wait()
+		_ = pio.Close()
+	}
+	if err := ctx.Err(); err != nil {
+		return ExecTaskResult{}, err
+	}
+	code, _, err := status.Result()
+	if err != nil {
+		return ExecTaskResult{}, err
+	}
+	return ExecTaskResult{ExitCode: code}, nil
+}
+
+// eofNotifyReader closes eof once the wrapped reader is exhausted.
+type eofNotifyReader struct {
+	r    io.Reader
+	once sync.Once
+	eof  chan struct{}
+}
+
+func newEOFNotifyReader(r io.Reader) *eofNotifyReader {
+	return &eofNotifyReader{r: r, eof: make(chan struct{})}
+}
+
+func (r *eofNotifyReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if err != nil {
+		r.once.Do(func() { close(r.eof) })
+	}
+	return n, err
+}
@@ -0,0 +1,29 @@
+package containerd
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestEOFNotifyReader(t *testing.T) {
+	r := newEOFNotifyReader(strings.NewReader("patch"))
+	select {
+	case <-r.eof:
+		t.Fatal("eof signalled before reading")
+	default:
+	}
+	data, err := io.ReadAll(r)
+	if err != nil || string(data) != "patch" {
+		t.Fatalf("read %q, %v", data, err)
+	}
+	select {
+	case <-r.eof:
+	default:
+		t.Fatal("expected eof to be signalled")
+	}
+	// Further reads must not close the channel twice.
+	if _, err := r.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatalf("err = %v, want EOF", err)
+	}
+}
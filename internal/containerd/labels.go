@@ -0,0 +1,48 @@
+package containerd
+
+import (
+	"context"
+
+	containerd "github.com/containerd/containerd/v2/client"
+	"github.com/containerd/containerd/v2/core/containers"
+)
+
+// UpdateContainerLabels merges patch into the labels of containerID. Keys with
+// an empty value are removed; labels not mentioned in patch are kept.
+func (s *DefaultService) UpdateContainerLabels(ctx context.Context, containerID string, patch map[string]string) error {
+	if containerID == "" {
+		return ErrInvalidArgument
+	}
+	if len(patch) == 0 {
+		return nil
+	}
+	ctx = s.withNamespace(ctx)
+	container, err := s.client.LoadContainer(ctx, containerID)
+	if err != nil {
+		return err
+	}
+	return container.Update(ctx, withMergedLabels(patch))
+}
+
+// withMergedLabels applies patch on top of the labels containerd currently
+// stores, so concurrent writers of other keys are not clobbered.
+func withMergedLabels(patch map[string]string) containerd.UpdateContainerOpts {
+	return func(ctx context.Context, client *containerd.Client, c *containers.Container) error {
+		return containerd.WithContainerLabels(mergeLabels(c.Labels, patch))(ctx, client, c)
+	}
+}
+
+func mergeLabels(current, patch map[string]string) map[string]string {
+	merged := make(map[string]string, len(current)+len(patch))
+	for k, v := range current {
+		merged[k] = v
+	}
+	for k, v := range patch {
+		if v == "" {
+			delete(merged, k)
+			continue
+		}
+		merged[k] = v
+	}
+	return merged
+}
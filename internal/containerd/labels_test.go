@@ -0,0 +1,34 @@
+package containerd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/containerd/containerd/v2/core/containers"
+)
+
+func TestWithMergedLabels(t *testing.T) {
+	c := &containers.Container{Labels: map[string]string{
+		"memoh.bot_id":  "bot-1",
+		"memoh.version": "3",
+		"memoh.stale":   "yes",
+	}}
+	patch := map[string]string{"memoh.version": "4", "memoh.last_activity": "1700000000", "memoh.stale": ""}
+
+	if err := withMergedLabels(patch)(context.Background(), nil, c); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	want := map[string]string{
+		"memoh.bot_id":        "bot-1",
+		"memoh.version":       "4",
+		"memoh.last_activity": "1700000000",
+	}
+	if len(c.Labels) != len(want) {
+		t.Fatalf("labels = %v, want %v", c.Labels, want)
+	}
+	for k, v := range want {
+		if c.Labels[k] != v {
+			t.Fatalf("labels[%q] = %q, want %q (all: %v)", k, c.Labels[k], v, c.Labels)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package containerd
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	containerd "github.com/containerd/containerd/v2/client"
+	"github.com/containerd/containerd/v2/core/content"
+	"github.com/containerd/containerd/v2/core/images"
+	"github.com/containerd/containerd/v2/pkg/namespaces"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// pullProgressInterval is how often active downloads are polled for progress.
+const pullProgressInterval = 500 * time.Millisecond
+
+// PullProgress reports the download state of one blob (layer, config or
+// manifest) of an image pull.
+type PullProgress struct {
+	ImageRef  string `json:"image_ref"`
+	Digest    string `json:"digest"`
+	MediaType string `json:"media_type"`
+	Offset    int64  `json:"offset"`
+	Total     int64  `json:"total"`
+	Done      bool   `json:"done"`
+}
+
+// pullGroup deduplicates concurrent pulls. Callers asking for an image that
+// is already being pulled with the same options wait for that pull and
+// receive its progress instead of starting their own.
+type pullGroup struct {
+	mu    sync.Mutex
+	calls map[string]*pullCall
+}
+
+type pullCall struct {
+	done  chan struct{}
+	image containerd.Image
+	err   error
+
+	mu       sync.Mutex
+	watchers []chan<- PullProgress
+}
+
+func pullKey(namespace, ref string, opts *PullImageOptions) string {
+	unpack, snapshotter := true, ""
+	if opts != nil {
+		unpack, snapshotter = opts.Unpack, opts.Snapshotter
+	}
+	return namespace + "\x00" + ref + "\x00" + snapshotter + "\x00" + strconv.FormatBool(unpack)
+}
+
+// do runs pull once per key. The shared pull is detached from the caller's
+// cancellation so one caller giving up does not fail the others; each caller
+// still returns as soon as its own ctx is done.
+func (g *pullGroup) do(ctx context.Context, key string, progress chan<- PullProgress, pull func(context.Context, func(PullProgress)) (containerd.Image, error)) (containerd.Image, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = map[string]*pullCall{}
+	}
+	call, running := g.calls[key]
+	if !running {
+		call = &pullCall{done: make(chan struct{})}
+		g.calls[key] = call
+	}
+	g.mu.Unlock()
+
+	call.watch(progress)
+	defer call.unwatch(progress)
+
+	if !running {
+		go func() {
+			call.image, call.err = pull(context.WithoutCancel(ctx), call.report)
+			g.mu.Lock()
+			delete(g.calls, key)
+			g.mu.Unlock()
+			close(call.done)
+		}()
+	}
+
+	select {
+	case <-call.done:
+		return call.image, call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (c *pullCall) watch(ch chan<- PullProgress) {
+	if ch == nil {
+		return
+	}
+	c.mu.Lock()
+	c.watchers = append(c.watchers, ch)
+	c.mu.Unlock()
+}
+
+func (c *pullCall) unwatch(ch chan<- PullProgress) {
+	if ch == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, w := range c.watchers {
+		if w == ch {
+			c.watchers = append(c.watchers[:i], c.watchers[i+1:]...)
+			return
+		}
+	}
+}
+
+// report fans p out to all watchers. Sends never block: a watcher that is
+// not keeping up misses updates rather than stalling the pull.
+func (c *pullCall) report(p PullProgress) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, w := range c.watchers {
+		select {
+		case w <- p:
+		default:
+		}
+	}
+}
+
+// pullProgressTracker follows the blobs fetched by a pull and reports their
+// progress from the content store's active ingestions.
+type pullProgressTracker struct {
+	ref    string
+	store  content.Store
+	report func(PullProgress)
+
+	mu    sync.Mutex
+	descs []ocispec.Descriptor
+	done  map[string]bool
+}
+
+func newPullProgressTracker(ref string, store content.Store, report func(PullProgress)) *pullProgressTracker {
+	return &pullProgressTracker{ref: ref, store: store, report: report, done: map[string]bool{}}
+}
+
+// handler registers every descriptor the pull dispatches.
+func (t *pullProgressTracker) handler() images.Handler {
+	return images.HandlerFunc(func(ctx context.Context, desc ocispec.Descriptor) ([]ocispec.Descriptor, error) {
+		t.mu.Lock()
+		t.descs = append(t.descs, desc)
+		t.mu.Unlock()
+		return nil, nil
+	})
+}
+
+// run polls until ctx is done, then reports a final state.
+func (t *pullProgressTracker) run(ctx context.Context) {
+	ticker := time.NewTicker(pullProgressInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			t.poll(context.WithoutCancel(ctx))
+			return
+		case <-ticker.C:
+			t.poll(ctx)
+		}
+	}
+}
+
+func (t *pullProgressTracker) poll(ctx context.Context) {
+	statuses, err := t.store.ListStatuses(ctx)
+	if err != nil {
+		return
+	}
+	t.mu.Lock()
+	descs := append([]ocispec.Descriptor(nil), t.descs...)
+	t.mu.Unlock()
+	for _, desc := range descs {
+		digest := desc.Digest.String()
+		if t.done[digest] {
+			continue
+		}
+		p := PullProgress{ImageRef: t.ref, Digest: digest, MediaType: desc.MediaType, Total: desc.Size}
+		if st, ok := findIngestStatus(statuses, digest); ok {
+			p.Offset = st.Offset
+			if st.Total > 0 {
+				p.Total = st.Total
+			}
+			t.report(p)
+			continue
+		}
+		if _, err := t.store.Info(ctx, desc.Digest); err == nil {
+			p.Offset, p.Done = desc.Size, true
+			t.done[digest] = true
+			t.report(p)
+		}
+	}
+}
+
+// findIngestStatus matches an ingestion by digest; ingest refs embed the
+// digest of the blob they write.
+func findIngestStatus(statuses []content.Status, digest string) (content.Status, bool) {
+	for _, st := range statuses {
+		if strings.HasSuffix(st.Ref, digest) {
+			return st, true
+		}
+	}
+	return content.Status{}, false
+}
+
+// pullImage performs the actual pull, reporting blob progress to report.
+func (s *DefaultService) pullImage(ctx context.Context, ref string, opts *PullImageOptions, report func(PullProgress)) (containerd.Image, error) {
+	ctx = namespaces.WithNamespace(ctx, s.namespace)
+	pullOpts := []containerd.RemoteOpt{}
+	if opts == nil || opts.Unpack {
+		pullOpts = append(pullOpts, containerd.WithPullUnpack)
+	}
+	if opts != nil && opts.Snapshotter != "" {
+		pullOpts = append(pullOpts, containerd.WithPullSnapshotter(opts.Snapshotter))
+	}
+
+	tracker := newPullProgressTracker(ref, s.client.ContentStore(), report)
+	pullOpts = append(pullOpts, containerd.WithImageHandler(tracker.handler()))
+	trackCtx, stopTracking := context.WithCancel(ctx)
+	tracked := make(chan struct{})
+	go func() {
+		defer close(tracked)
+		tracker.run(trackCtx)
+	}()
+	image, err := s.client.Pull(ctx, ref, pullOpts...)
+	stopTracking()
+	<-tracked
+	return image, err
+}
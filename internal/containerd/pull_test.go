@@ -0,0 +1,109 @@
+package containerd
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	containerd "github.com/containerd/containerd/v2/client"
+)
+
+func TestPullGroupDeduplicates(t *testing.T) {
+	var (
+		g       pullGroup
+		calls   atomic.Int32
+		release = make(chan struct{})
+		started = make(chan struct{})
+	)
+	pull := func(ctx context.Context, report func(PullProgress)) (containerd.Image, error) {
+		calls.Add(1)
+		close(started)
+		<-release
+		report(PullProgress{Digest: "sha256:layer", Done: true})
+		return nil, errors.New("pulled")
+	}
+	key := pullKey("default", "docker.io/library/alpine:latest", &PullImageOptions{Unpack: true})
+
+	progress := []chan PullProgress{make(chan PullProgress, 1), make(chan PullProgress, 1)}
+	var wg sync.WaitGroup
+	errs := make([]error, 3)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, errs[0] = g.do(context.Background(), key, nil, pull)
+	}()
+	<-started
+	for i := 1; i < len(errs); i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = g.do(context.Background(), key, progress[i-1], pull)
+		}(i)
+	}
+	// Wait until both followers joined before letting the pull finish.
+	for {
+		g.mu.Lock()
+		call := g.calls[key]
+		g.mu.Unlock()
+		call.mu.Lock()
+		n := len(call.watchers)
+		call.mu.Unlock()
+		if n == 2 {
+			break
+		}
+	}
+	close(release)
+	wg.Wait()
+
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("pull ran %d times, want 1", n)
+	}
+	for i, err := range errs {
+		if err == nil || err.Error() != "pulled" {
+			t.Fatalf("caller %d got %v", i, err)
+		}
+	}
+	for i, ch := range progress {
+		select {
+		case p := <-ch:
+			if !p.Done || p.Digest != "sha256:layer" {
+				t.Fatalf("progress = %+v", p)
+			}
+		default:
+			t.Fatalf("expected progress for waiting caller %d", i+1)
+		}
+	}
+}
+
+func TestPullGroupCallerCancel(t *testing.T) {
+	var g pullGroup
+	release := make(chan struct{})
+	finished := make(chan struct{})
+	pull := func(ctx context.Context, report func(PullProgress)) (containerd.Image, error) {
+		defer close(finished)
+		<-release
+		return nil, ctx.Err()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := g.do(ctx, "k", nil, pull); !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	close(release)
+	<-finished
+}
+
+func TestPullKey(t *testing.T) {
+	base := pullKey("default", "alpine", &PullImageOptions{Unpack: true})
+	if base != pullKey("default", "alpine", nil) {
+		t.Fatal("nil options should match the unpacking default")
+	}
+	if base == pullKey("other", "alpine", &PullImageOptions{Unpack: true}) {
+		t.Fatal("namespaces must not share pulls")
+	}
+	if base == pullKey("default", "alpine", &PullImageOptions{Unpack: true, Snapshotter: "native"}) {
+		t.Fatal("snapshotters must not share pulls")
+	}
+}
@@ -0,0 +1,35 @@
+package containerd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/containerd/containerd/v2/core/containers"
+	"github.com/containerd/containerd/v2/pkg/oci"
+	"github.com/opencontainers/runtime-spec/specs-go"
+
+	"github.com/memohai/memoh/internal/config"
+)
+
+func TestResourceLimits(t *testing.T) {
+	cfg := config.MCPConfig{CPUQuota: 50000, CPUPeriod: 200000, MemoryLimitBytes: 1 << 30}
+	req := CreateContainerRequest{MemoryLimitBytes: 1 << 20}.WithResourceLimits(cfg)
+	if req.CPUQuota != 50000 || req.CPUPeriod != 200000 || req.MemoryLimitBytes != 1<<20 {
+		t.Fatalf("WithResourceLimits = %+v", req)
+	}
+
+	spec := &oci.Spec{Linux: &specs.Linux{}}
+	for _, opt := range req.resourceSpecOpts() {
+		if err := opt(context.Background(), nil, &containers.Container{}, spec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	res := spec.Linux.Resources
+	if res == nil || *res.Memory.Limit != 1<<20 || *res.CPU.Quota != 50000 || *res.CPU.Period != 200000 {
+		t.Errorf("resources = %+v", res)
+	}
+
+	if opts := (CreateContainerRequest{}).WithResourceLimits(config.MCPConfig{}).resourceSpecOpts(); len(opts) != 0 {
+		t.Errorf("zero limits produced %d spec opts", len(opts))
+	}
+}
@@ -29,7 +29,6 @@ import (
 	"github.com/memohai/memoh/internal/config"
 	"github.com/opencontainers/go-digest"
 	"github.com/opencontainers/image-spec/identity"
-	"github.com/opencontainers/runtime-spec/specs-go"
 )
 
 var (
@@ -40,6 +39,10 @@ var (
 type PullImageOptions struct {
 	Unpack      bool
 	Snapshotter string
+	// Progress, if set, receives blob download progress. Sends never block,
+	// so a slow reader misses updates; nothing is sent after PullImage
+	// returns, and the channel is not closed.
+	Progress chan<- PullProgress
 }
 
 type DeleteImageOptions struct {
@@ -53,6 +56,53 @@ type CreateContainerRequest struct {
 	Snapshotter string
 	Labels      map[string]string
 	SpecOpts    []oci.SpecOpts
+	// CPUQuota is the CFS quota in microseconds per CPUPeriod; 0 means
+	// unlimited.
+	CPUQuota int64
+	// CPUPeriod is the CFS period in microseconds; 0 uses DefaultCPUPeriod.
+	CPUPeriod uint64
+	// CPUShares is the relative CPU weight; 0 keeps the runtime default.
+	CPUShares uint64
+	// MemoryLimitBytes caps the container's memory; 0 means unlimited.
+	MemoryLimitBytes uint64
+}
+
+// DefaultCPUPeriod is the CFS period, in microseconds, that CPUQuota applies
+// to unless CPUPeriod is set.
+const DefaultCPUPeriod uint64 = 100000
+
+// WithResourceLimits returns req with the CPU and memory caps configured in
+// cfg filled in where req leaves them unset.
+func (req CreateContainerRequest) WithResourceLimits(cfg config.MCPConfig) CreateContainerRequest {
+	if req.CPUQuota == 0 {
+		req.CPUQuota = cfg.CPUQuota
+		if req.CPUPeriod == 0 {
+			req.CPUPeriod = cfg.CPUPeriod
+		}
+	}
+	if req.MemoryLimitBytes == 0 {
+		req.MemoryLimitBytes = cfg.MemoryLimitBytes
+	}
+	return req
+}
+
+// resourceSpecOpts translates the request's resource limits into spec opts.
+func (req CreateContainerRequest) resourceSpecOpts() []oci.SpecOpts {
+	var opts []oci.SpecOpts
+	if req.MemoryLimitBytes > 0 {
+		opts = append(opts, oci.WithMemoryLimit(req.MemoryLimitBytes))
+	}
+	if req.CPUQuota > 0 {
+		period := req.CPUPeriod
+		if period == 0 {
+			period = DefaultCPUPeriod
+		}
+		opts = append(opts, oci.WithCPUCFS(req.CPUQuota, period))
+	}
+	if req.CPUShares > 0 {
+		opts = append(opts, oci.WithCPUShares(req.CPUShares))
+	}
+	return opts
 }
 
 type DeleteContainerOptions struct {
@@ -69,6 +119,9 @@ type StopTaskOptions struct {
 	Signal  syscall.Signal
 	Timeout time.Duration
 	Force   bool
+	// Steps, if set, replaces Signal, Timeout and Force with an ordered
+	// escalation, e.g. SIGINT, then SIGTERM, then SIGKILL.
+	Steps []StopStep
 }
 
 type DeleteTaskOptions struct {
@@ -99,6 +152,16 @@ type ExecTaskResult struct {
 	ExitCode uint32
 }
 
+// SnapshotInfo describes one snapshot in a snapshotter.
+type SnapshotInfo struct {
+	Name    string
+	Parent  string
+	Kind    string
+	Created time.Time
+	Updated time.Time
+	Labels  map[string]string
+}
+
 type SnapshotCommitResult struct {
 	VersionSnapshotID string
 	ActiveSnapshotID  string
@@ -130,14 +193,23 @@ type Service interface {
 	StartTask(ctx context.Context, containerID string, opts *StartTaskOptions) (containerd.Task, error)
 	GetTask(ctx context.Context, containerID string) (containerd.Task, error)
 	ListTasks(ctx context.Context, opts *ListTasksOptions) ([]TaskInfo, error)
-	StopTask(ctx context.Context, containerID string, opts *StopTaskOptions) error
+	ListTasksByLabel(ctx context.Context, key string) (map[string]LabeledTask, error)
+	Stats(ctx context.Context, containerID string) (TaskStats, error)
+	StopTask(ctx context.Context, containerID string, opts *StopTaskOptions) (StopTaskResult, error)
 	DeleteTask(ctx context.Context, containerID string, opts *DeleteTaskOptions) error
+	RestartTask(ctx context.Context, containerID string) (containerd.Task, error)
+	TaskLogs(ctx context.Context, containerID string, follow bool) (io.ReadCloser, error)
 	ExecTask(ctx context.Context, containerID string, req ExecTaskRequest) (ExecTaskResult, error)
 	ExecTaskStreaming(ctx context.Context, containerID string, req ExecTaskRequest) (*ExecTaskSession, error)
+	ExecTaskStream(ctx context.Context, containerID string, req ExecTaskRequest) (ExecTaskResult, error)
 	ListContainersByLabel(ctx context.Context, key, value string) ([]containerd.Container, error)
-	CommitSnapshot(ctx context.Context, snapshotter, name, key string) error
-	ListSnapshots(ctx context.Context, snapshotter string) ([]snapshots.Info, error)
+	UpdateContainerLabels(ctx context.Context, containerID string, patch map[string]string) error
+	CommitSnapshot(ctx context.Context, snapshotter, name, key string, labels map[string]string) error
+	ListSnapshots(ctx context.Context, snapshotter, prefix string) ([]SnapshotInfo, error)
 	PrepareSnapshot(ctx context.Context, snapshotter, key, parent string) error
+	ViewSnapshot(ctx context.Context, snapshotter, key, parent string) error
+	StatSnapshot(ctx context.Context, snapshotter, key string) (snapshots.Info, error)
+	RemoveSnapshot(ctx context.Context, snapshotter, key string) error
 	CreateContainerFromSnapshot(ctx context.Context, req CreateContainerRequest) (containerd.Container, error)
 	SnapshotMounts(ctx context.Context, snapshotter, key string) ([]mount.Mount, error)
 }
@@ -145,7 +217,9 @@ type Service interface {
 type DefaultService struct {
 	client    *containerd.Client
 	namespace string
+	logDir    string
 	logger    *slog.Logger
+	pulls     pullGroup
 }
 
 func NewDefaultService(log *slog.Logger, client *containerd.Client, cfg config.Config) *DefaultService {
@@ -156,6 +230,7 @@ func NewDefaultService(log *slog.Logger, client *containerd.Client, cfg config.C
 	return &DefaultService{
 		client:    client,
 		namespace: namespace,
+		logDir:    taskLogDir(cfg),
 		logger:    log.With(slog.String("service", "containerd")),
 	}
 }
@@ -165,16 +240,15 @@ func (s *DefaultService) PullImage(ctx context.Context, ref string, opts *PullIm
 		return nil, ErrInvalidArgument
 	}
 
-	ctx = s.withNamespace(ctx)
-	pullOpts := []containerd.RemoteOpt{}
-	if opts == nil || opts.Unpack {
-		pullOpts = append(pullOpts, containerd.WithPullUnpack)
-	}
-	if opts != nil && opts.Snapshotter != "" {
-		pullOpts = append(pullOpts, containerd.WithPullSnapshotter(opts.Snapshotter))
+	// Concurrent pulls of the same ref, namespace and unpack target share
+	// one download.
+	var progress chan<- PullProgress
+	if opts != nil {
+		progress = opts.Progress
 	}
-
-	return s.client.Pull(ctx, ref, pullOpts...)
+	return s.pulls.do(ctx, pullKey(s.namespace, ref, opts), progress, func(ctx context.Context, report func(PullProgress)) (containerd.Image, error) {
+		return s.pullImage(ctx, ref, opts, report)
+	})
 }
 
 func (s *DefaultService) GetImage(ctx context.Context, ref string) (containerd.Image, error) {
@@ -207,6 +281,7 @@ func (s *DefaultService) CreateContainer(ctx context.Context, req CreateContaine
 		return nil, ErrInvalidArgument
 	}
 
+	s.logger.DebugContext(ctx, "create container", slog.String("container_id", req.ID), slog.String("image", req.ImageRef))
 	ctx = s.withNamespace(ctx)
 	ctx, done, err := s.client.WithLease(ctx)
 	if err != nil {
@@ -236,6 +311,7 @@ func (s *DefaultService) CreateContainer(ctx context.Context, req CreateContaine
 	if len(req.SpecOpts) > 0 {
 		specOpts = append(specOpts, req.SpecOpts...)
 	}
+	specOpts = append(specOpts, req.resourceSpecOpts()...)
 
 	containerOpts := []containerd.NewContainerOpts{
 		containerd.WithImage(image),
@@ -387,6 +463,7 @@ func (s *DefaultService) DeleteContainer(ctx context.Context, id string, opts *D
 		return ErrInvalidArgument
 	}
 
+	s.logger.DebugContext(ctx, "delete container", slog.String("container_id", id))
 	ctx = s.withNamespace(ctx)
 	container, err := s.client.LoadContainer(ctx, id)
 	if err != nil {
@@ -410,6 +487,7 @@ func (s *DefaultService) StartTask(ctx context.Context, containerID string, opts
 		return nil, ErrInvalidArgument
 	}
 
+	s.logger.DebugContext(ctx, "start task", slog.String("container_id", containerID))
 	ctx = s.withNamespace(ctx)
 	container, err := s.client.LoadContainer(ctx, containerID)
 	if err != nil {
@@ -418,7 +496,7 @@ func (s *DefaultService) StartTask(ctx context.Context, containerID string, opts
 
 	var ioCreator cio.Creator
 	if opts == nil || !opts.UseStdio {
-		ioCreator = cio.NullIO
+		ioCreator = s.taskLogIO(ctx, containerID)
 	} else {
 		cioOpts := []cio.Opt{cio.WithStdio}
 		if opts.Terminal {
@@ -479,55 +557,27 @@ func (s *DefaultService) ListTasks(ctx context.Context, opts *ListTasksOptions)
 	return tasks, nil
 }
 
-func (s *DefaultService) StopTask(ctx context.Context, containerID string, opts *StopTaskOptions) error {
+func (s *DefaultService) StopTask(ctx context.Context, containerID string, opts *StopTaskOptions) (StopTaskResult, error) {
 	if containerID == "" {
-		return ErrInvalidArgument
+		return StopTaskResult{}, ErrInvalidArgument
 	}
 
+	s.logger.DebugContext(ctx, "stop task", slog.String("container_id", containerID))
 	ctx = s.withNamespace(ctx)
 	task, err := s.GetTask(ctx, containerID)
 	if err != nil {
-		return err
-	}
-
-	signal := syscall.SIGTERM
-	timeout := 10 * time.Second
-	force := false
-	if opts != nil {
-		if opts.Signal != 0 {
-			signal = opts.Signal
-		}
-		if opts.Timeout != 0 {
-			timeout = opts.Timeout
-		}
-		force = opts.Force
-	}
-
-	if err := task.Kill(ctx, signal); err != nil {
-		return err
+		return StopTaskResult{}, err
 	}
 
+	// Wait before signalling so an immediate exit is not missed.
 	statusC, err := task.Wait(ctx)
 	if err != nil {
-		return err
+		return StopTaskResult{}, err
 	}
-
-	timer := time.NewTimer(timeout)
-	defer timer.Stop()
-
-	select {
-	case <-statusC:
-		return nil
-	case <-timer.C:
-		if force {
-			if err := task.Kill(ctx, syscall.SIGKILL); err != nil {
-				return fmt.Errorf("force kill failed: %w", err)
-			}
-			<-statusC
-			return nil
-		}
-		return ErrTaskStopTimeout
+	kill := func(signal syscall.Signal) error {
+		return task.Kill(ctx, signal)
 	}
+	return runStopSteps(ctx, kill, statusC, opts.steps())
 }
 
 func (s *DefaultService) DeleteTask(ctx context.Context, containerID string, opts *DeleteTaskOptions) error {
@@ -535,6 +585,7 @@ func (s *DefaultService) DeleteTask(ctx context.Context, containerID string, opt
 		return ErrInvalidArgument
 	}
 
+	s.logger.DebugContext(ctx, "delete task", slog.String("container_id", containerID))
 	ctx = s.withNamespace(ctx)
 	task, err := s.GetTask(ctx, containerID)
 	if err != nil {
@@ -549,40 +600,35 @@ func (s *DefaultService) DeleteTask(ctx context.Context, containerID string, opt
 	return err
 }
 
-func (s *DefaultService) ExecTask(ctx context.Context, containerID string, req ExecTaskRequest) (ExecTaskResult, error) {
-	if containerID == "" || len(req.Args) == 0 {
-		return ExecTaskResult{}, ErrInvalidArgument
+// RestartTask deletes the container's task, killing it if it is still
+// running, and starts a fresh one from the same container the way StartTask
+// does without options.
+func (s *DefaultService) RestartTask(ctx context.Context, containerID string) (containerd.Task, error) {
+	if containerID == "" {
+		return nil, ErrInvalidArgument
 	}
 
+	s.logger.DebugContext(ctx, "restart task", slog.String("container_id", containerID))
 	ctx = s.withNamespace(ctx)
-	container, err := s.client.LoadContainer(ctx, containerID)
-	if err != nil {
-		return ExecTaskResult{}, err
-	}
-
-	spec, err := container.Spec(ctx)
-	if err != nil {
-		return ExecTaskResult{}, err
-	}
-	if spec.Process == nil {
-		spec.Process = &specs.Process{}
-	}
-
-	if len(req.Env) > 0 {
-		if err := oci.WithEnv(req.Env)(ctx, nil, nil, spec); err != nil {
-			return ExecTaskResult{}, err
+	task, err := s.GetTask(ctx, containerID)
+	switch {
+	case err == nil:
+		if _, err := task.Delete(ctx, containerd.WithProcessKill); err != nil && !errdefs.IsNotFound(err) {
+			return nil, fmt.Errorf("delete task: %w", err)
 		}
+	case !errdefs.IsNotFound(err):
+		return nil, err
 	}
+	return s.StartTask(ctx, containerID, nil)
+}
 
-	spec.Process.Args = req.Args
-	if req.WorkDir != "" {
-		spec.Process.Cwd = req.WorkDir
-	}
-	if req.Terminal {
-		spec.Process.Terminal = true
+func (s *DefaultService) ExecTask(ctx context.Context, containerID string, req ExecTaskRequest) (ExecTaskResult, error) {
+	if containerID == "" || len(req.Args) == 0 {
+		return ExecTaskResult{}, ErrInvalidArgument
 	}
 
-	task, err := container.Task(ctx, nil)
+	ctx = s.withNamespace(ctx)
+	task, procSpec, err := s.execProcessSpec(ctx, containerID, req)
 	if err != nil {
 		return ExecTaskResult{}, err
 	}
@@ -605,7 +651,7 @@ func (s *DefaultService) ExecTask(ctx context.Context, containerID string, req E
 	ioCreator := cio.NewCreator(ioOpts...)
 
 	execID := fmt.Sprintf("exec-%d", time.Now().UnixNano())
-	process, err := task.Exec(ctx, execID, spec.Process, ioCreator)
+	process, err := task.Exec(ctx, execID, procSpec, ioCreator)
 	if err != nil {
 		return ExecTaskResult{}, err
 	}
@@ -634,32 +680,7 @@ func (s *DefaultService) ExecTaskStreaming(ctx context.Context, containerID stri
 	}
 
 	ctx = s.withNamespace(ctx)
-	container, err := s.client.LoadContainer(ctx, containerID)
-	if err != nil {
-		return nil, err
-	}
-
-	spec, err := container.Spec(ctx)
-	if err != nil {
-		return nil, err
-	}
-	if spec.Process == nil {
-		spec.Process = &specs.Process{}
-	}
-	if len(req.Env) > 0 {
-		if err := oci.WithEnv(req.Env)(ctx, nil, nil, spec); err != nil {
-			return nil, err
-		}
-	}
-	spec.Process.Args = req.Args
-	if req.WorkDir != "" {
-		spec.Process.Cwd = req.WorkDir
-	}
-	if req.Terminal {
-		spec.Process.Terminal = true
-	}
-
-	task, err := container.Task(ctx, nil)
+	task, procSpec, err := s.execProcessSpec(ctx, containerID, req)
 	if err != nil {
 		return nil, err
 	}
@@ -688,7 +709,7 @@ func (s *DefaultService) ExecTaskStreaming(ctx context.Context, containerID stri
 	ioCreator := cio.NewCreator(ioOpts...)
 
 	execID := fmt.Sprintf("exec-%d", time.Now().UnixNano())
-	process, err := task.Exec(ctx, execID, spec.Process, ioCreator)
+	process, err := task.Exec(ctx, execID, procSpec, ioCreator)
 	if err != nil {
 		_ = stdinR.Close()
 		_ = stdinW.Close()
@@ -791,22 +812,39 @@ func (s *DefaultService) ListContainersByLabel(ctx context.Context, key, value s
 	return filtered, nil
 }
 
-func (s *DefaultService) CommitSnapshot(ctx context.Context, snapshotter, name, key string) error {
+// CommitSnapshot commits the active snapshot key as name, setting labels on
+// the committed snapshot.
+func (s *DefaultService) CommitSnapshot(ctx context.Context, snapshotter, name, key string, labels map[string]string) error {
 	if snapshotter == "" || name == "" || key == "" {
 		return ErrInvalidArgument
 	}
 	ctx = s.withNamespace(ctx)
-	return s.client.SnapshotService(snapshotter).Commit(ctx, name, key)
+	var opts []snapshots.Opt
+	if len(labels) > 0 {
+		opts = append(opts, snapshots.WithLabels(labels))
+	}
+	return s.client.SnapshotService(snapshotter).Commit(ctx, name, key, opts...)
 }
 
-func (s *DefaultService) ListSnapshots(ctx context.Context, snapshotter string) ([]snapshots.Info, error) {
+// ListSnapshots walks the snapshotter and returns the snapshots whose name
+// starts with prefix, or all of them when prefix is empty.
+func (s *DefaultService) ListSnapshots(ctx context.Context, snapshotter, prefix string) ([]SnapshotInfo, error) {
 	if snapshotter == "" {
 		return nil, ErrInvalidArgument
 	}
 	ctx = s.withNamespace(ctx)
-	infos := []snapshots.Info{}
+	infos := []SnapshotInfo{}
 	if err := s.client.SnapshotService(snapshotter).Walk(ctx, func(ctx context.Context, info snapshots.Info) error {
-		infos = append(infos, info)
+		if strings.HasPrefix(info.Name, prefix) {
+			infos = append(infos, SnapshotInfo{
+				Name:    info.Name,
+				Parent:  info.Parent,
+				Kind:    info.Kind.String(),
+				Created: info.Created,
+				Updated: info.Updated,
+				Labels:  info.Labels,
+			})
+		}
 		return nil
 	}); err != nil {
 		return nil, err
@@ -823,6 +861,32 @@ func (s *DefaultService) PrepareSnapshot(ctx context.Context, snapshotter, key,
 	return err
 }
 
+// ViewSnapshot creates a read-only view of parent under key.
+func (s *DefaultService) ViewSnapshot(ctx context.Context, snapshotter, key, parent string) error {
+	if snapshotter == "" || key == "" || parent == "" {
+		return ErrInvalidArgument
+	}
+	ctx = s.withNamespace(ctx)
+	_, err := s.client.SnapshotService(snapshotter).View(ctx, key, parent)
+	return err
+}
+
+func (s *DefaultService) StatSnapshot(ctx context.Context, snapshotter, key string) (snapshots.Info, error) {
+	if snapshotter == "" || key == "" {
+		return snapshots.Info{}, ErrInvalidArgument
+	}
+	ctx = s.withNamespace(ctx)
+	return s.client.SnapshotService(snapshotter).Stat(ctx, key)
+}
+
+func (s *DefaultService) RemoveSnapshot(ctx context.Context, snapshotter, key string) error {
+	if snapshotter == "" || key == "" {
+		return ErrInvalidArgument
+	}
+	ctx = s.withNamespace(ctx)
+	return s.client.SnapshotService(snapshotter).Remove(ctx, key)
+}
+
 func (s *DefaultService) CreateContainerFromSnapshot(ctx context.Context, req CreateContainerRequest) (containerd.Container, error) {
 	if req.ID == "" || req.SnapshotID == "" {
 		return nil, ErrInvalidArgument
@@ -853,6 +917,7 @@ func (s *DefaultService) CreateContainerFromSnapshot(ctx context.Context, req Cr
 	if len(req.SpecOpts) > 0 {
 		specOpts = append(specOpts, req.SpecOpts...)
 	}
+	specOpts = append(specOpts, req.resourceSpecOpts()...)
 
 	containerOpts := []containerd.NewContainerOpts{
 		containerd.WithImage(image),
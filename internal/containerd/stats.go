@@ -0,0 +1,220 @@
+package containerd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	containerd "github.com/containerd/containerd/v2/client"
+	"github.com/containerd/errdefs"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// TaskStats is a snapshot of a container task's resource usage. All fields are
+// zero when Running is false.
+type TaskStats struct {
+	Running          bool      `json:"running"`
+	PID              uint32    `json:"pid,omitempty"`
+	CPUUsageNanos    uint64    `json:"cpu_usage_nanos"`
+	MemoryUsageBytes uint64    `json:"memory_usage_bytes"`
+	MemoryLimitBytes uint64    `json:"memory_limit_bytes"`
+	Pids             uint64    `json:"pids"`
+	PidsLimit        uint64    `json:"pids_limit"`
+	Timestamp        time.Time `json:"timestamp,omitempty"`
+}
+
+// Stats returns resource usage of the container's task. A missing or stopped
+// task is not an error; it yields TaskStats{Running: false}.
+func (s *DefaultService) Stats(ctx context.Context, containerID string) (TaskStats, error) {
+	if containerID == "" {
+		return TaskStats{}, ErrInvalidArgument
+	}
+	ctx = s.withNamespace(ctx)
+	task, err := s.GetTask(ctx, containerID)
+	if err != nil {
+		if errdefs.IsNotFound(err) {
+			return TaskStats{}, nil
+		}
+		return TaskStats{}, err
+	}
+	status, err := task.Status(ctx)
+	if err != nil {
+		if errdefs.IsNotFound(err) {
+			return TaskStats{}, nil
+		}
+		return TaskStats{}, err
+	}
+	if status.Status != containerd.Running {
+		return TaskStats{}, nil
+	}
+	metric, err := task.Metrics(ctx)
+	if err != nil {
+		if errdefs.IsNotFound(err) {
+			return TaskStats{}, nil
+		}
+		return TaskStats{}, err
+	}
+	stats := TaskStats{Running: true, PID: task.Pid()}
+	if metric.GetTimestamp() != nil {
+		stats.Timestamp = metric.GetTimestamp().AsTime()
+	}
+	if data := metric.GetData(); data != nil {
+		if err := parseCgroupMetrics(data.GetTypeUrl(), data.GetValue(), &stats); err != nil {
+			return TaskStats{}, err
+		}
+	}
+	return stats, nil
+}
+
+// Field numbers of the cgroup metrics messages published by
+// github.com/containerd/cgroups/v3 (cgroup1/stats and cgroup2/stats). They are
+// decoded by hand to avoid pulling in the cgroups module for four numbers.
+const (
+	cgroupV1MetricsType = "io.containerd.cgroups.v1.Metrics"
+	cgroupV2MetricsType = "io.containerd.cgroups.v2.Metrics"
+
+	v1MetricsPids   = 2
+	v1MetricsCPU    = 3
+	v1MetricsMemory = 4
+	v1CPUUsage      = 1
+	v1CPUUsageTotal = 1
+	v1MemoryUsage   = 32
+	v1EntryLimit    = 1
+	v1EntryUsage    = 2
+
+	v2MetricsPids      = 1
+	v2MetricsCPU       = 2
+	v2MetricsMemory    = 4
+	v2CPUUsageUsec     = 1
+	v2MemoryUsage      = 32
+	v2MemoryUsageLimit = 33
+
+	pidsCurrent = 1
+	pidsLimit   = 2
+)
+
+func parseCgroupMetrics(typeURL string, data []byte, stats *TaskStats) error {
+	switch {
+	case strings.HasSuffix(typeURL, cgroupV1MetricsType):
+		return parseCgroupV1Metrics(data, stats)
+	case strings.HasSuffix(typeURL, cgroupV2MetricsType):
+		return parseCgroupV2Metrics(data, stats)
+	default:
+		return fmt.Errorf("unsupported metrics type %q", typeURL)
+	}
+}
+
+func parseCgroupV1Metrics(data []byte, stats *TaskStats) error {
+	return walkFields(data, func(num protowire.Number, v fieldValue) error {
+		switch num {
+		case v1MetricsPids:
+			return parsePids(v.bytes, stats)
+		case v1MetricsCPU:
+			return walkFields(v.bytes, func(num protowire.Number, v fieldValue) error {
+				if num != v1CPUUsage {
+					return nil
+				}
+				return walkFields(v.bytes, func(num protowire.Number, v fieldValue) error {
+					if num == v1CPUUsageTotal {
+						stats.CPUUsageNanos = v.varint
+					}
+					return nil
+				})
+			})
+		case v1MetricsMemory:
+			return walkFields(v.bytes, func(num protowire.Number, v fieldValue) error {
+				if num != v1MemoryUsage {
+					return nil
+				}
+				return walkFields(v.bytes, func(num protowire.Number, v fieldValue) error {
+					switch num {
+					case v1EntryLimit:
+						stats.MemoryLimitBytes = v.varint
+					case v1EntryUsage:
+						stats.MemoryUsageBytes = v.varint
+					}
+					return nil
+				})
+			})
+		}
+		return nil
+	})
+}
+
+func parseCgroupV2Metrics(data []byte, stats *TaskStats) error {
+	return walkFields(data, func(num protowire.Number, v fieldValue) error {
+		switch num {
+		case v2MetricsPids:
+			return parsePids(v.bytes, stats)
+		case v2MetricsCPU:
+			return walkFields(v.bytes, func(num protowire.Number, v fieldValue) error {
+				if num == v2CPUUsageUsec {
+					stats.CPUUsageNanos = v.varint * uint64(time.Microsecond)
+				}
+				return nil
+			})
+		case v2MetricsMemory:
+			return walkFields(v.bytes, func(num protowire.Number, v fieldValue) error {
+				switch num {
+				case v2MemoryUsage:
+					stats.MemoryUsageBytes = v.varint
+				case v2MemoryUsageLimit:
+					stats.MemoryLimitBytes = v.varint
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+}
+
+func parsePids(data []byte, stats *TaskStats) error {
+	return walkFields(data, func(num protowire.Number, v fieldValue) error {
+		switch num {
+		case pidsCurrent:
+			stats.Pids = v.varint
+		case pidsLimit:
+			stats.PidsLimit = v.varint
+		}
+		return nil
+	})
+}
+
+// fieldValue holds a decoded varint or length-delimited field; other wire
+// types are skipped.
+type fieldValue struct {
+	varint uint64
+	bytes  []byte
+}
+
+func walkFields(data []byte, fn func(protowire.Number, fieldValue) error) error {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return fmt.Errorf("decode metrics: %w", protowire.ParseError(n))
+		}
+		data = data[n:]
+		var v fieldValue
+		switch typ {
+		case protowire.VarintType:
+			v.varint, n = protowire.ConsumeVarint(data)
+		case protowire.BytesType:
+			v.bytes, n = protowire.ConsumeBytes(data)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, data)
+			if n >= 0 {
+				data = data[n:]
+				continue
+			}
+		}
+		if n < 0 {
+			return fmt.Errorf("decode metrics: %w", protowire.ParseError(n))
+		}
+		data = data[n:]
+		if err := fn(num, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package containerd
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func varintField(num protowire.Number, v uint64) []byte {
+	b := protowire.AppendTag(nil, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+func messageField(num protowire.Number, fields ...[]byte) []byte {
+	var body []byte
+	for _, f := range fields {
+		body = append(body, f...)
+	}
+	b := protowire.AppendTag(nil, num, protowire.BytesType)
+	return protowire.AppendBytes(b, body)
+}
+
+func concat(parts ...[]byte) []byte {
+	var out []byte
+	for _, p := range parts {
+		out = append(out, p...)
+	}
+	return out
+}
+
+func TestParseCgroupMetrics(t *testing.T) {
+	v2 := concat(
+		messageField(v2MetricsPids, varintField(pidsCurrent, 7), varintField(pidsLimit, 100)),
+		messageField(v2MetricsCPU, varintField(v2CPUUsageUsec, 1500), varintField(2, 999)),
+		messageField(v2MetricsMemory, varintField(1, 1), varintField(v2MemoryUsage, 4096), varintField(v2MemoryUsageLimit, 8192)),
+		messageField(6, []byte("ignored")),
+	)
+	var got TaskStats
+	if err := parseCgroupMetrics("types.containerd.io/"+cgroupV2MetricsType, v2, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := TaskStats{CPUUsageNanos: 1_500_000, MemoryUsageBytes: 4096, MemoryLimitBytes: 8192, Pids: 7, PidsLimit: 100}
+	if got != want {
+		t.Fatalf("v2 stats = %+v, want %+v", got, want)
+	}
+
+	v1 := concat(
+		messageField(v1MetricsPids, varintField(pidsCurrent, 3)),
+		messageField(v1MetricsCPU, messageField(v1CPUUsage, varintField(v1CPUUsageTotal, 123456))),
+		messageField(v1MetricsMemory, varintField(2, 5), messageField(v1MemoryUsage, varintField(v1EntryLimit, 2048), varintField(v1EntryUsage, 1024))),
+	)
+	got = TaskStats{}
+	if err := parseCgroupMetrics(cgroupV1MetricsType, v1, &got); err != nil {
+		t.Fatal(err)
+	}
+	want = TaskStats{CPUUsageNanos: 123456, MemoryUsageBytes: 1024, MemoryLimitBytes: 2048, Pids: 3}
+	if got != want {
+		t.Fatalf("v1 stats = %+v, want %+v", got, want)
+	}
+
+	if err := parseCgroupMetrics("io.containerd.windows.Stats", nil, &got); err == nil {
+		t.Fatal("expected unknown metrics type to fail")
+	}
+	if err := parseCgroupMetrics(cgroupV2MetricsType, []byte{0x0a, 0x05, 0x08}, &got); err == nil {
+		t.Fatal("expected truncated metrics to fail")
+	}
+}
@@ -0,0 +1,88 @@
+package containerd
+
+import (
+	"context"
+	"fmt"
+	"syscall"
+	"time"
+
+	containerd "github.com/containerd/containerd/v2/client"
+	"github.com/containerd/errdefs"
+)
+
+// StopStep is one stage of a stop escalation: Signal is sent and the task
+// gets GracePeriod to exit before the next step runs. A zero GracePeriod
+// waits until the task exits or the context ends.
+type StopStep struct {
+	Signal      syscall.Signal
+	GracePeriod time.Duration
+}
+
+// StopTaskResult tells which step stopped the task.
+type StopTaskResult struct {
+	// Step indexes the effective steps (StopTaskOptions.Steps, or the steps
+	// derived from Signal, Timeout and Force).
+	Step   int
+	Signal syscall.Signal
+}
+
+// steps returns the escalation to run. Without explicit Steps it is the
+// single Signal (SIGTERM by default) with Timeout (10s by default),
+// followed by an unbounded SIGKILL when Force is set.
+func (o *StopTaskOptions) steps() []StopStep {
+	if o != nil && len(o.Steps) > 0 {
+		return o.Steps
+	}
+	first := StopStep{Signal: syscall.SIGTERM, GracePeriod: 10 * time.Second}
+	force := false
+	if o != nil {
+		if o.Signal != 0 {
+			first.Signal = o.Signal
+		}
+		if o.Timeout != 0 {
+			first.GracePeriod = o.Timeout
+		}
+		force = o.Force
+	}
+	steps := []StopStep{first}
+	if force {
+		steps = append(steps, StopStep{Signal: syscall.SIGKILL})
+	}
+	return steps
+}
+
+// runStopSteps sends each step's signal until statusC reports the exit.
+// It fails with ErrTaskStopTimeout when the last grace period runs out.
+func runStopSteps(ctx context.Context, kill func(syscall.Signal) error, statusC <-chan containerd.ExitStatus, steps []StopStep) (StopTaskResult, error) {
+	for i, step := range steps {
+		result := StopTaskResult{Step: i, Signal: step.Signal}
+		if err := kill(step.Signal); err != nil {
+			// The task may have exited on the previous signal just now.
+			if errdefs.IsNotFound(err) && i > 0 {
+				select {
+				case <-statusC:
+					return StopTaskResult{Step: i - 1, Signal: steps[i-1].Signal}, nil
+				default:
+				}
+			}
+			if i == 0 {
+				return result, err
+			}
+			return result, fmt.Errorf("send %s: %w", step.Signal, err)
+		}
+		var timeout <-chan time.Time
+		if step.GracePeriod > 0 {
+			timer := time.NewTimer(step.GracePeriod)
+			defer timer.Stop()
+			timeout = timer.C
+		}
+		select {
+		case <-statusC:
+			return result, nil
+		case <-ctx.Done():
+			return result, ctx.Err()
+		case <-timeout:
+		}
+	}
+	return StopTaskResult{Step: len(steps) - 1, Signal: steps[len(steps)-1].Signal}, ErrTaskStopTimeout
+}
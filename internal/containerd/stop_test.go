@@ -0,0 +1,62 @@
+package containerd
+
+import (
+	"context"
+	"errors"
+	"syscall"
+	"testing"
+	"time"
+
+	containerd "github.com/containerd/containerd/v2/client"
+)
+
+func TestStopTaskOptionsSteps(t *testing.T) {
+	var nilOpts *StopTaskOptions
+	if got := nilOpts.steps(); len(got) != 1 || got[0] != (StopStep{Signal: syscall.SIGTERM, GracePeriod: 10 * time.Second}) {
+		t.Fatalf("default steps = %+v", got)
+	}
+	got := (&StopTaskOptions{Timeout: time.Second, Force: true}).steps()
+	want := []StopStep{{Signal: syscall.SIGTERM, GracePeriod: time.Second}, {Signal: syscall.SIGKILL}}
+	if len(got) != 2 || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("forced steps = %+v", got)
+	}
+	custom := []StopStep{{Signal: syscall.SIGINT, GracePeriod: time.Second}}
+	if got := (&StopTaskOptions{Signal: syscall.SIGHUP, Steps: custom}).steps(); len(got) != 1 || got[0] != custom[0] {
+		t.Fatalf("explicit steps = %+v", got)
+	}
+}
+
+func TestRunStopSteps(t *testing.T) {
+	steps := []StopStep{
+		{Signal: syscall.SIGINT, GracePeriod: 10 * time.Millisecond},
+		{Signal: syscall.SIGTERM, GracePeriod: 10 * time.Millisecond},
+		{Signal: syscall.SIGKILL},
+	}
+	// exitOn returns a kill func that makes the task exit on signal.
+	exitOn := func(signal syscall.Signal) (func(syscall.Signal) error, chan containerd.ExitStatus, *[]syscall.Signal) {
+		statusC := make(chan containerd.ExitStatus, 1)
+		var sent []syscall.Signal
+		return func(s syscall.Signal) error {
+			sent = append(sent, s)
+			if s == signal {
+				statusC <- containerd.ExitStatus{}
+			}
+			return nil
+		}, statusC, &sent
+	}
+
+	kill, statusC, sent := exitOn(syscall.SIGTERM)
+	result, err := runStopSteps(context.Background(), kill, statusC, steps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Step != 1 || result.Signal != syscall.SIGTERM || len(*sent) != 2 {
+		t.Fatalf("result = %+v, sent %v", result, *sent)
+	}
+
+	kill, statusC, _ = exitOn(syscall.SIGKILL)
+	result, err = runStopSteps(context.Background(), kill, statusC, steps[:2])
+	if !errors.Is(err, ErrTaskStopTimeout) || result.Step != 1 {
+		t.Fatalf("result = %+v, err = %v; want timeout on the last step", result, err)
+	}
+}
@@ -0,0 +1,148 @@
+package containerd
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	containerd "github.com/containerd/containerd/v2/client"
+	"github.com/containerd/containerd/v2/pkg/cio"
+	"github.com/containerd/errdefs"
+	"github.com/memohai/memoh/internal/config"
+)
+
+var (
+	// ErrTaskNotRunning is returned by TaskLogs when the container has no
+	// running task.
+	ErrTaskNotRunning = errors.New("task is not running")
+	// ErrNoTaskLogs is returned by TaskLogs when the task was started without
+	// a log file, e.g. because task logs are disabled.
+	ErrNoTaskLogs = errors.New("task has no log file")
+)
+
+// taskLogPollInterval is how often a followed log is checked for new output.
+const taskLogPollInterval = 250 * time.Millisecond
+
+// taskLogDir returns the absolute task log directory configured in cfg, or
+// "" when task logs are disabled, which they are unless a directory is set.
+func taskLogDir(cfg config.Config) string {
+	dir := strings.TrimSpace(cfg.Containerd.TaskLogDir)
+	if dir == "" {
+		return ""
+	}
+	// The shim opens the file itself, so a relative path would resolve
+	// against its working directory rather than ours.
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	return abs
+}
+
+func (s *DefaultService) taskLogPath(containerID string) string {
+	return filepath.Join(s.logDir, containerID+".log")
+}
+
+// taskLogIO returns an IO creator that has the shim write the task's stdout
+// and stderr to its log file, starting the file afresh. It falls back to
+// discarding output when logs are disabled or the file cannot be prepared.
+func (s *DefaultService) taskLogIO(ctx context.Context, containerID string) cio.Creator {
+	if s.logDir == "" {
+		return cio.NullIO
+	}
+	path := s.taskLogPath(containerID)
+	if err := os.MkdirAll(s.logDir, 0o750); err != nil {
+		s.logger.WarnContext(ctx, "task log dir unavailable, discarding output", slog.String("container_id", containerID), slog.Any("error", err))
+		return cio.NullIO
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		s.logger.WarnContext(ctx, "remove old task log failed", slog.String("container_id", containerID), slog.Any("error", err))
+	}
+	return cio.LogFile(path)
+}
+
+// TaskLogs returns the combined stdout and stderr the container's running
+// task has written so far. With follow set the reader keeps returning new
+// output until the task exits or ctx is done. It fails with
+// ErrTaskNotRunning rather than waiting for a task to appear.
+func (s *DefaultService) TaskLogs(ctx context.Context, containerID string, follow bool) (io.ReadCloser, error) {
+	if containerID == "" {
+		return nil, ErrInvalidArgument
+	}
+	ctx = s.withNamespace(ctx)
+	task, err := s.GetTask(ctx, containerID)
+	if err != nil {
+		if errdefs.IsNotFound(err) {
+			return nil, ErrTaskNotRunning
+		}
+		return nil, err
+	}
+	status, err := task.Status(ctx)
+	if err != nil {
+		if errdefs.IsNotFound(err) {
+			return nil, ErrTaskNotRunning
+		}
+		return nil, err
+	}
+	if status.Status != containerd.Running {
+		return nil, ErrTaskNotRunning
+	}
+	if s.logDir == "" {
+		return nil, ErrNoTaskLogs
+	}
+	f, err := os.Open(s.taskLogPath(containerID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNoTaskLogs
+		}
+		return nil, err
+	}
+	if !follow {
+		return f, nil
+	}
+	exited, err := task.Wait(ctx)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return &followReader{f: f, ctx: ctx, exited: exited, poll: taskLogPollInterval}, nil
+}
+
+// followReader reads a file that is still being appended to, waiting at
+// the end for more data until exited fires or ctx is done.
+type followReader struct {
+	f      *os.File
+	ctx    context.Context
+	exited <-chan containerd.ExitStatus
+	poll   time.Duration
+	// drained is set once the writer is gone; the next EOF is final.
+	drained bool
+}
+
+func (r *followReader) Read(p []byte) (int, error) {
+	for {
+		n, err := r.f.Read(p)
+		if n > 0 || !errors.Is(err, io.EOF) || r.drained {
+			return n, err
+		}
+		timer := time.NewTimer(r.poll)
+		select {
+		case <-r.ctx.Done():
+			timer.Stop()
+			return 0, r.ctx.Err()
+		case <-r.exited:
+			timer.Stop()
+			r.drained = true
+		case <-timer.C:
+		}
+	}
+}
+
+func (r *followReader) Close() error {
+	return r.f.Close()
+}
@@ -0,0 +1,64 @@
+package containerd
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	containerd "github.com/containerd/containerd/v2/client"
+	"github.com/memohai/memoh/internal/config"
+)
+
+func TestFollowReader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "task.log")
+	w, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	if _, err := w.WriteString("first\n"); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exited := make(chan containerd.ExitStatus)
+	r := &followReader{f: f, ctx: context.Background(), exited: exited, poll: time.Millisecond}
+	defer r.Close()
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		_, _ = w.WriteString("second\n")
+		time.Sleep(20 * time.Millisecond)
+		_, _ = w.WriteString("last\n")
+		close(exited)
+	}()
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "first\nsecond\nlast\n" {
+		t.Errorf("followed output = %q", got)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r = &followReader{f: f, ctx: ctx, poll: time.Millisecond}
+	if _, err := r.Read(make([]byte, 8)); err != context.Canceled {
+		t.Errorf("read after cancel = %v, want context.Canceled", err)
+	}
+}
+
+func TestTaskLogDir(t *testing.T) {
+	if dir := taskLogDir(config.Config{MCP: config.MCPConfig{DataRoot: "data"}}); dir != "" {
+		t.Errorf("task logs enabled by default: %q", dir)
+	}
+	dir := taskLogDir(config.Config{Containerd: config.ContainerdConfig{TaskLogDir: "data/task-logs"}})
+	if !filepath.IsAbs(dir) || filepath.Base(dir) != "task-logs" {
+		t.Errorf("configured task log dir = %q", dir)
+	}
+}
@@ -0,0 +1,50 @@
+package containerd
+
+import (
+	"context"
+	"strconv"
+)
+
+// LabeledTask joins a labeled container with its task. Task is nil when the
+// container has no task.
+type LabeledTask struct {
+	ContainerID string
+	Labels      map[string]string
+	Task        *TaskInfo
+}
+
+// ListTasksByLabel lists every container carrying the label key together
+// with its task, keyed by the label value. Containers and tasks are each
+// fetched with a single call and correlated by container ID.
+func (s *DefaultService) ListTasksByLabel(ctx context.Context, key string) (map[string]LabeledTask, error) {
+	if key == "" {
+		return nil, ErrInvalidArgument
+	}
+	ctx = s.withNamespace(ctx)
+	containers, err := s.client.ContainerService().List(ctx, "labels."+strconv.Quote(key))
+	if err != nil {
+		return nil, err
+	}
+	tasks, err := s.ListTasks(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	byContainer := make(map[string]TaskInfo, len(tasks))
+	for _, task := range tasks {
+		byContainer[task.ContainerID] = task
+	}
+
+	out := make(map[string]LabeledTask, len(containers))
+	for _, c := range containers {
+		value := c.Labels[key]
+		if value == "" {
+			continue
+		}
+		entry := LabeledTask{ContainerID: c.ID, Labels: c.Labels}
+		if task, ok := byContainer[c.ID]; ok {
+			entry.Task = &task
+		}
+		out[value] = entry
+	}
+	return out, nil
+}
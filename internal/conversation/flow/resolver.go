@@ -1,10 +1,10 @@
 package flow
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -18,8 +18,10 @@ import (
 	"github.com/memohai/memoh/internal/conversation"
 	"github.com/memohai/memoh/internal/db"
 	"github.com/memohai/memoh/internal/db/sqlc"
+	"github.com/memohai/memoh/internal/logger"
 	"github.com/memohai/memoh/internal/memory"
 	messagepkg "github.com/memohai/memoh/internal/message"
+	"github.com/memohai/memoh/internal/metrics"
 	"github.com/memohai/memoh/internal/models"
 	"github.com/memohai/memoh/internal/schedule"
 	"github.com/memohai/memoh/internal/settings"
@@ -65,6 +67,7 @@ type Resolver struct {
 	logger          *slog.Logger
 	httpClient      *http.Client
 	streamingClient *http.Client
+	terminalEvents  map[string][]string
 }
 
 // NewResolver creates a Resolver that communicates with the agent gateway.
@@ -288,8 +291,10 @@ func (r *Resolver) Chat(ctx context.Context, req conversation.ChatRequest) (conv
 	if err != nil {
 		return conversation.ChatResponse{}, err
 	}
+	metrics.ChatRequests.WithLabelValues("sync").Inc()
 	resp, err := r.postChat(ctx, rc.payload, req.Token)
 	if err != nil {
+		metrics.GatewayErrors.WithLabelValues("sync").Inc()
 		return conversation.ChatResponse{}, err
 	}
 	if err := r.storeRound(ctx, req, resp.Messages); err != nil {
@@ -387,7 +392,9 @@ func (r *Resolver) StreamChat(ctx context.Context, req conversation.ChatRequest)
 			}
 			streamReq.UserMessagePersisted = true
 		}
+		metrics.ChatRequests.WithLabelValues("stream").Inc()
 		if err := r.streamChat(ctx, rc.payload, streamReq, chunkCh); err != nil {
+			metrics.GatewayErrors.WithLabelValues("stream").Inc()
 			r.logger.Error("gateway stream request failed",
 				slog.String("bot_id", streamReq.BotID),
 				slog.String("chat_id", streamReq.ChatID),
@@ -407,13 +414,14 @@ func (r *Resolver) postChat(ctx context.Context, payload gatewayRequest, token s
 		return gatewayResponse{}, err
 	}
 	url := r.gatewayBaseURL + "/chat/"
-	r.logger.Info("gateway request", slog.String("url", url), slog.String("body_prefix", truncate(string(body), 200)))
+	r.logger.Info("gateway request", slog.String("url", url), slog.String("request_id", logger.RequestIDFromContext(ctx)), slog.String("body_prefix", truncate(string(body), 200)))
 
 	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
 	if err != nil {
 		return gatewayResponse{}, err
 	}
 	httpReq.Header.Set("Content-Type", "application/json")
+	setRequestIDHeader(ctx, httpReq)
 	if strings.TrimSpace(token) != "" {
 		httpReq.Header.Set("Authorization", token)
 	}
@@ -441,6 +449,14 @@ func (r *Resolver) postChat(ctx context.Context, payload gatewayRequest, token s
 	return parsed, nil
 }
 
+// setRequestIDHeader forwards the inbound request id to the agent gateway so
+// logs on both sides can be correlated.
+func setRequestIDHeader(ctx context.Context, httpReq *http.Request) {
+	if requestID := logger.RequestIDFromContext(ctx); requestID != "" {
+		httpReq.Header.Set(logger.RequestIDHeader, requestID)
+	}
+}
+
 // postTriggerSchedule sends a trigger-schedule request to the agent gateway.
 func (r *Resolver) postTriggerSchedule(ctx context.Context, payload triggerScheduleRequest, token string) (gatewayResponse, error) {
 	body, err := json.Marshal(payload)
@@ -455,6 +471,7 @@ func (r *Resolver) postTriggerSchedule(ctx context.Context, payload triggerSched
 		return gatewayResponse{}, err
 	}
 	httpReq.Header.Set("Content-Type", "application/json")
+	setRequestIDHeader(ctx, httpReq)
 	if strings.TrimSpace(token) != "" {
 		httpReq.Header.Set("Authorization", token)
 	}
@@ -488,12 +505,13 @@ func (r *Resolver) streamChat(ctx context.Context, payload gatewayRequest, req c
 		return err
 	}
 	url := r.gatewayBaseURL + "/chat/stream"
-	r.logger.Info("gateway stream request", slog.String("url", url), slog.String("body_prefix", truncate(string(body), 200)))
+	r.logger.Info("gateway stream request", slog.String("url", url), slog.String("request_id", logger.RequestIDFromContext(ctx)), slog.String("body_prefix", truncate(string(body), 200)))
 	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
 	if err != nil {
 		return err
 	}
 	httpReq.Header.Set("Content-Type", "application/json")
+	setRequestIDHeader(ctx, httpReq)
 	httpReq.Header.Set("Accept", "text/event-stream")
 	if strings.TrimSpace(req.Token) != "" {
 		httpReq.Header.Set("Authorization", req.Token)
@@ -512,48 +530,113 @@ func (r *Resolver) streamChat(ctx context.Context, payload gatewayRequest, req c
 		return fmt.Errorf("agent gateway error: %s", strings.TrimSpace(string(errBody)))
 	}
 
-	scanner := bufio.NewScanner(resp.Body)
-	scanner.Buffer(make([]byte, 0, 64*1024), 2*1024*1024)
-
-	currentEvent := ""
+	reader := newSSEReader(resp.Body)
+	terminal := r.terminalEventsFor(payload.Model.ClientType)
 	stored := false
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" {
-			continue
+	var round []conversation.ModelMessage
+	var partial streamTextAssembler
+	// storePartial keeps the text already delivered to the client when the
+	// stream ends or breaks off before a terminal event.
+	storePartial := func() {
+		if stored {
+			return
 		}
-		if strings.HasPrefix(line, "event:") {
-			currentEvent = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
-			continue
+		messages := partial.messages()
+		if len(messages) == 0 {
+			return
 		}
-		if !strings.HasPrefix(line, "data:") {
-			continue
+		stored = true
+		r.logger.Warn("gateway stream ended without a terminal event, storing assembled output",
+			slog.String("bot_id", req.BotID),
+			slog.String("chat_id", req.ChatID),
+		)
+		storeCtx := context.WithoutCancel(ctx)
+		if req.MemoryEvents {
+			round = r.persistRound(storeCtx, req, messages)
+			return
+		}
+		if err := r.storeRound(storeCtx, req, messages); err != nil {
+			r.logger.Error("store assembled stream round failed", slog.Any("error", err))
+		}
+	}
+	for {
+		event, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			storePartial()
+			if req.MemoryEvents && len(round) > 0 {
+				return r.streamMemoryEvent(ctx, req, round, chunkCh)
+			}
+			return nil
 		}
-		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if err != nil {
+			storePartial()
+			return err
+		}
+		data := strings.TrimSpace(event.Data)
 		if data == "" || data == "[DONE]" {
 			continue
 		}
-		chunkCh <- conversation.StreamChunk([]byte(data))
+		select {
+		case chunkCh <- conversation.StreamChunk([]byte(data)):
+		case <-ctx.Done():
+			storePartial()
+			return ctx.Err()
+		}
 
 		if stored {
 			continue
 		}
-		if handled, storeErr := r.tryStoreStream(ctx, req, currentEvent, data); storeErr != nil {
-			return storeErr
-		} else if handled {
-			stored = true
+		partial.add(data)
+		messages, ok := streamRoundMessages(event.Event, data, terminal)
+		if !ok {
+			continue
+		}
+		stored = true
+		if req.MemoryEvents {
+			// Memory is extracted once the stream ends so its summary can be
+			// sent as the final event.
+			round = r.persistRound(ctx, req, messages)
+			continue
+		}
+		if err := r.storeRound(ctx, req, messages); err != nil {
+			return err
 		}
 	}
-	return scanner.Err()
 }
 
-// tryStoreStream attempts to extract final messages from a stream event and persist them.
-func (r *Resolver) tryStoreStream(ctx context.Context, req conversation.ChatRequest, eventType, data string) (bool, error) {
+// memoryStreamEvent is the final chunk of a stream requested with MemoryEvents.
+type memoryStreamEvent struct {
+	Type string `json:"type"`
+	memory.AddSummary
+}
+
+// streamMemoryEvent stores memory for round synchronously and sends the
+// resulting summary as the last chunk of the stream.
+func (r *Resolver) streamMemoryEvent(ctx context.Context, req conversation.ChatRequest, round []conversation.ModelMessage, chunkCh chan<- conversation.StreamChunk) error {
+	summary, ok := r.storeMemory(context.WithoutCancel(ctx), req, round)
+	if !ok {
+		return nil
+	}
+	data, err := json.Marshal(memoryStreamEvent{Type: conversation.StreamEventMemory, AddSummary: summary})
+	if err != nil {
+		return err
+	}
+	select {
+	case chunkCh <- conversation.StreamChunk(data):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// streamRoundMessages extracts the final round messages from a stream event.
+// terminal lists the event types that carry the round.
+func streamRoundMessages(eventType, data string, terminal []string) ([]conversation.ModelMessage, bool) {
 	// event: done + data: {messages: [...]}
-	if eventType == "done" {
+	if isTerminalEvent(eventType, terminal) {
 		var resp gatewayResponse
 		if err := json.Unmarshal([]byte(data), &resp); err == nil && len(resp.Messages) > 0 {
-			return true, r.storeRound(ctx, req, resp.Messages)
+			return resp.Messages, true
 		}
 	}
 
@@ -565,13 +648,13 @@ func (r *Resolver) tryStoreStream(ctx context.Context, req conversation.ChatRequ
 		Skills   []string                    `json:"skills"`
 	}
 	if err := json.Unmarshal([]byte(data), &envelope); err == nil {
-		if (envelope.Type == "agent_end" || envelope.Type == "done") && len(envelope.Messages) > 0 {
-			return true, r.storeRound(ctx, req, envelope.Messages)
+		if isTerminalEvent(envelope.Type, terminal) && len(envelope.Messages) > 0 {
+			return envelope.Messages, true
 		}
-		if envelope.Type == "done" && len(envelope.Data) > 0 {
+		if isTerminalEvent(envelope.Type, terminal) && len(envelope.Data) > 0 {
 			var resp gatewayResponse
 			if err := json.Unmarshal(envelope.Data, &resp); err == nil && len(resp.Messages) > 0 {
-				return true, r.storeRound(ctx, req, resp.Messages)
+				return resp.Messages, true
 			}
 		}
 	}
@@ -579,9 +662,9 @@ func (r *Resolver) tryStoreStream(ctx context.Context, req conversation.ChatRequ
 	// fallback: data: {messages: [...]}
 	var resp gatewayResponse
 	if err := json.Unmarshal([]byte(data), &resp); err == nil && len(resp.Messages) > 0 {
-		return true, r.storeRound(ctx, req, resp.Messages)
+		return resp.Messages, true
 	}
-	return false, nil
+	return nil, false
 }
 
 // --- container resolution ---
@@ -749,6 +832,19 @@ func (r *Resolver) persistUserMessage(ctx context.Context, req conversation.Chat
 }
 
 func (r *Resolver) storeRound(ctx context.Context, req conversation.ChatRequest, messages []conversation.ModelMessage) error {
+	fullRound := r.persistRound(ctx, req, messages)
+	if len(fullRound) == 0 {
+		return nil
+	}
+	// Run memory extraction in the background so that the SSE stream can
+	// finish immediately after messages are persisted.
+	go r.storeMemory(context.WithoutCancel(ctx), req, fullRound)
+	return nil
+}
+
+// persistRound stores the round's messages and returns them, including the
+// user query when it was not persisted before.
+func (r *Resolver) persistRound(ctx context.Context, req conversation.ChatRequest, messages []conversation.ModelMessage) []conversation.ModelMessage {
 	// Add user query as the first message if not already present in the round.
 	// This ensures the user's prompt is persisted alongside the assistant's response.
 	fullRound := make([]conversation.ModelMessage, 0, len(messages)+1)
@@ -777,10 +873,7 @@ func (r *Resolver) storeRound(ctx context.Context, req conversation.ChatRequest,
 	}
 
 	r.storeMessages(ctx, req, fullRound)
-	// Run memory extraction in the background so that the SSE stream can
-	// finish immediately after messages are persisted.
-	go r.storeMemory(context.WithoutCancel(ctx), req.BotID, fullRound)
-	return nil
+	return fullRound
 }
 
 func (r *Resolver) storeMessages(ctx context.Context, req conversation.ChatRequest, messages []conversation.ModelMessage) {
@@ -942,13 +1035,17 @@ func (r *Resolver) resolveDisplayName(ctx context.Context, req conversation.Chat
 	return "User"
 }
 
-func (r *Resolver) storeMemory(ctx context.Context, botID string, messages []conversation.ModelMessage) {
+// storeMemory extracts memories from a round. ok is false when nothing was
+// attempted (no memory service, bot or text content).
+func (r *Resolver) storeMemory(ctx context.Context, req conversation.ChatRequest, messages []conversation.ModelMessage) (summary memory.AddSummary, ok bool) {
 	if r.memoryService == nil {
-		return
+		return memory.AddSummary{}, false
 	}
+	botID := req.BotID
 	if strings.TrimSpace(botID) == "" {
-		return
+		return memory.AddSummary{}, false
 	}
+	timestamp := time.Now().UTC().Format(time.RFC3339)
 	memMsgs := make([]memory.Message, 0, len(messages))
 	for _, msg := range messages {
 		text := strings.TrimSpace(msg.TextContent())
@@ -959,31 +1056,64 @@ func (r *Resolver) storeMemory(ctx context.Context, botID string, messages []con
 		if strings.TrimSpace(role) == "" {
 			role = "assistant"
 		}
-		memMsgs = append(memMsgs, memory.Message{Role: role, Content: text})
+		memMsgs = append(memMsgs, memory.Message{
+			Role:     role,
+			Content:  text,
+			Metadata: memoryMessageMetadata(req, msg, timestamp),
+		})
 	}
 	if len(memMsgs) == 0 {
-		return
+		return memory.AddSummary{}, false
 	}
-	r.addMemory(ctx, botID, memMsgs, sharedMemoryNamespace, botID)
+	return r.addMemory(ctx, botID, memMsgs, sharedMemoryNamespace, botID), true
 }
 
-func (r *Resolver) addMemory(ctx context.Context, botID string, msgs []memory.Message, namespace, scopeID string) {
+// memoryMessageMetadata keeps the context that flattening a model message to
+// role+content would otherwise drop, so it ends up on derived memories.
+func memoryMessageMetadata(req conversation.ChatRequest, msg conversation.ModelMessage, timestamp string) map[string]any {
+	meta := map[string]any{
+		"source":    "chat",
+		"timestamp": timestamp,
+	}
+	if platform := strings.TrimSpace(req.CurrentChannel); platform != "" {
+		meta["platform"] = platform
+	}
+	if name := strings.TrimSpace(msg.Name); name != "" {
+		meta["tool_name"] = name
+	}
+	if len(msg.ToolCalls) > 0 {
+		toolNames := make([]any, 0, len(msg.ToolCalls))
+		for _, call := range msg.ToolCalls {
+			if name := strings.TrimSpace(call.Function.Name); name != "" {
+				toolNames = append(toolNames, name)
+			}
+		}
+		if len(toolNames) > 0 {
+			meta["tool_calls"] = toolNames
+		}
+	}
+	return meta
+}
+
+func (r *Resolver) addMemory(ctx context.Context, botID string, msgs []memory.Message, namespace, scopeID string) memory.AddSummary {
 	filters := map[string]any{
 		"namespace": namespace,
 		"scopeId":   scopeID,
 		"bot_id":    botID,
 	}
-	if _, err := r.memoryService.Add(ctx, memory.AddRequest{
+	resp, err := r.memoryService.Add(ctx, memory.AddRequest{
 		Messages: msgs,
 		BotID:    botID,
 		Filters:  filters,
-	}); err != nil {
+	})
+	if err != nil {
 		r.logger.Warn("store memory failed",
 			slog.String("namespace", namespace),
 			slog.String("scope_id", scopeID),
 			slog.Any("error", err),
 		)
 	}
+	return memory.SummarizeAdd(resp, err)
 }
 
 // --- model selection ---
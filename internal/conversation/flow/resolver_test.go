@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/memohai/memoh/internal/conversation"
+	"github.com/memohai/memoh/internal/logger"
 )
 
 func TestPostTriggerSchedule_Endpoint(t *testing.T) {
@@ -164,3 +165,67 @@ func TestPostTriggerSchedule_GatewayError(t *testing.T) {
 		t.Fatal("expected error for 500 response")
 	}
 }
+
+func TestPostChat_ForwardsRequestID(t *testing.T) {
+	var capturedRequestID string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		capturedRequestID = r.Header.Get(logger.RequestIDHeader)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(gatewayResponse{})
+	}))
+	defer srv.Close()
+
+	resolver := &Resolver{
+		gatewayBaseURL: srv.URL,
+		httpClient:     &http.Client{Timeout: 5 * time.Second},
+		logger:         slog.Default(),
+	}
+
+	ctx := logger.WithRequestID(context.Background(), "req-123")
+	if _, err := resolver.postChat(ctx, gatewayRequest{}, ""); err != nil {
+		t.Fatalf("postChat: %v", err)
+	}
+	if capturedRequestID != "req-123" {
+		t.Errorf("expected request id to be forwarded, got %q", capturedRequestID)
+	}
+}
+
+func TestStreamRoundMessages(t *testing.T) {
+	cases := []struct {
+		name  string
+		event string
+		data  string
+		ok    bool
+	}{
+		{name: "done event", event: "done", data: `{"messages":[{"role":"assistant","content":"hi"}]}`, ok: true},
+		{name: "agent_end envelope", data: `{"type":"agent_end","messages":[{"role":"assistant","content":"hi"}]}`, ok: true},
+		{name: "done envelope data", data: `{"type":"done","data":{"messages":[{"role":"assistant","content":"hi"}]}}`, ok: true},
+		{name: "text delta", data: `{"type":"text_delta","delta":"h"}`, ok: false},
+		{name: "not json", data: `hello`, ok: false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			messages, ok := streamRoundMessages(tc.event, tc.data, defaultStreamTerminalEvents)
+			if ok != tc.ok {
+				t.Fatalf("ok = %v, want %v", ok, tc.ok)
+			}
+			if ok && (len(messages) != 1 || messages[0].TextContent() != "hi") {
+				t.Fatalf("unexpected messages: %+v", messages)
+			}
+		})
+	}
+}
+
+func TestStreamRoundMessages_ConfiguredTerminalEvents(t *testing.T) {
+	resolver := &Resolver{}
+	resolver.SetStreamTerminalEvents(map[string][]string{"ollama": {"finish"}})
+
+	data := `{"type":"finish","data":{"messages":[{"role":"assistant","content":"hi"}]}}`
+	if _, ok := streamRoundMessages("", data, resolver.terminalEventsFor("openai")); ok {
+		t.Fatal("finish should not be terminal for providers without an override")
+	}
+	messages, ok := streamRoundMessages("", data, resolver.terminalEventsFor("Ollama"))
+	if !ok || len(messages) != 1 || messages[0].TextContent() != "hi" {
+		t.Fatalf("expected configured terminal event to yield the round, got ok=%v %+v", ok, messages)
+	}
+}
@@ -0,0 +1,76 @@
+package flow
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+)
+
+// sseEvent is a single dispatched server-sent event.
+type sseEvent struct {
+	Event string
+	Data  string
+}
+
+// sseReader parses a text/event-stream body. Unlike bufio.Scanner it has no
+// line length cap, so a single data field may be arbitrarily large. Multiple
+// data lines belonging to one event are joined with "\n" as per the SSE spec.
+type sseReader struct {
+	r *bufio.Reader
+}
+
+func newSSEReader(r io.Reader) *sseReader {
+	return &sseReader{r: bufio.NewReaderSize(r, 64*1024)}
+}
+
+// Next returns the next event with a non-empty data field. It returns io.EOF
+// once the stream ends and no pending event remains.
+func (s *sseReader) Next() (sseEvent, error) {
+	var (
+		event   string
+		data    strings.Builder
+		hasData bool
+	)
+	for {
+		line, err := s.r.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return sseEvent{}, err
+		}
+		eof := err != nil
+		line = strings.TrimRight(line, "\r\n")
+
+		if line == "" {
+			if hasData {
+				return sseEvent{Event: event, Data: data.String()}, nil
+			}
+			if eof {
+				return sseEvent{}, io.EOF
+			}
+			event = ""
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			event = strings.TrimSpace(value)
+		case "data":
+			if hasData {
+				data.WriteByte('\n')
+			}
+			data.WriteString(value)
+			hasData = true
+		}
+		// Lines starting with ":" are comments; other fields (id, retry) are
+		// not used by the gateway and are ignored.
+
+		if eof {
+			if hasData {
+				return sseEvent{Event: event, Data: data.String()}, nil
+			}
+			return sseEvent{}, io.EOF
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package flow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/memohai/memoh/internal/conversation"
+	messagepkg "github.com/memohai/memoh/internal/message"
+)
+
+type fakeMessageService struct {
+	messagepkg.Service
+	mu        sync.Mutex
+	persisted []messagepkg.PersistInput
+}
+
+func (f *fakeMessageService) Persist(_ context.Context, input messagepkg.PersistInput) (messagepkg.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.persisted = append(f.persisted, input)
+	return messagepkg.Message{}, nil
+}
+
+func TestSSEReader_MultiLineData(t *testing.T) {
+	body := ": comment\nevent: delta\ndata: first\ndata: second\n\ndata: third\r\n\r\ndata: tail"
+	reader := newSSEReader(strings.NewReader(body))
+
+	want := []sseEvent{
+		{Event: "delta", Data: "first\nsecond"},
+		{Data: "third"},
+		{Data: "tail"},
+	}
+	for i, w := range want {
+		got, err := reader.Next()
+		if err != nil {
+			t.Fatalf("event %d: unexpected error: %v", i, err)
+		}
+		if got != w {
+			t.Fatalf("event %d: got %+v, want %+v", i, got, w)
+		}
+	}
+	if _, err := reader.Next(); err != io.EOF {
+		t.Fatalf("expected io.EOF, got %v", err)
+	}
+}
+
+func TestStreamChat_LargeEvent(t *testing.T) {
+	largeText := strings.Repeat("x", 3*1024*1024)
+	done := gatewayResponse{
+		Messages: []conversation.ModelMessage{{Role: "assistant", Content: conversation.NewTextContent(largeText)}},
+	}
+	payload, err := json.Marshal(done)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "event: done\ndata: %s\n\n", payload)
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer srv.Close()
+
+	messages := &fakeMessageService{}
+	resolver := &Resolver{
+		gatewayBaseURL:  srv.URL,
+		streamingClient: &http.Client{Timeout: 10 * time.Second},
+		messageService:  messages,
+		logger:          slog.Default(),
+	}
+
+	chunkCh := make(chan conversation.StreamChunk, 4)
+	req := conversation.ChatRequest{BotID: "bot-1", UserMessagePersisted: true}
+	if err := resolver.streamChat(context.Background(), gatewayRequest{}, req, chunkCh); err != nil {
+		t.Fatalf("streamChat: %v", err)
+	}
+	close(chunkCh)
+
+	var chunks []conversation.StreamChunk
+	for chunk := range chunkCh {
+		chunks = append(chunks, chunk)
+	}
+	if len(chunks) != 1 {
+		t.Fatalf("expected 1 chunk, got %d", len(chunks))
+	}
+	if string(chunks[0]) != string(payload) {
+		t.Fatalf("chunk was not delivered intact: got %d bytes, want %d", len(chunks[0]), len(payload))
+	}
+
+	if len(messages.persisted) != 1 {
+		t.Fatalf("expected 1 persisted message, got %d", len(messages.persisted))
+	}
+	var stored conversation.ModelMessage
+	if err := json.Unmarshal(messages.persisted[0].Content, &stored); err != nil {
+		t.Fatalf("decode stored message: %v", err)
+	}
+	if stored.TextContent() != largeText {
+		t.Fatalf("stored message was truncated: got %d bytes, want %d", len(stored.TextContent()), len(largeText))
+	}
+}
+
+func TestStreamChat_StoresAssembledTextWithoutDoneEvent(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"type\":\"text_start\"}\n\n")
+		fmt.Fprint(w, "data: {\"type\":\"text_delta\",\"delta\":\"hello \"}\n\n")
+		fmt.Fprint(w, "data: {\"type\":\"reasoning_delta\",\"delta\":\"thinking\"}\n\n")
+		fmt.Fprint(w, "data: {\"type\":\"text_delta\",\"delta\":\"world\"}\n\n")
+	}))
+	defer srv.Close()
+
+	messages := &fakeMessageService{}
+	resolver := &Resolver{
+		gatewayBaseURL:  srv.URL,
+		streamingClient: &http.Client{Timeout: 10 * time.Second},
+		messageService:  messages,
+		logger:          slog.Default(),
+	}
+
+	chunkCh := make(chan conversation.StreamChunk, 8)
+	req := conversation.ChatRequest{BotID: "bot-1", UserMessagePersisted: true}
+	if err := resolver.streamChat(context.Background(), gatewayRequest{}, req, chunkCh); err != nil {
+		t.Fatalf("streamChat: %v", err)
+	}
+	close(chunkCh)
+	if n := len(chunkCh); n != 4 {
+		t.Fatalf("expected 4 chunks delivered, got %d", n)
+	}
+
+	if len(messages.persisted) != 1 {
+		t.Fatalf("expected 1 persisted message, got %d", len(messages.persisted))
+	}
+	var stored conversation.ModelMessage
+	if err := json.Unmarshal(messages.persisted[0].Content, &stored); err != nil {
+		t.Fatalf("decode stored message: %v", err)
+	}
+	if stored.Role != "assistant" || stored.TextContent() != "hello world" {
+		t.Fatalf("unexpected stored message: role=%q text=%q", stored.Role, stored.TextContent())
+	}
+}
@@ -0,0 +1,71 @@
+package flow
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+
+	"github.com/memohai/memoh/internal/conversation"
+)
+
+// defaultStreamTerminalEvents are the event types that carry the final round
+// when no per-provider override is configured.
+var defaultStreamTerminalEvents = []string{"done", "agent_end"}
+
+// SetStreamTerminalEvents configures which stream event types carry the final
+// round messages, keyed by provider client type. The "*" key applies to
+// providers without their own entry.
+func (r *Resolver) SetStreamTerminalEvents(events map[string][]string) {
+	normalized := make(map[string][]string, len(events))
+	for clientType, types := range events {
+		key := strings.ToLower(strings.TrimSpace(clientType))
+		for _, t := range types {
+			if t = strings.TrimSpace(t); t != "" {
+				normalized[key] = append(normalized[key], t)
+			}
+		}
+	}
+	r.terminalEvents = normalized
+}
+
+func (r *Resolver) terminalEventsFor(clientType string) []string {
+	if events, ok := r.terminalEvents[strings.ToLower(strings.TrimSpace(clientType))]; ok {
+		return events
+	}
+	if events, ok := r.terminalEvents["*"]; ok {
+		return events
+	}
+	return defaultStreamTerminalEvents
+}
+
+// streamTextAssembler accumulates text deltas so a round can still be stored
+// when a stream ends or breaks off without a terminal event.
+type streamTextAssembler struct {
+	text strings.Builder
+}
+
+func (a *streamTextAssembler) add(data string) {
+	var envelope struct {
+		Type  string `json:"type"`
+		Delta string `json:"delta"`
+	}
+	if err := json.Unmarshal([]byte(data), &envelope); err != nil {
+		return
+	}
+	if envelope.Type == "text_delta" {
+		a.text.WriteString(envelope.Delta)
+	}
+}
+
+// messages returns the assembled assistant message, or nil if no text arrived.
+func (a *streamTextAssembler) messages() []conversation.ModelMessage {
+	text := a.text.String()
+	if strings.TrimSpace(text) == "" {
+		return nil
+	}
+	return []conversation.ModelMessage{{Role: "assistant", Content: conversation.NewTextContent(text)}}
+}
+
+func isTerminalEvent(eventType string, terminal []string) bool {
+	return eventType != "" && slices.Contains(terminal, eventType)
+}
@@ -215,6 +215,9 @@ type ChatRequest struct {
 	Messages           []ModelMessage `json:"messages,omitempty"`
 	Skills             []string       `json:"skills,omitempty"`
 	AllowedActions     []string       `json:"allowed_actions,omitempty"`
+	// MemoryEvents asks a streamed chat to wait for memory extraction and end
+	// with a "memory" event summarizing the stored changes.
+	MemoryEvents bool `json:"memory_events,omitempty"`
 }
 
 // ChatResponse is the output of a non-streaming chat call.
@@ -228,6 +231,10 @@ type ChatResponse struct {
 // StreamChunk is a raw JSON chunk from the streaming response.
 type StreamChunk = json.RawMessage
 
+// StreamEventMemory is the "type" of the chunk that closes a stream with a
+// summary of memory changes when ChatRequest.MemoryEvents is set.
+const StreamEventMemory = "memory"
+
 // AssistantOutput holds extracted assistant content for downstream consumers.
 type AssistantOutput struct {
 	Content string
@@ -21,7 +21,7 @@ func (q *Queries) DeleteContainerByBotID(ctx context.Context, botID pgtype.UUID)
 }
 
 const getContainerByBotID = `-- name: GetContainerByBotID :one
-SELECT id, bot_id, container_id, container_name, image, status, namespace, auto_start, host_path, container_path, created_at, updated_at, last_started_at, last_stopped_at FROM containers WHERE bot_id = $1 ORDER BY updated_at DESC LIMIT 1
+SELECT id, bot_id, container_id, container_name, image, status, namespace, auto_start, host_path, container_path, created_at, updated_at, last_started_at, last_stopped_at, last_active_at FROM containers WHERE bot_id = $1 ORDER BY updated_at DESC LIMIT 1
 `
 
 func (q *Queries) GetContainerByBotID(ctx context.Context, botID pgtype.UUID) (Container, error) {
@@ -42,12 +42,13 @@ func (q *Queries) GetContainerByBotID(ctx context.Context, botID pgtype.UUID) (C
 		&i.UpdatedAt,
 		&i.LastStartedAt,
 		&i.LastStoppedAt,
+		&i.LastActiveAt,
 	)
 	return i, err
 }
 
 const listAutoStartContainers = `-- name: ListAutoStartContainers :many
-SELECT id, bot_id, container_id, container_name, image, status, namespace, auto_start, host_path, container_path, created_at, updated_at, last_started_at, last_stopped_at FROM containers WHERE auto_start = true ORDER BY updated_at DESC
+SELECT id, bot_id, container_id, container_name, image, status, namespace, auto_start, host_path, container_path, created_at, updated_at, last_started_at, last_stopped_at, last_active_at FROM containers WHERE auto_start = true ORDER BY updated_at DESC
 `
 
 func (q *Queries) ListAutoStartContainers(ctx context.Context) ([]Container, error) {
@@ -74,6 +75,7 @@ func (q *Queries) ListAutoStartContainers(ctx context.Context) ([]Container, err
 			&i.UpdatedAt,
 			&i.LastStartedAt,
 			&i.LastStoppedAt,
+			&i.LastActiveAt,
 		); err != nil {
 			return nil, err
 		}
@@ -85,9 +87,76 @@ func (q *Queries) ListAutoStartContainers(ctx context.Context) ([]Container, err
 	return items, nil
 }
 
+const listContainersByBotIDs = `-- name: ListContainersByBotIDs :many
+SELECT id, bot_id, container_id, container_name, image, status, namespace, auto_start, host_path, container_path, created_at, updated_at, last_started_at, last_stopped_at, last_active_at FROM containers WHERE bot_id = ANY($1::uuid[]) ORDER BY updated_at DESC
+`
+
+func (q *Queries) ListContainersByBotIDs(ctx context.Context, botIds []pgtype.UUID) ([]Container, error) {
+	rows, err := q.db.Query(ctx, listContainersByBotIDs, botIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Container
+	for rows.Next() {
+		var i Container
+		if err := rows.Scan(
+			&i.ID,
+			&i.BotID,
+			&i.ContainerID,
+			&i.ContainerName,
+			&i.Image,
+			&i.Status,
+			&i.Namespace,
+			&i.AutoStart,
+			&i.HostPath,
+			&i.ContainerPath,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.LastStartedAt,
+			&i.LastStoppedAt,
+			&i.LastActiveAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateContainerActivity = `-- name: UpdateContainerActivity :exec
+UPDATE containers
+SET last_active_at = GREATEST(last_active_at, $1::timestamptz)
+WHERE bot_id = $2
+`
+
+type UpdateContainerActivityParams struct {
+	LastActiveAt pgtype.Timestamptz `json:"last_active_at"`
+	BotID        pgtype.UUID        `json:"bot_id"`
+}
+
+func (q *Queries) UpdateContainerActivity(ctx context.Context, arg UpdateContainerActivityParams) error {
+	_, err := q.db.Exec(ctx, updateContainerActivity, arg.LastActiveAt, arg.BotID)
+	return err
+}
+
+const updateContainerIdleStopped = `-- name: UpdateContainerIdleStopped :exec
+UPDATE containers
+SET status = 'idle_stopped', last_stopped_at = now(), updated_at = now()
+WHERE bot_id = $1
+`
+
+func (q *Queries) UpdateContainerIdleStopped(ctx context.Context, botID pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, updateContainerIdleStopped, botID)
+	return err
+}
+
 const updateContainerStarted = `-- name: UpdateContainerStarted :exec
 UPDATE containers
-SET status = 'running', last_started_at = now(), updated_at = now()
+SET status = 'running', last_started_at = now(), last_active_at = now(), updated_at = now()
 WHERE bot_id = $1
 `
 
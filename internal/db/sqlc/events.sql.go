@@ -7,6 +7,8 @@ package sqlc
 
 import (
 	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 const insertLifecycleEvent = `-- name: InsertLifecycleEvent :exec
@@ -35,3 +37,43 @@ func (q *Queries) InsertLifecycleEvent(ctx context.Context, arg InsertLifecycleE
 	)
 	return err
 }
+
+const listLifecycleEventsSince = `-- name: ListLifecycleEventsSince :many
+SELECT id, container_id, event_type, payload, created_at FROM lifecycle_events
+WHERE container_id = $1
+  AND event_type = $2
+  AND created_at > $3
+ORDER BY created_at ASC
+`
+
+type ListLifecycleEventsSinceParams struct {
+	ContainerID string             `json:"container_id"`
+	EventType   string             `json:"event_type"`
+	Since       pgtype.Timestamptz `json:"since"`
+}
+
+func (q *Queries) ListLifecycleEventsSince(ctx context.Context, arg ListLifecycleEventsSinceParams) ([]LifecycleEvent, error) {
+	rows, err := q.db.Query(ctx, listLifecycleEventsSince, arg.ContainerID, arg.EventType, arg.Since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []LifecycleEvent
+	for rows.Next() {
+		var i LifecycleEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.ContainerID,
+			&i.EventType,
+			&i.Payload,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
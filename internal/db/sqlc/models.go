@@ -126,14 +126,26 @@ type Container struct {
 	UpdatedAt     pgtype.Timestamptz `json:"updated_at"`
 	LastStartedAt pgtype.Timestamptz `json:"last_started_at"`
 	LastStoppedAt pgtype.Timestamptz `json:"last_stopped_at"`
+	LastActiveAt  pgtype.Timestamptz `json:"last_active_at"`
 }
 
 type ContainerVersion struct {
-	ID          string             `json:"id"`
-	ContainerID string             `json:"container_id"`
-	SnapshotID  string             `json:"snapshot_id"`
-	Version     int32              `json:"version"`
-	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	ID           string             `json:"id"`
+	ContainerID  string             `json:"container_id"`
+	SnapshotID   string             `json:"snapshot_id"`
+	Version      int32              `json:"version"`
+	CreatedAt    pgtype.Timestamptz `json:"created_at"`
+	ChangedPaths []byte             `json:"changed_paths"`
+	Label        pgtype.Text        `json:"label"`
+}
+
+type FsWriteAudit struct {
+	ID        pgtype.UUID        `json:"id"`
+	BotID     pgtype.UUID        `json:"bot_id"`
+	Path      string             `json:"path"`
+	Action    string             `json:"action"`
+	Metadata  []byte             `json:"metadata"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
 }
 
 type LifecycleEvent struct {
@@ -166,6 +178,15 @@ type McpConnection struct {
 	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
 }
 
+type MemoryHistory struct {
+	ID        string             `json:"id"`
+	MemoryID  string             `json:"memory_id"`
+	Event     string             `json:"event"`
+	Memory    string             `json:"memory"`
+	Hash      string             `json:"hash"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
 type Model struct {
 	ID            pgtype.UUID        `json:"id"`
 	ModelID       string             `json:"model_id"`
@@ -11,6 +11,15 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const deleteSnapshot = `-- name: DeleteSnapshot :exec
+DELETE FROM snapshots WHERE id = $1
+`
+
+func (q *Queries) DeleteSnapshot(ctx context.Context, id string) error {
+	_, err := q.db.Exec(ctx, deleteSnapshot, id)
+	return err
+}
+
 const insertSnapshot = `-- name: InsertSnapshot :exec
 INSERT INTO snapshots (id, container_id, parent_snapshot_id, snapshotter, digest)
 VALUES (
@@ -7,8 +7,40 @@ package sqlc
 
 import (
 	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const deleteVersion = `-- name: DeleteVersion :exec
+DELETE FROM container_versions WHERE container_id = $1 AND version = $2
+`
+
+type DeleteVersionParams struct {
+	ContainerID string `json:"container_id"`
+	Version     int32  `json:"version"`
+}
+
+func (q *Queries) DeleteVersion(ctx context.Context, arg DeleteVersionParams) error {
+	_, err := q.db.Exec(ctx, deleteVersion, arg.ContainerID, arg.Version)
+	return err
+}
+
+const getVersionByLabel = `-- name: GetVersionByLabel :one
+SELECT version FROM container_versions WHERE container_id = $1 AND label = $2
+`
+
+type GetVersionByLabelParams struct {
+	ContainerID string      `json:"container_id"`
+	Label       pgtype.Text `json:"label"`
+}
+
+func (q *Queries) GetVersionByLabel(ctx context.Context, arg GetVersionByLabelParams) (int32, error) {
+	row := q.db.QueryRow(ctx, getVersionByLabel, arg.ContainerID, arg.Label)
+	var version int32
+	err := row.Scan(&version)
+	return version, err
+}
+
 const getVersionSnapshotID = `-- name: GetVersionSnapshotID :one
 SELECT snapshot_id FROM container_versions WHERE container_id = $1 AND version = $2
 `
@@ -26,21 +58,23 @@ func (q *Queries) GetVersionSnapshotID(ctx context.Context, arg GetVersionSnapsh
 }
 
 const insertVersion = `-- name: InsertVersion :one
-INSERT INTO container_versions (id, container_id, snapshot_id, version)
+INSERT INTO container_versions (id, container_id, snapshot_id, version, label)
 VALUES (
   $1,
   $2,
   $3,
-  $4
+  $4,
+  $5
 )
-RETURNING id, container_id, snapshot_id, version, created_at
+RETURNING id, container_id, snapshot_id, version, created_at, changed_paths, label
 `
 
 type InsertVersionParams struct {
-	ID          string `json:"id"`
-	ContainerID string `json:"container_id"`
-	SnapshotID  string `json:"snapshot_id"`
-	Version     int32  `json:"version"`
+	ID          string      `json:"id"`
+	ContainerID string      `json:"container_id"`
+	SnapshotID  string      `json:"snapshot_id"`
+	Version     int32       `json:"version"`
+	Label       pgtype.Text `json:"label"`
 }
 
 func (q *Queries) InsertVersion(ctx context.Context, arg InsertVersionParams) (ContainerVersion, error) {
@@ -49,6 +83,7 @@ func (q *Queries) InsertVersion(ctx context.Context, arg InsertVersionParams) (C
 		arg.ContainerID,
 		arg.SnapshotID,
 		arg.Version,
+		arg.Label,
 	)
 	var i ContainerVersion
 	err := row.Scan(
@@ -57,12 +92,14 @@ func (q *Queries) InsertVersion(ctx context.Context, arg InsertVersionParams) (C
 		&i.SnapshotID,
 		&i.Version,
 		&i.CreatedAt,
+		&i.ChangedPaths,
+		&i.Label,
 	)
 	return i, err
 }
 
 const listVersionsByContainerID = `-- name: ListVersionsByContainerID :many
-SELECT id, container_id, snapshot_id, version, created_at FROM container_versions WHERE container_id = $1 ORDER BY version ASC
+SELECT id, container_id, snapshot_id, version, created_at, changed_paths, label FROM container_versions WHERE container_id = $1 ORDER BY version ASC
 `
 
 func (q *Queries) ListVersionsByContainerID(ctx context.Context, containerID string) ([]ContainerVersion, error) {
@@ -80,6 +117,8 @@ func (q *Queries) ListVersionsByContainerID(ctx context.Context, containerID str
 			&i.SnapshotID,
 			&i.Version,
 			&i.CreatedAt,
+			&i.ChangedPaths,
+			&i.Label,
 		); err != nil {
 			return nil, err
 		}
@@ -101,3 +140,17 @@ func (q *Queries) NextVersion(ctx context.Context, containerID string) (int32, e
 	err := row.Scan(&column_1)
 	return column_1, err
 }
+
+const setVersionChangedPaths = `-- name: SetVersionChangedPaths :exec
+UPDATE container_versions SET changed_paths = $1 WHERE id = $2
+`
+
+type SetVersionChangedPathsParams struct {
+	ChangedPaths []byte `json:"changed_paths"`
+	ID           string `json:"id"`
+}
+
+func (q *Queries) SetVersionChangedPaths(ctx context.Context, arg SetVersionChangedPathsParams) error {
+	_, err := q.db.Exec(ctx, setVersionChangedPaths, arg.ChangedPaths, arg.ID)
+	return err
+}
@@ -29,6 +29,11 @@ func (e *ResolverTextEmbedder) Dimensions() int {
 	return e.Dims
 }
 
+// Model returns the embedding model id used for requests.
+func (e *ResolverTextEmbedder) Model() string {
+	return e.ModelID
+}
+
 // CollectEmbeddingVectors gathers embedding model dimensions and defaults.
 func CollectEmbeddingVectors(ctx context.Context, service *models.Service) (map[string]int, models.GetResponse, models.GetResponse, bool, error) {
 	candidates, err := service.ListByType(ctx, models.ModelTypeEmbedding)
@@ -10,6 +10,8 @@ import (
 	"net/http"
 	"strings"
 	"time"
+
+	"github.com/memohai/memoh/internal/logger"
 )
 
 const (
@@ -102,6 +104,7 @@ func (e *DashScopeEmbedder) Embed(ctx context.Context, text string, imageURL str
 	}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+e.apiKey)
+	logger.SetOutgoingRequestID(req)
 
 	resp, err := e.http.Do(req)
 	if err != nil {
@@ -10,6 +10,8 @@ import (
 	"net/http"
 	"strings"
 	"time"
+
+	"github.com/memohai/memoh/internal/logger"
 )
 
 type Embedder interface {
@@ -69,6 +71,11 @@ func (e *OpenAIEmbedder) Dimensions() int {
 	return e.dims
 }
 
+// Model returns the embedding model name sent to the API.
+func (e *OpenAIEmbedder) Model() string {
+	return e.model
+}
+
 func (e *OpenAIEmbedder) Embed(ctx context.Context, input string) ([]float32, error) {
 	payload, err := json.Marshal(openAIEmbeddingRequest{
 		Input: input,
@@ -86,6 +93,7 @@ func (e *OpenAIEmbedder) Embed(ctx context.Context, input string) ([]float32, er
 	if e.apiKey != "" {
 		req.Header.Set("Authorization", "Bearer "+e.apiKey)
 	}
+	logger.SetOutgoingRequestID(req)
 
 	resp, err := e.http.Do(req)
 	if err != nil {
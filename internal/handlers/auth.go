@@ -4,6 +4,7 @@ import (
 	"errors"
 	"log/slog"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
 
@@ -44,8 +45,27 @@ func NewAuthHandler(log *slog.Logger, accountService *accounts.Service, jwtSecre
 	}
 }
 
+// issuableScopes are the scopes POST /auth/token may add to a token.
+var issuableScopes = map[string]struct{}{
+	auth.ScopeFSReadOnly: {},
+}
+
+type ScopedTokenRequest struct {
+	// Scopes to add, e.g. "fs:readonly". Scopes of the calling token are
+	// always kept, so a token can only be narrowed.
+	Scopes []string `json:"scopes"`
+}
+
+type ScopedTokenResponse struct {
+	AccessToken string   `json:"access_token"`
+	TokenType   string   `json:"token_type"`
+	ExpiresAt   string   `json:"expires_at"`
+	Scopes      []string `json:"scopes"`
+}
+
 func (h *AuthHandler) Register(e *echo.Echo) {
 	e.POST("/auth/login", h.Login)
+	e.POST("/auth/token", h.IssueScopedToken)
 }
 
 // Login godoc
@@ -103,3 +123,60 @@ func (h *AuthHandler) Login(c echo.Context) error {
 		DisplayName: account.DisplayName,
 	})
 }
+
+// IssueScopedToken godoc
+// @Summary Issue a scoped token
+// @Description Issues a token for the calling user that carries the requested scopes on top of the calling
+// @Description token's, e.g. "fs:readonly" for a token that can only read bot container filesystems.
+// @Tags auth
+// @Param payload body ScopedTokenRequest true "Scopes to add"
+// @Success 200 {object} ScopedTokenResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/token [post]
+func (h *AuthHandler) IssueScopedToken(c echo.Context) error {
+	if strings.TrimSpace(h.jwtSecret) == "" {
+		return echo.NewHTTPError(http.StatusInternalServerError, "jwt secret not configured")
+	}
+	if h.expiresIn <= 0 {
+		return echo.NewHTTPError(http.StatusInternalServerError, "jwt expiry not configured")
+	}
+	if auth.IsChatToken(c) {
+		return echo.NewHTTPError(http.StatusForbidden, "chat tokens cannot issue tokens")
+	}
+	userID, err := auth.UserIDFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	var req ScopedTokenRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if len(req.Scopes) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "scopes are required")
+	}
+	scopes := auth.Scopes(c)
+	for _, scope := range req.Scopes {
+		scope = strings.TrimSpace(scope)
+		if _, ok := issuableScopes[scope]; !ok {
+			return echo.NewHTTPError(http.StatusBadRequest, "unknown scope: "+scope)
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	token, expiresAt, err := auth.GenerateToken(userID, h.jwtSecret, h.expiresIn, scopes...)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, ScopedTokenResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresAt:   expiresAt.Format(time.RFC3339),
+		Scopes:      scopes,
+	})
+}
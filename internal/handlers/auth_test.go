@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+
+	"github.com/memohai/memoh/internal/auth"
+)
+
+func TestIssueScopedToken(t *testing.T) {
+	h := NewAuthHandler(slog.Default(), nil, "secret", time.Hour)
+	issue := func(signed, body string) (*httptest.ResponseRecorder, error) {
+		token, err := jwt.Parse(signed, func(*jwt.Token) (any, error) { return []byte("secret"), nil })
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := httptest.NewRequest(http.MethodPost, "/auth/token", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+		c.Set("user", token)
+		return rec, h.IssueScopedToken(c)
+	}
+
+	signed, _, err := auth.GenerateToken("user-1", "secret", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec, err := issue(signed, `{"scopes":["fs:readonly"]}`)
+	if err != nil {
+		t.Fatalf("issue fs:readonly: %v", err)
+	}
+	var resp ScopedTokenResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Scopes) != 1 || resp.Scopes[0] != auth.ScopeFSReadOnly {
+		t.Fatalf("scopes = %v", resp.Scopes)
+	}
+
+	if _, err := issue(signed, `{"scopes":["admin"]}`); err == nil {
+		t.Fatal("unknown scope was issued")
+	}
+	if _, err := issue(resp.AccessToken, `{"scopes":[]}`); err == nil {
+		t.Fatal("read-only token was exchanged for an unscoped one")
+	}
+}
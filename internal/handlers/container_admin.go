@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	tasktypes "github.com/containerd/containerd/api/types/task"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+
+	"github.com/memohai/memoh/internal/db"
+)
+
+// maxBatchContainerStatus caps the number of bots per batch status request.
+const maxBatchContainerStatus = 500
+
+type BatchContainerStatusRequest struct {
+	BotIDs []string `json:"bot_ids"`
+}
+
+type BatchContainerStatusItem struct {
+	BotID       string `json:"bot_id"`
+	ContainerID string `json:"container_id,omitempty"`
+	// Status is the stored container status, or "not_found" when the bot has no container.
+	Status      string `json:"status"`
+	TaskStatus  string `json:"task_status,omitempty"`
+	TaskRunning bool   `json:"task_running"`
+}
+
+type BatchContainerStatusResponse struct {
+	Items []BatchContainerStatusItem `json:"items"`
+}
+
+// BatchContainerStatus godoc
+// @Summary Get container status for multiple bots (admin only)
+// @Description Looks up all tasks in a single containerd call and correlates them by container ID.
+// @Tags containerd
+// @Param payload body BatchContainerStatusRequest true "Bot IDs"
+// @Success 200 {object} BatchContainerStatusResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/containers/status [post]
+func (h *ContainerdHandler) BatchContainerStatus(c echo.Context) error {
+	if err := h.requireAdmin(c); err != nil {
+		return err
+	}
+	if h.queries == nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "container store not configured")
+	}
+	var req BatchContainerStatusRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if len(req.BotIDs) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "bot_ids is required")
+	}
+	if len(req.BotIDs) > maxBatchContainerStatus {
+		return echo.NewHTTPError(http.StatusBadRequest, "too many bot_ids")
+	}
+
+	botIDs := make([]string, 0, len(req.BotIDs))
+	pgBotIDs := make([]pgtype.UUID, 0, len(req.BotIDs))
+	seen := make(map[string]struct{}, len(req.BotIDs))
+	for _, raw := range req.BotIDs {
+		pgID, err := db.ParseUUID(strings.TrimSpace(raw))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid bot id: "+raw)
+		}
+		botID := pgID.String()
+		if _, ok := seen[botID]; ok {
+			continue
+		}
+		seen[botID] = struct{}{}
+		botIDs = append(botIDs, botID)
+		pgBotIDs = append(pgBotIDs, pgID)
+	}
+
+	ctx := c.Request().Context()
+	rows, err := h.queries.ListContainersByBotIDs(ctx, pgBotIDs)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	tasks, err := h.service.ListTasks(ctx, nil)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	taskStatus := make(map[string]tasktypes.Status, len(tasks))
+	for _, task := range tasks {
+		taskStatus[task.ContainerID] = task.Status
+	}
+
+	items := make(map[string]BatchContainerStatusItem, len(rows))
+	for _, row := range rows {
+		botID := row.BotID.String()
+		// Rows are ordered by updated_at desc; keep the latest per bot.
+		if _, ok := items[botID]; ok {
+			continue
+		}
+		item := BatchContainerStatusItem{
+			BotID:       botID,
+			ContainerID: row.ContainerID,
+			Status:      row.Status,
+		}
+		if status, ok := taskStatus[row.ContainerID]; ok {
+			item.TaskStatus = strings.ToLower(status.String())
+			item.TaskRunning = status == tasktypes.Status_RUNNING
+		}
+		items[botID] = item
+	}
+
+	resp := BatchContainerStatusResponse{Items: make([]BatchContainerStatusItem, 0, len(botIDs))}
+	for _, botID := range botIDs {
+		item, ok := items[botID]
+		if !ok {
+			item = BatchContainerStatusItem{BotID: botID, Status: "not_found"}
+		}
+		resp.Items = append(resp.Items, item)
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *ContainerdHandler) requireAdmin(c echo.Context) error {
+	channelIdentityID, err := h.requireChannelIdentityID(c)
+	if err != nil {
+		return err
+	}
+	if h.accountService == nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "account service not configured")
+	}
+	isAdmin, err := h.accountService.IsAdmin(c.Request().Context(), channelIdentityID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	if !isAdmin {
+		return echo.NewHTTPError(http.StatusForbidden, "admin role required")
+	}
+	return nil
+}
@@ -0,0 +1,281 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/containerd/continuity/fs"
+	securejoin "github.com/cyphar/filepath-securejoin"
+	"github.com/labstack/echo/v4"
+	"github.com/pmezard/go-difflib/difflib"
+
+	ctr "github.com/memohai/memoh/internal/containerd"
+	"github.com/memohai/memoh/internal/mcp"
+)
+
+const (
+	defaultDiffTreeBytes = 1 << 20
+	maxDiffTreeBytes     = 8 << 20
+	// Files larger than this are listed without a diff.
+	maxDiffTreeFileSize = 1 << 20
+	diffTreeSniffLen    = 8000
+)
+
+// Diff tree file statuses.
+const (
+	DiffTreeAdded    = "added"
+	DiffTreeModified = "modified"
+	DiffTreeDeleted  = "deleted"
+)
+
+type FSDiffTreeFile struct {
+	Path   string `json:"path"`
+	Status string `json:"status"`
+	// Binary and TooLarge files are listed without a diff.
+	Binary   bool   `json:"binary,omitempty"`
+	TooLarge bool   `json:"too_large,omitempty"`
+	Diff     string `json:"diff,omitempty"`
+}
+
+type FSDiffTreeResponse struct {
+	Version   int              `json:"version"`
+	Path      string           `json:"path,omitempty"`
+	Files     []FSDiffTreeFile `json:"files"`
+	Truncated bool             `json:"truncated"`
+}
+
+// errDiffTreeLimit stops the walk once the output budget is spent.
+var errDiffTreeLimit = errors.New("diff output limit reached")
+
+// DiffTree godoc
+// @Summary Diff the container filesystem against a version
+// @Description Compares the version's snapshot with the container's current root filesystem and returns
+// @Description a unified diff per changed regular file. Binary files and files over 1 MiB are listed without a diff.
+// @Description The data mount is not part of snapshots and is not compared. With path set only that file
+// @Description or directory subtree is compared.
+// @Tags containerd
+// @Param bot_id path string true "Bot ID"
+// @Param version query int true "Version number to compare against"
+// @Param path query string false "Absolute file or directory to limit the diff to (default /)"
+// @Param max_bytes query int false "Cap on the total diff text (default 1 MiB, at most 8 MiB)"
+// @Success 200 {object} FSDiffTreeResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/container/fs/diff_tree [get]
+func (h *ContainerdHandler) DiffTree(c echo.Context) error {
+	botID, err := h.requireBotAccess(c)
+	if err != nil {
+		return err
+	}
+	if h.manager == nil {
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "container versioning is not configured")
+	}
+	version, err := strconv.Atoi(strings.TrimSpace(c.QueryParam("version")))
+	if err != nil || version <= 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "version must be a positive integer")
+	}
+	budget := defaultDiffTreeBytes
+	if raw := strings.TrimSpace(c.QueryParam("max_bytes")); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "max_bytes must be a positive integer")
+		}
+		budget = min(n, maxDiffTreeBytes)
+	}
+	scope := "/"
+	if raw := strings.TrimSpace(c.QueryParam("path")); raw != "" {
+		scope = path.Clean("/" + raw)
+	}
+
+	ctx := c.Request().Context()
+	snapshotID, err := h.manager.VersionSnapshotID(ctx, botID, version)
+	if err != nil {
+		return versionHTTPError(err)
+	}
+	current, err := ctr.MountContainerSnapshot(ctx, h.service, mcp.ContainerPrefix+botID)
+	if err != nil {
+		return versionHTTPError(err)
+	}
+	defer current.Unmount()
+	versionDir, cleanup, err := ctr.MountSnapshotView(ctx, h.service, current.Info.Snapshotter, snapshotID)
+	if err != nil {
+		return versionHTTPError(err)
+	}
+	defer cleanup()
+
+	files, truncated, err := diffTrees(ctx, versionDir, current.Dir, scope, budget)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	resp := FSDiffTreeResponse{Version: version, Files: files, Truncated: truncated}
+	if scope != "/" {
+		resp.Path = scope
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// diffTrees returns per-file diffs from oldDir to newDir until budget bytes of
+// diff text have been produced. Only changes at or below scope, a cleaned
+// absolute path, are included.
+func diffTrees(ctx context.Context, oldDir, newDir, scope string, budget int) ([]FSDiffTreeFile, bool, error) {
+	files := []FSDiffTreeFile{}
+	err := fs.Changes(ctx, oldDir, newDir, func(kind fs.ChangeKind, p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !inDiffScope(scope, p) {
+			return nil
+		}
+		file := FSDiffTreeFile{Path: p}
+		switch kind {
+		case fs.ChangeKindAdd:
+			file.Status = DiffTreeAdded
+		case fs.ChangeKindModify:
+			file.Status = DiffTreeModified
+		case fs.ChangeKindDelete:
+			file.Status = DiffTreeDeleted
+		default:
+			return nil
+		}
+		// Deletions carry no info; check the old side instead.
+		if info == nil {
+			if info, err = lstatUnder(oldDir, p); err != nil {
+				return nil
+			}
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		oldData, oldErr := readFileOrEmpty(oldDir, p)
+		newData, newErr := readFileOrEmpty(newDir, p)
+		switch {
+		case errors.Is(oldErr, errFileTooLarge) || errors.Is(newErr, errFileTooLarge):
+			file.TooLarge = true
+		case oldErr != nil:
+			return oldErr
+		case newErr != nil:
+			return newErr
+		case looksBinary(oldData) || looksBinary(newData):
+			file.Binary = true
+		default:
+			fromFile, toFile := "a"+p, "b"+p
+			switch kind {
+			case fs.ChangeKindAdd:
+				fromFile = "/dev/null"
+			case fs.ChangeKindDelete:
+				toFile = "/dev/null"
+			}
+			file.Diff = unifiedDiff(fromFile, toFile, string(oldData), string(newData))
+			if file.Diff == "" {
+				// Only metadata such as the mtime changed.
+				return nil
+			}
+		}
+		if len(file.Diff) > budget {
+			return errDiffTreeLimit
+		}
+		budget -= len(file.Diff)
+		files = append(files, file)
+		return nil
+	})
+	if errors.Is(err, errDiffTreeLimit) {
+		return files, true, nil
+	}
+	return files, false, err
+}
+
+func inDiffScope(scope, p string) bool {
+	return scope == "/" || p == scope || strings.HasPrefix(p, scope+"/")
+}
+
+var errFileTooLarge = errors.New("file too large to diff")
+
+// readFileOrEmpty reads the regular file p under root without following
+// symlinks out of it. A missing path, or one that is not a regular file,
+// reads as empty, so additions and deletions diff against nothing.
+func readFileOrEmpty(root, p string) ([]byte, error) {
+	info, err := lstatUnder(root, p)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, nil
+	}
+	if info.Size() > maxDiffTreeFileSize {
+		return nil, errFileTooLarge
+	}
+	target, err := joinUnder(root, p)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(target)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(io.LimitReader(f, maxDiffTreeFileSize+1))
+}
+
+func lstatUnder(root, p string) (os.FileInfo, error) {
+	target, err := joinUnder(root, p)
+	if err != nil {
+		return nil, err
+	}
+	return os.Lstat(target)
+}
+
+// joinUnder resolves the parent of p inside root and appends the final
+// element unresolved, so a symlink at p itself is not followed.
+func joinUnder(root, p string) (string, error) {
+	parent, err := securejoin.SecureJoin(root, filepath.Dir(p))
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(parent, filepath.Base(p)), nil
+}
+
+func looksBinary(data []byte) bool {
+	return bytes.IndexByte(data[:min(len(data), diffTreeSniffLen)], 0) >= 0
+}
+
+// unifiedDiff renders a git-style unified diff with three lines of context.
+func unifiedDiff(fromFile, toFile, oldText, newText string) string {
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        diffLines(oldText),
+		B:        diffLines(newText),
+		FromFile: fromFile,
+		ToFile:   toFile,
+		Context:  3,
+	})
+	if err != nil {
+		return ""
+	}
+	return diff
+}
+
+// diffLines splits text into newline-terminated lines. A missing final
+// newline is marked the way git does, so it shows up in the diff.
+func diffLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if last := len(lines) - 1; lines[last] == "" {
+		lines = lines[:last]
+	} else {
+		lines[last] += "\n\\ No newline at end of file\n"
+	}
+	return lines
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiffTrees(t *testing.T) {
+	oldDir, newDir := t.TempDir(), t.TempDir()
+	write := func(root, name, content string) {
+		t.Helper()
+		p := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(oldDir, "etc/app.conf", "a=1\nb=2\n")
+	write(newDir, "etc/app.conf", "a=1\nb=3\n")
+	write(oldDir, "gone.txt", "bye\n")
+	write(newDir, "new.txt", "hi\n")
+	write(newDir, "blob.bin", "x\x00y")
+	write(oldDir, "same.txt", "same\n")
+	write(newDir, "same.txt", "same\n")
+	// Same-size files are told apart by mtime; only the content counts for
+	// the diff, so same.txt must not be listed.
+	past := time.Now().Add(-time.Hour)
+	for _, p := range []string{"etc/app.conf", "same.txt"} {
+		if err := os.Chtimes(filepath.Join(oldDir, p), past, past); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, truncated, err := diffTrees(context.Background(), oldDir, newDir, "/", 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if truncated {
+		t.Fatal("unexpected truncation")
+	}
+	got := map[string]FSDiffTreeFile{}
+	for _, f := range files {
+		got[f.Path] = f
+	}
+	if len(got) != 4 {
+		t.Fatalf("files = %+v", files)
+	}
+	if f := got["/etc/app.conf"]; f.Status != DiffTreeModified || !strings.Contains(f.Diff, "-b=2\n+b=3\n") {
+		t.Fatalf("modified file = %+v", f)
+	}
+	if f := got["/gone.txt"]; f.Status != DiffTreeDeleted || !strings.Contains(f.Diff, "+++ /dev/null") {
+		t.Fatalf("deleted file = %+v", f)
+	}
+	if f := got["/new.txt"]; f.Status != DiffTreeAdded || !strings.Contains(f.Diff, "--- /dev/null") {
+		t.Fatalf("added file = %+v", f)
+	}
+	if f := got["/blob.bin"]; !f.Binary || f.Diff != "" {
+		t.Fatalf("binary file = %+v", f)
+	}
+
+	files, truncated, err = diffTrees(context.Background(), oldDir, newDir, "/", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !truncated {
+		t.Fatal("expected truncation with a tiny budget")
+	}
+	for _, f := range files {
+		if f.Diff != "" {
+			t.Fatalf("diff over budget was included: %+v", f)
+		}
+	}
+
+	files, _, err = diffTrees(context.Background(), oldDir, newDir, "/etc", 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Path != "/etc/app.conf" {
+		t.Fatalf("scoped files = %+v, want only /etc/app.conf", files)
+	}
+}
+
+func TestInDiffScope(t *testing.T) {
+	for _, tc := range []struct {
+		scope, p string
+		want     bool
+	}{
+		{"/", "/etc/app.conf", true},
+		{"/etc", "/etc", true},
+		{"/etc", "/etc/app.conf", true},
+		{"/etc", "/etcetera/x", false},
+		{"/etc/app.conf", "/etc/app.conf", true},
+	} {
+		if got := inDiffScope(tc.scope, tc.p); got != tc.want {
+			t.Errorf("inDiffScope(%q, %q) = %v, want %v", tc.scope, tc.p, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,667 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	securejoin "github.com/cyphar/filepath-securejoin"
+	"github.com/labstack/echo/v4"
+
+	ctr "github.com/memohai/memoh/internal/containerd"
+	"github.com/memohai/memoh/internal/mcp"
+)
+
+type FSMkdirRequest struct {
+	Path string `json:"path"`
+	// Mode is an octal permission string such as "0750"; it defaults to "0755".
+	Mode string `json:"mode,omitempty"`
+	// Parents creates missing intermediate directories; it defaults to true.
+	Parents *bool `json:"parents,omitempty"`
+}
+
+type FSMkdirResponse struct {
+	Path string `json:"path"`
+	// Created is false when the directory already existed.
+	Created bool `json:"created"`
+}
+
+type FSUploadResponse struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+type FSMoveRequest struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Overwrite replaces an existing destination of the same kind.
+	Overwrite bool `json:"overwrite,omitempty"`
+}
+
+// DownloadFSFile godoc
+// @Summary Download a file from the bot data mount
+// @Description Streams the raw file. Range and If-Range requests are supported, so interrupted downloads
+// @Description can resume from an offset. The strong ETag is the md5 of the content; send it as If-Range
+// @Description when resuming, or as If-Match on upload to avoid overwriting concurrent changes.
+// @Description With version set the file is read from that version's snapshot instead, where path is
+// @Description resolved against the container root filesystem.
+// @Tags containerd
+// @Produce octet-stream
+// @Param bot_id path string true "Bot ID"
+// @Param path query string true "Path relative to (or absolute under) the data mount"
+// @Param version query int false "Read the file from this container version"
+// @Success 200 {file} binary
+// @Success 206 {file} binary
+// @Success 304 "Not Modified"
+// @Header 200 {string} ETag "Quoted md5 of the file content"
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 412 {object} ErrorResponse
+// @Failure 416 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/container/fs/download [get]
+func (h *ContainerdHandler) DownloadFSFile(c echo.Context) error {
+	botID, err := h.requireBotAccess(c)
+	if err != nil {
+		return err
+	}
+	if raw := strings.TrimSpace(c.QueryParam("version")); raw != "" {
+		version, err := strconv.Atoi(raw)
+		if err != nil || version <= 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "version must be a positive integer")
+		}
+		return h.downloadVersionFile(c, botID, version, c.QueryParam("path"))
+	}
+	_, target, err := h.resolveFSPath(botID, c.QueryParam("path"))
+	if err != nil {
+		return err
+	}
+	// Hold the read lock until the body is written so the file is not
+	// replaced or committed away mid-stream.
+	unlock := h.rlockBotFS(botID)
+	defer unlock()
+	f, err := openRegularFile(target)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return serveFSFile(c, f, filepath.Base(target))
+}
+
+// downloadVersionFile serves rawPath from a read-only view of a version's
+// snapshot. Relative paths are taken relative to the data mount.
+func (h *ContainerdHandler) downloadVersionFile(c echo.Context, botID string, version int, rawPath string) error {
+	if h.manager == nil {
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "container versioning is not configured")
+	}
+	rawPath = strings.TrimSpace(rawPath)
+	if rawPath == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "path is required")
+	}
+	if !path.IsAbs(rawPath) {
+		rawPath = path.Join(h.botDataMount(botID), rawPath)
+	}
+	ctx := c.Request().Context()
+	snapshotID, err := h.manager.VersionSnapshotID(ctx, botID, version)
+	if err != nil {
+		return versionHTTPError(err)
+	}
+	container, err := h.service.GetContainer(ctx, mcp.ContainerPrefix+botID)
+	if err != nil {
+		return versionHTTPError(err)
+	}
+	info, err := container.Info(ctx)
+	if err != nil {
+		return versionHTTPError(err)
+	}
+	dir, cleanup, err := ctr.MountSnapshotView(ctx, h.service, info.Snapshotter, snapshotID)
+	if err != nil {
+		return versionHTTPError(err)
+	}
+	defer cleanup()
+	target, err := securejoin.SecureJoin(dir, rawPath)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	f, err := openRegularFile(target)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return serveFSFile(c, f, path.Base(rawPath))
+}
+
+// serveFSFile writes f as an attachment with a strong ETag, leaving Range,
+// If-Range, If-Match and If-None-Match handling to http.ServeContent.
+func serveFSFile(c echo.Context, f *os.File, name string) error {
+	info, err := f.Stat()
+	if err != nil {
+		return fsHTTPError(err)
+	}
+	etag, err := fileETag(f)
+	if err != nil {
+		return fsHTTPError(err)
+	}
+	header := c.Response().Header()
+	header.Set("ETag", etag)
+	if contentType := mime.TypeByExtension(filepath.Ext(name)); contentType != "" {
+		header.Set(echo.HeaderContentType, contentType)
+	}
+	header.Set(echo.HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+	http.ServeContent(c.Response(), c.Request(), name, info.ModTime(), f)
+	return nil
+}
+
+// UploadFSFile godoc
+// @Summary Upload a file into the bot data mount
+// @Description Accepts multipart/form-data with a "path" field, optional "mode", "mtime" and "metadata"
+// @Description (JSON object recorded in the write audit log) fields and a file part. The fields must precede
+// @Description the file part. Any other content type is taken as the raw file content, with the fields
+// @Description given as query parameters instead. Either way the body is streamed to a temp file next to
+// @Description the target and renamed into place, so readers never see a partial file.
+// @Tags containerd
+// @Accept multipart/form-data
+// @Accept octet-stream
+// @Param bot_id path string true "Bot ID"
+// @Param path formData string true "Destination path relative to (or absolute under) the data mount"
+// @Param If-Match header string false "ETag from download; the upload is refused with 412 if the file changed"
+// @Param mode formData string false "Octal file mode, default 0644"
+// @Param mtime formData string false "Modification time to set, RFC 3339 or Unix seconds"
+// @Param metadata formData string false "JSON object attributing the change, e.g. {\"agent\":\"sync\"}"
+// @Param file formData file true "File content"
+// @Success 200 {object} FSUploadResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 412 {object} ErrorResponse
+// @Failure 415 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/container/fs/upload [post]
+func (h *ContainerdHandler) UploadFSFile(c echo.Context) error {
+	botID, err := h.requireBotAccess(c)
+	if err != nil {
+		return err
+	}
+	mediaType, _, _ := mime.ParseMediaType(c.Request().Header.Get(echo.HeaderContentType))
+	if mediaType != echo.MIMEMultipartForm {
+		upload := fsUpload{
+			Path:  c.QueryParam("path"),
+			Mode:  c.QueryParam("mode"),
+			MTime: c.QueryParam("mtime"),
+		}
+		if raw := c.QueryParam("metadata"); raw != "" {
+			if upload.Metadata, err = parseAuditMetadata([]byte(raw)); err != nil {
+				return err
+			}
+		}
+		return h.storeUpload(c, botID, upload, c.Request().Body)
+	}
+	reader, err := c.Request().MultipartReader()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	var upload fsUpload
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return echo.NewHTTPError(http.StatusBadRequest, "file part is required")
+		}
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		if part.FileName() == "" {
+			value, err := io.ReadAll(io.LimitReader(part, 4096))
+			if err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+			}
+			switch part.FormName() {
+			case "path":
+				upload.Path = string(value)
+			case "mode":
+				upload.Mode = string(value)
+			case "mtime":
+				upload.MTime = string(value)
+			case "metadata":
+				if upload.Metadata, err = parseAuditMetadata(value); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		return h.storeUpload(c, botID, upload, part)
+	}
+}
+
+// fsUpload holds the fields sent along with an upload, unparsed.
+type fsUpload struct {
+	Path     string
+	Mode     string
+	MTime    string
+	Metadata map[string]any
+}
+
+func (h *ContainerdHandler) storeUpload(c echo.Context, botID string, upload fsUpload, body io.Reader) error {
+	mode, err := parseFileMode(upload.Mode, 0o644)
+	if err != nil {
+		return err
+	}
+	mtime, err := parseFileMTime(upload.MTime)
+	if err != nil {
+		return err
+	}
+	rawPath := upload.Path
+	target, err := h.resolveFSMutationPath(botID, rawPath)
+	if err != nil {
+		return err
+	}
+	unlock := h.lockBotFS(botID)
+	defer unlock()
+	if err := checkRegularFileTarget(target); err != nil {
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	}
+	if err := checkIfMatch(target, c.Request().Header.Get("If-Match")); err != nil {
+		return err
+	}
+	// Sniff the head up front so a denied type is refused before any bytes
+	// land on disk; the size limit is checked once the length is known.
+	buffered := bufio.NewReaderSize(body, 512)
+	head, _ := buffered.Peek(512)
+	if err := h.writePolicy.Check(target, head, 0); err != nil {
+		return echo.NewHTTPError(http.StatusUnsupportedMediaType, err.Error())
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return fsHTTPError(err)
+	}
+	tmpName, size, err := stageFile(target, buffered, mode, func(size int64) error {
+		return h.writePolicy.Check(target, head, size)
+	})
+	if err != nil {
+		if errors.Is(err, mcp.ErrWriteNotAllowed) {
+			return echo.NewHTTPError(http.StatusUnsupportedMediaType, err.Error())
+		}
+		return fsHTTPError(err)
+	}
+	if !mtime.IsZero() {
+		if err := os.Chtimes(tmpName, mtime, mtime); err != nil {
+			_ = os.Remove(tmpName)
+			return fsHTTPError(err)
+		}
+	}
+	if err := os.Rename(tmpName, target); err != nil {
+		_ = os.Remove(tmpName)
+		return fsHTTPError(err)
+	}
+	h.recordFSWrite(c, botID, rawPath, mcp.WriteAuditUpload, upload.Metadata)
+	return c.JSON(http.StatusOK, FSUploadResponse{Path: rawPath, Size: size})
+}
+
+// DeleteFSPath godoc
+// @Summary Delete a file or directory in the bot data mount
+// @Description Directories are only removed when recursive=true.
+// @Tags containerd
+// @Param bot_id path string true "Bot ID"
+// @Param path query string true "Path relative to (or absolute under) the data mount"
+// @Param recursive query bool false "Remove directories and their contents"
+// @Success 204 "No Content"
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/container/fs/delete [delete]
+func (h *ContainerdHandler) DeleteFSPath(c echo.Context) error {
+	botID, err := h.requireBotAccess(c)
+	if err != nil {
+		return err
+	}
+	recursive, _ := strconv.ParseBool(c.QueryParam("recursive"))
+	target, err := h.resolveFSMutationPath(botID, c.QueryParam("path"))
+	if err != nil {
+		return err
+	}
+	unlock := h.lockBotFS(botID)
+	defer unlock()
+	info, err := os.Lstat(target)
+	if err != nil {
+		return fsHTTPError(err)
+	}
+	if info.IsDir() {
+		if !recursive {
+			return echo.NewHTTPError(http.StatusBadRequest, "path is a directory; set recursive=true to delete it")
+		}
+		err = os.RemoveAll(target)
+	} else {
+		err = os.Remove(target)
+	}
+	if err != nil {
+		return fsHTTPError(err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// MoveFSPath godoc
+// @Summary Move or rename a file or directory in the bot data mount
+// @Description An existing destination is a 409 unless overwrite is set; even then a file cannot replace a
+// @Description directory or the other way round. Moves across devices are copied, then the source removed.
+// @Tags containerd
+// @Param bot_id path string true "Bot ID"
+// @Param payload body FSMoveRequest true "Source and destination paths"
+// @Success 204 "No Content"
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/container/fs/move [post]
+func (h *ContainerdHandler) MoveFSPath(c echo.Context) error {
+	botID, err := h.requireBotAccess(c)
+	if err != nil {
+		return err
+	}
+	var req FSMoveRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	from, err := h.resolveFSMutationPath(botID, req.From)
+	if err != nil {
+		return err
+	}
+	to, err := h.resolveFSMutationPath(botID, req.To)
+	if err != nil {
+		return err
+	}
+	if from == to {
+		return echo.NewHTTPError(http.StatusBadRequest, "source and destination are the same")
+	}
+	if isWithinDir(from, to) {
+		return echo.NewHTTPError(http.StatusBadRequest, "cannot move a directory into itself")
+	}
+	unlock := h.lockBotFS(botID)
+	defer unlock()
+	if _, err := os.Lstat(from); err != nil {
+		return fsHTTPError(err)
+	}
+	if err := movePath(from, to, req.Overwrite); err != nil {
+		switch {
+		case errors.Is(err, os.ErrExist):
+			return echo.NewHTTPError(http.StatusConflict, "destination already exists; set overwrite to replace it")
+		case errors.Is(err, errMoveTypeMismatch):
+			return echo.NewHTTPError(http.StatusConflict, err.Error())
+		}
+		return fsHTTPError(err)
+	}
+	h.recordFSRename(c.Request().Context(), botID, from, to)
+	return c.NoContent(http.StatusNoContent)
+}
+
+// recordFSRename notes a move so the next version shows it as a rename.
+// Failures only lose the rename annotation and are logged.
+func (h *ContainerdHandler) recordFSRename(ctx context.Context, botID, from, to string) {
+	if h.manager == nil {
+		return
+	}
+	root, err := h.ensureBotDataRoot(botID)
+	if err != nil {
+		return
+	}
+	mount := path.Clean(h.botDataMount(botID))
+	containerPath := func(hostPath string) string {
+		rel, _ := filepath.Rel(root, hostPath)
+		return path.Join(mount, filepath.ToSlash(rel))
+	}
+	if err := h.manager.RecordRename(ctx, botID, containerPath(from), containerPath(to)); err != nil {
+		h.logger.Warn("record rename failed", slog.String("bot_id", botID), slog.Any("error", err))
+	}
+}
+
+// MkdirFSPath godoc
+// @Summary Create a directory in the bot data mount
+// @Description Succeeds without changes when the directory already exists, leaving its mode alone.
+// @Tags containerd
+// @Param bot_id path string true "Bot ID"
+// @Param payload body FSMkdirRequest true "Directory to create"
+// @Success 200 {object} FSMkdirResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/container/fs/mkdir [post]
+func (h *ContainerdHandler) MkdirFSPath(c echo.Context) error {
+	botID, err := h.requireBotAccess(c)
+	if err != nil {
+		return err
+	}
+	var req FSMkdirRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	mode, err := parseFileMode(req.Mode, 0o755)
+	if err != nil {
+		return err
+	}
+	target, err := h.resolveFSMutationPath(botID, req.Path)
+	if err != nil {
+		return err
+	}
+	unlock := h.lockBotFS(botID)
+	defer unlock()
+	if info, err := os.Lstat(target); err == nil {
+		if !info.IsDir() {
+			return echo.NewHTTPError(http.StatusBadRequest, "path exists and is not a directory")
+		}
+		return c.JSON(http.StatusOK, FSMkdirResponse{Path: req.Path})
+	} else if !os.IsNotExist(err) {
+		return fsHTTPError(err)
+	}
+	if req.Parents == nil || *req.Parents {
+		err = os.MkdirAll(target, mode)
+	} else {
+		err = os.Mkdir(target, mode)
+	}
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return echo.NewHTTPError(http.StatusNotFound, "parent directory does not exist")
+		}
+		if errors.Is(err, syscall.ENOTDIR) {
+			return echo.NewHTTPError(http.StatusBadRequest, "a parent of path is not a directory")
+		}
+		return fsHTTPError(err)
+	}
+	// MkdirAll is subject to the umask; apply the requested mode explicitly.
+	if err := os.Chmod(target, mode); err != nil {
+		return fsHTTPError(err)
+	}
+	return c.JSON(http.StatusOK, FSMkdirResponse{Path: req.Path, Created: true})
+}
+
+// resolveFSPath maps a client path onto the bot's data root on the host.
+// Paths are relative to the data mount or absolute under it (e.g.
+// "/data/notes.md"). Intermediate symlinks are resolved inside the root; the
+// final component is left untouched so links themselves can be managed.
+func (h *ContainerdHandler) resolveFSPath(botID, raw string) (root, target string, err error) {
+	rel, err := fsRelPath(path.Clean(h.botDataMount(botID)), raw)
+	if err != nil {
+		return "", "", err
+	}
+	root, err = h.ensureBotDataRoot(botID)
+	if err != nil {
+		return "", "", echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	if rel == "" {
+		return root, root, nil
+	}
+	parent, err := securejoin.SecureJoin(root, path.Dir(rel))
+	if err != nil {
+		return "", "", echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return root, filepath.Join(parent, path.Base(rel)), nil
+}
+
+// fsRelPath maps a path relative to, or absolute under, mount to a clean
+// path relative to mount; "" is the mount itself.
+func fsRelPath(mount, raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", echo.NewHTTPError(http.StatusBadRequest, "path is required")
+	}
+	rel := raw
+	if path.IsAbs(raw) {
+		cleaned := path.Clean(raw)
+		switch {
+		case cleaned == mount:
+			rel = "."
+		case strings.HasPrefix(cleaned, mount+"/"):
+			rel = strings.TrimPrefix(cleaned, mount+"/")
+		default:
+			return "", echo.NewHTTPError(http.StatusBadRequest, "path must be inside "+mount)
+		}
+	}
+	return strings.TrimPrefix(path.Clean("/"+rel), "/"), nil
+}
+
+// resolveFSMutationPath is resolveFSPath for operations that must never touch
+// the data mount root itself.
+func (h *ContainerdHandler) resolveFSMutationPath(botID, raw string) (string, error) {
+	root, target, err := h.resolveFSPath(botID, raw)
+	if err != nil {
+		return "", err
+	}
+	if target == root {
+		return "", echo.NewHTTPError(http.StatusBadRequest, "refusing to modify the data mount root")
+	}
+	return target, nil
+}
+
+// openRegularFile opens target for reading without following a final
+// symlink, and rejects anything that is not a regular file.
+func openRegularFile(target string) (*os.File, error) {
+	f, err := os.OpenFile(target, os.O_RDONLY|syscall.O_NOFOLLOW|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		if errors.Is(err, syscall.ELOOP) {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "path is a symlink")
+		}
+		return nil, fsHTTPError(err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, fsHTTPError(err)
+	}
+	if !info.Mode().IsRegular() {
+		_ = f.Close()
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "path is not a regular file")
+	}
+	return f, nil
+}
+
+// writeFileAtomic streams r into a temp file in target's directory and renames
+// it over target. verify, if set, sees the final size and can veto the rename.
+func writeFileAtomic(target string, r io.Reader, mode os.FileMode, verify func(size int64) error) (int64, error) {
+	tmpName, size, err := stageFile(target, r, mode, verify)
+	if err != nil {
+		return 0, err
+	}
+	if err := os.Rename(tmpName, target); err != nil {
+		_ = os.Remove(tmpName)
+		return 0, err
+	}
+	return size, nil
+}
+
+// isStagingFile reports whether name is a temp file left by stageFile, for
+// example by a write interrupted by a crash.
+func isStagingFile(name string) bool {
+	return strings.HasPrefix(name, ".") && strings.Contains(name, ".tmp-")
+}
+
+// stageFile writes r to a synced temp file next to target and returns its
+// name, leaving the rename to the caller. The temp file is removed on error.
+func stageFile(target string, r io.Reader, mode os.FileMode, verify func(size int64) error) (string, int64, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(target), "."+filepath.Base(target)+".tmp-*")
+	if err != nil {
+		return "", 0, err
+	}
+	tmpName := tmp.Name()
+	staged := false
+	defer func() {
+		if !staged {
+			_ = tmp.Close()
+			_ = os.Remove(tmpName)
+		}
+	}()
+	size, err := io.Copy(tmp, r)
+	if err != nil {
+		return "", 0, err
+	}
+	if verify != nil {
+		if err := verify(size); err != nil {
+			return "", 0, err
+		}
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		return "", 0, err
+	}
+	if err := tmp.Sync(); err != nil {
+		return "", 0, err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", 0, err
+	}
+	staged = true
+	return tmpName, size, nil
+}
+
+// parseFileMode parses an octal permission string, returning def when raw is empty.
+func parseFileMode(raw string, def os.FileMode) (os.FileMode, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return def, nil
+	}
+	parsed, err := strconv.ParseUint(raw, 8, 32)
+	if err != nil || parsed > 0o777 {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, "mode must be an octal permission such as 0755")
+	}
+	return os.FileMode(parsed), nil
+}
+
+// parseFileMTime parses an RFC 3339 timestamp or Unix seconds, returning the
+// zero time when raw is empty.
+func parseFileMTime(raw string) (time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	if secs, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		return time.Unix(secs, 0), nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, raw)
+	if err != nil {
+		return time.Time{}, echo.NewHTTPError(http.StatusBadRequest, "mtime must be an RFC 3339 timestamp or Unix seconds")
+	}
+	return t, nil
+}
+
+// isWithinDir reports whether target is dir or lies beneath it.
+func isWithinDir(dir, target string) bool {
+	rel, err := filepath.Rel(dir, target)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
+
+func fsHTTPError(err error) error {
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return echo.NewHTTPError(http.StatusNotFound, "path not found")
+	case errors.Is(err, os.ErrExist):
+		return echo.NewHTTPError(http.StatusConflict, "path already exists")
+	case errors.Is(err, os.ErrPermission):
+		return echo.NewHTTPError(http.StatusForbidden, "permission denied")
+	default:
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// defaultMaxArchiveBytes caps the file content of one archive download
+// when mcp.max_archive_bytes is unset (1 GiB).
+const defaultMaxArchiveBytes = 1 << 30
+
+// Archive formats accepted by ArchiveFS.
+const (
+	archiveTarGz = "tar.gz"
+	archiveZip   = "zip"
+)
+
+// ArchiveFS godoc
+// @Summary Download a directory of the bot data mount as an archive
+// @Description The archive is built while it is sent. File modes and mod times are kept; symlinks are
+// @Description stored as links only when they point inside the directory, and special files are skipped.
+// @Description Temp files of unfinished writes are left out. Directories whose files add up to more than the
+// @Description configured cap are refused with 413.
+// @Tags containerd
+// @Param bot_id path string true "Bot ID"
+// @Param path query string false "Directory to archive; defaults to the data mount root"
+// @Param format query string false "tar.gz (default) or zip"
+// @Success 200 {file} binary
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 413 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/container/fs/archive [get]
+func (h *ContainerdHandler) ArchiveFS(c echo.Context) error {
+	botID, err := h.requireBotAccess(c)
+	if err != nil {
+		return err
+	}
+	format := strings.TrimSpace(c.QueryParam("format"))
+	if format == "" {
+		format = archiveTarGz
+	}
+	var contentType string
+	switch format {
+	case archiveTarGz:
+		contentType = "application/gzip"
+	case archiveZip:
+		contentType = "application/zip"
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, "format must be tar.gz or zip")
+	}
+	rawPath := c.QueryParam("path")
+	if strings.TrimSpace(rawPath) == "" {
+		rawPath = "."
+	}
+	root, target, err := h.resolveFSPath(botID, rawPath)
+	if err != nil {
+		return err
+	}
+
+	unlock := h.rlockBotFS(botID)
+	defer unlock()
+	info, err := os.Lstat(target)
+	if err != nil {
+		return fsHTTPError(err)
+	}
+	if !info.IsDir() {
+		return echo.NewHTTPError(http.StatusBadRequest, "path is not a directory")
+	}
+	limit := int64(defaultMaxArchiveBytes)
+	if h.cfg.MaxArchiveBytes > 0 {
+		limit = h.cfg.MaxArchiveBytes
+	}
+	ctx := c.Request().Context()
+	// Sizing first lets an oversized request fail before any byte is sent.
+	size, err := archiveSize(ctx, target)
+	if err != nil {
+		return fsHTTPError(err)
+	}
+	if size > limit {
+		return echo.NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Sprintf("directory holds %d bytes, more than the %d byte archive limit", size, limit))
+	}
+
+	name := "data"
+	if target != root {
+		name = filepath.Base(target)
+	}
+	header := c.Response().Header()
+	header.Set(echo.HeaderContentType, contentType)
+	header.Set(echo.HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{"filename": name + "." + format}))
+	c.Response().WriteHeader(http.StatusOK)
+	if format == archiveZip {
+		err = writeZipArchive(ctx, c.Response(), target)
+	} else {
+		err = writeTarGzArchive(ctx, c.Response(), target)
+	}
+	if err != nil {
+		// The status is already sent; all that is left is to cut the body short.
+		h.logger.Warn("fs archive aborted", slog.String("bot_id", botID), slog.String("path", rawPath), slog.Any("error", err))
+	}
+	return nil
+}
+
+// archiveSize sums the sizes of the regular files under dir.
+func archiveSize(ctx context.Context, dir string) (int64, error) {
+	var total int64
+	err := walkArchive(ctx, dir, func(_ string, _ string, info fs.FileInfo, _ string) error {
+		if info.Mode().IsRegular() {
+			total += info.Size()
+		}
+		return nil
+	})
+	return total, err
+}
+
+// walkArchive calls fn with the slash-separated path relative to dir of
+// every directory, regular file and contained symlink below dir, skipping
+// staging temp files. link is the target of symlinks.
+func walkArchive(ctx context.Context, dir string, fn func(p, rel string, info fs.FileInfo, link string) error) error {
+	return filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == dir {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		link := ""
+		switch {
+		case d.IsDir():
+		case d.Type().IsRegular():
+			if isStagingFile(d.Name()) {
+				return nil
+			}
+		case d.Type()&fs.ModeSymlink != 0:
+			if link, err = os.Readlink(p); err != nil {
+				return err
+			}
+			if filepath.IsAbs(link) || !isWithinDir(dir, filepath.Join(filepath.Dir(p), link)) {
+				return nil
+			}
+		default:
+			return nil
+		}
+		return fn(p, filepath.ToSlash(rel), info, link)
+	})
+}
+
+func writeTarGzArchive(ctx context.Context, w io.Writer, dir string) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	err := walkArchive(ctx, dir, func(p, rel string, info fs.FileInfo, link string) error {
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		hdr.Name = rel
+		if info.IsDir() {
+			hdr.Name += "/"
+		}
+		// Host user and group names mean nothing inside the container.
+		hdr.Uname, hdr.Gname = "", ""
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		return copyArchiveFile(tw, p, hdr.Size)
+	})
+	if err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func writeZipArchive(ctx context.Context, w io.Writer, dir string) error {
+	zw := zip.NewWriter(w)
+	err := walkArchive(ctx, dir, func(p, rel string, info fs.FileInfo, link string) error {
+		hdr, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		hdr.Name = rel
+		switch {
+		case info.IsDir():
+			hdr.Name += "/"
+		case info.Mode().IsRegular():
+			hdr.Method = zip.Deflate
+		}
+		entry, err := zw.CreateHeader(hdr)
+		if err != nil {
+			return err
+		}
+		switch {
+		case link != "":
+			_, err = io.WriteString(entry, link)
+			return err
+		case info.Mode().IsRegular():
+			return copyArchiveFile(entry, p, info.Size())
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// copyArchiveFile copies exactly size bytes of p, the size recorded in the
+// entry header, padding with zeros if the file shrank meanwhile.
+func copyArchiveFile(w io.Writer, p string, size int64) error {
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	n, err := io.Copy(w, io.LimitReader(f, size))
+	if err != nil {
+		return err
+	}
+	if n < size {
+		_, err = io.CopyN(w, zeroReader{}, size-n)
+	}
+	return err
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestWriteArchives(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "sub", "run.sh"), []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "sub", ".run.sh.tmp-123"), []byte("partial"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("sub/run.sh", filepath.Join(dir, "inside")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("../../etc/passwd", filepath.Join(dir, "escape")); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"inside", "sub/", "sub/run.sh"}
+
+	if size, err := archiveSize(context.Background(), dir); err != nil || size != int64(len("#!/bin/sh\n")) {
+		t.Fatalf("archiveSize = %d, %v", size, err)
+	}
+
+	var tgz bytes.Buffer
+	if err := writeTarGzArchive(context.Background(), &tgz, dir); err != nil {
+		t.Fatal(err)
+	}
+	gz, err := gzip.NewReader(&tgz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+	var names []string
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, hdr.Name)
+		if hdr.Name == "sub/run.sh" {
+			body, _ := io.ReadAll(tr)
+			if string(body) != "#!/bin/sh\n" || hdr.FileInfo().Mode().Perm() != 0o755 {
+				t.Errorf("tar entry %s: mode %v, body %q", hdr.Name, hdr.FileInfo().Mode(), body)
+			}
+		}
+	}
+	slices.Sort(names)
+	if !slices.Equal(names, want) {
+		t.Errorf("tar entries = %v, want %v", names, want)
+	}
+
+	var zipped bytes.Buffer
+	if err := writeZipArchive(context.Background(), &zipped, dir); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(zipped.Bytes()), int64(zipped.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	names = names[:0]
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	slices.Sort(names)
+	if !slices.Equal(names, want) {
+		t.Errorf("zip entries = %v, want %v", names, want)
+	}
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/memohai/memoh/internal/mcp"
+)
+
+const (
+	defaultFSAuditRecords = 100
+	maxFSAuditRecords     = 1000
+)
+
+type FSWriteAuditResponse struct {
+	Records []mcp.WriteAuditRecord `json:"records"`
+}
+
+// ListFSWriteAudit godoc
+// @Summary List write audit records for the bot data mount
+// @Description Returns the newest write, edit and upload records with their attribution metadata.
+// @Tags containerd
+// @Param bot_id path string true "Bot ID"
+// @Param path query string false "Only records for this path (relative to or absolute under the data mount)"
+// @Param limit query int false "Maximum number of records (default 100, at most 1000)"
+// @Success 200 {object} FSWriteAuditResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /bots/{bot_id}/container/fs/audit [get]
+func (h *ContainerdHandler) ListFSWriteAudit(c echo.Context) error {
+	botID, err := h.requireBotAccess(c)
+	if err != nil {
+		return err
+	}
+	if h.writeAudit == nil {
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "write audit log not configured")
+	}
+	limit := defaultFSAuditRecords
+	if raw := strings.TrimSpace(c.QueryParam("limit")); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "limit must be a positive integer")
+		}
+		limit = min(n, maxFSAuditRecords)
+	}
+	var auditPath string
+	if raw := c.QueryParam("path"); strings.TrimSpace(raw) != "" {
+		if auditPath, err = h.fsContainerPath(botID, raw); err != nil {
+			return err
+		}
+	}
+	records, err := h.writeAudit.List(c.Request().Context(), botID, auditPath, limit)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, FSWriteAuditResponse{Records: records})
+}
+
+// fsContainerPath returns raw as an absolute path inside the container, the
+// form the tool executor records writes under.
+func (h *ContainerdHandler) fsContainerPath(botID, raw string) (string, error) {
+	mount := path.Clean(h.botDataMount(botID))
+	rel, err := fsRelPath(mount, raw)
+	if err != nil {
+		return "", err
+	}
+	return path.Join(mount, rel), nil
+}
+
+// parseAuditMetadata decodes the optional metadata form field of an upload.
+func parseAuditMetadata(raw []byte) (map[string]any, error) {
+	if strings.TrimSpace(string(raw)) == "" {
+		return nil, nil
+	}
+	var metadata map[string]any
+	if err := json.Unmarshal(raw, &metadata); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "metadata must be a JSON object")
+	}
+	return metadata, nil
+}
+
+// recordFSWrite logs a completed write to the audit log. The file is already
+// in place, so failures are only logged.
+func (h *ContainerdHandler) recordFSWrite(c echo.Context, botID, rawPath, action string, metadata map[string]any) {
+	if h.writeAudit == nil {
+		return
+	}
+	auditPath, err := h.fsContainerPath(botID, rawPath)
+	if err != nil {
+		return
+	}
+	entry := mcp.WriteAuditEntry{BotID: botID, Path: auditPath, Action: action, Metadata: metadata}
+	if err := h.writeAudit.RecordWrite(c.Request().Context(), entry); err != nil {
+		h.logger.Warn("record write audit failed", slog.String("bot_id", botID), slog.String("path", auditPath), slog.Any("error", err))
+	}
+}
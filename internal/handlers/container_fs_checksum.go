@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+type FSChecksumResponse struct {
+	Path      string    `json:"path"`
+	Algo      string    `json:"algo"`
+	Checksum  string    `json:"checksum"`
+	Size      int64     `json:"size"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// checksumAlgos are the hashes FSChecksum can compute.
+var checksumAlgos = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+}
+
+// FSChecksum godoc
+// @Summary Hash a file in the bot data mount without downloading it
+// @Description The file is hashed as a stream, so size is not limited. The md5 checksum equals the download ETag.
+// @Tags containerd
+// @Param bot_id path string true "Bot ID"
+// @Param path query string true "File path"
+// @Param algo query string false "md5, sha1 or sha256 (default)"
+// @Success 200 {object} FSChecksumResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/container/fs/checksum [get]
+func (h *ContainerdHandler) FSChecksum(c echo.Context) error {
+	botID, err := h.requireBotAccess(c)
+	if err != nil {
+		return err
+	}
+	algo := strings.ToLower(strings.TrimSpace(c.QueryParam("algo")))
+	if algo == "" {
+		algo = "sha256"
+	}
+	newHash, ok := checksumAlgos[algo]
+	if !ok {
+		return echo.NewHTTPError(http.StatusBadRequest, "algo must be md5, sha1 or sha256")
+	}
+	_, target, err := h.resolveFSPath(botID, c.QueryParam("path"))
+	if err != nil {
+		return err
+	}
+	unlock := h.rlockBotFS(botID)
+	defer unlock()
+	f, err := openRegularFile(target)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	resp, err := fileChecksum(f, newHash())
+	if err != nil {
+		return fsHTTPError(err)
+	}
+	resp.Path = c.QueryParam("path")
+	resp.Algo = algo
+	return c.JSON(http.StatusOK, resp)
+}
+
+// fileChecksum streams f through h and reports the digest with the size and
+// mod time of what was hashed.
+func fileChecksum(f *os.File, h hash.Hash) (FSChecksumResponse, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return FSChecksumResponse{}, err
+	}
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return FSChecksumResponse{}, err
+	}
+	return FSChecksumResponse{
+		Checksum:  hex.EncodeToString(h.Sum(nil)),
+		Size:      size,
+		UpdatedAt: info.ModTime().UTC(),
+	}, nil
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/labstack/echo/v4"
+)
+
+type FSCopyRequest struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Overwrite replaces an existing destination of the same kind.
+	Overwrite bool `json:"overwrite,omitempty"`
+}
+
+type FSCopyResponse struct {
+	Path string `json:"path"`
+	// Files is the number of files and symlinks copied.
+	Files int `json:"files"`
+}
+
+// CopyFSPath godoc
+// @Summary Copy a file or directory in the bot data mount
+// @Description Directories are copied recursively; modes and modification times are kept and symlinks are
+// @Description copied as links. An existing destination is a 409 unless overwrite is set; even then a file
+// @Description cannot replace a directory or the other way round. The copy is staged next to the
+// @Description destination and renamed into place, so a failed copy leaves the destination untouched.
+// @Tags containerd
+// @Param bot_id path string true "Bot ID"
+// @Param payload body FSCopyRequest true "Source and destination paths"
+// @Success 200 {object} FSCopyResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/container/fs/copy [post]
+func (h *ContainerdHandler) CopyFSPath(c echo.Context) error {
+	botID, err := h.requireBotAccess(c)
+	if err != nil {
+		return err
+	}
+	var req FSCopyRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	from, err := h.resolveFSMutationPath(botID, req.From)
+	if err != nil {
+		return err
+	}
+	to, err := h.resolveFSMutationPath(botID, req.To)
+	if err != nil {
+		return err
+	}
+	if from == to {
+		return echo.NewHTTPError(http.StatusBadRequest, "source and destination are the same")
+	}
+	if isWithinDir(from, to) {
+		return echo.NewHTTPError(http.StatusBadRequest, "cannot copy a directory into itself")
+	}
+	unlock := h.lockBotFS(botID)
+	defer unlock()
+	if _, err := os.Lstat(from); err != nil {
+		return fsHTTPError(err)
+	}
+	files, err := copyPath(from, to, req.Overwrite)
+	if err != nil {
+		switch {
+		case errors.Is(err, os.ErrExist):
+			return echo.NewHTTPError(http.StatusConflict, "destination already exists; set overwrite to replace it")
+		case errors.Is(err, errMoveTypeMismatch):
+			return echo.NewHTTPError(http.StatusConflict, err.Error())
+		}
+		return fsHTTPError(err)
+	}
+	return c.JSON(http.StatusOK, FSCopyResponse{Path: req.To, Files: files})
+}
+
+// copyPath copies from to to and returns the number of files copied. When
+// to exists it is replaced only if overwrite is set and it is of the same
+// kind. The copy is built in a temp entry next to to and renamed into place,
+// so to is either the old entry or the complete copy.
+func copyPath(from, to string, overwrite bool) (int, error) {
+	src, err := os.Lstat(from)
+	if err != nil {
+		return 0, err
+	}
+	replaceDir := false
+	if dst, err := os.Lstat(to); err == nil {
+		if !overwrite {
+			return 0, os.ErrExist
+		}
+		if src.IsDir() != dst.IsDir() {
+			return 0, errMoveTypeMismatch
+		}
+		replaceDir = dst.IsDir()
+	} else if !os.IsNotExist(err) {
+		return 0, err
+	}
+	if err := os.MkdirAll(filepath.Dir(to), 0o755); err != nil {
+		return 0, err
+	}
+	tmp, err := os.MkdirTemp(filepath.Dir(to), "."+filepath.Base(to)+".copy-*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.RemoveAll(tmp)
+	staged := filepath.Join(tmp, "entry")
+	files, err := copyTree(from, staged)
+	if err != nil {
+		return 0, err
+	}
+	if replaceDir {
+		if err := os.RemoveAll(to); err != nil {
+			return 0, err
+		}
+	}
+	if err := os.Rename(staged, to); err != nil {
+		return 0, err
+	}
+	return files, nil
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCopyPath(t *testing.T) {
+	root := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		p := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func(name string) string {
+		data, _ := os.ReadFile(filepath.Join(root, name))
+		return string(data)
+	}
+	at := func(name string) string { return filepath.Join(root, name) }
+	write("a.txt", "a")
+	write("b.txt", "b")
+	write("dir/x.txt", "x")
+	write("dir/sub/y.txt", "y")
+	write("dir/.y.txt.tmp-123", "staging")
+	write("other/z.txt", "z")
+	mtime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := os.Chtimes(at("dir/x.txt"), mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := copyPath(at("a.txt"), at("b.txt"), false); !errors.Is(err, os.ErrExist) {
+		t.Fatalf("copy onto existing file: err = %v, want ErrExist", err)
+	}
+	if _, err := copyPath(at("a.txt"), at("dir"), true); !errors.Is(err, errMoveTypeMismatch) {
+		t.Fatalf("file over directory: err = %v, want errMoveTypeMismatch", err)
+	}
+	if n, err := copyPath(at("a.txt"), at("b.txt"), true); err != nil || n != 1 {
+		t.Fatalf("copy file: n = %d, err = %v", n, err)
+	}
+	if read("a.txt") != "a" || read("b.txt") != "a" {
+		t.Errorf("a.txt = %q, b.txt = %q after overwrite", read("a.txt"), read("b.txt"))
+	}
+
+	n, err := copyPath(at("dir"), at("new/copy"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("copied %d files, want 2", n)
+	}
+	if read("new/copy/x.txt") != "x" || read("new/copy/sub/y.txt") != "y" || read("dir/x.txt") != "x" {
+		t.Error("directory copy should keep the source and copy every file")
+	}
+	if _, err := os.Stat(at("new/copy/.y.txt.tmp-123")); !os.IsNotExist(err) {
+		t.Error("staging temp files should not be copied")
+	}
+	if info, err := os.Stat(at("new/copy/x.txt")); err != nil || !info.ModTime().Equal(mtime) {
+		t.Errorf("copied file should keep its mtime: %v, %v", info, err)
+	}
+
+	if _, err := copyPath(at("dir"), at("other"), true); err != nil {
+		t.Fatal(err)
+	}
+	if read("other/x.txt") != "x" || read("other/z.txt") != "" {
+		t.Error("directory overwrite should replace the old contents")
+	}
+	entries, _ := os.ReadDir(at("new"))
+	if len(entries) != 1 {
+		t.Errorf("temp entries left next to the destination: %v", entries)
+	}
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// fileETag returns the strong ETag of f: its quoted hex md5, the same hash
+// the file tools report. f is rewound afterwards.
+func fileETag(f *os.File) (string, error) {
+	h := md5.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return `"` + hex.EncodeToString(h.Sum(nil)) + `"`, nil
+}
+
+// checkIfMatch enforces an If-Match precondition against target's current
+// content. "*" requires the file to exist; otherwise one of the listed
+// strong ETags (bare md5 hashes are accepted too) must match. A missing
+// header always passes.
+func checkIfMatch(target, header string) error {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return nil
+	}
+	failed := echo.NewHTTPError(http.StatusPreconditionFailed, "file changed since it was read")
+	f, err := openRegularFile(target)
+	if err != nil {
+		var httpErr *echo.HTTPError
+		if errors.As(err, &httpErr) && httpErr.Code == http.StatusNotFound {
+			return failed
+		}
+		return err
+	}
+	defer f.Close()
+	if header == "*" {
+		return nil
+	}
+	current, err := fileETag(f)
+	if err != nil {
+		return fsHTTPError(err)
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		if etagMatches(candidate, current) {
+			return nil
+		}
+	}
+	return failed
+}
+
+// contentETag is fileETag for content already in memory.
+func contentETag(data []byte) string {
+	sum := md5.Sum(data)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+// etagMatches compares candidate, a strong ETag or bare md5 hash, with
+// current under strong comparison.
+func etagMatches(candidate, current string) bool {
+	candidate = strings.TrimSpace(candidate)
+	// Weak tags never match under strong comparison.
+	if strings.HasPrefix(candidate, "W/") {
+		return false
+	}
+	if !strings.HasPrefix(candidate, `"`) {
+		candidate = `"` + candidate + `"`
+	}
+	return candidate == current
+}
@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// defaultFSListEntries caps a listing when mcp.list_max_entries is unset.
+const defaultFSListEntries = 10000
+
+type FSListEntry struct {
+	// Path is relative to the listed directory, with forward slashes.
+	Path    string    `json:"path"`
+	IsDir   bool      `json:"is_dir"`
+	Size    int64     `json:"size"`
+	Mode    uint32    `json:"mode"`
+	ModTime time.Time `json:"mod_time"`
+	// SHA256 is the lowercase hex SHA-256 of a regular file, "" for other
+	// entries. It is only set when checksums were requested.
+	SHA256 *string `json:"sha256,omitempty"`
+}
+
+type FSListResponse struct {
+	Path      string        `json:"path"`
+	Entries   []FSListEntry `json:"entries"`
+	Truncated bool          `json:"truncated"`
+}
+
+// errListLimit stops the walk once enough entries were collected.
+var errListLimit = errors.New("list limit reached")
+
+// ListFS godoc
+// @Summary List a directory in the bot data mount
+// @Description Symlinks are listed but not followed. With glob set only entries whose path relative to the
+// @Description listed directory matches are returned; "*" and "?" stay within one path segment and "**"
+// @Description matches any number of segments, e.g. "**/*.go" or "src/**/*.ts". Recursive listings skip
+// @Description directories the glob cannot match below.
+// @Tags containerd
+// @Param bot_id path string true "Bot ID"
+// @Param path query string false "Directory to list; defaults to the data mount root"
+// @Param recursive query bool false "Walk subdirectories"
+// @Param glob query string false "Doublestar pattern entries must match"
+// @Param checksum query bool false "Include the sha256 of each regular file"
+// @Success 200 {object} FSListResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/container/fs/list [get]
+func (h *ContainerdHandler) ListFS(c echo.Context) error {
+	botID, err := h.requireBotAccess(c)
+	if err != nil {
+		return err
+	}
+	recursive, _ := strconv.ParseBool(c.QueryParam("recursive"))
+	checksum, _ := strconv.ParseBool(c.QueryParam("checksum"))
+	var glob []string
+	if raw := strings.TrimSpace(c.QueryParam("glob")); raw != "" {
+		if glob, err = parseGlob(raw); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+	}
+	rawPath := c.QueryParam("path")
+	if strings.TrimSpace(rawPath) == "" {
+		rawPath = "."
+	}
+	_, target, err := h.resolveFSPath(botID, rawPath)
+	if err != nil {
+		return err
+	}
+	limit := defaultFSListEntries
+	if h.cfg.ListMaxEntries > 0 {
+		limit = h.cfg.ListMaxEntries
+	}
+
+	unlock := h.rlockBotFS(botID)
+	defer unlock()
+	info, err := os.Lstat(target)
+	if err != nil {
+		return fsHTTPError(err)
+	}
+	if !info.IsDir() {
+		return echo.NewHTTPError(http.StatusBadRequest, "path is not a directory")
+	}
+	resp, err := listDir(c.Request().Context(), target, listOptions{Recursive: recursive, Glob: glob, Limit: limit, Checksum: checksum})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	resp.Path = rawPath
+	return c.JSON(http.StatusOK, resp)
+}
+
+type listOptions struct {
+	// Recursive walks subdirectories.
+	Recursive bool
+	// Glob keeps the entries it matches; nil keeps all of them.
+	Glob  []string
+	Limit int
+	// Checksum hashes every listed regular file.
+	Checksum bool
+}
+
+// listDir lists dir, or walks it when recursive, keeping the entries that
+// match the glob up to the limit.
+func listDir(ctx context.Context, dir string, opts listOptions) (FSListResponse, error) {
+	resp := FSListResponse{Entries: []FSListEntry{}}
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if p == dir {
+			return err
+		}
+		if err != nil {
+			// Unreadable entries are skipped; the rest of the tree is still listed.
+			if d != nil && d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return nil
+		}
+		segments := strings.Split(filepath.ToSlash(rel), "/")
+		descend := opts.Recursive && d.IsDir() && (opts.Glob == nil || globMatchesBelow(opts.Glob, segments))
+		if opts.Glob == nil || globMatch(opts.Glob, segments) {
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			if len(resp.Entries) >= opts.Limit {
+				return errListLimit
+			}
+			entry := FSListEntry{
+				Path:    filepath.ToSlash(rel),
+				IsDir:   d.IsDir(),
+				Size:    info.Size(),
+				Mode:    uint32(info.Mode().Perm()),
+				ModTime: info.ModTime().UTC(),
+			}
+			if opts.Checksum {
+				sum := ""
+				if d.Type().IsRegular() {
+					// A file that cannot be read is listed without its hash.
+					sum, _ = sha256File(p)
+				}
+				entry.SHA256 = &sum
+			}
+			resp.Entries = append(resp.Entries, entry)
+		}
+		if d.IsDir() && !descend {
+			return fs.SkipDir
+		}
+		return nil
+	})
+	if errors.Is(err, errListLimit) {
+		resp.Truncated = true
+		err = nil
+	}
+	return resp, err
+}
+
+func sha256File(p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// parseGlob splits a doublestar pattern into segments and validates them.
+func parseGlob(pattern string) ([]string, error) {
+	segments := strings.Split(strings.Trim(path.Clean("/"+pattern), "/"), "/")
+	for _, seg := range segments {
+		if seg == "**" {
+			continue
+		}
+		if strings.Contains(seg, "**") {
+			return nil, errors.New("invalid glob: ** must be a whole path segment")
+		}
+		if _, err := path.Match(seg, ""); err != nil {
+			return nil, errors.New("invalid glob: " + err.Error())
+		}
+	}
+	return segments, nil
+}
+
+// globMatch reports whether the path segments name match the glob.
+func globMatch(glob, name []string) bool {
+	for len(glob) > 0 {
+		if glob[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if globMatch(glob[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(glob[0], name[0]); !ok {
+			return false
+		}
+		glob, name = glob[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// globMatchesBelow reports whether anything inside directory dir could match
+// the glob, so that directories that cannot are not walked.
+func globMatchesBelow(glob, dir []string) bool {
+	for len(dir) > 0 {
+		if len(glob) == 0 {
+			return false
+		}
+		if glob[0] == "**" {
+			return true
+		}
+		if ok, _ := path.Match(glob[0], dir[0]); !ok {
+			return false
+		}
+		glob, dir = glob[1:], dir[1:]
+	}
+	return len(glob) > 0
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestListDirGlob(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"main.go", "README.md", "src/app/index.ts", "src/app/util.go", "src/lib.ts", "docs/guide.ts"} {
+		p := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	paths := func(glob string, recursive bool) []string {
+		t.Helper()
+		var segments []string
+		if glob != "" {
+			var err error
+			if segments, err = parseGlob(glob); err != nil {
+				t.Fatal(err)
+			}
+		}
+		resp, err := listDir(context.Background(), root, listOptions{Recursive: recursive, Glob: segments, Limit: 100})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out []string
+		for _, e := range resp.Entries {
+			out = append(out, e.Path)
+		}
+		slices.Sort(out)
+		return out
+	}
+
+	cases := []struct {
+		glob      string
+		recursive bool
+		want      []string
+	}{
+		{glob: "", recursive: false, want: []string{"README.md", "docs", "main.go", "src"}},
+		{glob: "*.go", recursive: true, want: []string{"main.go"}},
+		{glob: "**/*.go", recursive: true, want: []string{"main.go", "src/app/util.go"}},
+		{glob: "src/**/*.ts", recursive: true, want: []string{"src/app/index.ts", "src/lib.ts"}},
+		{glob: "src/*", recursive: false, want: nil},
+	}
+	for _, tc := range cases {
+		if got := paths(tc.glob, tc.recursive); !slices.Equal(got, tc.want) {
+			t.Errorf("glob %q recursive=%v: got %v, want %v", tc.glob, tc.recursive, got, tc.want)
+		}
+	}
+
+	if !globMatchesBelow([]string{"src", "**", "*.ts"}, []string{"src", "app"}) || globMatchesBelow([]string{"src", "**", "*.ts"}, []string{"docs"}) {
+		t.Error("globMatchesBelow should only descend into directories the glob can reach")
+	}
+	if _, err := parseGlob("src/[a-"); err == nil {
+		t.Error("expected an invalid pattern to be rejected")
+	}
+
+	resp, err := listDir(context.Background(), root, listOptions{Recursive: true, Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Entries) != 2 || !resp.Truncated {
+		t.Errorf("limited listing = %d entries, truncated %v", len(resp.Entries), resp.Truncated)
+	}
+
+	if err := os.WriteFile(filepath.Join(root, "main.go"), []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	resp, err = listDir(context.Background(), root, listOptions{Limit: 100, Checksum: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range resp.Entries {
+		switch {
+		case e.SHA256 == nil:
+			t.Errorf("%s: checksum missing", e.Path)
+		case e.Path == "main.go" && *e.SHA256 != "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824":
+			t.Errorf("main.go sha256 = %s", *e.SHA256)
+		case e.IsDir && *e.SHA256 != "":
+			t.Errorf("%s: directory has checksum %s", e.Path, *e.SHA256)
+		}
+	}
+}
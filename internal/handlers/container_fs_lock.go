@@ -0,0 +1,78 @@
+package handlers
+
+import "sync"
+
+// botFSLocks hands out one read/write lock per bot. Mutations of a bot's data
+// mount and snapshot commits take the write side so they never interleave;
+// reads share the read side. Different bots never contend. Entries are
+// dropped once nobody holds or waits on them.
+type botFSLocks struct {
+	mu    sync.Mutex
+	locks map[string]*botFSLock
+}
+
+type botFSLock struct {
+	sync.RWMutex
+	refs int
+}
+
+// acquire blocks until the lock for botID is held (exclusively when write is
+// set) and returns the function that releases it.
+func (l *botFSLocks) acquire(botID string, write bool) func() {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = map[string]*botFSLock{}
+	}
+	lock, ok := l.locks[botID]
+	if !ok {
+		lock = &botFSLock{}
+		l.locks[botID] = lock
+	}
+	lock.refs++
+	l.mu.Unlock()
+
+	if write {
+		lock.Lock()
+	} else {
+		lock.RLock()
+	}
+	return func() {
+		if write {
+			lock.Unlock()
+		} else {
+			lock.RUnlock()
+		}
+		l.mu.Lock()
+		lock.refs--
+		if lock.refs == 0 {
+			delete(l.locks, botID)
+		}
+		l.mu.Unlock()
+	}
+}
+
+// lockBotFS serializes a mutation of botID's data mount.
+func (h *ContainerdHandler) lockBotFS(botID string) func() {
+	h.touchBot(botID)
+	return h.fsLocks.acquire(botID, true)
+}
+
+// rlockBotFS lets a read of botID's data mount run alongside other reads.
+func (h *ContainerdHandler) rlockBotFS(botID string) func() {
+	h.touchBot(botID)
+	return h.fsLocks.acquire(botID, false)
+}
+
+// lockBotFSPair serializes mutations of two bots' data mounts. The locks are
+// taken in bot ID order, so operations locking the same pair never deadlock.
+func (h *ContainerdHandler) lockBotFSPair(a, b string) func() {
+	if b < a {
+		a, b = b, a
+	}
+	unlockA := h.lockBotFS(a)
+	unlockB := h.lockBotFS(b)
+	return func() {
+		unlockB()
+		unlockA()
+	}
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBotFSLocks_CommitWaitsForWrite(t *testing.T) {
+	h := &ContainerdHandler{}
+
+	var (
+		mu     sync.Mutex
+		events []string
+	)
+	record := func(e string) {
+		mu.Lock()
+		events = append(events, e)
+		mu.Unlock()
+	}
+
+	releaseWrite := h.lockBotFS("bot-1")
+	record("write:start")
+
+	committed := make(chan struct{})
+	go func() {
+		unlock := h.lockBotFS("bot-1")
+		record("commit")
+		unlock()
+		close(committed)
+	}()
+
+	// Another bot is not blocked by bot-1's writer.
+	otherDone := make(chan struct{})
+	go func() {
+		unlock := h.lockBotFS("bot-2")
+		unlock()
+		close(otherDone)
+	}()
+	select {
+	case <-otherDone:
+	case <-time.After(time.Second):
+		t.Fatal("a different bot was blocked by bot-1's lock")
+	}
+
+	select {
+	case <-committed:
+		t.Fatal("commit ran while a write was in progress")
+	case <-time.After(50 * time.Millisecond):
+	}
+	record("write:end")
+	releaseWrite()
+
+	select {
+	case <-committed:
+	case <-time.After(time.Second):
+		t.Fatal("commit never acquired the lock")
+	}
+	want := []string{"write:start", "write:end", "commit"}
+	for i := range want {
+		if events[i] != want[i] {
+			t.Fatalf("events = %v, want %v", events, want)
+		}
+	}
+
+	h.fsLocks.mu.Lock()
+	defer h.fsLocks.mu.Unlock()
+	if len(h.fsLocks.locks) != 0 {
+		t.Fatalf("expected released locks to be dropped, got %d", len(h.fsLocks.locks))
+	}
+}
+
+func TestBotFSLocks_ReadsShareLock(t *testing.T) {
+	h := &ContainerdHandler{}
+	release := h.rlockBotFS("bot-1")
+	defer release()
+
+	done := make(chan struct{})
+	go func() {
+		unlock := h.rlockBotFS("bot-1")
+		unlock()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("concurrent reads should not block each other")
+	}
+}
+
+func TestBotFSLocks_PairOrderDoesNotDeadlock(t *testing.T) {
+	h := &ContainerdHandler{}
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			h.lockBotFSPair("bot-1", "bot-2")()
+		}()
+		go func() {
+			defer wg.Done()
+			h.lockBotFSPair("bot-2", "bot-1")()
+		}()
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("opposite pair orders deadlocked")
+	}
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// errMoveTypeMismatch refuses overwriting a file with a directory or the
+// other way round.
+var errMoveTypeMismatch = errors.New("destination exists and is not the same kind of entry as the source")
+
+// movePath renames from to to. When to exists it is replaced only if
+// overwrite is set and it is of the same kind: files are replaced
+// atomically, directories are removed first. Renames across devices fall
+// back to copying into a temp entry next to to, renaming it into place and
+// removing from.
+func movePath(from, to string, overwrite bool) error {
+	src, err := os.Lstat(from)
+	if err != nil {
+		return err
+	}
+	if dst, err := os.Lstat(to); err == nil {
+		if !overwrite {
+			return os.ErrExist
+		}
+		if src.IsDir() != dst.IsDir() {
+			return errMoveTypeMismatch
+		}
+		if dst.IsDir() {
+			if err := os.RemoveAll(to); err != nil {
+				return err
+			}
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(to), 0o755); err != nil {
+		return err
+	}
+	err = os.Rename(from, to)
+	if !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+	tmp, err := os.MkdirTemp(filepath.Dir(to), "."+filepath.Base(to)+".move-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	staged := filepath.Join(tmp, "entry")
+	if _, err := copyTree(from, staged); err != nil {
+		return err
+	}
+	if err := os.Rename(staged, to); err != nil {
+		return err
+	}
+	return os.RemoveAll(from)
+}
+
+// copyTree copies from to to without following symlinks, keeping
+// permissions and modification times, and returns how many files and links
+// it copied. Special files and staging temp files are skipped.
+func copyTree(from, to string) (int, error) {
+	type dirTime struct {
+		path  string
+		mtime time.Time
+	}
+	var (
+		copied int
+		dirs   []dirTime
+	)
+	err := filepath.WalkDir(from, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p != from && isStagingFile(d.Name()) {
+			return nil
+		}
+		rel, err := filepath.Rel(from, p)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(to, rel)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.IsDir():
+			if err := os.Mkdir(target, info.Mode().Perm()); err != nil {
+				return err
+			}
+			dirs = append(dirs, dirTime{target, info.ModTime()})
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(p)
+			if err != nil {
+				return err
+			}
+			if err := os.Symlink(link, target); err != nil {
+				return err
+			}
+			copied++
+		case d.Type().IsRegular():
+			if err := copyFile(p, target, info.Mode().Perm(), info.ModTime()); err != nil {
+				return err
+			}
+			copied++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	// Children are done, so directory times are no longer bumped.
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err := os.Chtimes(dirs[i].path, dirs[i].mtime, dirs[i].mtime); err != nil {
+			return 0, err
+		}
+	}
+	return copied, nil
+}
+
+func copyFile(from, to string, mode os.FileMode, mtime time.Time) error {
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		_ = dst.Close()
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	return os.Chtimes(to, mtime, mtime)
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMovePath(t *testing.T) {
+	root := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		p := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func(name string) string {
+		data, _ := os.ReadFile(filepath.Join(root, name))
+		return string(data)
+	}
+	at := func(name string) string { return filepath.Join(root, name) }
+	write("a.txt", "a")
+	write("b.txt", "b")
+	write("dir/x.txt", "x")
+	write("other/y.txt", "y")
+
+	if err := movePath(at("a.txt"), at("b.txt"), false); !errors.Is(err, os.ErrExist) {
+		t.Fatalf("move onto existing file: err = %v, want ErrExist", err)
+	}
+	if err := movePath(at("a.txt"), at("dir"), true); !errors.Is(err, errMoveTypeMismatch) {
+		t.Fatalf("file over directory: err = %v, want errMoveTypeMismatch", err)
+	}
+	if err := movePath(at("a.txt"), at("b.txt"), true); err != nil {
+		t.Fatal(err)
+	}
+	if read("b.txt") != "a" {
+		t.Errorf("b.txt = %q after overwrite", read("b.txt"))
+	}
+	if err := movePath(at("dir"), at("other"), true); err != nil {
+		t.Fatal(err)
+	}
+	if read("other/x.txt") != "x" || read("other/y.txt") != "" {
+		t.Error("directory overwrite should replace the old contents")
+	}
+	if err := movePath(at("other"), at("new/nested"), false); err != nil {
+		t.Fatal(err)
+	}
+	if read("new/nested/x.txt") != "x" {
+		t.Error("move should create missing parents")
+	}
+}
+
+func TestCopyTree(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "src")
+	if err := os.MkdirAll(filepath.Join(src, "sub"), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "sub", "run.sh"), []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("/etc/passwd", filepath.Join(src, "link")); err != nil {
+		t.Fatal(err)
+	}
+	dst := filepath.Join(t.TempDir(), "dst")
+	if _, err := copyTree(src, dst); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(filepath.Join(dst, "sub", "run.sh")); err != nil || info.Mode().Perm() != 0o755 {
+		t.Errorf("copied file: %v, %v", info, err)
+	}
+	if link, err := os.Readlink(filepath.Join(dst, "link")); err != nil || link != "/etc/passwd" {
+		t.Errorf("symlink should be copied as a link, got %q, %v", link, err)
+	}
+}
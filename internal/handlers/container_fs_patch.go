@@ -0,0 +1,357 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/memohai/memoh/internal/mcp"
+)
+
+// maxPatchFileSize caps the files a patch may be applied to (10 MiB).
+const maxPatchFileSize = 10 << 20
+
+type ApplyPatchRequest struct {
+	Path string `json:"path"`
+	// Patch is a single-file unified diff.
+	Patch string `json:"patch"`
+	// Fuzzy lets hunks with stale context apply up to 20 lines away from
+	// their header position, ignoring trailing whitespace if need be.
+	Fuzzy bool `json:"fuzzy,omitempty"`
+	// Fuzz sets how many lines away a hunk may apply, up to 1000, and
+	// implies Fuzzy.
+	Fuzz int `json:"fuzz,omitempty"`
+	// BaseHash, when set, is the md5 (or ETag) of the file the patch was
+	// made against; the patch is refused with 409 if the file has changed.
+	BaseHash string `json:"base_hash,omitempty"`
+	// DryRun checks that the patch applies and returns the patched content
+	// without writing it.
+	DryRun   bool           `json:"dry_run,omitempty"`
+	Metadata map[string]any `json:"metadata,omitempty"`
+}
+
+type ApplyPatchResponse struct {
+	Path  string `json:"path"`
+	Hunks int    `json:"hunks"`
+	Size  int64  `json:"size"`
+	// Offsets are, for fuzzy patches, how many lines each hunk moved from
+	// its header position.
+	Offsets []int `json:"offsets,omitempty"`
+	// Content is the would-be file content of a dry run.
+	Content *string `json:"content,omitempty"`
+}
+
+// PatchConflict locates the hunk that did not match the file.
+type PatchConflict struct {
+	// Hunk is 1-based; Line is the 1-based line of the original file where
+	// the mismatch was found.
+	Hunk int `json:"hunk"`
+	Line int `json:"line"`
+	// Expected is the patch's context or removed line, Found the file's line
+	// in its place; both are empty when the hunk ran past the end of the file.
+	Expected string `json:"expected,omitempty"`
+	Found    string `json:"found,omitempty"`
+}
+
+// ApplyPatchError is the failure of a single patch.
+type ApplyPatchError struct {
+	Message  string         `json:"message"`
+	Conflict *PatchConflict `json:"conflict,omitempty"`
+}
+
+type ApplyPatchesResponse struct {
+	Files []ApplyPatchResponse `json:"files"`
+}
+
+// ApplyPatchesError names the patch that stopped a batch.
+type ApplyPatchesError struct {
+	Message  string         `json:"message"`
+	Index    int            `json:"index"`
+	Path     string         `json:"path"`
+	Conflict *PatchConflict `json:"conflict,omitempty"`
+}
+
+// ApplyPatch godoc
+// @Summary Apply a unified diff to a file in the bot data mount
+// @Description Hunks must match the file exactly unless fuzzy is set, in which case a hunk may apply up to
+// @Description 20 lines (or fuzz lines) from its header position and trailing whitespace is ignored; the
+// @Description response reports each hunk's offset. CRLF files are matched and written with CRLF endings. A missing file is patched as empty, so creation diffs work. With base_hash
+// @Description set the file must still hash to it, as reported by the download ETag, or the patch fails with 409.
+// @Description With dry_run nothing is written and the response carries the patched content. A hunk that does
+// @Description not match fails with 409 and a conflict naming the hunk, the line and the mismatching text.
+// @Tags containerd
+// @Param bot_id path string true "Bot ID"
+// @Param payload body ApplyPatchRequest true "Patch"
+// @Success 200 {object} ApplyPatchResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ApplyPatchError
+// @Failure 415 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/container/fs/apply_patch [post]
+func (h *ContainerdHandler) ApplyPatch(c echo.Context) error {
+	botID, err := h.requireBotAccess(c)
+	if err != nil {
+		return err
+	}
+	var req ApplyPatchRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	files, failed := h.applyPatches(c, botID, []ApplyPatchRequest{req}, req.DryRun)
+	if failed != nil {
+		if conflict := failed.conflict(); conflict != nil {
+			return c.JSON(failed.status, ApplyPatchError{Message: failed.err.Error(), Conflict: conflict})
+		}
+		return echo.NewHTTPError(failed.status, failed.err.Error())
+	}
+	return c.JSON(http.StatusOK, files[0])
+}
+
+// ApplyPatches godoc
+// @Summary Apply several unified diffs as one transaction
+// @Description Every patch is checked against its file before anything is written; if one fails, no file
+// @Description changes and the response names the failing patch. Patches to the same path apply in order.
+// @Description With dry_run=true nothing is written and each file's patched content is returned.
+// @Tags containerd
+// @Param bot_id path string true "Bot ID"
+// @Param dry_run query bool false "Check the patches without writing"
+// @Param payload body []ApplyPatchRequest true "Patches"
+// @Success 200 {object} ApplyPatchesResponse
+// @Failure 400 {object} ApplyPatchesError
+// @Failure 409 {object} ApplyPatchesError
+// @Failure 415 {object} ApplyPatchesError
+// @Failure 500 {object} ApplyPatchesError
+// @Router /bots/{bot_id}/container/fs/apply_patches [post]
+func (h *ContainerdHandler) ApplyPatches(c echo.Context) error {
+	botID, err := h.requireBotAccess(c)
+	if err != nil {
+		return err
+	}
+	var reqs []ApplyPatchRequest
+	if err := c.Bind(&reqs); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if len(reqs) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "at least one patch is required")
+	}
+	dryRun, _ := strconv.ParseBool(c.QueryParam("dry_run"))
+	files, failed := h.applyPatches(c, botID, reqs, dryRun)
+	if failed != nil {
+		return c.JSON(failed.status, ApplyPatchesError{
+			Message:  failed.err.Error(),
+			Index:    failed.index,
+			Path:     reqs[failed.index].Path,
+			Conflict: failed.conflict(),
+		})
+	}
+	return c.JSON(http.StatusOK, ApplyPatchesResponse{Files: files})
+}
+
+func (req ApplyPatchRequest) patchOptions() (patchOptions, error) {
+	switch {
+	case req.Fuzz < 0 || req.Fuzz > maxPatchFuzz:
+		return patchOptions{}, fmt.Errorf("fuzz must be between 0 and %d", maxPatchFuzz)
+	case req.Fuzz > 0:
+		return patchOptions{Fuzz: req.Fuzz}, nil
+	case req.Fuzzy:
+		return patchOptions{Fuzz: patchFuzzWindow}, nil
+	}
+	return patchOptions{}, nil
+}
+
+// patchFailure is the first patch of a batch that could not be applied.
+type patchFailure struct {
+	index  int
+	status int
+	err    error
+}
+
+// conflict describes the failure if a hunk did not match, or returns nil.
+func (f *patchFailure) conflict() *PatchConflict {
+	var conflict *patchConflictError
+	if !errors.As(f.err, &conflict) {
+		return nil
+	}
+	return &PatchConflict{Hunk: conflict.Hunk, Line: conflict.Line, Expected: conflict.Expected, Found: conflict.Found}
+}
+
+// patchedFile is the pending new content of one target.
+type patchedFile struct {
+	// first and last are the indexes of the first and last patch to this
+	// file.
+	first    int
+	last     int
+	target   string
+	original []byte
+	existed  bool
+	mode     os.FileMode
+	content  []byte
+	tmpName  string
+}
+
+// applyPatches applies reqs all-or-nothing: every patch is applied in memory
+// first, then all results are staged next to their targets and only renamed
+// into place once every one has been staged. A rename failing part way
+// restores the files already replaced. A dry run stops before staging and
+// returns each file's final content with its last patch.
+func (h *ContainerdHandler) applyPatches(c echo.Context, botID string, reqs []ApplyPatchRequest, dryRun bool) ([]ApplyPatchResponse, *patchFailure) {
+	unlock := h.lockBotFS(botID)
+	defer unlock()
+
+	var files []*patchedFile
+	byTarget := map[string]*patchedFile{}
+	results := make([]ApplyPatchResponse, len(reqs))
+	targets := make([]string, len(reqs))
+	for i, req := range reqs {
+		target, err := h.resolveFSMutationPath(botID, req.Path)
+		if err != nil {
+			return nil, httpPatchFailure(i, err)
+		}
+		targets[i] = target
+		file, ok := byTarget[target]
+		if !ok {
+			if file, err = loadPatchTarget(target); err != nil {
+				return nil, httpPatchFailure(i, err)
+			}
+			file.first = i
+			byTarget[target] = file
+			files = append(files, file)
+		}
+		if req.BaseHash != "" {
+			if err := checkPatchBase(file, i, req.BaseHash); err != nil {
+				return nil, &patchFailure{index: i, status: http.StatusConflict, err: err}
+			}
+		}
+		opts, err := req.patchOptions()
+		if err != nil {
+			return nil, &patchFailure{index: i, status: http.StatusBadRequest, err: err}
+		}
+		patched, err := applyUnifiedPatch(string(file.content), req.Patch, opts)
+		if err != nil {
+			status := http.StatusConflict
+			if errors.Is(err, errMalformedPatch) {
+				status = http.StatusBadRequest
+			}
+			return nil, &patchFailure{index: i, status: status, err: err}
+		}
+		file.content = []byte(patched.Content)
+		file.last = i
+		results[i] = ApplyPatchResponse{Path: req.Path, Hunks: patched.Hunks, Size: int64(len(patched.Content))}
+		if opts.Fuzz > 0 {
+			results[i].Offsets = patched.Offsets
+		}
+	}
+	for _, file := range files {
+		if err := h.writePolicy.Check(file.target, file.content[:min(len(file.content), 512)], int64(len(file.content))); err != nil {
+			return nil, &patchFailure{index: file.first, status: http.StatusUnsupportedMediaType, err: err}
+		}
+	}
+	if dryRun {
+		for i := len(reqs) - 1; i >= 0; i-- {
+			if file := byTarget[targets[i]]; file.last == i {
+				content := string(file.content)
+				results[i].Content = &content
+			}
+		}
+		return results, nil
+	}
+
+	defer func() {
+		for _, file := range files {
+			if file.tmpName != "" {
+				_ = os.Remove(file.tmpName)
+			}
+		}
+	}()
+	for _, file := range files {
+		if err := os.MkdirAll(filepath.Dir(file.target), 0o755); err != nil {
+			return nil, httpPatchFailure(file.first, fsHTTPError(err))
+		}
+		tmpName, _, err := stageFile(file.target, bytes.NewReader(file.content), file.mode, nil)
+		if err != nil {
+			return nil, httpPatchFailure(file.first, fsHTTPError(err))
+		}
+		file.tmpName = tmpName
+	}
+	for i, file := range files {
+		if err := os.Rename(file.tmpName, file.target); err != nil {
+			restorePatchedFiles(files[:i])
+			return nil, httpPatchFailure(file.first, fsHTTPError(err))
+		}
+		file.tmpName = ""
+	}
+	for _, req := range reqs {
+		h.recordFSWrite(c, botID, req.Path, mcp.WriteAuditEdit, req.Metadata)
+	}
+	return results, nil
+}
+
+// loadPatchTarget reads the current content of target. A missing file loads
+// as empty so that a patch can create it.
+func loadPatchTarget(target string) (*patchedFile, error) {
+	if err := checkRegularFileTarget(target); err != nil {
+		return nil, echo.NewHTTPError(http.StatusConflict, err.Error())
+	}
+	file := &patchedFile{target: target, mode: 0o644}
+	f, err := openRegularFile(target)
+	if err != nil {
+		var httpErr *echo.HTTPError
+		if errors.As(err, &httpErr) && httpErr.Code == http.StatusNotFound {
+			return file, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fsHTTPError(err)
+	}
+	if info.Size() > maxPatchFileSize {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("file exceeds the %d byte patch limit", maxPatchFileSize))
+	}
+	data, err := io.ReadAll(io.LimitReader(f, maxPatchFileSize+1))
+	if err != nil {
+		return nil, fsHTTPError(err)
+	}
+	file.original, file.content, file.existed, file.mode = data, data, true, info.Mode().Perm()
+	return file, nil
+}
+
+// checkPatchBase verifies that the content patch index will apply to, the
+// file as it is or as earlier patches of the batch left it, hashes to base.
+func checkPatchBase(file *patchedFile, index int, base string) error {
+	if !file.existed && index == file.first {
+		return errors.New("base_hash given but the file does not exist")
+	}
+	if current := contentETag(file.content); !etagMatches(base, current) {
+		return fmt.Errorf("file changed since the patch was made: base_hash %s, current %s", base, current)
+	}
+	return nil
+}
+
+// restorePatchedFiles puts back the original content of files that were
+// already replaced, removing those the batch created.
+func restorePatchedFiles(files []*patchedFile) {
+	for _, file := range files {
+		if !file.existed {
+			_ = os.Remove(file.target)
+			continue
+		}
+		_, _ = writeFileAtomic(file.target, bytes.NewReader(file.original), file.mode, nil)
+	}
+}
+
+func httpPatchFailure(index int, err error) *patchFailure {
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		return &patchFailure{index: index, status: httpErr.Code, err: fmt.Errorf("%v", httpErr.Message)}
+	}
+	return &patchFailure{index: index, status: http.StatusInternalServerError, err: err}
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/memohai/memoh/internal/config"
+)
+
+func TestApplyPatchesTransactional(t *testing.T) {
+	dataRoot := t.TempDir()
+	h := &ContainerdHandler{cfg: config.MCPConfig{DataRoot: dataRoot, DataMount: "/data"}}
+	root := filepath.Join(dataRoot, "bots", "bot-1")
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{"a.txt": "alpha\n", "b.txt": "beta\n"} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx := echo.New().NewContext(httptest.NewRequest(http.MethodPost, "/", nil), httptest.NewRecorder())
+	read := func(name string) string {
+		data, _ := os.ReadFile(filepath.Join(root, name))
+		return string(data)
+	}
+
+	_, failed := h.applyPatches(ctx, "bot-1", []ApplyPatchRequest{
+		{Path: "a.txt", Patch: "@@ -1 +1 @@\n-alpha\n+ALPHA\n"},
+		{Path: "new.txt", Patch: "@@ -0,0 +1 @@\n+new\n"},
+		{Path: "b.txt", Patch: "@@ -1 +1 @@\n-gamma\n+GAMMA\n"},
+	}, false)
+	if failed == nil || failed.index != 2 || failed.status != http.StatusConflict {
+		t.Fatalf("failure = %+v, want conflict on patch 2", failed)
+	}
+	if read("a.txt") != "alpha\n" || read("b.txt") != "beta\n" {
+		t.Fatal("failed batch modified files")
+	}
+	if _, err := os.Stat(filepath.Join(root, "new.txt")); !os.IsNotExist(err) {
+		t.Fatal("failed batch created a file")
+	}
+	entries, _ := os.ReadDir(root)
+	for _, entry := range entries {
+		if strings.Contains(entry.Name(), ".tmp-") {
+			t.Fatalf("temp file %s left behind", entry.Name())
+		}
+	}
+
+	results, failed := h.applyPatches(ctx, "bot-1", []ApplyPatchRequest{
+		{Path: "a.txt", Patch: "@@ -1 +1 @@\n-alpha\n+ALPHA\n"},
+		{Path: "/data/a.txt", Patch: "@@ -1 +1,2 @@\n ALPHA\n+again\n"},
+		{Path: "dir/new.txt", Patch: "@@ -0,0 +1 @@\n+new\n"},
+	}, false)
+	if failed != nil {
+		t.Fatalf("apply: %v", failed.err)
+	}
+	if len(results) != 3 || results[1].Size != int64(len("ALPHA\nagain\n")) {
+		t.Fatalf("results = %+v", results)
+	}
+	if got := read("a.txt"); got != "ALPHA\nagain\n" {
+		t.Errorf("a.txt = %q", got)
+	}
+	if got := read("dir/new.txt"); got != "new\n" {
+		t.Errorf("dir/new.txt = %q", got)
+	}
+	if info, _ := os.Stat(filepath.Join(root, "a.txt")); info.Mode().Perm() != 0o600 {
+		t.Errorf("a.txt mode = %v, want 0600 kept", info.Mode().Perm())
+	}
+}
+
+func TestApplyPatchBaseHash(t *testing.T) {
+	dataRoot := t.TempDir()
+	h := &ContainerdHandler{cfg: config.MCPConfig{DataRoot: dataRoot, DataMount: "/data"}}
+	root := filepath.Join(dataRoot, "bots", "bot-1")
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "a.txt"), []byte("alpha\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ctx := echo.New().NewContext(httptest.NewRequest(http.MethodPost, "/", nil), httptest.NewRecorder())
+	patch := "@@ -1 +1 @@\n-alpha\n+ALPHA\n"
+
+	for name, req := range map[string]ApplyPatchRequest{
+		"stale base":   {Path: "a.txt", Patch: patch, BaseHash: strings.Trim(contentETag([]byte("old\n")), `"`)},
+		"missing file": {Path: "b.txt", Patch: "@@ -0,0 +1 @@\n+b\n", BaseHash: contentETag(nil)},
+	} {
+		if _, failed := h.applyPatches(ctx, "bot-1", []ApplyPatchRequest{req}, false); failed == nil || failed.status != http.StatusConflict {
+			t.Errorf("%s: failure = %+v, want conflict", name, failed)
+		}
+	}
+	if _, failed := h.applyPatches(ctx, "bot-1", []ApplyPatchRequest{
+		{Path: "a.txt", Patch: patch, BaseHash: strings.Trim(contentETag([]byte("alpha\n")), `"`)},
+		{Path: "a.txt", Patch: "@@ -1 +1 @@\n-ALPHA\n+done\n", BaseHash: contentETag([]byte("ALPHA\n"))},
+	}, false); failed != nil {
+		t.Fatalf("apply with matching base: %v", failed.err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(root, "a.txt")); string(data) != "done\n" {
+		t.Errorf("a.txt = %q", data)
+	}
+}
+
+func TestApplyPatchDryRun(t *testing.T) {
+	dataRoot := t.TempDir()
+	h := &ContainerdHandler{cfg: config.MCPConfig{DataRoot: dataRoot, DataMount: "/data"}}
+	root := filepath.Join(dataRoot, "bots", "bot-1")
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "a.txt"), []byte("one\ntwo\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ctx := echo.New().NewContext(httptest.NewRequest(http.MethodPost, "/", nil), httptest.NewRecorder())
+
+	results, failed := h.applyPatches(ctx, "bot-1", []ApplyPatchRequest{
+		{Path: "a.txt", Patch: "@@ -1 +1 @@\n-one\n+ONE\n"},
+		{Path: "a.txt", Patch: "@@ -2 +2 @@\n-two\n+TWO\n"},
+		{Path: "new.txt", Patch: "@@ -0,0 +1 @@\n+new\n"},
+	}, true)
+	if failed != nil {
+		t.Fatalf("dry run: %v", failed.err)
+	}
+	if results[0].Content != nil {
+		t.Errorf("content on superseded patch: %q", *results[0].Content)
+	}
+	if results[1].Content == nil || *results[1].Content != "ONE\nTWO\n" {
+		t.Errorf("a.txt content = %v", results[1].Content)
+	}
+	if results[2].Content == nil || *results[2].Content != "new\n" {
+		t.Errorf("new.txt content = %v", results[2].Content)
+	}
+	if data, _ := os.ReadFile(filepath.Join(root, "a.txt")); string(data) != "one\ntwo\n" {
+		t.Errorf("dry run wrote a.txt: %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(root, "new.txt")); !os.IsNotExist(err) {
+		t.Error("dry run created new.txt")
+	}
+
+	_, failed = h.applyPatches(ctx, "bot-1", []ApplyPatchRequest{
+		{Path: "a.txt", Patch: "@@ -1,2 +1,2 @@\n one\n-three\n+THREE\n"},
+	}, true)
+	if failed == nil || failed.status != http.StatusConflict {
+		t.Fatalf("failure = %+v, want conflict", failed)
+	}
+	want := PatchConflict{Hunk: 1, Line: 2, Expected: "three", Found: "two"}
+	if conflict := failed.conflict(); conflict == nil || *conflict != want {
+		t.Errorf("conflict = %+v, want %+v", conflict, want)
+	}
+}
@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+type ApplyPatchsetRequest struct {
+	// Patch is a unified diff touching any number of files, as produced by
+	// diff -ru or git diff.
+	Patch string `json:"patch"`
+	Fuzzy bool   `json:"fuzzy,omitempty"`
+	Fuzz  int    `json:"fuzz,omitempty"`
+	// DryRun checks that every file applies and returns the patched content
+	// without writing it.
+	DryRun   bool           `json:"dry_run,omitempty"`
+	Metadata map[string]any `json:"metadata,omitempty"`
+}
+
+// ApplyPatchsetFile is one file section of a patchset that could not be
+// parsed.
+type ApplyPatchsetFile struct {
+	Path  string `json:"path"`
+	Error string `json:"error,omitempty"`
+}
+
+// ApplyPatchsetError lists every file section of a patchset with the reason
+// it was rejected, if any, when the patchset could not be parsed.
+type ApplyPatchsetError struct {
+	Message string              `json:"message"`
+	Files   []ApplyPatchsetFile `json:"files"`
+}
+
+// ApplyPatchset godoc
+// @Summary Apply a multi-file unified diff as one transaction
+// @Description The diff is split at its ---/+++ file headers; git's a/ and b/ prefixes are dropped. If any
+// @Description file section cannot be parsed nothing is applied and every section is listed with its error.
+// @Description Otherwise the files are patched all-or-nothing like apply_patches. Deleting files through a
+// @Description patch (+++ /dev/null) is not supported.
+// @Tags containerd
+// @Param bot_id path string true "Bot ID"
+// @Param payload body ApplyPatchsetRequest true "Patchset"
+// @Success 200 {object} ApplyPatchesResponse
+// @Failure 400 {object} ApplyPatchsetError
+// @Failure 409 {object} ApplyPatchesError
+// @Failure 415 {object} ApplyPatchesError
+// @Failure 500 {object} ApplyPatchesError
+// @Router /bots/{bot_id}/container/fs/apply_patchset [post]
+func (h *ContainerdHandler) ApplyPatchset(c echo.Context) error {
+	botID, err := h.requireBotAccess(c)
+	if err != nil {
+		return err
+	}
+	var req ApplyPatchsetRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	sections := splitPatchset(req.Patch)
+	if len(sections) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "patch has no ---/+++ file headers")
+	}
+	reqs := make([]ApplyPatchRequest, len(sections))
+	report := make([]ApplyPatchsetFile, len(sections))
+	failed := false
+	for i, section := range sections {
+		report[i].Path = section.path
+		reqs[i] = ApplyPatchRequest{Path: section.path, Patch: section.patch, Fuzzy: req.Fuzzy, Fuzz: req.Fuzz, Metadata: req.Metadata}
+		if err := section.validate(); err != nil {
+			report[i].Error = err.Error()
+			failed = true
+		}
+	}
+	if failed {
+		return c.JSON(http.StatusBadRequest, ApplyPatchsetError{Message: "patch could not be parsed", Files: report})
+	}
+	files, failure := h.applyPatches(c, botID, reqs, req.DryRun)
+	if failure != nil {
+		return c.JSON(failure.status, ApplyPatchesError{
+			Message:  failure.err.Error(),
+			Index:    failure.index,
+			Path:     reqs[failure.index].Path,
+			Conflict: failure.conflict(),
+		})
+	}
+	return c.JSON(http.StatusOK, ApplyPatchesResponse{Files: files})
+}
+
+// patchsetSection is the part of a patchset that touches one file.
+type patchsetSection struct {
+	path  string
+	patch string
+}
+
+func (s patchsetSection) validate() error {
+	if s.path == "/dev/null" {
+		return errors.New("deleting files is not supported; use DELETE /fs/delete")
+	}
+	if s.path == "" {
+		return errors.New("missing file name in +++ header")
+	}
+	_, err := parseUnifiedPatch(s.patch)
+	return err
+}
+
+// splitPatchset splits a multi-file unified diff at its ---/+++ header
+// pairs. Hunk bodies are tracked by their line counts, so a removed line
+// that happens to start with "-- " is not mistaken for a header. Lines
+// between one file's last hunk and the next header, such as git's diff and
+// index lines, are dropped.
+func splitPatchset(patch string) []patchsetSection {
+	lines := strings.Split(strings.ReplaceAll(patch, "\r\n", "\n"), "\n")
+	var sections []patchsetSection
+	var body []string
+	flush := func() {
+		if len(sections) > 0 {
+			sections[len(sections)-1].patch = strings.Join(body, "\n") + "\n"
+		}
+		body = nil
+	}
+	oldLeft, newLeft := 0, 0
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if oldLeft > 0 || newLeft > 0 {
+			switch {
+			case strings.HasPrefix(line, `\`):
+			case line == "" || line[0] == ' ':
+				oldLeft--
+				newLeft--
+			case line[0] == '-':
+				oldLeft--
+			case line[0] == '+':
+				newLeft--
+			default:
+				// A short hunk; leave the error to parseUnifiedPatch.
+				oldLeft, newLeft = 0, 0
+				i--
+				continue
+			}
+			body = append(body, line)
+			continue
+		}
+		if strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ ") {
+			flush()
+			sections = append(sections, patchsetSection{path: patchsetPath(line[4:], lines[i+1][4:])})
+			body = []string{line, lines[i+1]}
+			i++
+			continue
+		}
+		if m := hunkHeaderRe.FindStringSubmatch(line); m != nil {
+			oldLeft, newLeft = atoiDefault(m[2], 1), atoiDefault(m[4], 1)
+			body = append(body, line)
+			continue
+		}
+		if strings.HasPrefix(line, `\`) {
+			// A no-newline marker right after a hunk's last line.
+			body = append(body, line)
+		}
+	}
+	flush()
+	return sections
+}
+
+// patchsetPath returns the file a ---/+++ header pair targets: the new
+// name, without a timestamp and without git's b/ prefix.
+func patchsetPath(oldName, newName string) string {
+	clean := func(name string) string {
+		name, _, _ = strings.Cut(name, "\t")
+		return strings.TrimSpace(name)
+	}
+	oldName, newName = clean(oldName), clean(newName)
+	if (oldName == "/dev/null" || strings.HasPrefix(oldName, "a/")) && strings.HasPrefix(newName, "b/") {
+		return strings.TrimPrefix(newName, "b/")
+	}
+	return newName
+}
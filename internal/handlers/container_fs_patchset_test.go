@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitPatchset(t *testing.T) {
+	patch := `diff --git a/notes.md b/notes.md
+index 1111111..2222222 100644
+--- a/notes.md
++++ b/notes.md
+@@ -1,2 +1,2 @@
+--- old rule
++--- new rule
+ keep
+diff --git a/new.txt b/new.txt
+new file mode 100644
+--- /dev/null
++++ b/new.txt
+@@ -0,0 +1 @@
++hello
+\ No newline at end of file
+--- plain/a.txt	2024-01-01 00:00:00
++++ plain/a.txt	2024-01-02 00:00:00
+@@ -1 +1 @@
+-x
++y
+`
+	sections := splitPatchset(patch)
+	var paths []string
+	for _, s := range sections {
+		paths = append(paths, s.path)
+		if err := s.validate(); err != nil {
+			t.Errorf("%s: %v", s.path, err)
+		}
+	}
+	if got := strings.Join(paths, ","); got != "notes.md,new.txt,plain/a.txt" {
+		t.Fatalf("paths = %s", got)
+	}
+	if !strings.Contains(sections[0].patch, "\n--- old rule\n+--- new rule\n") || strings.Contains(sections[0].patch, "diff --git") {
+		t.Errorf("first section = %q", sections[0].patch)
+	}
+	got, err := applyUnifiedPatch("", sections[1].patch, patchOptions{})
+	if err != nil || got.Content != "hello" {
+		t.Errorf("creation section applied to %q, %v", got.Content, err)
+	}
+
+	deletion := splitPatchset("--- a/gone.txt\n+++ /dev/null\n@@ -1 +0,0 @@\n-bye\n")
+	if len(deletion) != 1 || deletion[0].validate() == nil {
+		t.Errorf("deletion should be rejected: %+v", deletion)
+	}
+	if len(splitPatchset("@@ -1 +1 @@\n-a\n+b\n")) != 0 {
+		t.Error("a patch without file headers has no sections")
+	}
+}
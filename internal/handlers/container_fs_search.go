@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	defaultFSSearchMatches = 100
+	maxFSSearchMatches     = 1000
+	// Files larger than this are skipped rather than scanned.
+	maxFSSearchFileSize = 10 << 20
+	// Lines longer than this end the scan of their file.
+	maxFSSearchLineSize = 1 << 20
+	fsSearchPreviewLen  = 200
+	fsSearchSniffLen    = 8000
+)
+
+type FSSearchMatch struct {
+	Path    string `json:"path"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Preview string `json:"preview"`
+}
+
+type FSSearchResponse struct {
+	Matches   []FSSearchMatch `json:"matches"`
+	Truncated bool            `json:"truncated"`
+}
+
+// errSearchLimit stops the walk once enough matches were collected.
+var errSearchLimit = errors.New("search limit reached")
+
+// SearchFS godoc
+// @Summary Search file contents in the bot data mount
+// @Description Recursively matches file contents under path against a literal or regex query.
+// @Description Binary files, symlinks and files over 10 MiB are skipped.
+// @Tags containerd
+// @Param bot_id path string true "Bot ID"
+// @Param path query string false "Directory or file to search; defaults to the data mount root"
+// @Param query query string true "Text or regular expression to find"
+// @Param regex query bool false "Treat query as a Go regular expression"
+// @Param max query int false "Maximum number of matches (default 100, at most mcp.search_max_matches or 1000)"
+// @Success 200 {object} FSSearchResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/container/fs/search [get]
+func (h *ContainerdHandler) SearchFS(c echo.Context) error {
+	botID, err := h.requireBotAccess(c)
+	if err != nil {
+		return err
+	}
+	query := c.QueryParam("query")
+	if query == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "query is required")
+	}
+	useRegex, _ := strconv.ParseBool(c.QueryParam("regex"))
+	match, err := fsSearchMatcher(query, useRegex)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	maxMatches := maxFSSearchMatches
+	if h.cfg.SearchMaxMatches > 0 {
+		maxMatches = h.cfg.SearchMaxMatches
+	}
+	limit := min(defaultFSSearchMatches, maxMatches)
+	if raw := strings.TrimSpace(c.QueryParam("max")); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "max must be a positive integer")
+		}
+		limit = min(n, maxMatches)
+	}
+	rawPath := c.QueryParam("path")
+	if strings.TrimSpace(rawPath) == "" {
+		rawPath = "."
+	}
+	root, target, err := h.resolveFSPath(botID, rawPath)
+	if err != nil {
+		return err
+	}
+
+	unlock := h.rlockBotFS(botID)
+	defer unlock()
+	if _, err := os.Lstat(target); err != nil {
+		return fsHTTPError(err)
+	}
+	mount := path.Clean(h.botDataMount(botID))
+	resp, err := searchFiles(c.Request().Context(), root, target, mount, match, limit)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// fsSearchMatcher returns a function giving the byte offset of the first
+// match in a line, or -1.
+func fsSearchMatcher(query string, useRegex bool) (func(string) int, error) {
+	if !useRegex {
+		return func(line string) int { return strings.Index(line, query) }, nil
+	}
+	re, err := regexp.Compile(query)
+	if err != nil {
+		return nil, err
+	}
+	return func(line string) int {
+		if loc := re.FindStringIndex(line); loc != nil {
+			return loc[0]
+		}
+		return -1
+	}, nil
+}
+
+// searchFiles walks target (a file or directory under root) without
+// following symlinks and reports matches with paths under mount.
+func searchFiles(ctx context.Context, root, target, mount string, match func(string) int, limit int) (FSSearchResponse, error) {
+	resp := FSSearchResponse{Matches: []FSSearchMatch{}}
+	err := filepath.WalkDir(target, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Unreadable entries are skipped; the rest of the tree is still searched.
+			if d != nil && d.IsDir() && p != target {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return nil
+		}
+		display := path.Join(mount, filepath.ToSlash(rel))
+		return searchFile(p, display, match, limit, &resp)
+	})
+	if errors.Is(err, errSearchLimit) {
+		resp.Truncated = true
+		err = nil
+	}
+	return resp, err
+}
+
+func searchFile(p, display string, match func(string) int, limit int, resp *FSSearchResponse) error {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	if info, err := f.Stat(); err != nil || info.Size() > maxFSSearchFileSize {
+		return nil
+	}
+	reader := bufio.NewReaderSize(f, fsSearchSniffLen)
+	head, _ := reader.Peek(fsSearchSniffLen)
+	if bytes.IndexByte(head, 0) >= 0 {
+		return nil
+	}
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxFSSearchLineSize)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Text()
+		idx := match(line)
+		if idx < 0 {
+			continue
+		}
+		if len(resp.Matches) >= limit {
+			return errSearchLimit
+		}
+		resp.Matches = append(resp.Matches, FSSearchMatch{
+			Path:    display,
+			Line:    lineNo,
+			Column:  utf8.RuneCountInString(line[:idx]) + 1,
+			Preview: searchPreview(line, idx),
+		})
+	}
+	// A scan error (e.g. an overlong line) only ends this file.
+	return nil
+}
+
+// searchPreview trims line to at most fsSearchPreviewLen bytes around the
+// match at idx, keeping UTF-8 sequences intact.
+func searchPreview(line string, idx int) string {
+	line = strings.TrimRight(line, "\r")
+	if len(line) <= fsSearchPreviewLen {
+		return line
+	}
+	start := max(0, idx-fsSearchPreviewLen/4)
+	for start > 0 && !utf8.RuneStart(line[start]) {
+		start--
+	}
+	end := min(len(line), start+fsSearchPreviewLen)
+	for end < len(line) && !utf8.RuneStart(line[end]) {
+		end--
+	}
+	return line[start:end]
+}
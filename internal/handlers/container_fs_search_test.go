@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSearchFiles(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		p := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("notes/a.md", "first line\nthe needle is here\n")
+	write("notes/b.md", "héllo needle\n")
+	write("blob.bin", "needle\x00binary")
+	if err := os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("needle"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "escape")); err != nil {
+		t.Fatal(err)
+	}
+
+	match, err := fsSearchMatcher("needle", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := searchFiles(context.Background(), root, root, "/data", match, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []FSSearchMatch{
+		{Path: "/data/notes/a.md", Line: 2, Column: 5, Preview: "the needle is here"},
+		{Path: "/data/notes/b.md", Line: 1, Column: 7, Preview: "héllo needle"},
+	}
+	if len(resp.Matches) != len(want) || resp.Truncated {
+		t.Fatalf("unexpected matches: %+v", resp)
+	}
+	for i := range want {
+		if resp.Matches[i] != want[i] {
+			t.Fatalf("match %d = %+v, want %+v", i, resp.Matches[i], want[i])
+		}
+	}
+
+	resp, err = searchFiles(context.Background(), root, root, "/data", match, 1)
+	if err != nil || len(resp.Matches) != 1 || !resp.Truncated {
+		t.Fatalf("expected one truncated match, got %+v %v", resp, err)
+	}
+
+	re, err := fsSearchMatcher(`n[e]+dle is`, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, _ = searchFiles(context.Background(), root, filepath.Join(root, "notes"), "/data", re, 10)
+	if len(resp.Matches) != 1 || resp.Matches[0].Path != "/data/notes/a.md" {
+		t.Fatalf("regex search = %+v", resp)
+	}
+	if _, err := fsSearchMatcher("(", true); err == nil {
+		t.Fatal("expected invalid regex to fail")
+	}
+}
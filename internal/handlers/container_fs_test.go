@@ -0,0 +1,299 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+
+	"github.com/memohai/memoh/internal/auth"
+	"github.com/memohai/memoh/internal/config"
+)
+
+func TestResolveFSPath(t *testing.T) {
+	dataRoot := t.TempDir()
+	h := &ContainerdHandler{cfg: config.MCPConfig{DataRoot: dataRoot, DataMount: "/data"}}
+	root := filepath.Join(dataRoot, "bots", "bot-1")
+
+	outside := t.TempDir()
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "escape")); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "notes/a.md", want: filepath.Join(root, "notes", "a.md")},
+		{in: "/data/notes/a.md", want: filepath.Join(root, "notes", "a.md")},
+		{in: "/data", want: root},
+		{in: "../../etc/passwd", want: filepath.Join(root, "etc", "passwd")},
+		{in: "escape/secret", want: filepath.Join(root, outside, "secret")},
+		{in: "escape", want: filepath.Join(root, "escape")},
+		{in: "/etc/passwd", wantErr: true},
+		{in: "  ", wantErr: true},
+	}
+	for _, tc := range cases {
+		_, got, err := h.resolveFSPath("bot-1", tc.in)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("resolveFSPath(%q) = %q, want error", tc.in, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("resolveFSPath(%q): %v", tc.in, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("resolveFSPath(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+
+	if _, err := h.resolveFSMutationPath("bot-1", "/data/"); err == nil {
+		t.Error("mutation of the data mount root should be refused")
+	}
+}
+
+func TestOpenRegularFile(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(file, []byte("hi"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(file, link); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := openRegularFile(file)
+	if err != nil {
+		t.Fatalf("open regular file: %v", err)
+	}
+	_ = f.Close()
+	if _, err := openRegularFile(link); err == nil {
+		t.Error("symlinks should not be followed")
+	}
+	if _, err := openRegularFile(dir); err == nil {
+		t.Error("directories should be rejected")
+	}
+	if _, err := openRegularFile(filepath.Join(dir, "missing")); err == nil {
+		t.Error("missing files should be rejected")
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "asset.bin")
+	if err := os.WriteFile(target, []byte("old"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	size, err := writeFileAtomic(target, strings.NewReader("new content"), 0o640, nil)
+	if err != nil || size != 11 {
+		t.Fatalf("write: size=%d err=%v", size, err)
+	}
+	info, err := os.Stat(target)
+	if err != nil || info.Mode().Perm() != 0o640 {
+		t.Fatalf("stat: %v %v", info, err)
+	}
+
+	denied := errors.New("too big")
+	if _, err := writeFileAtomic(target, strings.NewReader("x"), 0o644, func(int64) error { return denied }); !errors.Is(err, denied) {
+		t.Fatalf("expected veto error, got %v", err)
+	}
+	if got, _ := os.ReadFile(target); string(got) != "new content" {
+		t.Fatalf("vetoed write replaced the file: %q", got)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Fatalf("temp files left behind: %v", entries)
+	}
+}
+
+func TestCheckIfMatch(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "notes.md")
+	if err := os.WriteFile(target, []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// md5("hello")
+	const etag = `"5d41402abc4b2a76b9719d911017c592"`
+
+	for _, header := range []string{"", "*", etag, `"other", ` + etag, strings.Trim(etag, `"`)} {
+		if err := checkIfMatch(target, header); err != nil {
+			t.Errorf("If-Match %q: unexpected error %v", header, err)
+		}
+	}
+	for _, header := range []string{`"0123"`, "W/" + etag} {
+		if err := checkIfMatch(target, header); !isHTTPStatus(err, 412) {
+			t.Errorf("If-Match %q: got %v, want 412", header, err)
+		}
+	}
+	missing := filepath.Join(dir, "missing.md")
+	if err := checkIfMatch(missing, "*"); !isHTTPStatus(err, 412) {
+		t.Errorf("If-Match * on a missing file: got %v, want 412", err)
+	}
+	if err := checkIfMatch(missing, ""); err != nil {
+		t.Errorf("no precondition on a missing file: %v", err)
+	}
+}
+
+func isHTTPStatus(err error, code int) bool {
+	var httpErr *echo.HTTPError
+	return errors.As(err, &httpErr) && httpErr.Code == code
+}
+
+func TestServeFSFileRange(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(target, []byte("0123456789"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	serve := func(header map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/fs/download", nil)
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		f, err := os.Open(target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		if err := serveFSFile(echo.New().NewContext(req, rec), f, "notes.txt"); err != nil {
+			t.Fatal(err)
+		}
+		return rec
+	}
+
+	full := serve(nil)
+	etag := full.Header().Get("ETag")
+	if full.Code != http.StatusOK || full.Body.String() != "0123456789" || etag == "" {
+		t.Fatalf("full download = %d %q etag %q", full.Code, full.Body.String(), etag)
+	}
+	if got := full.Header().Get("Accept-Ranges"); got != "bytes" {
+		t.Errorf("Accept-Ranges = %q, want bytes", got)
+	}
+
+	cases := []struct {
+		name     string
+		header   map[string]string
+		wantCode int
+		wantBody string
+	}{
+		{name: "resume", header: map[string]string{"Range": "bytes=4-"}, wantCode: http.StatusPartialContent, wantBody: "456789"},
+		{name: "if-range match", header: map[string]string{"Range": "bytes=2-3", "If-Range": etag}, wantCode: http.StatusPartialContent, wantBody: "23"},
+		{name: "if-range stale", header: map[string]string{"Range": "bytes=2-3", "If-Range": `"stale"`}, wantCode: http.StatusOK, wantBody: "0123456789"},
+		{name: "unsatisfiable", header: map[string]string{"Range": "bytes=20-"}, wantCode: http.StatusRequestedRangeNotSatisfiable},
+		{name: "not modified", header: map[string]string{"If-None-Match": etag}, wantCode: http.StatusNotModified},
+	}
+	for _, tc := range cases {
+		rec := serve(tc.header)
+		if rec.Code != tc.wantCode {
+			t.Errorf("%s: status = %d, want %d", tc.name, rec.Code, tc.wantCode)
+			continue
+		}
+		if tc.wantBody != "" && rec.Body.String() != tc.wantBody {
+			t.Errorf("%s: body = %q, want %q", tc.name, rec.Body.String(), tc.wantBody)
+		}
+	}
+}
+
+func TestRequireFSWrite(t *testing.T) {
+	ok := func(c echo.Context) error { return c.NoContent(http.StatusNoContent) }
+	cases := []struct {
+		name     string
+		readOnly bool
+		scope    string
+		want     int
+	}{
+		{name: "writable", want: http.StatusNoContent},
+		{name: "read-only server", readOnly: true, want: http.StatusForbidden},
+		{name: "read-only token", scope: "chat " + auth.ScopeFSReadOnly, want: http.StatusForbidden},
+		{name: "other scope", scope: "chat", want: http.StatusNoContent},
+	}
+	for _, tc := range cases {
+		h := &ContainerdHandler{cfg: config.MCPConfig{ReadOnlyFS: tc.readOnly}}
+		c := echo.New().NewContext(httptest.NewRequest(http.MethodPost, "/", nil), httptest.NewRecorder())
+		claims := jwt.MapClaims{}
+		if tc.scope != "" {
+			claims["scope"] = tc.scope
+		}
+		c.Set("user", &jwt.Token{Claims: claims, Valid: true})
+		err := h.requireFSWrite(ok)(c)
+		code := c.Response().Status
+		var httpErr *echo.HTTPError
+		if errors.As(err, &httpErr) {
+			code = httpErr.Code
+		}
+		if code != tc.want {
+			t.Errorf("%s: status = %d, want %d", tc.name, code, tc.want)
+		}
+	}
+}
+
+func TestFileChecksum(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "a.txt")
+	if err := os.WriteFile(target, []byte("hello\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := openRegularFile(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	got, err := fileChecksum(f, checksumAlgos["sha256"]())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"; got.Checksum != want || got.Size != 6 {
+		t.Errorf("checksum = %s (%d bytes), want %s (6 bytes)", got.Checksum, got.Size, want)
+	}
+}
+
+func TestStoreUploadMTime(t *testing.T) {
+	dataRoot := t.TempDir()
+	h := &ContainerdHandler{cfg: config.MCPConfig{DataRoot: dataRoot, DataMount: "/data"}}
+	root := filepath.Join(dataRoot, "bots", "bot-1")
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	e := echo.New()
+	upload := func(mtime string) error {
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(http.MethodPost, "/", nil), rec)
+		return h.storeUpload(c, "bot-1", fsUpload{Path: "weights/model.bin", Mode: "0600", MTime: mtime}, strings.NewReader("payload"))
+	}
+
+	if err := upload("2024-05-01T12:00:00Z"); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(filepath.Join(root, "weights", "model.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC); !info.ModTime().Equal(want) || info.Mode().Perm() != 0o600 {
+		t.Errorf("uploaded file: mtime %v mode %v", info.ModTime(), info.Mode().Perm())
+	}
+	if err := upload("1700000000"); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := os.Stat(filepath.Join(root, "weights", "model.bin")); info.ModTime().Unix() != 1700000000 {
+		t.Errorf("unix mtime not applied: %v", info.ModTime())
+	}
+	var httpErr *echo.HTTPError
+	if err := upload("yesterday"); !errors.As(err, &httpErr) || httpErr.Code != http.StatusBadRequest {
+		t.Errorf("invalid mtime = %v, want 400", err)
+	}
+}
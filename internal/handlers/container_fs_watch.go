@@ -0,0 +1,265 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/labstack/echo/v4"
+)
+
+// maxFSWatchDirs caps the directories one watch stream observes; deeper or
+// wider trees are only partially watched.
+const maxFSWatchDirs = 4096
+
+// fsWatchDebounce is how long a file must go without writes before its
+// modified event is sent, so a burst of writes yields one event.
+const fsWatchDebounce = 100 * time.Millisecond
+
+// Event types sent by WatchFS.
+const (
+	fsWatchCreated  = "created"
+	fsWatchModified = "modified"
+	fsWatchDeleted  = "deleted"
+	fsWatchRenamed  = "renamed"
+)
+
+type FSWatchEvent struct {
+	Type string `json:"type"`
+	// Path is relative to the watched directory, with forward slashes.
+	Path string `json:"path"`
+	// ContainerPath is the absolute path inside the container.
+	ContainerPath string `json:"container_path,omitempty"`
+}
+
+// WatchFS godoc
+// @Summary Stream changes below a directory of the bot data mount
+// @Description Sends server-sent events named created, modified, deleted or renamed with an FSWatchEvent
+// @Description payload. Subdirectories are watched as they appear. A rename is reported as renamed for the
+// @Description old path and created for the new one. Bursts of writes to a file are merged into one modified
+// @Description event once the file has been quiet for 100ms. Writes by the container's own processes are
+// @Description reported as well, since they land on the host data mount. The stream ends with a closed
+// @Description event when the watched directory is removed, e.g. when the bot's data is unmounted.
+// @Tags containerd
+// @Produce text/event-stream
+// @Param bot_id path string true "Bot ID"
+// @Param path query string false "Directory to watch; defaults to the data mount root"
+// @Success 200 {string} string "SSE stream"
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/container/fs/watch [get]
+func (h *ContainerdHandler) WatchFS(c echo.Context) error {
+	botID, err := h.requireBotAccess(c)
+	if err != nil {
+		return err
+	}
+	rawPath := c.QueryParam("path")
+	if strings.TrimSpace(rawPath) == "" {
+		rawPath = "."
+	}
+	root, target, err := h.resolveFSPath(botID, rawPath)
+	if err != nil {
+		return err
+	}
+	info, err := os.Lstat(target)
+	if err != nil {
+		return fsHTTPError(err)
+	}
+	if !info.IsDir() {
+		return echo.NewHTTPError(http.StatusBadRequest, "path is not a directory")
+	}
+	// The stream is long-lived, so it does not hold the bot's FS lock.
+	watch, err := newFSWatch(target, maxFSWatchDirs)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	defer watch.Close()
+	if rel, err := filepath.Rel(root, target); err == nil {
+		watch.containerDir = path.Join(h.botDataMount(botID), filepath.ToSlash(rel))
+	}
+
+	stream, err := newSSEWriter(c)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+	err = watch.Run(c.Request().Context(), func(ev FSWatchEvent) error {
+		return stream.SendJSON(ev.Type, ev)
+	})
+	switch {
+	case err == nil:
+		_ = stream.SendJSON("closed", FSWatchEvent{Path: ".", ContainerPath: watch.containerDir})
+	case !errors.Is(err, context.Canceled):
+		h.logger.Warn("fs watch ended", slog.String("bot_id", botID), slog.String("path", rawPath), slog.Any("error", err))
+	}
+	return nil
+}
+
+// fsWatch reports changes below root, adding watches for subdirectories as
+// they are created.
+type fsWatch struct {
+	watcher *fsnotify.Watcher
+	root    string
+	maxDirs int
+	// containerDir is root as seen inside the container; events carry a
+	// ContainerPath when it is set.
+	containerDir string
+	debounce     time.Duration
+}
+
+func newFSWatch(root string, maxDirs int) (*fsWatch, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	w := &fsWatch{watcher: watcher, root: root, maxDirs: maxDirs, debounce: fsWatchDebounce}
+	if err := w.addTree(root); err != nil {
+		_ = watcher.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *fsWatch) Close() error {
+	return w.watcher.Close()
+}
+
+// Run calls emit for every change until ctx is done, emit fails or root is
+// removed. It returns nil only in the last case. Modified events wait until
+// their file has had no writes for the debounce interval; one pending for a
+// file that is then deleted or renamed is dropped.
+func (w *fsWatch) Run(ctx context.Context, emit func(FSWatchEvent) error) error {
+	ticker := time.NewTicker(w.debounce)
+	defer ticker.Stop()
+	// pending maps paths with unsent modified events to their last write.
+	pending := map[string]time.Time{}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return nil
+			}
+			return err
+		case now := <-ticker.C:
+			if err := w.flush(pending, now.Add(-w.debounce), emit); err != nil {
+				return err
+			}
+		case ev, ok := <-w.watcher.Events:
+			if !ok {
+				return nil
+			}
+			if ev.Name == w.root && ev.Has(fsnotify.Remove|fsnotify.Rename) {
+				return nil
+			}
+			out, ok := w.translate(ev)
+			if !ok {
+				continue
+			}
+			switch out.Type {
+			case fsWatchModified:
+				pending[out.Path] = time.Now()
+				continue
+			case fsWatchDeleted, fsWatchRenamed:
+				delete(pending, out.Path)
+			}
+			if err := emit(out); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// flush emits, in path order, the pending modified events whose last write
+// is not after quietSince.
+func (w *fsWatch) flush(pending map[string]time.Time, quietSince time.Time, emit func(FSWatchEvent) error) error {
+	var due []string
+	for p, at := range pending {
+		if !at.After(quietSince) {
+			due = append(due, p)
+		}
+	}
+	slices.Sort(due)
+	for _, p := range due {
+		delete(pending, p)
+		if err := emit(w.event(fsWatchModified, p)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *fsWatch) event(typ, rel string) FSWatchEvent {
+	ev := FSWatchEvent{Type: typ, Path: rel}
+	if w.containerDir != "" {
+		ev.ContainerPath = path.Join(w.containerDir, rel)
+	}
+	return ev
+}
+
+// translate maps an fsnotify event onto an FSWatchEvent, watching new
+// directories on the way. Attribute-only changes are dropped.
+func (w *fsWatch) translate(ev fsnotify.Event) (FSWatchEvent, bool) {
+	rel, err := filepath.Rel(w.root, ev.Name)
+	if err != nil {
+		return FSWatchEvent{}, false
+	}
+	var typ string
+	switch {
+	case ev.Has(fsnotify.Create):
+		typ = fsWatchCreated
+		if info, err := os.Lstat(ev.Name); err == nil && info.IsDir() {
+			// Entries created before the watch is in place are not reported.
+			_ = w.addTree(ev.Name)
+		}
+	case ev.Has(fsnotify.Write):
+		typ = fsWatchModified
+	case ev.Has(fsnotify.Remove):
+		typ = fsWatchDeleted
+	case ev.Has(fsnotify.Rename):
+		typ = fsWatchRenamed
+	default:
+		return FSWatchEvent{}, false
+	}
+	return w.event(typ, filepath.ToSlash(rel)), true
+}
+
+// addTree watches dir and the directories below it, without following
+// symlinks, until maxDirs is reached.
+func (w *fsWatch) addTree(dir string) error {
+	// Watches of removed directories are dropped, so count the live ones.
+	dirs := len(w.watcher.WatchList())
+	return filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p == dir {
+				return err
+			}
+			return nil
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if dirs >= w.maxDirs {
+			return fs.SkipAll
+		}
+		if err := w.watcher.Add(p); err != nil {
+			if p == dir {
+				return err
+			}
+			return fs.SkipDir
+		}
+		dirs++
+		return nil
+	})
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFSWatch(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "data")
+	if err := os.Mkdir(root, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	w, err := newFSWatch(root, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	w.containerDir = "/data"
+	w.debounce = 20 * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	events := make(chan FSWatchEvent, 64)
+	done := make(chan error, 1)
+	go func() {
+		done <- w.Run(ctx, func(ev FSWatchEvent) error {
+			events <- ev
+			return nil
+		})
+	}()
+	// expect waits for an event of typ for path and returns how many
+	// modified events for path came before it.
+	expect := func(typ, path string) int {
+		t.Helper()
+		modified := 0
+		for {
+			select {
+			case ev := <-events:
+				if ev.Type == typ && ev.Path == path {
+					if want := "/data/" + path; ev.ContainerPath != want {
+						t.Errorf("container path = %q, want %q", ev.ContainerPath, want)
+					}
+					return modified
+				}
+				if ev.Type == fsWatchModified && ev.Path == path {
+					modified++
+				}
+			case <-ctx.Done():
+				t.Fatalf("no %s event for %s", typ, path)
+			}
+		}
+	}
+
+	if err := os.Mkdir(filepath.Join(root, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	expect(fsWatchCreated, "sub")
+	if err := os.WriteFile(filepath.Join(root, "sub", "a.txt"), []byte("hi"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	expect(fsWatchCreated, "sub/a.txt")
+	expect(fsWatchModified, "sub/a.txt")
+	if err := os.Remove(filepath.Join(root, "sub", "a.txt")); err != nil {
+		t.Fatal(err)
+	}
+	expect(fsWatchDeleted, "sub/a.txt")
+
+	// A burst of writes is reported once.
+	f, err := os.Create(filepath.Join(root, "sub", "b.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect(fsWatchCreated, "sub/b.txt")
+	for range 20 {
+		if _, err := f.WriteString("x"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = f.Close()
+	if n := expect(fsWatchModified, "sub/b.txt"); n != 0 {
+		t.Fatalf("burst produced %d extra modified events", n)
+	}
+	if err := os.Rename(filepath.Join(root, "sub", "b.txt"), filepath.Join(root, "sub", "c.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if n := expect(fsWatchRenamed, "sub/b.txt"); n != 0 {
+		t.Fatalf("burst produced %d extra modified events", n)
+	}
+	expect(fsWatchCreated, "sub/c.txt")
+
+	if err := os.RemoveAll(root); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("Run after removing the root = %v, want nil", err)
+	}
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+
+	ctr "github.com/memohai/memoh/internal/containerd"
+)
+
+// GetContainerLogs godoc
+// @Summary Stream the output of the bot's container task
+// @Description Returns the task's stdout and stderr, interleaved as written. With follow the response stays
+// @Description open and new output is sent as it arrives, until the task exits or the client disconnects.
+// @Description Task logs are only kept when containerd.task_log_dir is configured.
+// @Tags containerd
+// @Produce plain
+// @Param bot_id path string true "Bot ID"
+// @Param follow query bool false "Keep streaming new output"
+// @Success 200 {string} string "Task output"
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/container/logs [get]
+func (h *ContainerdHandler) GetContainerLogs(c echo.Context) error {
+	botID, err := h.requireBotAccess(c)
+	if err != nil {
+		return err
+	}
+	follow, _ := strconv.ParseBool(c.QueryParam("follow"))
+	ctx := c.Request().Context()
+	containerID, err := h.botContainerID(ctx, botID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "container not found for bot")
+	}
+	logs, err := h.service.TaskLogs(ctx, containerID, follow)
+	if err != nil {
+		if errors.Is(err, ctr.ErrTaskNotRunning) || errors.Is(err, ctr.ErrNoTaskLogs) {
+			return echo.NewHTTPError(http.StatusNotFound, err.Error())
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	defer logs.Close()
+
+	c.Response().Header().Set(echo.HeaderContentType, echo.MIMETextPlainCharsetUTF8)
+	c.Response().WriteHeader(http.StatusOK)
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := logs.Read(buf)
+		if n > 0 {
+			if _, werr := c.Response().Write(buf[:n]); werr != nil {
+				return nil
+			}
+			c.Response().Flush()
+		}
+		if err != nil {
+			// Once the status is sent, the end of the body is all that is left
+			// to report, whatever stopped the read.
+			if !errors.Is(err, io.EOF) && ctx.Err() == nil {
+				h.logger.Warn("container logs aborted", slog.String("bot_id", botID), slog.Any("error", err))
+			}
+			return nil
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"github.com/labstack/echo/v4"
+
+	ctr "github.com/memohai/memoh/internal/containerd"
+)
+
+// PullImageEvent ends a pull stream: done carries the image, error the
+// failure.
+type PullImageEvent struct {
+	Image string `json:"image"`
+	Error string `json:"error,omitempty"`
+}
+
+// PullContainerImage godoc
+// @Summary Pull the bot's container image, streaming progress
+// @Description Sends a progress event with a ctr.PullProgress payload as each blob downloads, then a single
+// @Description done or error event with a PullImageEvent payload. Images already present finish at once.
+// @Description Pulling ahead of creating the container shows progress that the create call does not.
+// @Tags containerd
+// @Produce text/event-stream
+// @Param bot_id path string true "Bot ID"
+// @Success 200 {string} string "SSE stream"
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/container/pull [post]
+func (h *ContainerdHandler) PullContainerImage(c echo.Context) error {
+	if _, err := h.requireBotAccess(c); err != nil {
+		return err
+	}
+	image := h.mcpImageRef()
+	ctx := c.Request().Context()
+
+	stream, err := newSSEWriter(c)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+	progress := make(chan ctr.PullProgress, 64)
+	done := make(chan error, 1)
+	go func() {
+		_, err := h.service.PullImage(ctx, image, &ctr.PullImageOptions{
+			Unpack:      true,
+			Snapshotter: h.cfg.Snapshotter,
+			Progress:    progress,
+		})
+		done <- err
+	}()
+	for {
+		select {
+		case p := <-progress:
+			if err := stream.SendJSON("progress", p); err != nil {
+				// The client is gone; ctx cancels the pull for this caller.
+				return nil
+			}
+		case err := <-done:
+			if err != nil {
+				_ = stream.SendJSON("error", PullImageEvent{Image: image, Error: err.Error()})
+				return nil
+			}
+			_ = stream.SendJSON("done", PullImageEvent{Image: image})
+			return nil
+		}
+	}
+}
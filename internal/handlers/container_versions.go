@@ -0,0 +1,416 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/containerd/errdefs"
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+
+	ctr "github.com/memohai/memoh/internal/containerd"
+	"github.com/memohai/memoh/internal/mcp"
+)
+
+// SetVersionManager wires the MCP manager used by the container version endpoints.
+func (h *ContainerdHandler) SetVersionManager(manager *mcp.Manager) {
+	h.manager = manager
+}
+
+type CreateVersionRequest struct {
+	// Label optionally names the version. Labels are unique per bot and may
+	// be used in place of the version number.
+	Label string `json:"label,omitempty"`
+}
+
+// CreateVersion godoc
+// @Summary Commit the container filesystem as a new version
+// @Description The running task is stopped for the commit, the container recreated on top of the new
+// @Description version and the task started again. A label must be unique among the bot's versions, printable, not a number and free of '/'.
+// @Tags containerd
+// @Param bot_id path string true "Bot ID"
+// @Param payload body CreateVersionRequest false "Version label"
+// @Success 201 {object} VersionResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/container/versions [post]
+func (h *ContainerdHandler) CreateVersion(c echo.Context) error {
+	botID, err := h.requireBotAccess(c)
+	if err != nil {
+		return err
+	}
+	if h.manager == nil {
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "container versioning is not configured")
+	}
+	var req CreateVersionRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	unlock := h.lockBotFS(botID)
+	defer unlock()
+	info, err := h.manager.CreateVersion(c.Request().Context(), botID, req.Label)
+	if err != nil {
+		return versionHTTPError(err)
+	}
+	return c.JSON(http.StatusCreated, newVersionResponse(*info))
+}
+
+// DeleteVersion godoc
+// @Summary Delete a container version
+// @Description Removes the version's snapshot and records. Versions the active snapshot derives from cannot be deleted.
+// @Tags containerd
+// @Param bot_id path string true "Bot ID"
+// @Param version path string true "Version number or label"
+// @Success 204 "No Content"
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/container/versions/{version} [delete]
+func (h *ContainerdHandler) DeleteVersion(c echo.Context) error {
+	botID, err := h.requireBotAccess(c)
+	if err != nil {
+		return err
+	}
+	if h.manager == nil {
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "container versioning is not configured")
+	}
+	version, err := h.versionParam(c, botID)
+	if err != nil {
+		return err
+	}
+	unlock := h.lockBotFS(botID)
+	defer unlock()
+	if err := h.manager.DeleteVersion(c.Request().Context(), botID, version); err != nil {
+		return versionHTTPError(err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+type PruneVersionsResponse struct {
+	Deleted int `json:"deleted"`
+	// InUse lists versions older than keep that could not be deleted
+	// because the active snapshot or other snapshots build on them.
+	InUse []int `json:"in_use,omitempty"`
+}
+
+// PruneVersions godoc
+// @Summary Delete all but the newest container versions
+// @Description Deletes versions older than the newest keep, newest first. Labeled versions are kept.
+// @Description Versions still in use, such as those the active snapshot derives from, are kept and
+// @Description listed in in_use; without rollbacks that is every version.
+// @Tags containerd
+// @Param bot_id path string true "Bot ID"
+// @Param keep query int true "Number of newest versions to keep"
+// @Success 200 {object} PruneVersionsResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/container/versions/prune [post]
+func (h *ContainerdHandler) PruneVersions(c echo.Context) error {
+	botID, err := h.requireBotAccess(c)
+	if err != nil {
+		return err
+	}
+	if h.manager == nil {
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "container versioning is not configured")
+	}
+	keep, err := strconv.Atoi(strings.TrimSpace(c.QueryParam("keep")))
+	if err != nil || keep < 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "keep must be a non-negative integer")
+	}
+	unlock := h.lockBotFS(botID)
+	defer unlock()
+	result, err := h.manager.PruneVersions(c.Request().Context(), botID, keep)
+	if err != nil {
+		return versionHTTPError(err)
+	}
+	return c.JSON(http.StatusOK, PruneVersionsResponse{Deleted: result.Deleted, InUse: result.InUse})
+}
+
+type CloneContainerRequest struct {
+	// SourceBotID is the bot whose container filesystem is cloned.
+	SourceBotID string `json:"source_bot_id"`
+	// Version of the source to clone, by number or Label; by default the
+	// source's current state is committed as a new version and cloned.
+	Version int    `json:"version,omitempty"`
+	Label   string `json:"label,omitempty"`
+}
+
+type CloneContainerResponse struct {
+	ContainerID string `json:"container_id"`
+	// Source is the source version the container was created from.
+	Source VersionResponse `json:"source"`
+}
+
+// CloneContainer godoc
+// @Summary Create the bot's container as a clone of another bot's
+// @Description The container is created from a version of the source bot's container filesystem, and the
+// @Description source's data mount is copied over the bot's, so its workspace files come along. The caller
+// @Description needs access to both bots, and the bot must not have a container yet.
+// @Tags containerd
+// @Param bot_id path string true "Destination bot ID"
+// @Param payload body CloneContainerRequest true "Source bot and version"
+// @Success 201 {object} CloneContainerResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/container/clone [post]
+func (h *ContainerdHandler) CloneContainer(c echo.Context) error {
+	botID, err := h.requireBotAccess(c)
+	if err != nil {
+		return err
+	}
+	if h.manager == nil {
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "container versioning is not configured")
+	}
+	var req CloneContainerRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	srcBotID := strings.TrimSpace(req.SourceBotID)
+	if srcBotID == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "source_bot_id is required")
+	}
+	if req.Version < 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "version must be a positive integer")
+	}
+	channelIdentityID, err := h.requireChannelIdentityID(c)
+	if err != nil {
+		return err
+	}
+	ctx := c.Request().Context()
+	if _, err := h.authorizeBotAccess(ctx, channelIdentityID, srcBotID); err != nil {
+		return err
+	}
+	version := req.Version
+	if version == 0 && strings.TrimSpace(req.Label) != "" {
+		if version, err = h.manager.VersionByLabel(ctx, srcBotID, req.Label); err != nil {
+			return versionHTTPError(err)
+		}
+	}
+	unlock := h.lockBotFSPair(srcBotID, botID)
+	defer unlock()
+	source, err := h.manager.CloneBot(ctx, srcBotID, botID, version)
+	if err != nil {
+		if errdefs.IsAlreadyExists(err) {
+			return echo.NewHTTPError(http.StatusConflict, "bot already has a container")
+		}
+		return versionHTTPError(err)
+	}
+	if err := h.cloneBotData(srcBotID, botID); err != nil {
+		h.logger.Error("clone: copy data mount failed",
+			slog.String("source_bot_id", srcBotID), slog.String("bot_id", botID), slog.Any("error", err))
+		if cleanupErr := h.CleanupBotContainer(ctx, botID); cleanupErr != nil {
+			h.logger.Warn("cleanup: remove cloned container failed", slog.String("bot_id", botID), slog.Any("error", cleanupErr))
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "copy data mount: "+err.Error())
+	}
+	return c.JSON(http.StatusCreated, CloneContainerResponse{
+		ContainerID: mcp.ContainerPrefix + botID,
+		Source:      newVersionResponse(*source),
+	})
+}
+
+// cloneBotData replaces dstBotID's data directory with a copy of srcBotID's.
+// A source without a data directory leaves the destination as it is.
+func (h *ContainerdHandler) cloneBotData(srcBotID, dstBotID string) error {
+	srcDir, err := h.manager.DataDir(srcBotID)
+	if err != nil {
+		return err
+	}
+	dstDir, err := h.manager.DataDir(dstBotID)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Lstat(srcDir); os.IsNotExist(err) {
+		return nil
+	}
+	_, err = copyPath(srcDir, dstDir, true)
+	return err
+}
+
+type ListVersionsResponse struct {
+	Versions []VersionResponse `json:"versions"`
+}
+
+// ListVersions godoc
+// @Summary List container versions
+// @Description Returns the container's versions, newest first.
+// @Tags containerd
+// @Param bot_id path string true "Bot ID"
+// @Param limit query int false "Maximum number of versions to return"
+// @Success 200 {object} ListVersionsResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/container/versions [get]
+func (h *ContainerdHandler) ListVersions(c echo.Context) error {
+	botID, err := h.requireBotAccess(c)
+	if err != nil {
+		return err
+	}
+	if h.manager == nil {
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "container versioning is not configured")
+	}
+	limit := 0
+	if raw := strings.TrimSpace(c.QueryParam("limit")); raw != "" {
+		if limit, err = strconv.Atoi(raw); err != nil || limit <= 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "limit must be a positive integer")
+		}
+	}
+	versions, err := h.manager.ListVersions(c.Request().Context(), botID)
+	if err != nil {
+		return versionHTTPError(err)
+	}
+	resp := ListVersionsResponse{Versions: make([]VersionResponse, 0, len(versions))}
+	for i := len(versions) - 1; i >= 0; i-- {
+		if limit > 0 && len(resp.Versions) == limit {
+			break
+		}
+		resp.Versions = append(resp.Versions, newVersionResponse(versions[i]))
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+type RollbackVersionRequest struct {
+	Version int `json:"version"`
+	// Label selects the version by its label instead of its number.
+	Label string `json:"label,omitempty"`
+	// DryRun reports the changes a rollback would make without making them.
+	DryRun bool `json:"dry_run,omitempty"`
+}
+
+type VersionResponse struct {
+	ID           string           `json:"id"`
+	Version      int              `json:"version"`
+	SnapshotID   string           `json:"snapshot_id"`
+	Label        string           `json:"label,omitempty"`
+	CreatedAt    time.Time        `json:"created_at"`
+	ChangedPaths []ctr.PathChange `json:"changed_paths,omitempty"`
+}
+
+type RollbackVersionResponse struct {
+	VersionResponse
+	// RolledBack is false when the container already ran on the version, or
+	// for a dry run.
+	RolledBack bool `json:"rolled_back"`
+	DryRun     bool `json:"dry_run,omitempty"`
+	// Changes are the root filesystem changes a dry run found.
+	Changes   []ctr.PathChange `json:"changes,omitempty"`
+	Truncated bool             `json:"truncated,omitempty"`
+}
+
+// RollbackVersion godoc
+// @Summary Roll the container back to a version
+// @Description Recreates the container from the version's snapshot. The data mount is not affected.
+// @Description Rolling back to the version the container already runs on is a no-op. A running task is
+// @Description stopped and started again on the restored filesystem. With dry_run set nothing changes;
+// @Description the response lists the paths a rollback would add, modify or delete instead. The version may
+// @Description be given by label instead of number.
+// @Tags containerd
+// @Param bot_id path string true "Bot ID"
+// @Param payload body RollbackVersionRequest true "Version to restore"
+// @Success 200 {object} RollbackVersionResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/container/versions/rollback [post]
+func (h *ContainerdHandler) RollbackVersion(c echo.Context) error {
+	botID, err := h.requireBotAccess(c)
+	if err != nil {
+		return err
+	}
+	if h.manager == nil {
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "container versioning is not configured")
+	}
+	var req RollbackVersionRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if req.Version == 0 && strings.TrimSpace(req.Label) != "" {
+		if req.Version, err = h.manager.VersionByLabel(c.Request().Context(), botID, req.Label); err != nil {
+			return versionHTTPError(err)
+		}
+	}
+	if req.Version <= 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "version must be a positive integer")
+	}
+	if req.DryRun {
+		unlock := h.rlockBotFS(botID)
+		defer unlock()
+		info, changes, truncated, err := h.manager.RollbackPreview(c.Request().Context(), botID, req.Version)
+		if err != nil {
+			return versionHTTPError(err)
+		}
+		return c.JSON(http.StatusOK, RollbackVersionResponse{
+			VersionResponse: newVersionResponse(*info),
+			DryRun:          true,
+			Changes:         changes,
+			Truncated:       truncated,
+		})
+	}
+	unlock := h.lockBotFS(botID)
+	defer unlock()
+	info, rolledBack, err := h.manager.Rollback(c.Request().Context(), botID, req.Version)
+	if err != nil {
+		return versionHTTPError(err)
+	}
+	return c.JSON(http.StatusOK, RollbackVersionResponse{
+		VersionResponse: newVersionResponse(*info),
+		RolledBack:      rolledBack,
+	})
+}
+
+func newVersionResponse(info mcp.VersionInfo) VersionResponse {
+	return VersionResponse{
+		ID:           info.ID,
+		Version:      info.Version,
+		SnapshotID:   info.SnapshotID,
+		Label:        info.Label,
+		CreatedAt:    info.CreatedAt,
+		ChangedPaths: info.ChangedPaths,
+	}
+}
+
+// versionParam resolves the version path parameter, a version number or a
+// version label.
+func (h *ContainerdHandler) versionParam(c echo.Context, botID string) (int, error) {
+	raw := strings.TrimSpace(c.Param("version"))
+	if raw == "" {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, "version is required")
+	}
+	version, err := strconv.Atoi(raw)
+	if err != nil {
+		if version, err = h.manager.VersionByLabel(c.Request().Context(), botID, raw); err != nil {
+			return 0, versionHTTPError(err)
+		}
+	}
+	if version <= 0 {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, "version must be a positive integer")
+	}
+	return version, nil
+}
+
+func versionHTTPError(err error) error {
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return echo.NewHTTPError(http.StatusNotFound, "version not found")
+	case errors.Is(err, mcp.ErrVersionInUse), errors.Is(err, mcp.ErrBotLabelMismatch), errors.Is(err, mcp.ErrVersionLabelTaken):
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	case errors.Is(err, ctr.ErrInvalidArgument):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	case errdefs.IsNotFound(err):
+		return echo.NewHTTPError(http.StatusNotFound, "container not found")
+	default:
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+}
@@ -24,6 +24,7 @@ import (
 	"github.com/opencontainers/runtime-spec/specs-go"
 
 	"github.com/memohai/memoh/internal/accounts"
+	"github.com/memohai/memoh/internal/auth"
 	"github.com/memohai/memoh/internal/bots"
 	"github.com/memohai/memoh/internal/config"
 	ctr "github.com/memohai/memoh/internal/containerd"
@@ -47,6 +48,10 @@ type ContainerdHandler struct {
 	accountService *accounts.Service
 	policyService  *policy.Service
 	queries        *dbsqlc.Queries
+	manager        *mcp.Manager
+	writePolicy    *mcp.WritePolicy
+	writeAudit     *mcp.WriteAuditLog
+	fsLocks        botFSLocks
 }
 
 type CreateContainerRequest struct {
@@ -109,6 +114,8 @@ func NewContainerdHandler(log *slog.Logger, service ctr.Service, cfg config.MCPC
 		accountService: accountService,
 		policyService:  policyService,
 		queries:        queries,
+		writePolicy:    mcp.NewWritePolicy(cfg.WritePolicy),
+		writeAudit:     mcp.NewWriteAuditLog(queries),
 	}
 }
 
@@ -117,17 +124,43 @@ func (h *ContainerdHandler) Register(e *echo.Echo) {
 	group.POST("", h.CreateContainer)
 	group.GET("", h.GetContainer)
 	group.DELETE("", h.DeleteContainer)
+	group.POST("/pull", h.PullContainerImage)
+	group.POST("/clone", h.CloneContainer, h.requireFSWrite)
 	group.POST("/start", h.StartContainer)
 	group.POST("/stop", h.StopContainer)
-	group.POST("/snapshots", h.CreateSnapshot)
+	group.GET("/stats", h.GetContainerStats)
+	group.GET("/logs", h.GetContainerLogs)
+	group.POST("/snapshots", h.CreateSnapshot, h.requireFSWrite)
 	group.GET("/snapshots", h.ListSnapshots)
 	group.GET("/skills", h.ListSkills)
-	group.POST("/skills", h.UpsertSkills)
-	group.DELETE("/skills", h.DeleteSkills)
+	group.POST("/skills", h.UpsertSkills, h.requireFSWrite)
+	group.DELETE("/skills", h.DeleteSkills, h.requireFSWrite)
+	group.GET("/versions", h.ListVersions)
+	group.POST("/versions", h.CreateVersion, h.requireFSWrite)
+	group.POST("/versions/rollback", h.RollbackVersion, h.requireFSWrite)
+	group.POST("/versions/prune", h.PruneVersions, h.requireFSWrite)
+	group.DELETE("/versions/:version", h.DeleteVersion, h.requireFSWrite)
+	group.GET("/fs/list", h.ListFS)
+	group.GET("/fs/download", h.DownloadFSFile)
+	group.GET("/fs/archive", h.ArchiveFS)
+	group.GET("/fs/watch", h.WatchFS)
+	group.POST("/fs/upload", h.UploadFSFile, h.requireFSWrite)
+	group.GET("/fs/checksum", h.FSChecksum)
+	group.GET("/fs/search", h.SearchFS)
+	group.GET("/fs/diff_tree", h.DiffTree)
+	group.GET("/fs/audit", h.ListFSWriteAudit)
+	group.DELETE("/fs/delete", h.DeleteFSPath, h.requireFSWrite)
+	group.POST("/fs/move", h.MoveFSPath, h.requireFSWrite)
+	group.POST("/fs/copy", h.CopyFSPath, h.requireFSWrite)
+	group.POST("/fs/mkdir", h.MkdirFSPath, h.requireFSWrite)
+	group.POST("/fs/apply_patch", h.ApplyPatch, h.requireFSWrite)
+	group.POST("/fs/apply_patches", h.ApplyPatches, h.requireFSWrite)
+	group.POST("/fs/apply_patchset", h.ApplyPatchset, h.requireFSWrite)
 	root := e.Group("/bots/:bot_id")
-	root.POST("/mcp-stdio", h.CreateMCPStdio)
-	root.POST("/mcp-stdio/:connection_id", h.HandleMCPStdio)
+	root.POST("/mcp-stdio", h.CreateMCPStdio, h.requireFSWrite)
+	root.POST("/mcp-stdio/:connection_id", h.HandleMCPStdio, h.requireFSWrite)
 	root.POST("/tools", h.HandleMCPTools)
+	e.POST("/admin/containers/status", h.BatchContainerStatus)
 }
 
 // CreateContainer godoc
@@ -169,10 +202,7 @@ func (h *ContainerdHandler) CreateContainer(c echo.Context) error {
 	if err != nil {
 		h.logger.Warn("filepath.Abs failed", slog.Any("error", err))
 	}
-	dataMount := strings.TrimSpace(h.cfg.DataMount)
-	if dataMount == "" {
-		dataMount = config.DefaultDataMount
-	}
+	dataMount := h.cfg.DataMountFor(image)
 	dataDir := filepath.Join(dataRoot, "bots", botID)
 	if err := os.MkdirAll(dataDir, 0o755); err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
@@ -211,7 +241,7 @@ func (h *ContainerdHandler) CreateContainer(c echo.Context) error {
 			mcp.BotLabelKey: botID,
 		},
 		SpecOpts: specOpts,
-	})
+	}.WithResourceLimits(h.cfg))
 	if err != nil && !errdefs.IsAlreadyExists(err) {
 		return echo.NewHTTPError(http.StatusInternalServerError, "snapshotter="+snapshotter+" image="+image+" err="+err.Error())
 	}
@@ -251,6 +281,7 @@ func (h *ContainerdHandler) CreateContainer(c echo.Context) error {
 				slog.Any("error", netErr),
 			)
 		}
+		h.superviseTask(containerID)
 		if h.queries != nil {
 			if pgBotID, parseErr := db.ParseUUID(botID); parseErr == nil {
 				if dbErr := h.queries.UpdateContainerStarted(c.Request().Context(), pgBotID); dbErr != nil {
@@ -318,6 +349,7 @@ func (h *ContainerdHandler) ensureContainerAndTask(ctx context.Context, containe
 		h.logger.Warn("network setup failed, task kept running",
 			slog.String("container_id", containerID), slog.Any("error", netErr))
 	}
+	h.superviseTask(containerID)
 	return nil
 }
 
@@ -510,7 +542,8 @@ func (h *ContainerdHandler) StopContainer(c echo.Context) error {
 	if err != nil {
 		return echo.NewHTTPError(http.StatusNotFound, "container not found for bot")
 	}
-	if err := h.service.StopTask(ctx, containerID, &ctr.StopTaskOptions{
+	h.unsuperviseTask(containerID)
+	if _, err := h.service.StopTask(ctx, containerID, &ctr.StopTaskOptions{
 		Timeout: 10 * time.Second,
 		Force:   true,
 	}); err != nil && !errdefs.IsNotFound(err) {
@@ -530,6 +563,32 @@ func (h *ContainerdHandler) StopContainer(c echo.Context) error {
 	return c.JSON(http.StatusOK, map[string]bool{"stopped": true})
 }
 
+// GetContainerStats godoc
+// @Summary Get container resource usage for bot
+// @Description Returns zeroed stats with running=false when the container has no running task.
+// @Tags containerd
+// @Param bot_id path string true "Bot ID"
+// @Success 200 {object} ctr.TaskStats
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/container/stats [get]
+func (h *ContainerdHandler) GetContainerStats(c echo.Context) error {
+	botID, err := h.requireBotAccess(c)
+	if err != nil {
+		return err
+	}
+	ctx := c.Request().Context()
+	containerID, err := h.botContainerID(ctx, botID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "container not found for bot")
+	}
+	stats, err := h.service.Stats(ctx, containerID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, stats)
+}
+
 // CreateSnapshot godoc
 // @Summary Create container snapshot for bot
 // @Tags containerd
@@ -572,7 +631,9 @@ func (h *ContainerdHandler) CreateSnapshot(c echo.Context) error {
 	if snapshotName == "" {
 		snapshotName = containerID + "-" + time.Now().Format("20060102150405")
 	}
-	if err := h.service.CommitSnapshot(ctx, info.Snapshotter, snapshotName, info.SnapshotKey); err != nil {
+	unlock := h.lockBotFS(botID)
+	defer unlock()
+	if err := h.service.CommitSnapshot(ctx, info.Snapshotter, snapshotName, info.SnapshotKey, nil); err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
 	return c.JSON(http.StatusOK, CreateSnapshotResponse{
@@ -584,15 +645,25 @@ func (h *ContainerdHandler) CreateSnapshot(c echo.Context) error {
 
 // ListSnapshots godoc
 // @Summary List snapshots
+// @Description Lists the snapshot lineage of the bot's container: its active snapshot, committed versions and
+// @Description manual snapshots, oldest first.
 // @Tags containerd
 // @Param bot_id path string true "Bot ID"
 // @Param snapshotter query string false "Snapshotter name"
 // @Success 200 {object} ListSnapshotsResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
 // @Router /bots/{bot_id}/container/snapshots [get]
 func (h *ContainerdHandler) ListSnapshots(c echo.Context) error {
-	if _, err := h.requireBotAccess(c); err != nil {
+	botID, err := h.requireBotAccess(c)
+	if err != nil {
 		return err
 	}
+	ctx := c.Request().Context()
+	containerID, err := h.botContainerID(ctx, botID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "container not found for bot")
+	}
 	snapshotter := strings.TrimSpace(c.QueryParam("snapshotter"))
 	if snapshotter == "" {
 		snapshotter = strings.TrimSpace(h.cfg.Snapshotter)
@@ -600,7 +671,8 @@ func (h *ContainerdHandler) ListSnapshots(c echo.Context) error {
 	if snapshotter == "" {
 		snapshotter = "overlayfs"
 	}
-	snapshots, err := h.service.ListSnapshots(c.Request().Context(), snapshotter)
+	// Every snapshot of the container is named after it.
+	snapshots, err := h.service.ListSnapshots(ctx, snapshotter, containerID)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
@@ -610,7 +682,7 @@ func (h *ContainerdHandler) ListSnapshots(c echo.Context) error {
 			Snapshotter: snapshotter,
 			Name:        info.Name,
 			Parent:      info.Parent,
-			Kind:        info.Kind.String(),
+			Kind:        info.Kind,
 			CreatedAt:   info.Created,
 			UpdatedAt:   info.Updated,
 			Labels:      info.Labels,
@@ -637,6 +709,17 @@ func (h *ContainerdHandler) mcpImageRef() string {
 	return config.DefaultMCPImage
 }
 
+// botDataMount returns the data mount path inside the bot's container, which
+// follows the image the container was created from; see mcp.Manager.DataMount.
+func (h *ContainerdHandler) botDataMount(botID string) string {
+	if h.manager == nil {
+		return h.cfg.DataMountFor(h.mcpImageRef())
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return h.manager.DataMount(ctx, botID)
+}
+
 // requireBotAccess extracts bot_id from path, validates user auth, and authorizes bot access.
 func (h *ContainerdHandler) requireBotAccess(c echo.Context) (string, error) {
 	channelIdentityID, err := h.requireChannelIdentityID(c)
@@ -653,6 +736,22 @@ func (h *ContainerdHandler) requireBotAccess(c echo.Context) (string, error) {
 	return botID, nil
 }
 
+// requireFSWrite guards the mutating FS and version endpoints and MCP stdio
+// sessions, which run commands in the container: they answer
+// 403 when the server runs with mcp.read_only_fs or the token is scoped to
+// fs:readonly.
+func (h *ContainerdHandler) requireFSWrite(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if h.cfg.ReadOnlyFS {
+			return echo.NewHTTPError(http.StatusForbidden, "container filesystem is read-only")
+		}
+		if auth.HasScope(c, auth.ScopeFSReadOnly) {
+			return echo.NewHTTPError(http.StatusForbidden, "token is limited to read-only filesystem access")
+		}
+		return next(c)
+	}
+}
+
 func (h *ContainerdHandler) requireChannelIdentityID(c echo.Context) (string, error) {
 	return RequireChannelIdentityID(c)
 }
@@ -699,10 +798,7 @@ func (h *ContainerdHandler) SetupBotContainer(ctx context.Context, botID string)
 	} else {
 		dataRoot = absRoot
 	}
-	dataMount := strings.TrimSpace(h.cfg.DataMount)
-	if dataMount == "" {
-		dataMount = config.DefaultDataMount
-	}
+	dataMount := h.cfg.DataMountFor(image)
 	dataDir := filepath.Join(dataRoot, "bots", botID)
 	if err := os.MkdirAll(dataDir, 0o755); err != nil {
 		return err
@@ -741,7 +837,7 @@ func (h *ContainerdHandler) SetupBotContainer(ctx context.Context, botID string)
 			mcp.BotLabelKey: botID,
 		},
 		SpecOpts: specOpts,
-	})
+	}.WithResourceLimits(h.cfg))
 	if err != nil && !errdefs.IsAlreadyExists(err) {
 		return err
 	}
@@ -780,6 +876,7 @@ func (h *ContainerdHandler) SetupBotContainer(ctx context.Context, botID string)
 				slog.Any("error", netErr),
 			)
 		}
+		h.superviseTask(containerID)
 		if h.queries != nil {
 			if pgBotID, parseErr := db.ParseUUID(botID); parseErr == nil {
 				if dbErr := h.queries.UpdateContainerStarted(ctx, pgBotID); dbErr != nil {
@@ -823,6 +920,7 @@ func (h *ContainerdHandler) CleanupBotContainer(ctx context.Context, botID strin
 		slog.String("container_id", containerID),
 	)
 
+	h.unsuperviseTask(containerID)
 	if task, taskErr := h.service.GetTask(ctx, containerID); taskErr == nil {
 		h.logger.Info("CleanupBotContainer: removing network", slog.String("container_id", containerID))
 		if err := ctr.RemoveNetwork(ctx, task, containerID); err != nil {
@@ -830,7 +928,7 @@ func (h *ContainerdHandler) CleanupBotContainer(ctx context.Context, botID strin
 		}
 	}
 	h.logger.Info("CleanupBotContainer: stopping task", slog.String("container_id", containerID))
-	if err := h.service.StopTask(ctx, containerID, &ctr.StopTaskOptions{
+	if _, err := h.service.StopTask(ctx, containerID, &ctr.StopTaskOptions{
 		Timeout: 5 * time.Second,
 		Force:   true,
 	}); err != nil {
@@ -865,6 +963,28 @@ func (h *ContainerdHandler) CleanupBotContainer(ctx context.Context, botID strin
 	return nil
 }
 
+// superviseTask hands a running task to the MCP manager's restart
+// supervisor, when the manager is wired.
+func (h *ContainerdHandler) superviseTask(containerID string) {
+	if h.manager != nil {
+		h.manager.Supervise(containerID)
+	}
+}
+
+// unsuperviseTask must precede deliberate stops so they are not restarted.
+func (h *ContainerdHandler) unsuperviseTask(containerID string) {
+	if h.manager != nil {
+		h.manager.Unsupervise(containerID)
+	}
+}
+
+// touchBot counts a request as activity of botID for the idle reaper.
+func (h *ContainerdHandler) touchBot(botID string) {
+	if h.manager != nil {
+		h.manager.Touch(botID)
+	}
+}
+
 func (h *ContainerdHandler) isTaskRunning(ctx context.Context, containerID string) bool {
 	tasks, err := h.service.ListTasks(ctx, &ctr.ListTasksOptions{
 		Filter: "container.id==" + containerID,
@@ -876,6 +996,7 @@ func (h *ContainerdHandler) isTaskRunning(ctx context.Context, containerID strin
 // state on startup. For each auto_start container in DB it verifies the container
 // and task exist; if missing they are rebuilt via SetupBotContainer. Containers that
 // the DB claims are running but are not present in containerd get corrected.
+// Containers the idle reaper stopped stay stopped until their bot is used.
 func (h *ContainerdHandler) ReconcileContainers(ctx context.Context) {
 	if h.queries == nil {
 		return
@@ -930,6 +1051,14 @@ func (h *ContainerdHandler) ReconcileContainers(ctx context.Context) {
 			}
 			h.logger.Info("reconcile: container healthy",
 				slog.String("bot_id", botID), slog.String("container_id", containerID))
+			h.superviseTask(containerID)
+			continue
+		}
+
+		// The idle reaper stopped it: leave it until the bot is used.
+		if row.Status == mcp.ContainerStatusIdleStopped {
+			h.logger.Info("reconcile: container stopped as idle, not starting",
+				slog.String("bot_id", botID), slog.String("container_id", containerID))
 			continue
 		}
 
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	containerd "github.com/containerd/containerd/v2/client"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	ctr "github.com/memohai/memoh/internal/containerd"
+	dbsqlc "github.com/memohai/memoh/internal/db/sqlc"
+	"github.com/memohai/memoh/internal/mcp"
+)
+
+// containerRows yields containers rows whose only populated columns are
+// bot_id, container_id and status.
+type containerRows struct {
+	pgx.Rows
+	rows []dbsqlc.Container
+	pos  int
+}
+
+func (r *containerRows) Next() bool {
+	r.pos++
+	return r.pos <= len(r.rows)
+}
+
+func (r *containerRows) Scan(dest ...any) error {
+	row := r.rows[r.pos-1]
+	*dest[1].(*pgtype.UUID) = row.BotID
+	*dest[2].(*string) = row.ContainerID
+	*dest[5].(*string) = row.Status
+	return nil
+}
+
+func (r *containerRows) Close()     {}
+func (r *containerRows) Err() error { return nil }
+
+// reconcileDB lists the given auto-start containers and records every
+// statement executed.
+type reconcileDB struct {
+	rows  []dbsqlc.Container
+	execs []string
+}
+
+func (d *reconcileDB) Exec(_ context.Context, sql string, _ ...any) (pgconn.CommandTag, error) {
+	d.execs = append(d.execs, sql)
+	return pgconn.CommandTag{}, nil
+}
+
+func (d *reconcileDB) Query(context.Context, string, ...any) (pgx.Rows, error) {
+	return &containerRows{rows: d.rows}, nil
+}
+
+func (d *reconcileDB) QueryRow(context.Context, string, ...any) pgx.Row {
+	return nil
+}
+
+// stoppedContainerService reports an existing container without a task and
+// counts task starts.
+type stoppedContainerService struct {
+	ctr.Service
+	starts int
+}
+
+func (s *stoppedContainerService) GetContainer(context.Context, string) (containerd.Container, error) {
+	return nil, nil
+}
+
+func (s *stoppedContainerService) ListTasks(context.Context, *ctr.ListTasksOptions) ([]ctr.TaskInfo, error) {
+	return nil, nil
+}
+
+func (s *stoppedContainerService) StartTask(context.Context, string, *ctr.StartTaskOptions) (containerd.Task, error) {
+	s.starts++
+	return nil, errors.New("not implemented")
+}
+
+func TestReconcileContainers_LeavesIdleStoppedDown(t *testing.T) {
+	botID := pgtype.UUID{Bytes: [16]byte{1}, Valid: true}
+	db := &reconcileDB{rows: []dbsqlc.Container{{
+		BotID:       botID,
+		ContainerID: mcp.ContainerPrefix + "bot-1",
+		Status:      mcp.ContainerStatusIdleStopped,
+	}}}
+	svc := &stoppedContainerService{}
+	h := &ContainerdHandler{
+		service: svc,
+		queries: dbsqlc.New(db),
+		logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+
+	h.ReconcileContainers(context.Background())
+
+	if svc.starts != 0 {
+		t.Fatalf("reconcile started %d idle-stopped tasks, want 0", svc.starts)
+	}
+	if len(db.execs) != 0 {
+		t.Fatalf("reconcile updated the DB %d times, want 0: %v", len(db.execs), db.execs)
+	}
+}
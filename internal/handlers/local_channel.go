@@ -1,9 +1,7 @@
 package handlers
 
 import (
-	"bufio"
 	"context"
-	"encoding/json"
 	"fmt"
 	"net/http"
 	"strings"
@@ -70,16 +68,11 @@ func (h *LocalChannelHandler) StreamMessages(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusInternalServerError, "route hub not configured")
 	}
 
-	c.Response().Header().Set(echo.HeaderContentType, "text/event-stream")
-	c.Response().Header().Set(echo.HeaderCacheControl, "no-cache")
-	c.Response().Header().Set(echo.HeaderConnection, "keep-alive")
-	c.Response().WriteHeader(http.StatusOK)
-
-	flusher, ok := c.Response().Writer.(http.Flusher)
-	if !ok {
-		return echo.NewHTTPError(http.StatusInternalServerError, "streaming not supported")
+	sse, err := newSSEWriter(c)
+	if err != nil {
+		return err
 	}
-	writer := bufio.NewWriter(c.Response().Writer)
+	defer sse.Close()
 
 	_, stream, cancel := h.routeHub.Subscribe(botID)
 	defer cancel()
@@ -96,15 +89,9 @@ func (h *LocalChannelHandler) StreamMessages(c echo.Context) error {
 				"target": msg.Target,
 				"event":  msg.Event,
 			}
-			data, err := json.Marshal(payload)
-			if err != nil {
-				continue
-			}
-			if _, err := writer.WriteString(fmt.Sprintf("data: %s\n\n", string(data))); err != nil {
+			if err := sse.SendJSON("", payload); err != nil {
 				return nil // client disconnected
 			}
-			writer.Flush()
-			flusher.Flush()
 		}
 	}
 }
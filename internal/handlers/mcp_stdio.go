@@ -157,6 +157,7 @@ func (h *ContainerdHandler) HandleMCPStdio(c echo.Context) error {
 		return c.JSON(http.StatusOK, mcptools.JSONRPCErrorResponse(req.ID, -32601, "method not found"))
 	}
 	session.lastUsedAt = time.Now().UTC()
+	h.touchBot(botID)
 	if mcptools.IsNotification(req) {
 		if err := session.session.notify(c.Request().Context(), req); err != nil {
 			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
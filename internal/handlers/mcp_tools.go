@@ -237,5 +237,6 @@ func (h *ContainerdHandler) buildToolSessionContext(c echo.Context, botID string
 		SessionToken:      strings.TrimSpace(c.Request().Header.Get(headerSessionToken)),
 		CurrentPlatform:   strings.TrimSpace(c.Request().Header.Get(headerCurrentPlatform)),
 		ReplyTarget:       strings.TrimSpace(c.Request().Header.Get(headerReplyTarget)),
+		ReadOnlyFS:        h.cfg.ReadOnlyFS || auth.HasScope(c, auth.ScopeFSReadOnly),
 	}
 }
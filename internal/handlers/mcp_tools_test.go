@@ -8,11 +8,15 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/labstack/echo/v4"
 	sdkmcp "github.com/modelcontextprotocol/go-sdk/mcp"
 
+	"github.com/memohai/memoh/internal/auth"
 	mcpgw "github.com/memohai/memoh/internal/mcp"
+	mcpcontainer "github.com/memohai/memoh/internal/mcp/providers/container"
 )
 
 func TestBuildToolCallPayloadFromRaw(t *testing.T) {
@@ -163,3 +167,58 @@ func TestHandleMCPToolsWithGatewayAcceptCompatibility(t *testing.T) {
 		t.Fatalf("unexpected channel identity id: %#v", structured["channel_identity_id"])
 	}
 }
+
+type readOnlyTestRunner struct {
+	calls int
+}
+
+func (r *readOnlyTestRunner) ExecWithCapture(ctx context.Context, req mcpgw.ExecRequest) (*mcpgw.ExecWithCaptureResult, error) {
+	r.calls++
+	return &mcpgw.ExecWithCaptureResult{}, nil
+}
+
+func TestHandleMCPToolsReadOnlyTokenCannotWrite(t *testing.T) {
+	signed, _, err := auth.GenerateToken("user-1", "secret", time.Hour, auth.ScopeFSReadOnly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := jwt.Parse(signed, func(*jwt.Token) (any, error) { return []byte("secret"), nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runner := &readOnlyTestRunner{}
+	executor := mcpcontainer.NewExecutor(slog.Default(), runner, "/data")
+	handler := &ContainerdHandler{
+		logger:      slog.Default(),
+		toolGateway: mcpgw.NewToolGatewayService(slog.Default(), []mcpgw.ToolExecutor{executor}, nil),
+	}
+
+	e := echo.New()
+	for _, call := range []string{
+		`{"name":"write","arguments":{"path":"a.txt","content":"x"}}`,
+		`{"name":"edit","arguments":{"path":"a.txt","old_text":"x","new_text":"y"}}`,
+		`{"name":"exec","arguments":{"command":"touch a.txt"}}`,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/bots/bot-1/tools", strings.NewReader(`{"jsonrpc":"2.0","id":"1","method":"tools/call","params":`+call+`}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.Set("user", token)
+
+		if err := handler.handleMCPToolsWithBotID(c, "bot-1"); err != nil {
+			t.Fatalf("tools/call %s: %v", call, err)
+		}
+		var payload map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+			t.Fatalf("decode %s: %v body=%s", call, err, rec.Body.String())
+		}
+		result, _ := payload["result"].(map[string]any)
+		if isError, _ := result["isError"].(bool); !isError {
+			t.Fatalf("tools/call %s succeeded with a read-only token: %s", call, rec.Body.String())
+		}
+	}
+	if runner.calls != 0 {
+		t.Fatalf("read-only token reached the container %d times", runner.calls)
+	}
+}
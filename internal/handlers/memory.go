@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"net/http"
 	"sort"
@@ -25,14 +26,15 @@ type MemoryHandler struct {
 }
 
 type memoryAddPayload struct {
-	Message          string           `json:"message,omitempty"`
-	Messages         []memory.Message `json:"messages,omitempty"`
-	Namespace        string           `json:"namespace,omitempty"`
-	RunID            string           `json:"run_id,omitempty"`
-	Metadata         map[string]any   `json:"metadata,omitempty"`
-	Filters          map[string]any   `json:"filters,omitempty"`
-	Infer            *bool            `json:"infer,omitempty"`
-	EmbeddingEnabled *bool            `json:"embedding_enabled,omitempty"`
+	Message          string                `json:"message,omitempty"`
+	Messages         []memory.Message      `json:"messages,omitempty"`
+	Namespace        string                `json:"namespace,omitempty"`
+	RunID            string                `json:"run_id,omitempty"`
+	Metadata         map[string]any        `json:"metadata,omitempty"`
+	Filters          map[string]any        `json:"filters,omitempty"`
+	Infer            *bool                 `json:"infer,omitempty"`
+	EmbeddingEnabled *bool                 `json:"embedding_enabled,omitempty"`
+	Origin           *memory.OriginOptions `json:"origin,omitempty"`
 }
 
 type memorySearchPayload struct {
@@ -43,6 +45,11 @@ type memorySearchPayload struct {
 	Sources          []string       `json:"sources,omitempty"`
 	EmbeddingEnabled *bool          `json:"embedding_enabled,omitempty"`
 	NoStats          bool           `json:"no_stats,omitempty"`
+	// RecencyWeight and RecencyHalfLifeDays favor recent memories; see
+	// memory.SearchRequest.
+	RecencyWeight       float64 `json:"recency_weight,omitempty"`
+	RecencyHalfLifeDays float64 `json:"recency_half_life_days,omitempty"`
+	memory.TimeRange
 }
 
 type memoryDeletePayload struct {
@@ -54,6 +61,13 @@ type memoryCompactPayload struct {
 	DecayDays *int    `json:"decay_days,omitempty"`
 }
 
+// memoryAddPartialResponse is returned with 207 when only some of the
+// decided memory actions could be applied.
+type memoryAddPartialResponse struct {
+	Results []memory.MemoryItem `json:"results"`
+	Error   string              `json:"error"`
+}
+
 // namespaceScope holds namespace + scopeId for a single memory scope.
 type namespaceScope struct {
 	Namespace string
@@ -82,11 +96,13 @@ func (h *MemoryHandler) Register(e *echo.Echo) {
 	chatGroup := e.Group("/bots/:bot_id/memory")
 	chatGroup.POST("", h.ChatAdd)
 	chatGroup.POST("/search", h.ChatSearch)
+	chatGroup.POST("/search_stream", h.ChatSearchStream)
 	chatGroup.POST("/compact", h.ChatCompact)
 	chatGroup.POST("/rebuild", h.ChatRebuild)
 	chatGroup.GET("", h.ChatGetAll)
 	chatGroup.GET("/usage", h.ChatUsage)
 	chatGroup.DELETE("", h.ChatDelete)
+	chatGroup.GET("/:memory_id/history", h.ChatHistory)
 	chatGroup.DELETE("/:memory_id", h.ChatDeleteOne)
 }
 
@@ -108,8 +124,10 @@ func (h *MemoryHandler) checkService() error {
 // @Param bot_id path string true "Bot ID"
 // @Param payload body memoryAddPayload true "Memory add payload"
 // @Success 200 {object} memory.SearchResponse
+// @Success 207 {object} memoryAddPartialResponse
 // @Failure 400 {object} ErrorResponse
 // @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Failure 503 {object} ErrorResponse
 // @Router /bots/{bot_id}/memory [post]
@@ -155,9 +173,15 @@ func (h *MemoryHandler) ChatAdd(c echo.Context) error {
 		Filters:          filters,
 		Infer:            payload.Infer,
 		EmbeddingEnabled: payload.EmbeddingEnabled,
+		Origin:           payload.Origin,
 	}
 	resp, err := h.service.Add(c.Request().Context(), req)
-	if err != nil {
+	var partialErr *memory.PartialApplyError
+	if err != nil && !errors.As(err, &partialErr) {
+		var dimErr *memory.EmbeddingDimensionError
+		if errors.As(err, &dimErr) {
+			return echo.NewHTTPError(http.StatusConflict, err.Error())
+		}
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
 
@@ -173,6 +197,9 @@ func (h *MemoryHandler) ChatAdd(c echo.Context) error {
 		}()
 	}
 
+	if partialErr != nil {
+		return c.JSON(http.StatusMultiStatus, memoryAddPartialResponse{Results: resp.Results, Error: partialErr.Error()})
+	}
 	return c.JSON(http.StatusOK, resp)
 }
 
@@ -225,20 +252,7 @@ func (h *MemoryHandler) ChatSearch(c echo.Context) error {
 	// Search shared namespace and merge results.
 	var allResults []memory.MemoryItem
 	for _, scope := range scopes {
-		filters := buildNamespaceFilters(scope.Namespace, scope.ScopeID, payload.Filters)
-		if botID != "" {
-			filters["bot_id"] = botID
-		}
-		req := memory.SearchRequest{
-			Query:            payload.Query,
-			BotID:            botID,
-			RunID:            payload.RunID,
-			Limit:            payload.Limit,
-			Filters:          filters,
-			Sources:          payload.Sources,
-			EmbeddingEnabled: payload.EmbeddingEnabled,
-			NoStats:          payload.NoStats,
-		}
+		req := buildScopeSearchRequest(scope, botID, payload)
 		resp, err := h.service.Search(c.Request().Context(), req)
 		if err != nil {
 			h.logger.Warn("search namespace failed", slog.String("namespace", scope.Namespace), slog.Any("error", err))
@@ -247,16 +261,174 @@ func (h *MemoryHandler) ChatSearch(c echo.Context) error {
 		allResults = append(allResults, resp.Results...)
 	}
 
-	// Deduplicate by ID and sort by score descending.
-	allResults = deduplicateMemoryItems(allResults)
-	sort.Slice(allResults, func(i, j int) bool {
-		return allResults[i].Score > allResults[j].Score
-	})
-	if payload.Limit > 0 && len(allResults) > payload.Limit {
-		allResults = allResults[:payload.Limit]
+	allResults = mergeSearchResults(allResults, payload.Limit)
+	return c.JSON(http.StatusOK, memory.SearchResponse{Results: allResults})
+}
+
+// ChatSearchStream godoc
+// @Summary Search memories (streaming)
+// @Description Stream memory search results as SSE. The first event carries the raw vector hits;
+// @Description when a reranker is configured a second event carries the reranked results.
+// @Description Each event is {"type":"results","stage":"initial"|"reranked"|"final","results":[...]}, followed by [DONE].
+// @Tags memory
+// @Accept json
+// @Produce text/event-stream
+// @Param bot_id path string true "Bot ID"
+// @Param payload body memorySearchPayload true "Memory search payload"
+// @Success 200 {string} string
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /bots/{bot_id}/memory/search_stream [post]
+func (h *MemoryHandler) ChatSearchStream(c echo.Context) error {
+	if err := h.checkService(); err != nil {
+		return err
+	}
+	channelIdentityID, err := h.requireChannelIdentityID(c)
+	if err != nil {
+		return err
+	}
+	containerID, err := h.resolveBotContainerID(c)
+	if err != nil {
+		return err
+	}
+	ctx := c.Request().Context()
+	if err := h.requireChatParticipant(ctx, containerID, channelIdentityID); err != nil {
+		return err
 	}
 
-	return c.JSON(http.StatusOK, memory.SearchResponse{Results: allResults})
+	var payload memorySearchPayload
+	if err := c.Bind(&payload); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if strings.TrimSpace(payload.Query) == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "query is required")
+	}
+
+	scopes, err := h.resolveEnabledScopes(ctx, containerID)
+	if err != nil {
+		return err
+	}
+	chatObj, err := h.chatService.Get(ctx, containerID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "chat not found")
+	}
+	botID := strings.TrimSpace(chatObj.BotID)
+
+	sse, err := newSSEWriter(c)
+	if err != nil {
+		return err
+	}
+	defer sse.Close()
+
+	var candidates []memory.MemoryItem
+	for _, scope := range scopes {
+		req := buildScopeSearchRequest(scope, botID, payload)
+		resp, err := h.service.SearchCandidates(ctx, req)
+		if err != nil {
+			h.logger.Warn("search namespace failed", slog.String("namespace", scope.Namespace), slog.Any("error", err))
+			continue
+		}
+		candidates = append(candidates, resp.Results...)
+	}
+	candidates = deduplicateMemoryItems(candidates)
+
+	if !h.service.RerankEnabled() {
+		results := mergeSearchResults(candidates, payload.Limit)
+		if err := sse.SendJSON("", memorySearchStreamEvent{Type: "results", Stage: "final", Results: results}); err != nil {
+			return nil
+		}
+		_ = sse.Send("", "[DONE]")
+		return nil
+	}
+
+	initial := mergeSearchResults(candidates, payload.Limit)
+	if err := sse.SendJSON("", memorySearchStreamEvent{Type: "results", Stage: "initial", Results: initial}); err != nil {
+		return nil
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	reranked, err := h.service.Rerank(ctx, payload.Query, candidates, payload.Limit)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		h.logger.Warn("memory rerank failed", slog.Any("error", err))
+		_ = sse.SendJSON("", memorySearchStreamEvent{Type: "error", Message: err.Error()})
+		return nil
+	}
+	if err := sse.SendJSON("", memorySearchStreamEvent{Type: "results", Stage: "reranked", Results: reranked}); err != nil {
+		return nil
+	}
+	_ = sse.Send("", "[DONE]")
+	return nil
+}
+
+// memorySearchStreamEvent is one SSE payload emitted by ChatSearchStream.
+type memorySearchStreamEvent struct {
+	Type    string              `json:"type"`
+	Stage   string              `json:"stage,omitempty"`
+	Results []memory.MemoryItem `json:"results,omitempty"`
+	Message string              `json:"message,omitempty"`
+}
+
+// parseTimeRangeQuery reads the optional created_*/updated_* RFC3339 query
+// parameters.
+func parseTimeRangeQuery(c echo.Context) (memory.TimeRange, error) {
+	var r memory.TimeRange
+	for param, dst := range map[string]**time.Time{
+		"created_after":  &r.CreatedAfter,
+		"created_before": &r.CreatedBefore,
+		"updated_after":  &r.UpdatedAfter,
+		"updated_before": &r.UpdatedBefore,
+	} {
+		raw := strings.TrimSpace(c.QueryParam(param))
+		if raw == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return memory.TimeRange{}, echo.NewHTTPError(http.StatusBadRequest, param+" must be an RFC3339 timestamp")
+		}
+		*dst = &t
+	}
+	return r, nil
+}
+
+// buildScopeSearchRequest builds the search request for one memory scope.
+func buildScopeSearchRequest(scope namespaceScope, botID string, payload memorySearchPayload) memory.SearchRequest {
+	filters := buildNamespaceFilters(scope.Namespace, scope.ScopeID, payload.Filters)
+	if botID != "" {
+		filters["bot_id"] = botID
+	}
+	return memory.SearchRequest{
+		Query:               payload.Query,
+		BotID:               botID,
+		RunID:               payload.RunID,
+		Limit:               payload.Limit,
+		Filters:             filters,
+		Sources:             payload.Sources,
+		EmbeddingEnabled:    payload.EmbeddingEnabled,
+		NoStats:             payload.NoStats,
+		RecencyWeight:       payload.RecencyWeight,
+		RecencyHalfLifeDays: payload.RecencyHalfLifeDays,
+		TimeRange:           payload.TimeRange,
+	}
+}
+
+// mergeSearchResults deduplicates by ID, sorts by score descending and trims to limit.
+func mergeSearchResults(items []memory.MemoryItem, limit int) []memory.MemoryItem {
+	items = deduplicateMemoryItems(items)
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Score > items[j].Score
+	})
+	if limit > 0 && len(items) > limit {
+		items = items[:limit]
+	}
+	return items
 }
 
 // ChatGetAll godoc
@@ -266,6 +438,10 @@ func (h *MemoryHandler) ChatSearch(c echo.Context) error {
 // @Produce json
 // @Param bot_id path string true "Bot ID"
 // @Param no_stats query bool false "Skip sparse vector stats (top_k_buckets, cdf_curve) to reduce overhead"
+// @Param created_after query string false "Only memories created at or after this RFC3339 time"
+// @Param created_before query string false "Only memories created before this RFC3339 time"
+// @Param updated_after query string false "Only memories updated at or after this RFC3339 time"
+// @Param updated_before query string false "Only memories updated before this RFC3339 time"
 // @Success 200 {object} memory.SearchResponse
 // @Failure 400 {object} ErrorResponse
 // @Failure 403 {object} ErrorResponse
@@ -289,6 +465,10 @@ func (h *MemoryHandler) ChatGetAll(c echo.Context) error {
 	}
 
 	noStats := strings.EqualFold(c.QueryParam("no_stats"), "true")
+	timeRange, err := parseTimeRangeQuery(c)
+	if err != nil {
+		return err
+	}
 	scopes, err := h.resolveEnabledScopes(c.Request().Context(), containerID)
 	if err != nil {
 		return err
@@ -297,8 +477,9 @@ func (h *MemoryHandler) ChatGetAll(c echo.Context) error {
 	var allResults []memory.MemoryItem
 	for _, scope := range scopes {
 		req := memory.GetAllRequest{
-			Filters: buildNamespaceFilters(scope.Namespace, scope.ScopeID, nil),
-			NoStats: noStats,
+			Filters:   buildNamespaceFilters(scope.Namespace, scope.ScopeID, nil),
+			NoStats:   noStats,
+			TimeRange: timeRange,
 		}
 		resp, err := h.service.GetAll(c.Request().Context(), req)
 		if err != nil {
@@ -429,6 +610,46 @@ func (h *MemoryHandler) ChatDeleteOne(c echo.Context) error {
 	return c.JSON(http.StatusOK, resp)
 }
 
+// ChatHistory godoc
+// @Summary Get memory history
+// @Description List the recorded revisions of a single memory, oldest first
+// @Tags memory
+// @Produce json
+// @Param bot_id path string true "Bot ID"
+// @Param memory_id path string true "Memory ID"
+// @Success 200 {array} memory.MemoryRevision
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /bots/{bot_id}/memory/{memory_id}/history [get]
+func (h *MemoryHandler) ChatHistory(c echo.Context) error {
+	if err := h.checkService(); err != nil {
+		return err
+	}
+	channelIdentityID, err := h.requireChannelIdentityID(c)
+	if err != nil {
+		return err
+	}
+	containerID, err := h.resolveBotContainerID(c)
+	if err != nil {
+		return err
+	}
+	if err := h.requireChatParticipant(c.Request().Context(), containerID, channelIdentityID); err != nil {
+		return err
+	}
+
+	memoryID := strings.TrimSpace(c.Param("memory_id"))
+	if memoryID == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "memory_id is required")
+	}
+	revisions, err := h.service.History(c.Request().Context(), memoryID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, revisions)
+}
+
 // ChatCompact godoc
 // @Summary Compact memories
 // @Description Consolidate memories by merging similar/redundant entries using LLM.
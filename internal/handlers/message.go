@@ -1,7 +1,6 @@
 package handlers
 
 import (
-	"bufio"
 	"context"
 	"encoding/json"
 	"errors"
@@ -159,19 +158,15 @@ func (h *MessageHandler) StreamMessage(c echo.Context) error {
 	if h.runner == nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "conversation runner not configured")
 	}
-	c.Response().Header().Set(echo.HeaderContentType, "text/event-stream")
-	c.Response().Header().Set(echo.HeaderCacheControl, "no-cache")
-	c.Response().Header().Set(echo.HeaderConnection, "keep-alive")
-	c.Response().WriteHeader(http.StatusOK)
+	sse, err := newSSEWriter(c)
+	if err != nil {
+		return err
+	}
+	defer sse.Close()
 
 	chunkChan, errChan := h.runner.StreamChat(c.Request().Context(), req)
-	flusher, ok := c.Response().Writer.(http.Flusher)
-	if !ok {
-		return echo.NewHTTPError(http.StatusInternalServerError, "streaming not supported")
-	}
-	writer := bufio.NewWriter(c.Response().Writer)
 	processingState := "started"
-	if err := writeSSEJSON(writer, flusher, map[string]string{"type": "processing_started"}); err != nil {
+	if err := sse.SendJSON("", map[string]string{"type": "processing_started"}); err != nil {
 		return nil
 	}
 
@@ -181,22 +176,28 @@ func (h *MessageHandler) StreamMessage(c echo.Context) error {
 			if !ok {
 				if processingState == "started" {
 					processingState = "completed"
-					if err := writeSSEJSON(writer, flusher, map[string]string{"type": "processing_completed"}); err != nil {
+					if err := sse.SendJSON("", map[string]string{"type": "processing_completed"}); err != nil {
 						return nil
 					}
 				}
-				if err := writeSSEData(writer, flusher, "[DONE]"); err != nil {
+				if err := sse.Send("", "[DONE]"); err != nil {
 					return nil
 				}
 				return nil
 			}
 			if processingState == "started" {
 				processingState = "completed"
-				if err := writeSSEJSON(writer, flusher, map[string]string{"type": "processing_completed"}); err != nil {
+				if err := sse.SendJSON("", map[string]string{"type": "processing_completed"}); err != nil {
 					return nil
 				}
 			}
-			if err := writeSSEData(writer, flusher, string(chunk)); err != nil {
+			if req.MemoryEvents && isMemoryStreamChunk(chunk) {
+				if err := sse.Send(conversation.StreamEventMemory, string(chunk)); err != nil {
+					return nil
+				}
+				continue
+			}
+			if err := sse.Send("", string(chunk)); err != nil {
 				return nil
 			}
 		case err := <-errChan:
@@ -204,7 +205,7 @@ func (h *MessageHandler) StreamMessage(c echo.Context) error {
 				h.logger.Error("conversation stream failed", slog.Any("error", err))
 				if processingState == "started" {
 					processingState = "failed"
-					if writeErr := writeSSEJSON(writer, flusher, map[string]string{
+					if writeErr := sse.SendJSON("", map[string]string{
 						"type":  "processing_failed",
 						"error": err.Error(),
 					}); writeErr != nil {
@@ -216,7 +217,7 @@ func (h *MessageHandler) StreamMessage(c echo.Context) error {
 					"error":   err.Error(),
 					"message": err.Error(),
 				}
-				if writeErr := writeSSEJSON(writer, flusher, errData); writeErr != nil {
+				if writeErr := sse.SendJSON("", errData); writeErr != nil {
 					return nil
 				}
 				return nil
@@ -225,23 +226,13 @@ func (h *MessageHandler) StreamMessage(c echo.Context) error {
 	}
 }
 
-func writeSSEData(writer *bufio.Writer, flusher http.Flusher, payload string) error {
-	if _, err := writer.WriteString(fmt.Sprintf("data: %s\n\n", payload)); err != nil {
-		return err
-	}
-	if err := writer.Flush(); err != nil {
-		return err
-	}
-	flusher.Flush()
-	return nil
-}
-
-func writeSSEJSON(writer *bufio.Writer, flusher http.Flusher, payload any) error {
-	data, err := json.Marshal(payload)
-	if err != nil {
-		return err
+// isMemoryStreamChunk reports whether chunk is the memory summary that ends a
+// stream requested with memory_events.
+func isMemoryStreamChunk(chunk conversation.StreamChunk) bool {
+	var envelope struct {
+		Type string `json:"type"`
 	}
-	return writeSSEData(writer, flusher, string(data))
+	return json.Unmarshal(chunk, &envelope) == nil && envelope.Type == conversation.StreamEventMemory
 }
 
 func parseSinceParam(raw string) (time.Time, bool, error) {
@@ -370,16 +361,11 @@ func (h *MessageHandler) StreamMessageEvents(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
 
-	c.Response().Header().Set(echo.HeaderContentType, "text/event-stream")
-	c.Response().Header().Set(echo.HeaderCacheControl, "no-cache")
-	c.Response().Header().Set(echo.HeaderConnection, "keep-alive")
-	c.Response().WriteHeader(http.StatusOK)
-
-	flusher, ok := c.Response().Writer.(http.Flusher)
-	if !ok {
-		return echo.NewHTTPError(http.StatusInternalServerError, "streaming not supported")
+	sse, err := newSSEWriter(c)
+	if err != nil {
+		return err
 	}
-	writer := bufio.NewWriter(c.Response().Writer)
+	defer sse.Close()
 
 	sentMessageIDs := map[string]struct{}{}
 	writeCreatedEvent := func(message messagepkg.Message) error {
@@ -390,7 +376,7 @@ func (h *MessageHandler) StreamMessageEvents(c echo.Context) error {
 			}
 			sentMessageIDs[msgID] = struct{}{}
 		}
-		return writeSSEJSON(writer, flusher, map[string]any{
+		return sse.SendJSON("", map[string]any{
 			"type":    string(messageevent.EventTypeMessageCreated),
 			"bot_id":  botID,
 			"message": message,
@@ -420,7 +406,7 @@ func (h *MessageHandler) StreamMessageEvents(c echo.Context) error {
 		case <-c.Request().Context().Done():
 			return nil
 		case <-heartbeatTicker.C:
-			if err := writeSSEJSON(writer, flusher, map[string]any{"type": "ping"}); err != nil {
+			if err := sse.SendJSON("", map[string]any{"type": "ping"}); err != nil {
 				return nil
 			}
 		case event, ok := <-stream:
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"os"
 	"path"
@@ -53,6 +54,8 @@ func (h *ContainerdHandler) ListSkills(c echo.Context) error {
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
+	unlock := h.rlockBotFS(botID)
+	defer unlock()
 	entries, err := listSkillEntries(skillsDir)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
@@ -88,6 +91,8 @@ func (h *ContainerdHandler) ListSkills(c echo.Context) error {
 // @Success 200 {object} skillsOpResponse
 // @Failure 400 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 415 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /bots/{bot_id}/container/skills [post]
 func (h *ContainerdHandler) UpsertSkills(c echo.Context) error {
@@ -107,6 +112,8 @@ func (h *ContainerdHandler) UpsertSkills(c echo.Context) error {
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
+	unlock := h.lockBotFS(botID)
+	defer unlock()
 	for _, skill := range req.Skills {
 		name := strings.TrimSpace(skill.Name)
 		if !isValidSkillName(name) {
@@ -117,10 +124,16 @@ func (h *ContainerdHandler) UpsertSkills(c echo.Context) error {
 			content = buildSkillContent(name, strings.TrimSpace(skill.Description))
 		}
 		dirPath := filepath.Join(skillsDir, name)
+		filePath := filepath.Join(dirPath, "SKILL.md")
+		if err := h.writePolicy.Check(filePath, []byte(content), int64(len(content))); err != nil {
+			return echo.NewHTTPError(http.StatusUnsupportedMediaType, err.Error())
+		}
 		if err := os.MkdirAll(dirPath, 0o755); err != nil {
 			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 		}
-		filePath := filepath.Join(dirPath, "SKILL.md")
+		if err := checkRegularFileTarget(filePath); err != nil {
+			return echo.NewHTTPError(http.StatusConflict, err.Error())
+		}
 		if err := os.WriteFile(filePath, []byte(content), 0o644); err != nil {
 			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 		}
@@ -156,6 +169,8 @@ func (h *ContainerdHandler) DeleteSkills(c echo.Context) error {
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
+	unlock := h.lockBotFS(botID)
+	defer unlock()
 
 	for _, name := range req.Names {
 		skillName := strings.TrimSpace(name)
@@ -178,6 +193,8 @@ func (h *ContainerdHandler) LoadSkills(ctx context.Context, botID string) ([]Ski
 	if err != nil {
 		return nil, err
 	}
+	unlock := h.rlockBotFS(botID)
+	defer unlock()
 
 	entries, err := listSkillEntries(skillsDir)
 	if err != nil {
@@ -230,6 +247,23 @@ func (h *ContainerdHandler) readSkillFile(skillsDir, filePath string) (string, e
 	return string(data), nil
 }
 
+// checkRegularFileTarget rejects write targets in the shared data mount that
+// exist but are not regular files (FIFOs, sockets, device nodes, symlinks).
+// It uses lstat so a planted symlink is not followed.
+func checkRegularFileTarget(filePath string) error {
+	info, err := os.Lstat(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("refusing to write %s: not a regular file (%s)", filepath.Base(filePath), info.Mode().Type())
+	}
+	return nil
+}
+
 func listSkillEntries(skillsDir string) ([]skillEntry, error) {
 	dirEntries, err := os.ReadDir(skillsDir)
 	if err != nil {
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// DefaultSSEKeepAlive is how long a stream may stay quiet before a ping
+// comment is sent, unless changed with SetSSEKeepAlive.
+const DefaultSSEKeepAlive = 15 * time.Second
+
+var sseKeepAlive atomic.Int64
+
+func init() {
+	sseKeepAlive.Store(int64(DefaultSSEKeepAlive))
+}
+
+// SetSSEKeepAlive sets the idle interval after which streams opened with
+// newSSEWriter send a ping comment. Zero or less disables the pings.
+func SetSSEKeepAlive(d time.Duration) {
+	sseKeepAlive.Store(int64(d))
+}
+
+// sseWriter writes server-sent events and flushes after each one, so clients
+// never wait on a buffered event. It is safe for concurrent use, which lets a
+// keep-alive goroutine share the stream with the handler.
+type sseWriter struct {
+	mu        sync.Mutex
+	w         io.Writer
+	flusher   http.Flusher
+	lastWrite time.Time
+	stopPing  func()
+}
+
+// newSSEWriter sets the event-stream headers and writes the 200 status. It
+// fails before anything is written when the response cannot be flushed, so
+// callers can still return a regular HTTP error. Keep-alive pings run until
+// the request ends or Close is called; callers should defer Close.
+func newSSEWriter(c echo.Context) (*sseWriter, error) {
+	flusher, ok := c.Response().Writer.(http.Flusher)
+	if !ok {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "streaming not supported")
+	}
+	header := c.Response().Header()
+	header.Set(echo.HeaderContentType, "text/event-stream")
+	header.Set(echo.HeaderCacheControl, "no-cache")
+	header.Set(echo.HeaderConnection, "keep-alive")
+	// Stop reverse proxies such as nginx from buffering the stream.
+	header.Set("X-Accel-Buffering", "no")
+	c.Response().WriteHeader(http.StatusOK)
+	flusher.Flush()
+	s := &sseWriter{w: c.Response().Writer, flusher: flusher, lastWrite: time.Now()}
+	if interval := time.Duration(sseKeepAlive.Load()); interval > 0 {
+		s.stopPing = s.KeepAlive(c.Request().Context(), interval)
+	}
+	return s, nil
+}
+
+// Close stops the keep-alive pings. It does not end the response.
+func (s *sseWriter) Close() {
+	if s.stopPing != nil {
+		s.stopPing()
+		s.stopPing = nil
+	}
+}
+
+// Send writes one event. An empty event name sends an unnamed "message"
+// event; multi-line data is split into one data field per line.
+func (s *sseWriter) Send(event, data string) error {
+	var b strings.Builder
+	if event != "" {
+		b.WriteString("event: ")
+		b.WriteString(event)
+		b.WriteByte('\n')
+	}
+	for _, line := range strings.Split(data, "\n") {
+		b.WriteString("data: ")
+		b.WriteString(strings.TrimSuffix(line, "\r"))
+		b.WriteByte('\n')
+	}
+	b.WriteByte('\n')
+	return s.write(b.String())
+}
+
+// SendJSON sends payload encoded as JSON.
+func (s *sseWriter) SendJSON(event string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return s.Send(event, string(data))
+}
+
+// Ping writes an SSE comment. EventSource and spec-compliant parsers drop
+// comment lines, so clients never see it, but it keeps proxies from closing
+// the connection as idle.
+func (s *sseWriter) Ping() error {
+	return s.write(": ping\n\n")
+}
+
+// KeepAlive pings whenever the stream has been quiet for interval, until
+// ctx is done, a ping fails or the returned stop function is called. stop
+// waits for the pinger to exit.
+func (s *sseWriter) KeepAlive(ctx context.Context, interval time.Duration) (stop func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		timer := time.NewTimer(interval)
+		defer timer.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-timer.C:
+				idle := s.idle()
+				if idle >= interval {
+					if err := s.Ping(); err != nil {
+						return
+					}
+					idle = 0
+				}
+				timer.Reset(interval - idle)
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+func (s *sseWriter) write(frame string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := io.WriteString(s.w, frame); err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	s.lastWrite = time.Now()
+	return nil
+}
+
+func (s *sseWriter) idle() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return time.Since(s.lastWrite)
+}
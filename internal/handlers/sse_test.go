@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestSSEWriter(t *testing.T) {
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+
+	SetSSEKeepAlive(0)
+	defer SetSSEKeepAlive(DefaultSSEKeepAlive)
+	sse, err := newSSEWriter(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sse.Close()
+	if got := rec.Header().Get(echo.HeaderContentType); got != "text/event-stream" {
+		t.Fatalf("content type = %q", got)
+	}
+	if err := sse.Send("", "plain"); err != nil {
+		t.Fatal(err)
+	}
+	if err := sse.Send("memory", "line one\nline two"); err != nil {
+		t.Fatal(err)
+	}
+	if err := sse.SendJSON("", map[string]string{"type": "ping"}); err != nil {
+		t.Fatal(err)
+	}
+	want := "data: plain\n\n" +
+		"event: memory\ndata: line one\ndata: line two\n\n" +
+		"data: {\"type\":\"ping\"}\n\n"
+	if got := rec.Body.String(); got != want {
+		t.Fatalf("body = %q, want %q", got, want)
+	}
+	if !rec.Flushed {
+		t.Fatal("expected the response to be flushed")
+	}
+
+	rec.Body.Reset()
+	stop := sse.KeepAlive(context.Background(), 5*time.Millisecond)
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		sse.mu.Lock()
+		pinged := strings.Contains(rec.Body.String(), ": ping\n\n")
+		sse.mu.Unlock()
+		if pinged {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	stop()
+	if !strings.Contains(rec.Body.String(), ": ping\n\n") {
+		t.Fatalf("expected a keep-alive comment, got %q", rec.Body.String())
+	}
+}
+
+func TestSSEWriterKeepAliveOnlyWhenIdle(t *testing.T) {
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+	SetSSEKeepAlive(50 * time.Millisecond)
+	defer SetSSEKeepAlive(DefaultSSEKeepAlive)
+	sse, err := newSSEWriter(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A busy stream gets no pings.
+	for i := 0; i < 10; i++ {
+		if err := sse.Send("", "tick"); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	sse.mu.Lock()
+	busy := rec.Body.String()
+	sse.mu.Unlock()
+	if strings.Contains(busy, ": ping") {
+		t.Fatalf("unexpected ping on a busy stream: %q", busy)
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	sse.Close()
+	if !strings.Contains(rec.Body.String(), ": ping\n\n") {
+		t.Fatalf("expected a ping on an idle stream, got %q", rec.Body.String())
+	}
+}
@@ -0,0 +1,306 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// errMalformedPatch marks patches that cannot be parsed at all, as opposed
+// to well-formed patches that do not match the file.
+var errMalformedPatch = errors.New("malformed patch")
+
+// patchConflictError reports a hunk whose context or removed lines do not
+// match the file.
+type patchConflictError struct {
+	// Hunk is 1-based; Line is the 1-based line in the original file.
+	Hunk int
+	Line int
+	Msg  string
+	// Expected and Found are the mismatching patch and file lines, when the
+	// conflict is a mismatch rather than a position out of range.
+	Expected string
+	Found    string
+}
+
+func (e *patchConflictError) Error() string {
+	return fmt.Sprintf("hunk %d does not apply at line %d: %s", e.Hunk, e.Line, e.Msg)
+}
+
+var hunkHeaderRe = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+type patchHunk struct {
+	oldStart, oldLines int
+	newStart, newLines int
+	// lines keep their ' ', '-' or '+' prefix.
+	lines []string
+	// newNoEOL is set by "\ No newline at end of file" after a line of the
+	// new side.
+	newNoEOL bool
+}
+
+// parseUnifiedPatch parses the hunks of a single-file unified diff. File
+// headers (diff, index, ---, +++) before the first hunk are ignored.
+func parseUnifiedPatch(patch string) ([]patchHunk, error) {
+	lines := strings.Split(strings.ReplaceAll(patch, "\r\n", "\n"), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	var hunks []patchHunk
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		if !strings.HasPrefix(line, "@@") {
+			// Anything before the first hunk, such as a commit message, is
+			// skipped; after it only another file's headers may follow.
+			if len(hunks) > 0 && strings.TrimSpace(line) != "" {
+				if isPatchFileHeader(line) {
+					return nil, fmt.Errorf("%w: patch touches more than one file", errMalformedPatch)
+				}
+				return nil, fmt.Errorf("%w: unexpected line %d outside a hunk", errMalformedPatch, i+1)
+			}
+			i++
+			continue
+		}
+		m := hunkHeaderRe.FindStringSubmatch(line)
+		if m == nil {
+			return nil, fmt.Errorf("%w: bad hunk header on line %d", errMalformedPatch, i+1)
+		}
+		h := patchHunk{
+			oldStart: atoiDefault(m[1], 0),
+			oldLines: atoiDefault(m[2], 1),
+			newStart: atoiDefault(m[3], 0),
+			newLines: atoiDefault(m[4], 1),
+		}
+		i++
+		oldSeen, newSeen := 0, 0
+		for i < len(lines) && (oldSeen < h.oldLines || newSeen < h.newLines || strings.HasPrefix(lines[i], `\`)) {
+			body := lines[i]
+			switch {
+			case strings.HasPrefix(body, `\`):
+				if len(h.lines) == 0 {
+					return nil, fmt.Errorf("%w: stray no-newline marker on line %d", errMalformedPatch, i+1)
+				}
+				if h.lines[len(h.lines)-1][0] != '-' {
+					h.newNoEOL = true
+				}
+				i++
+				continue
+			case body == "":
+				// Some tools strip the trailing space of empty context lines.
+				body = " "
+			}
+			switch body[0] {
+			case ' ':
+				oldSeen++
+				newSeen++
+			case '-':
+				oldSeen++
+			case '+':
+				newSeen++
+			default:
+				return nil, fmt.Errorf("%w: unexpected line %d inside a hunk", errMalformedPatch, i+1)
+			}
+			h.lines = append(h.lines, body)
+			i++
+		}
+		if oldSeen != h.oldLines || newSeen != h.newLines {
+			return nil, fmt.Errorf("%w: hunk %d line counts do not match its header", errMalformedPatch, len(hunks)+1)
+		}
+		hunks = append(hunks, h)
+	}
+	if len(hunks) == 0 {
+		return nil, fmt.Errorf("%w: no hunks", errMalformedPatch)
+	}
+	return hunks, nil
+}
+
+func isPatchFileHeader(line string) bool {
+	for _, prefix := range []string{"diff ", "index ", "--- ", "+++ "} {
+		if strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func atoiDefault(s string, def int) int {
+	if s == "" {
+		return def
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return def
+	}
+	return n
+}
+
+// patchFuzzWindow is how many lines away from its header position a hunk
+// may apply in fuzzy mode unless a window is given.
+const patchFuzzWindow = 20
+
+// maxPatchFuzz caps the fuzz window a caller may ask for.
+const maxPatchFuzz = 1000
+
+type patchOptions struct {
+	// Fuzz lets a hunk whose context does not match where its header says
+	// apply up to Fuzz lines away, as GNU patch does, and then retries
+	// ignoring trailing whitespace. Zero means strict matching.
+	Fuzz int
+}
+
+type patchResult struct {
+	Content string
+	Hunks   int
+	// Offsets holds, per hunk, how many lines from its header position the
+	// hunk applied. They are always zero without Fuzz.
+	Offsets []int
+}
+
+// applyUnifiedPatch applies a single-file unified diff to original. Hunks
+// must be in file order and, unless opts.Fuzz is set, match exactly at the
+// lines their headers name. A file with CRLF line endings is matched
+// without its carriage returns and keeps CRLF endings, added lines
+// included.
+func applyUnifiedPatch(original, patch string, opts patchOptions) (patchResult, error) {
+	hunks, err := parseUnifiedPatch(patch)
+	if err != nil {
+		return patchResult{}, err
+	}
+	eol := "\n"
+	if usesCRLF(original) {
+		eol = "\r\n"
+		original = strings.ReplaceAll(original, "\r\n", "\n")
+	}
+	src, eofNewline := splitPatchLines(original)
+	out := make([]string, 0, len(src))
+	offsets := make([]int, len(hunks))
+	// Hunk headers address the original file, so hunks are located in src
+	// and the gaps between them copied from src as well. Lines added or
+	// removed by earlier hunks only shift positions in out, which is built
+	// by appending and never indexed.
+	cursor := 0
+	// A fuzzy hunk's offset carries over to the next one, since drift
+	// usually comes from lines added or removed above both.
+	carried := 0
+	for i, h := range hunks {
+		// A hunk without old lines inserts after line oldStart.
+		want := h.oldStart - 1
+		if h.oldLines == 0 {
+			want = h.oldStart
+		}
+		start, err := locateHunk(src, cursor, want, carried, h.oldSide(), opts.Fuzz)
+		if err != nil {
+			err.Hunk = i + 1
+			return patchResult{}, err
+		}
+		offsets[i] = start - want
+		carried = offsets[i]
+		out = append(out, src[cursor:start]...)
+		pos := start
+		for _, line := range h.lines {
+			switch line[0] {
+			case ' ':
+				// Keep the file's own line, which may differ in trailing
+				// whitespace under fuzzy matching.
+				out = append(out, src[pos])
+				pos++
+			case '-':
+				pos++
+			case '+':
+				out = append(out, line[1:])
+			}
+		}
+		cursor = pos
+		if cursor == len(src) {
+			eofNewline = !h.newNoEOL
+		}
+	}
+	out = append(out, src[cursor:]...)
+	result := patchResult{Hunks: len(hunks), Offsets: offsets}
+	if len(out) > 0 {
+		result.Content = strings.Join(out, eol)
+		if eofNewline {
+			result.Content += eol
+		}
+	}
+	return result, nil
+}
+
+// oldSide returns the context and removed lines the hunk expects to find.
+func (h patchHunk) oldSide() []string {
+	old := make([]string, 0, h.oldLines)
+	for _, line := range h.lines {
+		if line[0] != '+' {
+			old = append(old, line[1:])
+		}
+	}
+	return old
+}
+
+// locateHunk returns where in src the old lines apply: at want, or with a
+// fuzz window the nearest position to want+carried within fuzz lines,
+// trying exact matches before whitespace-insensitive ones. Positions before
+// cursor belong to earlier hunks and are never used.
+func locateHunk(src []string, cursor, want, carried int, old []string, fuzz int) (int, *patchConflictError) {
+	strictErr := matchHunkAt(src, cursor, want, old, exactLineMatch)
+	if strictErr == nil || fuzz <= 0 {
+		return want, strictErr
+	}
+	for _, eq := range []func(a, b string) bool{exactLineMatch, trailingSpaceLineMatch} {
+		for d := 0; d <= fuzz; d++ {
+			for _, start := range []int{want + carried - d, want + carried + d} {
+				if matchHunkAt(src, cursor, start, old, eq) == nil {
+					return start, nil
+				}
+				if d == 0 {
+					break
+				}
+			}
+		}
+	}
+	return 0, strictErr
+}
+
+func matchHunkAt(src []string, cursor, start int, old []string, eq func(a, b string) bool) *patchConflictError {
+	if start < cursor || start > len(src) {
+		return &patchConflictError{Line: start + 1, Msg: "hunk is out of order or past the end of the file"}
+	}
+	for j, text := range old {
+		pos := start + j
+		if pos >= len(src) {
+			return &patchConflictError{Line: pos + 1, Msg: "unexpected end of file"}
+		}
+		if !eq(src[pos], text) {
+			return &patchConflictError{Line: pos + 1, Msg: fmt.Sprintf("expected %q, found %q", text, src[pos]), Expected: text, Found: src[pos]}
+		}
+	}
+	return nil
+}
+
+func exactLineMatch(a, b string) bool { return a == b }
+
+func trailingSpaceLineMatch(a, b string) bool {
+	return strings.TrimRight(a, " \t\r") == strings.TrimRight(b, " \t\r")
+}
+
+// usesCRLF reports whether text has line breaks and every one of them is
+// CRLF. Files with mixed endings are patched byte for byte.
+func usesCRLF(text string) bool {
+	lf := strings.Count(text, "\n")
+	return lf > 0 && strings.Count(text, "\r\n") == lf
+}
+
+// splitPatchLines splits text into lines without their terminators and
+// reports whether the last line ended with a newline.
+func splitPatchLines(text string) ([]string, bool) {
+	if text == "" {
+		return nil, true
+	}
+	lines := strings.Split(text, "\n")
+	if lines[len(lines)-1] == "" {
+		return lines[:len(lines)-1], true
+	}
+	return lines, false
+}
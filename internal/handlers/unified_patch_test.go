@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestApplyUnifiedPatch(t *testing.T) {
+	original := "one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\n"
+	cases := []struct {
+		name     string
+		original string
+		patch    string
+		want     string
+		hunks    int
+	}{
+		{
+			name:     "single hunk with headers",
+			original: original,
+			patch:    "--- a/n.txt\n+++ b/n.txt\n@@ -2,3 +2,3 @@\n two\n-three\n+THREE\n four\n",
+			want:     "one\ntwo\nTHREE\nfour\nfive\nsix\nseven\neight\nnine\nten\n",
+			hunks:    1,
+		},
+		{
+			name:     "two hunks changing line counts",
+			original: original,
+			patch:    "@@ -1,2 +1,3 @@\n one\n+one and a half\n two\n@@ -8,3 +9,2 @@\n eight\n-nine\n ten\n",
+			want:     "one\none and a half\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nten\n",
+			hunks:    2,
+		},
+		{
+			name:     "first hunk adds lines before a second",
+			original: original,
+			patch:    "@@ -1,3 +1,5 @@\n one\n+1a\n+1b\n two\n three\n@@ -5,3 +7,3 @@\n five\n-six\n+SIX\n seven\n",
+			want:     "one\n1a\n1b\ntwo\nthree\nfour\nfive\nSIX\nseven\neight\nnine\nten\n",
+			hunks:    2,
+		},
+		{
+			name:     "three hunks adding, removing and replacing",
+			original: original,
+			patch: "@@ -1,2 +1,3 @@\n+zero\n one\n two\n" +
+				"@@ -4,3 +5,2 @@\n four\n-five\n six\n" +
+				"@@ -9,2 +9,3 @@\n nine\n-ten\n+TEN\n+eleven\n",
+			want:  "zero\none\ntwo\nthree\nfour\nsix\nseven\neight\nnine\nTEN\neleven\n",
+			hunks: 3,
+		},
+		{
+			name:     "adjacent hunks",
+			original: original,
+			patch:    "@@ -1 +1 @@\n-one\n+ONE\n@@ -2 +2 @@\n-two\n+TWO\n",
+			want:     "ONE\nTWO\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\n",
+			hunks:    2,
+		},
+		{
+			name:     "pure insertions without context",
+			original: "a\nb\nc\n",
+			patch:    "@@ -0,0 +1 @@\n+start\n@@ -1,0 +3 @@\n+after a\n@@ -3,0 +6 @@\n+end\n",
+			want:     "start\na\nafter a\nb\nc\nend\n",
+			hunks:    3,
+		},
+		{
+			name:     "append to file without final newline",
+			original: "a\nb",
+			patch:    "@@ -2 +2,2 @@\n-b\n\\ No newline at end of file\n+b\n+c\n\\ No newline at end of file\n",
+			want:     "a\nb\nc",
+			hunks:    1,
+		},
+		{
+			name:     "edit away from the end keeps a missing final newline",
+			original: "a\nb\nc",
+			patch:    "@@ -1 +1 @@\n-a\n+A\n",
+			want:     "A\nb\nc",
+			hunks:    1,
+		},
+		{
+			name:     "delete everything",
+			original: "a\nb\n",
+			patch:    "@@ -1,2 +0,0 @@\n-a\n-b\n",
+			want:     "",
+			hunks:    1,
+		},
+		{
+			name:     "create file",
+			original: "",
+			patch:    "--- /dev/null\n+++ b/new.txt\n@@ -0,0 +1,2 @@\n+hello\n+world\n",
+			want:     "hello\nworld\n",
+			hunks:    1,
+		},
+		{
+			name:     "drop final newline",
+			original: "a\nb\n",
+			patch:    "@@ -2 +2 @@\n-b\n+b\n\\ No newline at end of file\n",
+			want:     "a\nb",
+			hunks:    1,
+		},
+		{
+			name:     "add final newline",
+			original: "a\nb",
+			patch:    "@@ -2 +2 @@\n-b\n\\ No newline at end of file\n+b\n",
+			want:     "a\nb\n",
+			hunks:    1,
+		},
+	}
+	for _, tc := range cases {
+		got, err := applyUnifiedPatch(tc.original, tc.patch, patchOptions{})
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if got.Content != tc.want || got.Hunks != tc.hunks {
+			t.Errorf("%s: got %q (%d hunks), want %q (%d hunks)", tc.name, got.Content, got.Hunks, tc.want, tc.hunks)
+		}
+	}
+}
+
+func TestApplyUnifiedPatchErrors(t *testing.T) {
+	original := "one\ntwo\nthree\n"
+	var conflict *patchConflictError
+	if _, err := applyUnifiedPatch(original, "@@ -2,2 +2,2 @@\n two\n-four\n+FOUR\n", patchOptions{}); !errors.As(err, &conflict) || conflict.Hunk != 1 || conflict.Line != 3 {
+		t.Errorf("mismatched line: err = %v, want conflict in hunk 1 at line 3", err)
+	}
+	if _, err := applyUnifiedPatch(original, "@@ -3 +3 @@\n-three\n+3\n@@ -1 +1 @@\n-one\n+1\n", patchOptions{}); !errors.As(err, &conflict) || conflict.Hunk != 2 {
+		t.Errorf("out of order hunks: err = %v, want conflict in hunk 2", err)
+	}
+	for name, patch := range map[string]string{
+		"no hunks":        "--- a/x\n+++ b/x\n",
+		"short hunk":      "@@ -1,2 +1,2 @@\n-one\n+1\n",
+		"two files":       "@@ -1 +1 @@\n-one\n+1\n--- a/y\n+++ b/y\n@@ -1 +1 @@\n-a\n+b\n",
+		"bad body prefix": "@@ -1 +1 @@\n*one\n",
+	} {
+		if _, err := applyUnifiedPatch(original, patch, patchOptions{}); !errors.Is(err, errMalformedPatch) {
+			t.Errorf("%s: err = %v, want errMalformedPatch", name, err)
+		}
+	}
+}
+
+func TestApplyUnifiedPatchFuzzy(t *testing.T) {
+	// Two lines were inserted at the top since the patch was made.
+	original := "new1\nnew2\none\ntwo\nthree\nfour\nfive\nsix\n"
+	patch := "@@ -2 +2 @@\n-two\n+TWO\n@@ -5 +5 @@\n-five\n+FIVE\n"
+	if _, err := applyUnifiedPatch(original, patch, patchOptions{}); err == nil {
+		t.Fatal("strict mode applied a patch with shifted context")
+	}
+	got, err := applyUnifiedPatch(original, patch, patchOptions{Fuzz: patchFuzzWindow})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "new1\nnew2\none\nTWO\nthree\nfour\nFIVE\nsix\n"; got.Content != want {
+		t.Errorf("content = %q, want %q", got.Content, want)
+	}
+	if len(got.Offsets) != 2 || got.Offsets[0] != 2 || got.Offsets[1] != 2 {
+		t.Errorf("offsets = %v, want [2 2]", got.Offsets)
+	}
+
+	// Trailing whitespace only matches in fuzzy mode, and the file keeps its own.
+	original = "one  \ntwo\nthree\n"
+	patch = "@@ -1,2 +1,2 @@\n one\n-two\n+TWO\n"
+	if _, err := applyUnifiedPatch(original, patch, patchOptions{}); err == nil {
+		t.Error("strict mode ignored trailing whitespace")
+	}
+	got, err = applyUnifiedPatch(original, patch, patchOptions{Fuzz: patchFuzzWindow})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "one  \nTWO\nthree\n"; got.Content != want {
+		t.Errorf("content = %q, want %q", got.Content, want)
+	}
+
+	// Context beyond the window still conflicts.
+	original = strings.Repeat("x\n", patchFuzzWindow+1) + "one\n"
+	var conflict *patchConflictError
+	if _, err := applyUnifiedPatch(original, "@@ -1 +1 @@\n-one\n+1\n", patchOptions{Fuzz: patchFuzzWindow}); !errors.As(err, &conflict) {
+		t.Errorf("err = %v, want a conflict outside the fuzz window", err)
+	}
+}
+
+func TestApplyUnifiedPatchFuzzWindow(t *testing.T) {
+	// One line was inserted above the hunk: off by one.
+	original := "zero\none\ntwo\nthree\n"
+	patch := "@@ -1,2 +1,2 @@\n one\n-two\n+TWO\n"
+	if _, err := applyUnifiedPatch(original, patch, patchOptions{}); err == nil {
+		t.Fatal("strict mode applied an off-by-one hunk")
+	}
+	got, err := applyUnifiedPatch(original, patch, patchOptions{Fuzz: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "zero\none\nTWO\nthree\n"; got.Content != want || got.Offsets[0] != 1 {
+		t.Errorf("content = %q offsets = %v, want %q [1]", got.Content, got.Offsets, want)
+	}
+	if _, err := applyUnifiedPatch("a\nb\n"+original, patch, patchOptions{Fuzz: 1}); err == nil {
+		t.Error("hunk applied outside a fuzz window of 1")
+	}
+}
+
+func TestApplyUnifiedPatchCRLF(t *testing.T) {
+	original := "one\r\ntwo\r\nthree\r\n"
+	// Patches usually arrive with their CRLFs stripped or kept; both apply.
+	for _, patch := range []string{
+		"@@ -1,2 +1,3 @@\n one\n-two\n+TWO\n+2b\n",
+		"@@ -1,2 +1,3 @@\r\n one\r\n-two\r\n+TWO\r\n+2b\r\n",
+	} {
+		got, err := applyUnifiedPatch(original, patch, patchOptions{})
+		if err != nil {
+			t.Fatalf("patch %q: %v", patch, err)
+		}
+		if want := "one\r\nTWO\r\n2b\r\nthree\r\n"; got.Content != want {
+			t.Errorf("content = %q, want %q", got.Content, want)
+		}
+	}
+}
+
+func TestApplyUnifiedPatchAddFinalNewline(t *testing.T) {
+	original := "one\ntwo"
+	patch := "@@ -1,2 +1,2 @@\n one\n-two\n\\ No newline at end of file\n+two\n"
+	got, err := applyUnifiedPatch(original, patch, patchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "one\ntwo\n"; got.Content != want {
+		t.Errorf("content = %q, want %q", got.Content, want)
+	}
+
+	got, err = applyUnifiedPatch("one\r\ntwo", patch, patchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "one\r\ntwo\r\n"; got.Content != want {
+		t.Errorf("CRLF content = %q, want %q", got.Content, want)
+	}
+}
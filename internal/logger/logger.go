@@ -3,15 +3,25 @@ package logger
 import (
 	"context"
 	"log/slog"
+	"net/http"
 	"os"
 	"strings"
+	"sync/atomic"
 )
 
-type ctxKey struct{}
+type (
+	ctxKey       struct{}
+	requestIDKey struct{}
+)
+
+// RequestIDHeader carries the request correlation id between services.
+const RequestIDHeader = "X-Request-ID"
 
 var (
 	L      *slog.Logger = slog.Default()
 	logKey              = ctxKey{}
+
+	propagateRequestID atomic.Bool
 )
 
 // Init initializes the global logger with the given level and format (e.g. "debug", "json").
@@ -27,10 +37,32 @@ func Init(level, format string) {
 		handler = slog.NewTextHandler(os.Stdout, opts)
 	}
 
-	L = slog.New(handler)
+	L = slog.New(requestIDHandler{handler})
 	slog.SetDefault(L)
 }
 
+// requestIDHandler adds the request id of the context to records logged
+// with the *Context methods, so that every log line of a request can be tied
+// to it without passing the id around.
+type requestIDHandler struct {
+	slog.Handler
+}
+
+func (h requestIDHandler) Handle(ctx context.Context, r slog.Record) error {
+	if requestID := RequestIDFromContext(ctx); requestID != "" {
+		r.AddAttrs(slog.String("request_id", requestID))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h requestIDHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return requestIDHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h requestIDHandler) WithGroup(name string) slog.Handler {
+	return requestIDHandler{h.Handler.WithGroup(name)}
+}
+
 // FromContext returns the logger from ctx, or the global logger if not set.
 func FromContext(ctx context.Context) *slog.Logger {
 	if l, ok := ctx.Value(logKey).(*slog.Logger); ok {
@@ -44,6 +76,43 @@ func WithContext(ctx context.Context, l *slog.Logger) context.Context {
 	return context.WithValue(ctx, logKey, l)
 }
 
+// WithRequestID stores the request correlation id in ctx.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFromContext returns the request correlation id, or "" if none is set.
+func RequestIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// SetRequestIDPropagation controls whether request ids are forwarded to
+// external services such as LLM, embedding and Qdrant endpoints.
+func SetRequestIDPropagation(enabled bool) {
+	propagateRequestID.Store(enabled)
+}
+
+// OutgoingRequestID returns the request id to forward to external services,
+// or "" when there is none or propagation is disabled.
+func OutgoingRequestID(ctx context.Context) string {
+	if !propagateRequestID.Load() {
+		return ""
+	}
+	return RequestIDFromContext(ctx)
+}
+
+// SetOutgoingRequestID sets RequestIDHeader on req from its context when
+// propagation is enabled.
+func SetOutgoingRequestID(req *http.Request) {
+	if requestID := OutgoingRequestID(req.Context()); requestID != "" {
+		req.Header.Set(RequestIDHeader, requestID)
+	}
+}
+
 func parseLevel(level string) slog.Level {
 	switch strings.ToLower(level) {
 	case "debug":
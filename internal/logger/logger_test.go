@@ -1,8 +1,11 @@
 package logger
 
 import (
+	"bytes"
 	"context"
 	"log/slog"
+	"net/http"
+	"strings"
 	"testing"
 )
 
@@ -49,3 +52,34 @@ func TestParseLevel(t *testing.T) {
 		}
 	}
 }
+
+func TestRequestIDHandler(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(requestIDHandler{slog.NewJSONHandler(&buf, nil)}).With("service", "test")
+
+	log.InfoContext(WithRequestID(context.Background(), "req-1"), "hello")
+	if !strings.Contains(buf.String(), `"request_id":"req-1"`) {
+		t.Errorf("log line without request id: %s", buf.String())
+	}
+	buf.Reset()
+	log.InfoContext(context.Background(), "hello")
+	if strings.Contains(buf.String(), "request_id") {
+		t.Errorf("unexpected request id: %s", buf.String())
+	}
+}
+
+func TestOutgoingRequestID(t *testing.T) {
+	defer SetRequestIDPropagation(false)
+	ctx := WithRequestID(context.Background(), "req-1")
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://example.com", nil)
+
+	SetOutgoingRequestID(req)
+	if got := req.Header.Get(RequestIDHeader); got != "" {
+		t.Errorf("header set while propagation disabled: %q", got)
+	}
+	SetRequestIDPropagation(true)
+	SetOutgoingRequestID(req)
+	if got := req.Header.Get(RequestIDHeader); got != "req-1" {
+		t.Errorf("header = %q, want req-1", got)
+	}
+}
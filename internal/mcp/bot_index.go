@@ -0,0 +1,124 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+
+	ctr "github.com/memohai/memoh/internal/containerd"
+)
+
+// botIndex caches the container ID -> bot ID mapping derived from container
+// labels, so fleet-wide operations can attribute containers without a scan.
+type botIndex struct {
+	mu          sync.RWMutex
+	loaded      bool
+	byContainer map[string]string
+}
+
+func (idx *botIndex) lookup(containerID string) (string, bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	botID, ok := idx.byContainer[containerID]
+	return botID, ok
+}
+
+func (idx *botIndex) set(containerID, botID string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if idx.byContainer == nil {
+		idx.byContainer = map[string]string{}
+	}
+	idx.byContainer[containerID] = botID
+}
+
+func (idx *botIndex) remove(containerID string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	delete(idx.byContainer, containerID)
+}
+
+func (idx *botIndex) replace(entries map[string]string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.byContainer = entries
+	idx.loaded = true
+}
+
+// botIDs returns every indexed bot.
+func (idx *botIndex) botIDs() []string {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	seen := make(map[string]struct{}, len(idx.byContainer))
+	out := make([]string, 0, len(idx.byContainer))
+	for _, botID := range idx.byContainer {
+		if _, ok := seen[botID]; ok {
+			continue
+		}
+		seen[botID] = struct{}{}
+		out = append(out, botID)
+	}
+	return out
+}
+
+func (idx *botIndex) isLoaded() bool {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.loaded
+}
+
+// BotForContainer returns the bot that owns containerID, based on the
+// BotLabelKey label. Results are cached and kept current by EnsureBot,
+// Delete and the idle reaper; unknown containers fall back to a direct label
+// lookup.
+func (m *Manager) BotForContainer(ctx context.Context, containerID string) (string, error) {
+	containerID = strings.TrimSpace(containerID)
+	if containerID == "" {
+		return "", fmt.Errorf("container id is required")
+	}
+	if !m.bots.isLoaded() {
+		if err := m.RefreshBotIndex(ctx); err != nil {
+			m.logger.Warn("refresh bot index failed", slog.Any("error", err))
+		}
+	}
+	if botID, ok := m.bots.lookup(containerID); ok {
+		return botID, nil
+	}
+	container, err := m.service.GetContainer(ctx, containerID)
+	if err != nil {
+		return "", err
+	}
+	info, err := container.Info(ctx)
+	if err != nil {
+		return "", err
+	}
+	botID := strings.TrimSpace(info.Labels[BotLabelKey])
+	if botID == "" {
+		return "", fmt.Errorf("container %s has no %s label", containerID, BotLabelKey)
+	}
+	m.bots.set(containerID, botID)
+	return botID, nil
+}
+
+// RefreshBotIndex rebuilds the container -> bot index from container labels.
+func (m *Manager) RefreshBotIndex(ctx context.Context) error {
+	tasks, err := m.ListBotTasks(ctx)
+	if err != nil {
+		return err
+	}
+	entries := make(map[string]string, len(tasks))
+	for botID, entry := range tasks {
+		if botID = strings.TrimSpace(botID); botID != "" {
+			entries[entry.ContainerID] = botID
+		}
+	}
+	m.bots.replace(entries)
+	return nil
+}
+
+// ListBotTasks returns every bot container and its task, keyed by bot ID.
+func (m *Manager) ListBotTasks(ctx context.Context) (map[string]ctr.LabeledTask, error) {
+	return m.service.ListTasksByLabel(ctx, BotLabelKey)
+}
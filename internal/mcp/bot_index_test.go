@@ -0,0 +1,35 @@
+package mcp
+
+import "testing"
+
+func TestBotIndex(t *testing.T) {
+	var idx botIndex
+	if _, ok := idx.lookup("mcp-a"); ok {
+		t.Fatal("empty index should not resolve containers")
+	}
+	if idx.isLoaded() {
+		t.Fatal("zero index should not be marked loaded")
+	}
+
+	idx.replace(map[string]string{"mcp-a": "a", "mcp-b": "b"})
+	if !idx.isLoaded() {
+		t.Fatal("index should be loaded after replace")
+	}
+	if botID, ok := idx.lookup("mcp-b"); !ok || botID != "b" {
+		t.Fatalf("lookup(mcp-b) = %q, %v", botID, ok)
+	}
+
+	idx.set("mcp-c", "c")
+	if botID, ok := idx.lookup("mcp-c"); !ok || botID != "c" {
+		t.Fatalf("lookup(mcp-c) = %q, %v", botID, ok)
+	}
+
+	if got := len(idx.botIDs()); got != 3 {
+		t.Fatalf("botIDs() has %d entries, want 3", got)
+	}
+
+	idx.remove("mcp-a")
+	if _, ok := idx.lookup("mcp-a"); ok {
+		t.Fatal("removed container should not resolve")
+	}
+}
@@ -0,0 +1,127 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/containerd/errdefs"
+
+	ctr "github.com/memohai/memoh/internal/containerd"
+	"github.com/memohai/memoh/internal/db"
+)
+
+// CloneBot creates the container of dstBotID from the root filesystem of
+// srcBotID: the snapshot of the given version, or a fresh version of the
+// source's current state when version is 0, in which case a running source
+// task is started again afterwards. The destination bot must exist and must
+// not have a container yet. Only the container filesystem is cloned; copying
+// the data mount is up to the caller, see DataDir.
+func (m *Manager) CloneBot(ctx context.Context, srcBotID, dstBotID string, version int) (*VersionInfo, error) {
+	if m.db == nil || m.queries == nil {
+		return nil, fmt.Errorf("db is not configured")
+	}
+	if err := validateBotID(srcBotID); err != nil {
+		return nil, err
+	}
+	if err := validateBotID(dstBotID); err != nil {
+		return nil, err
+	}
+	if srcBotID == dstBotID {
+		return nil, fmt.Errorf("%w: cannot clone a bot onto itself", ctr.ErrInvalidArgument)
+	}
+
+	dstUUID, err := db.ParseUUID(dstBotID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := m.queries.GetBotByID(ctx, dstUUID); err != nil {
+		return nil, err
+	}
+	dstContainerID := m.containerID(dstBotID)
+	if _, err := m.service.GetContainer(ctx, dstContainerID); err == nil {
+		return nil, fmt.Errorf("%w: container %s", errdefs.ErrAlreadyExists, dstContainerID)
+	} else if !errdefs.IsNotFound(err) {
+		return nil, err
+	}
+
+	srcContainer, err := m.service.GetContainer(ctx, m.containerID(srcBotID))
+	if err != nil {
+		return nil, err
+	}
+	srcInfo, err := srcContainer.Info(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	source, err := m.cloneSource(ctx, srcBotID, version)
+	if err != nil {
+		return nil, err
+	}
+	dataDir, err := m.ensureBotDir(dstBotID)
+	if err != nil {
+		return nil, err
+	}
+	specOpts, err := botMountSpecOpts(dataDir, m.cfg.DataMountFor(srcInfo.Image))
+	if err != nil {
+		return nil, err
+	}
+
+	activeSnapshotID := fmt.Sprintf("%s-clone-%d", dstContainerID, time.Now().UnixNano())
+	if err := m.service.PrepareSnapshot(ctx, srcInfo.Snapshotter, activeSnapshotID, source.SnapshotID); err != nil {
+		return nil, err
+	}
+	_, err = m.service.CreateContainerFromSnapshot(ctx, ctr.CreateContainerRequest{
+		ID:          dstContainerID,
+		ImageRef:    srcInfo.Image,
+		SnapshotID:  activeSnapshotID,
+		Snapshotter: srcInfo.Snapshotter,
+		Labels: map[string]string{
+			BotLabelKey: dstBotID,
+		},
+		SpecOpts: specOpts,
+	}.WithResourceLimits(m.cfg))
+	if err != nil {
+		if rmErr := m.service.RemoveSnapshot(ctx, srcInfo.Snapshotter, activeSnapshotID); rmErr != nil {
+			m.logger.Warn("cleanup: remove clone snapshot failed", slog.String("snapshot_id", activeSnapshotID), slog.Any("error", rmErr))
+		}
+		return nil, err
+	}
+	// Recorded only once the container exists, so failures above leave no
+	// row behind.
+	if _, err := m.ensureDBRecords(ctx, dstBotID, dstContainerID, srcInfo.Runtime.Name, srcInfo.Image); err != nil {
+		if rmErr := m.service.DeleteContainer(ctx, dstContainerID, &ctr.DeleteContainerOptions{CleanupSnapshot: true}); rmErr != nil {
+			m.logger.Warn("cleanup: delete clone container failed", slog.String("container_id", dstContainerID), slog.Any("error", rmErr))
+		}
+		return nil, err
+	}
+	m.bots.set(dstContainerID, dstBotID)
+
+	if err := m.insertEvent(ctx, dstContainerID, "clone", map[string]any{
+		"source_bot_id": srcBotID,
+		"version":       source.Version,
+		"snapshot_id":   source.SnapshotID,
+	}); err != nil {
+		return nil, err
+	}
+	return source, nil
+}
+
+// cloneSource returns the version to clone from, committing the current
+// state as a new version when version is 0.
+func (m *Manager) cloneSource(ctx context.Context, botID string, version int) (*VersionInfo, error) {
+	if version == 0 {
+		return m.CreateVersion(ctx, botID, "")
+	}
+	versions, err := m.ListVersions(ctx, botID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range versions {
+		if versions[i].Version == version {
+			return &versions[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: version %d of bot %s", errdefs.ErrNotFound, version, botID)
+}
@@ -0,0 +1,221 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	containerd "github.com/containerd/containerd/v2/client"
+	"github.com/containerd/containerd/v2/pkg/namespaces"
+	"github.com/containerd/errdefs"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	ctr "github.com/memohai/memoh/internal/containerd"
+	"github.com/memohai/memoh/internal/db"
+	dbsqlc "github.com/memohai/memoh/internal/db/sqlc"
+)
+
+// ContainerStatusIdleStopped marks a container whose task the idle reaper
+// stopped. Such tasks are not started again on server start, only when the
+// bot is used; see Exec and ExecWithCapture.
+const ContainerStatusIdleStopped = "idle_stopped"
+
+// maxIdleCheckInterval bounds how often the idle reaper looks for idle
+// containers and persists activity.
+const maxIdleCheckInterval = time.Minute
+
+// botActivity holds the last activity of each bot, and which of those have
+// not been persisted yet.
+type botActivity struct {
+	mu      sync.Mutex
+	last    map[string]time.Time
+	pending map[string]time.Time
+}
+
+func (a *botActivity) touch(botID string, at time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.last == nil {
+		a.last = map[string]time.Time{}
+		a.pending = map[string]time.Time{}
+	}
+	if at.After(a.last[botID]) {
+		a.last[botID] = at
+		a.pending[botID] = at
+	}
+}
+
+func (a *botActivity) get(botID string) time.Time {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.last[botID]
+}
+
+// takePending returns the activity not yet persisted and forgets it.
+func (a *botActivity) takePending() map[string]time.Time {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	pending := a.pending
+	a.pending = map[string]time.Time{}
+	return pending
+}
+
+// Touch records activity of botID, keeping its container from being stopped
+// as idle. It is cheap enough to call on every request; the timestamp is
+// persisted by the idle reaper.
+func (m *Manager) Touch(botID string) {
+	if m.cfg.IdleTimeoutSeconds <= 0 || botID == "" {
+		return
+	}
+	m.activity.touch(botID, time.Now())
+}
+
+// RunIdleReaper stops the task of every container whose bot has had no
+// activity for MCPConfig.IdleTimeoutSeconds, until ctx is done. Activity
+// is persisted as it goes, so a restart of the server does not make idle
+// containers look fresh. It returns at once when the timeout is zero.
+func (m *Manager) RunIdleReaper(ctx context.Context) {
+	timeout := time.Duration(m.cfg.IdleTimeoutSeconds) * time.Second
+	if timeout <= 0 || m.db == nil {
+		return
+	}
+	interval := min(timeout, maxIdleCheckInterval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			// Persist what has been seen so far with a context of its own,
+			// since ctx is already done.
+			flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			m.flushActivity(flushCtx)
+			cancel()
+			return
+		case <-ticker.C:
+			m.flushActivity(ctx)
+			m.reapIdle(ctx, time.Now(), timeout)
+		}
+	}
+}
+
+func (m *Manager) flushActivity(ctx context.Context) {
+	for botID, at := range m.activity.takePending() {
+		pgBotID, err := db.ParseUUID(botID)
+		if err != nil {
+			continue
+		}
+		if err := m.queries.UpdateContainerActivity(ctx, dbsqlc.UpdateContainerActivityParams{
+			LastActiveAt: pgtype.Timestamptz{Time: at, Valid: true},
+			BotID:        pgBotID,
+		}); err != nil {
+			m.logger.Warn("idle reaper: persist activity failed", slog.String("bot_id", botID), slog.Any("error", err))
+		}
+	}
+}
+
+func (m *Manager) reapIdle(ctx context.Context, now time.Time, timeout time.Duration) {
+	ctx = namespaces.WithNamespace(ctx, m.namespace)
+	// Refreshing the bot index lists containers and tasks in two calls and
+	// keeps it current for BotForContainer.
+	if err := m.RefreshBotIndex(ctx); err != nil {
+		m.logger.Warn("idle reaper: list bots failed", slog.Any("error", err))
+		return
+	}
+	botIDs := m.bots.botIDs()
+	pgBotIDs := make([]pgtype.UUID, 0, len(botIDs))
+	for _, botID := range botIDs {
+		if pgBotID, err := db.ParseUUID(botID); err == nil {
+			pgBotIDs = append(pgBotIDs, pgBotID)
+		}
+	}
+	rows, err := m.queries.ListContainersByBotIDs(ctx, pgBotIDs)
+	if err != nil {
+		m.logger.Warn("idle reaper: load containers failed", slog.Any("error", err))
+		return
+	}
+	for _, row := range rows {
+		botID := uuid.UUID(row.BotID.Bytes).String()
+		if !m.taskRunning(ctx, row.ContainerID) {
+			continue
+		}
+		last := lastActivity(m.activity.get(botID), row)
+		if last.IsZero() {
+			// Never seen active: start the clock now.
+			m.activity.touch(botID, now)
+			continue
+		}
+		if now.Sub(last) < timeout {
+			continue
+		}
+		m.logger.Info("stopping idle container", slog.String("bot_id", botID), slog.Time("last_active_at", last))
+		m.stopIdle(ctx, botID, row.BotID)
+	}
+}
+
+// lastActivity is the latest of the in-memory activity, the persisted one
+// and the last start of the container.
+func lastActivity(seen time.Time, row dbsqlc.Container) time.Time {
+	last := seen
+	for _, ts := range []pgtype.Timestamptz{row.LastActiveAt, row.LastStartedAt} {
+		if ts.Valid && ts.Time.After(last) {
+			last = ts.Time
+		}
+	}
+	return last
+}
+
+func (m *Manager) taskRunning(ctx context.Context, containerID string) bool {
+	task, err := m.service.GetTask(ctx, containerID)
+	if err != nil {
+		return false
+	}
+	status, err := task.Status(ctx)
+	return err == nil && status.Status == containerd.Running
+}
+
+func (m *Manager) stopIdle(ctx context.Context, botID string, pgBotID pgtype.UUID) {
+	if err := m.Stop(ctx, botID, 10*time.Second); err != nil && !errdefs.IsNotFound(err) {
+		m.logger.Warn("idle reaper: stop task failed", slog.String("bot_id", botID), slog.Any("error", err))
+		return
+	}
+	if err := m.service.DeleteTask(ctx, m.containerID(botID), &ctr.DeleteTaskOptions{Force: true}); err != nil {
+		m.logger.Warn("cleanup: delete task failed", slog.String("container_id", m.containerID(botID)), slog.Any("error", err))
+	}
+	if err := m.queries.UpdateContainerIdleStopped(ctx, pgBotID); err != nil {
+		m.logger.Error("failed to update container stopped status", slog.String("bot_id", botID), slog.Any("error", err))
+	}
+}
+
+// wakeIdle starts the task of botID's container again if the idle reaper
+// stopped it, so the bot's tools work as soon as it is used. Containers
+// stopped any other way are left alone.
+func (m *Manager) wakeIdle(ctx context.Context, botID string) error {
+	if m.queries == nil {
+		return nil
+	}
+	containerID := m.containerID(botID)
+	if m.taskRunning(ctx, containerID) {
+		return nil
+	}
+	pgBotID, err := db.ParseUUID(botID)
+	if err != nil {
+		return nil
+	}
+	m.waking.Lock()
+	defer m.waking.Unlock()
+	row, err := m.queries.GetContainerByBotID(ctx, pgBotID)
+	if err != nil || row.Status != ContainerStatusIdleStopped {
+		return nil
+	}
+	m.logger.Info("starting idle-stopped container", slog.String("bot_id", botID))
+	if err := m.Start(ctx, botID); err != nil {
+		return fmt.Errorf("start idle-stopped container: %w", err)
+	}
+	if err := m.queries.UpdateContainerStarted(ctx, pgBotID); err != nil {
+		m.logger.Error("failed to update container started status", slog.String("bot_id", botID), slog.Any("error", err))
+	}
+	return nil
+}
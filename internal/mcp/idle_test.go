@@ -0,0 +1,151 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	containerd "github.com/containerd/containerd/v2/client"
+	"github.com/containerd/errdefs"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/memohai/memoh/internal/config"
+	ctr "github.com/memohai/memoh/internal/containerd"
+	dbsqlc "github.com/memohai/memoh/internal/db/sqlc"
+)
+
+func TestTouchDisabledWithoutIdleTimeout(t *testing.T) {
+	m := &Manager{}
+	m.Touch("bot")
+	if !m.activity.get("bot").IsZero() {
+		t.Fatal("activity recorded with IdleTimeoutSeconds unset")
+	}
+}
+
+func TestBotActivityPending(t *testing.T) {
+	m := &Manager{cfg: config.MCPConfig{IdleTimeoutSeconds: 60}}
+	m.Touch("bot")
+	first := m.activity.get("bot")
+	if first.IsZero() {
+		t.Fatal("activity not recorded")
+	}
+	// An older timestamp never moves activity back.
+	m.activity.touch("bot", first.Add(-time.Hour))
+	if got := m.activity.get("bot"); !got.Equal(first) {
+		t.Errorf("activity = %v, want %v", got, first)
+	}
+	if pending := m.activity.takePending(); len(pending) != 1 || !pending["bot"].Equal(first) {
+		t.Errorf("pending = %v", pending)
+	}
+	if pending := m.activity.takePending(); len(pending) != 0 {
+		t.Errorf("pending after take = %v, want none", pending)
+	}
+}
+
+func TestLastActivity(t *testing.T) {
+	base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	ts := func(d time.Duration) pgtype.Timestamptz {
+		return pgtype.Timestamptz{Time: base.Add(d), Valid: true}
+	}
+	cases := []struct {
+		name string
+		seen time.Time
+		row  dbsqlc.Container
+		want time.Time
+	}{
+		{name: "nothing", want: time.Time{}},
+		{name: "started only", row: dbsqlc.Container{LastStartedAt: ts(0)}, want: base},
+		{name: "persisted after start", row: dbsqlc.Container{LastStartedAt: ts(0), LastActiveAt: ts(time.Hour)}, want: base.Add(time.Hour)},
+		{name: "restarted after activity", row: dbsqlc.Container{LastStartedAt: ts(2 * time.Hour), LastActiveAt: ts(time.Hour)}, want: base.Add(2 * time.Hour)},
+		{name: "seen in memory", seen: base.Add(3 * time.Hour), row: dbsqlc.Container{LastActiveAt: ts(time.Hour)}, want: base.Add(3 * time.Hour)},
+	}
+	for _, tc := range cases {
+		if got := lastActivity(tc.seen, tc.row); !got.Equal(tc.want) {
+			t.Errorf("%s: lastActivity = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+// containerRowDB answers GetContainerByBotID with a row of the given status
+// and records every statement executed.
+type containerRowDB struct {
+	status string
+	execs  []string
+}
+
+func (d *containerRowDB) Exec(_ context.Context, sql string, _ ...any) (pgconn.CommandTag, error) {
+	d.execs = append(d.execs, sql)
+	return pgconn.CommandTag{}, nil
+}
+
+func (d *containerRowDB) Query(context.Context, string, ...any) (pgx.Rows, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (d *containerRowDB) QueryRow(context.Context, string, ...any) pgx.Row {
+	return containerStatusRow(d.status)
+}
+
+type containerStatusRow string
+
+// Scan fills the status column of a containers row.
+func (r containerStatusRow) Scan(dest ...any) error {
+	*dest[5].(*string) = string(r)
+	return nil
+}
+
+// stoppedTaskService has no running task and counts task starts.
+type stoppedTaskService struct {
+	ctr.Service
+	starts int
+}
+
+func (s *stoppedTaskService) GetTask(context.Context, string) (containerd.Task, error) {
+	return nil, errdefs.ErrNotFound
+}
+
+func (s *stoppedTaskService) CreateContainer(context.Context, ctr.CreateContainerRequest) (containerd.Container, error) {
+	return nil, errdefs.ErrAlreadyExists
+}
+
+func (s *stoppedTaskService) StartTask(context.Context, string, *ctr.StartTaskOptions) (containerd.Task, error) {
+	s.starts++
+	return newStoppableTask(), nil
+}
+
+func (s *stoppedTaskService) StopTask(context.Context, string, *ctr.StopTaskOptions) (ctr.StopTaskResult, error) {
+	return ctr.StopTaskResult{}, nil
+}
+
+func TestWakeIdleStartsOnlyIdleStoppedContainers(t *testing.T) {
+	const botID = "00000000-0000-0000-0000-000000000001"
+	cases := []struct {
+		status     string
+		wantStarts int
+	}{
+		{status: ContainerStatusIdleStopped, wantStarts: 1},
+		{status: "stopped", wantStarts: 0},
+		{status: "running", wantStarts: 0},
+	}
+	for _, tc := range cases {
+		svc := &stoppedTaskService{}
+		m := &Manager{
+			service:     svc,
+			cfg:         config.MCPConfig{DataRoot: t.TempDir()},
+			queries:     dbsqlc.New(&containerRowDB{status: tc.status}),
+			logger:      slog.New(slog.NewTextHandler(io.Discard, nil)),
+			containerID: func(id string) string { return ContainerPrefix + id },
+		}
+		// Starting fails later on network setup without CNI; only whether a
+		// start was attempted matters here.
+		_ = m.wakeIdle(context.Background(), botID)
+		if svc.starts != tc.wantStarts {
+			t.Errorf("status %q: %d task starts, want %d", tc.status, svc.starts, tc.wantStarts)
+		}
+	}
+}
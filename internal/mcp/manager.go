@@ -11,8 +11,10 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/containerd/containerd/v2/pkg/namespaces"
 	"github.com/containerd/containerd/v2/pkg/oci"
 	"github.com/containerd/errdefs"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -22,6 +24,7 @@ import (
 	ctr "github.com/memohai/memoh/internal/containerd"
 	dbsqlc "github.com/memohai/memoh/internal/db/sqlc"
 	"github.com/memohai/memoh/internal/identity"
+	"github.com/memohai/memoh/internal/metrics"
 )
 
 const (
@@ -57,6 +60,11 @@ type Manager struct {
 	db          *pgxpool.Pool
 	queries     *dbsqlc.Queries
 	logger      *slog.Logger
+	bots        botIndex
+	supervisors taskSupervisors
+	activity    botActivity
+	// waking serializes starting idle-stopped containers on demand.
+	waking sync.Mutex
 }
 
 func NewManager(log *slog.Logger, service ctr.Service, cfg config.MCPConfig, namespace string, conn *pgxpool.Pool) *Manager {
@@ -97,14 +105,37 @@ func (m *Manager) EnsureBot(ctx context.Context, botID string) error {
 		return err
 	}
 
-	dataMount := m.dataMount()
 	image := m.imageRef()
-	resolvPath, err := ctr.ResolveConfSource(dataDir)
+	specOpts, err := botMountSpecOpts(dataDir, m.cfg.DataMountFor(image))
 	if err != nil {
 		return err
 	}
 
-	specOpts := []oci.SpecOpts{
+	_, err = m.service.CreateContainer(ctx, ctr.CreateContainerRequest{
+		ID:          m.containerID(botID),
+		ImageRef:    image,
+		Snapshotter: m.cfg.Snapshotter,
+		Labels: map[string]string{
+			BotLabelKey: botID,
+		},
+		SpecOpts: specOpts,
+	}.WithResourceLimits(m.cfg))
+	if err != nil && !errdefs.IsAlreadyExists(err) {
+		return err
+	}
+
+	m.bots.set(m.containerID(botID), botID)
+	return nil
+}
+
+// botMountSpecOpts bind-mounts the bot's data directory at dataMount and the
+// host resolv.conf read-only.
+func botMountSpecOpts(dataDir, dataMount string) ([]oci.SpecOpts, error) {
+	resolvPath, err := ctr.ResolveConfSource(dataDir)
+	if err != nil {
+		return nil, err
+	}
+	return []oci.SpecOpts{
 		oci.WithMounts([]specs.Mount{
 			{
 				Destination: dataMount,
@@ -119,26 +150,7 @@ func (m *Manager) EnsureBot(ctx context.Context, botID string) error {
 				Options:     []string{"rbind", "ro"},
 			},
 		}),
-	}
-
-	_, err = m.service.CreateContainer(ctx, ctr.CreateContainerRequest{
-		ID:          m.containerID(botID),
-		ImageRef:    image,
-		Snapshotter: m.cfg.Snapshotter,
-		Labels: map[string]string{
-			BotLabelKey: botID,
-		},
-		SpecOpts: specOpts,
-	})
-	if err == nil {
-		return nil
-	}
-
-	if !errdefs.IsAlreadyExists(err) {
-		return err
-	}
-
-	return nil
+	}, nil
 }
 
 // ListBots returns the bot IDs that have MCP containers.
@@ -175,11 +187,12 @@ func (m *Manager) Start(ctx context.Context, botID string) error {
 		return err
 	}
 	if err := ctr.SetupNetwork(ctx, task, m.containerID(botID)); err != nil {
-		if stopErr := m.service.StopTask(ctx, m.containerID(botID), &ctr.StopTaskOptions{Force: true}); stopErr != nil {
+		if _, stopErr := m.service.StopTask(ctx, m.containerID(botID), &ctr.StopTaskOptions{Force: true}); stopErr != nil {
 			m.logger.Warn("cleanup: stop task failed", slog.String("container_id", m.containerID(botID)), slog.Any("error", stopErr))
 		}
 		return err
 	}
+	m.Supervise(m.containerID(botID))
 	return nil
 }
 
@@ -187,10 +200,12 @@ func (m *Manager) Stop(ctx context.Context, botID string, timeout time.Duration)
 	if err := validateBotID(botID); err != nil {
 		return err
 	}
-	return m.service.StopTask(ctx, m.containerID(botID), &ctr.StopTaskOptions{
+	m.Unsupervise(m.containerID(botID))
+	_, err := m.service.StopTask(ctx, m.containerID(botID), &ctr.StopTaskOptions{
 		Timeout: timeout,
 		Force:   true,
 	})
+	return err
 }
 
 func (m *Manager) Delete(ctx context.Context, botID string) error {
@@ -198,6 +213,7 @@ func (m *Manager) Delete(ctx context.Context, botID string) error {
 		return err
 	}
 
+	m.Unsupervise(m.containerID(botID))
 	if task, taskErr := m.service.GetTask(ctx, m.containerID(botID)); taskErr == nil {
 		if err := ctr.RemoveNetwork(ctx, task, m.containerID(botID)); err != nil {
 			m.logger.Warn("cleanup: remove network failed", slog.String("container_id", m.containerID(botID)), slog.Any("error", err))
@@ -206,24 +222,32 @@ func (m *Manager) Delete(ctx context.Context, botID string) error {
 	if err := m.service.DeleteTask(ctx, m.containerID(botID), &ctr.DeleteTaskOptions{Force: true}); err != nil {
 		m.logger.Warn("cleanup: delete task failed", slog.String("container_id", m.containerID(botID)), slog.Any("error", err))
 	}
-	return m.service.DeleteContainer(ctx, m.containerID(botID), &ctr.DeleteContainerOptions{
+	if err := m.service.DeleteContainer(ctx, m.containerID(botID), &ctr.DeleteContainerOptions{
 		CleanupSnapshot: true,
-	})
+	}); err != nil {
+		return err
+	}
+	m.bots.remove(m.containerID(botID))
+	return nil
 }
 
 func (m *Manager) Exec(ctx context.Context, req ExecRequest) (*ExecResult, error) {
 	if err := validateBotID(req.BotID); err != nil {
 		return nil, err
 	}
+	m.Touch(req.BotID)
 	if len(req.Command) == 0 {
 		return nil, fmt.Errorf("%w: empty command", ctr.ErrInvalidArgument)
 	}
 	if m.queries == nil {
 		return nil, fmt.Errorf("db is not configured")
 	}
+	if err := m.wakeIdle(ctx, req.BotID); err != nil {
+		return nil, err
+	}
 
 	startedAt := time.Now()
-	if _, err := m.CreateVersion(ctx, req.BotID); err != nil {
+	if _, err := m.CreateVersion(ctx, req.BotID, ""); err != nil {
 		return nil, err
 	}
 
@@ -234,6 +258,7 @@ func (m *Manager) Exec(ctx context.Context, req ExecRequest) (*ExecResult, error
 		Terminal: req.Terminal,
 		UseStdio: req.UseStdio,
 	})
+	metrics.ContainerExecDuration.WithLabelValues("exec").Observe(time.Since(startedAt).Seconds())
 	if err != nil {
 		return nil, err
 	}
@@ -259,13 +284,21 @@ func (m *Manager) ExecWithCapture(ctx context.Context, req ExecRequest) (*ExecWi
 	if err := validateBotID(req.BotID); err != nil {
 		return nil, err
 	}
+	m.Touch(req.BotID)
 	if len(req.Command) == 0 {
 		return nil, fmt.Errorf("%w: empty command", ctr.ErrInvalidArgument)
 	}
 	if m.queries == nil {
 		return nil, fmt.Errorf("db is not configured")
 	}
+	if err := m.wakeIdle(ctx, req.BotID); err != nil {
+		return nil, err
+	}
 
+	startedAt := time.Now()
+	defer func() {
+		metrics.ContainerExecDuration.WithLabelValues("capture").Observe(time.Since(startedAt).Seconds())
+	}()
 	if runtime.GOOS == "darwin" {
 		return m.execWithCaptureLima(ctx, req)
 	}
@@ -377,13 +410,6 @@ func (m *Manager) dataRoot() string {
 	return m.cfg.DataRoot
 }
 
-func (m *Manager) dataMount() string {
-	if m.cfg.DataMount == "" {
-		return config.DefaultDataMount
-	}
-	return m.cfg.DataMount
-}
-
 func (m *Manager) imageRef() string {
 	if m.cfg.Image != "" {
 		return m.cfg.Image
@@ -391,6 +417,21 @@ func (m *Manager) imageRef() string {
 	return config.DefaultMCPImage
 }
 
+// DataMount returns where the bot's data directory is mounted inside its
+// container. It follows the image the container was created from, which
+// may differ from the configured one; the configured image is used when
+// the container cannot be inspected.
+func (m *Manager) DataMount(ctx context.Context, botID string) string {
+	image := m.imageRef()
+	ctx = namespaces.WithNamespace(ctx, m.namespace)
+	if container, err := m.service.GetContainer(ctx, m.containerID(botID)); err == nil {
+		if info, err := container.Info(ctx); err == nil && strings.TrimSpace(info.Image) != "" {
+			image = info.Image
+		}
+	}
+	return m.cfg.DataMountFor(image)
+}
+
 func validateBotID(botID string) error {
 	return identity.ValidateChannelIdentityID(botID)
 }
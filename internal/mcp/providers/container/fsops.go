@@ -2,7 +2,10 @@ package container
 
 import (
 	"context"
+	"crypto/md5"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"path"
 	"strconv"
@@ -24,14 +27,120 @@ type FileEntry struct {
 
 // ExecRead reads a file inside the container via cat.
 func ExecRead(ctx context.Context, runner ExecRunner, botID, workDir, filePath string) (string, error) {
+	return ExecReadLimited(ctx, runner, botID, workDir, filePath, 0)
+}
+
+// FileTooLargeError is returned when a file exceeds the configured read limit.
+type FileTooLargeError struct {
+	Path  string
+	Size  int64
+	Limit int64
+}
+
+func (e *FileTooLargeError) Error() string {
+	return fmt.Sprintf("file %s is too large (%d bytes, limit %d bytes); read a range with exec (e.g. head, tail or sed -n) instead", e.Path, e.Size, e.Limit)
+}
+
+// SpecialFileError is returned when a read or write target is a FIFO, device
+// node, socket or (for writes) symlink rather than a regular file.
+type SpecialFileError struct {
+	Path string
+	Type string
+}
+
+func (e *SpecialFileError) Error() string {
+	return fmt.Sprintf("refusing to access %s: not a regular file (%s)", e.Path, e.Type)
+}
+
+const (
+	// exitFileTooLarge is the exit code used by the read script when the size
+	// check fails, so it can be told apart from cat/stat errors.
+	exitFileTooLarge = 113
+	// exitSpecialFile is the exit code used when the target is not a regular file.
+	exitSpecialFile = 114
+	// exitHashMismatch is the exit code used when a conditional write finds
+	// content other than expected.
+	exitHashMismatch = 115
+)
+
+// HashMismatchError is returned by ExecWriteIfMatch when the file no longer
+// has the content the caller last read.
+type HashMismatchError struct {
+	Path     string
+	Expected string
+	Actual   string
+}
+
+func (e *HashMismatchError) Error() string {
+	actual := e.Actual
+	if actual == "" {
+		actual = "no readable file"
+	}
+	return fmt.Sprintf("file %s changed since it was read (expected md5 %s, found %s); read it again before writing", e.Path, e.Expected, actual)
+}
+
+// ContentHash returns the hex md5 of content, the value write preconditions
+// compare against.
+func ContentHash(content string) string {
+	sum := md5.Sum([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// ContentSHA256 returns the lowercase hex SHA-256 of content, matching the
+// checksums of the container FS API.
+func ContentSHA256(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// hashGuard returns a shell prefix that exits with exitHashMismatch, printing
+// the actual hash to stderr, unless filePath's md5 is expected.
+func hashGuard(filePath, expected string) string {
+	return fmt.Sprintf(`actual=$(md5sum < %s 2>/dev/null | cut -d' ' -f1); if [ "$actual" != %s ]; then printf %%s "$actual" >&2; exit %d; fi; `,
+		ShellQuote(filePath), ShellQuote(expected), exitHashMismatch)
+}
+
+// regularFileGuard returns a shell prefix that exits with exitSpecialFile when
+// an existing target is not a regular file, printing its type to stderr.
+// Writes use lstat semantics so a planted symlink is rejected too; reads
+// follow symlinks but still refuse FIFOs, sockets and device nodes, which
+// would otherwise block or stream without end.
+func regularFileGuard(filePath string, followSymlinks bool) string {
+	quoted := ShellQuote(filePath)
+	if followSymlinks {
+		return fmt.Sprintf(`if [ -e %s ] && [ ! -f %s ]; then stat -L -c %%F %s >&2; exit %d; fi; `,
+			quoted, quoted, quoted, exitSpecialFile)
+	}
+	return fmt.Sprintf(`if [ -L %s ] || { [ -e %s ] && [ ! -f %s ]; }; then stat -c %%F %s >&2; exit %d; fi; `,
+		quoted, quoted, quoted, quoted, exitSpecialFile)
+}
+
+// ExecReadLimited reads a file inside the container, refusing files larger
+// than maxBytes before any content is transferred. maxBytes <= 0 disables the check.
+func ExecReadLimited(ctx context.Context, runner ExecRunner, botID, workDir, filePath string, maxBytes int64) (string, error) {
+	script := "cat " + ShellQuote(filePath)
+	if maxBytes > 0 {
+		script = fmt.Sprintf(
+			`size=$(stat -c %%s %s) || exit 1; if [ "$size" -gt %d ]; then echo "$size" >&2; exit %d; fi; %s`,
+			ShellQuote(filePath), maxBytes, exitFileTooLarge, script,
+		)
+	}
+	script = regularFileGuard(filePath, true) + script
 	result, err := runner.ExecWithCapture(ctx, mcpgw.ExecRequest{
 		BotID:   botID,
-		Command: []string{"/bin/sh", "-c", "cat " + ShellQuote(filePath)},
+		Command: []string{"/bin/sh", "-c", script},
 		WorkDir: workDir,
 	})
 	if err != nil {
 		return "", err
 	}
+	if maxBytes > 0 && result.ExitCode == exitFileTooLarge {
+		size, _ := strconv.ParseInt(strings.TrimSpace(result.Stderr), 10, 64)
+		return "", &FileTooLargeError{Path: filePath, Size: size, Limit: maxBytes}
+	}
+	if result.ExitCode == exitSpecialFile {
+		return "", &SpecialFileError{Path: filePath, Type: strings.TrimSpace(result.Stderr)}
+	}
 	if result.ExitCode != 0 {
 		return "", fmt.Errorf("%s", strings.TrimSpace(result.Stderr))
 	}
@@ -39,11 +148,23 @@ func ExecRead(ctx context.Context, runner ExecRunner, botID, workDir, filePath s
 }
 
 // ExecWrite writes content to a file inside the container using base64 encoding
-// to avoid shell escaping issues.
+// to avoid shell escaping issues. Existing targets that are not regular files
+// are rejected with a SpecialFileError.
 func ExecWrite(ctx context.Context, runner ExecRunner, botID, workDir, filePath, content string) error {
+	return ExecWriteIfMatch(ctx, runner, botID, workDir, filePath, content, "")
+}
+
+// ExecWriteIfMatch is ExecWrite with a precondition: when expectedHash is
+// set the file is only written if its current content has that md5, and a
+// *HashMismatchError is returned otherwise.
+func ExecWriteIfMatch(ctx context.Context, runner ExecRunner, botID, workDir, filePath, content, expectedHash string) error {
 	encoded := base64.StdEncoding.EncodeToString([]byte(content))
 	dir := path.Dir(filePath)
-	script := fmt.Sprintf("mkdir -p %s && echo %s | base64 -d > %s",
+	script := regularFileGuard(filePath, false)
+	if expectedHash != "" {
+		script += hashGuard(filePath, expectedHash)
+	}
+	script += fmt.Sprintf("mkdir -p %s && echo %s | base64 -d > %s",
 		ShellQuote(dir), ShellQuote(encoded), ShellQuote(filePath))
 	result, err := runner.ExecWithCapture(ctx, mcpgw.ExecRequest{
 		BotID:   botID,
@@ -53,6 +174,12 @@ func ExecWrite(ctx context.Context, runner ExecRunner, botID, workDir, filePath,
 	if err != nil {
 		return err
 	}
+	if result.ExitCode == exitSpecialFile {
+		return &SpecialFileError{Path: filePath, Type: strings.TrimSpace(result.Stderr)}
+	}
+	if result.ExitCode == exitHashMismatch {
+		return &HashMismatchError{Path: filePath, Expected: expectedHash, Actual: strings.TrimSpace(result.Stderr)}
+	}
 	if result.ExitCode != 0 {
 		return fmt.Errorf("%s", strings.TrimSpace(result.Stderr))
 	}
@@ -62,15 +189,78 @@ func ExecWrite(ctx context.Context, runner ExecRunner, botID, workDir, filePath,
 // ExecList lists directory entries inside the container via find + stat.
 // Output format per line: <name>|<type>|<size>|<mode>|<mtime_epoch>
 func ExecList(ctx context.Context, runner ExecRunner, botID, workDir, dirPath string, recursive bool) ([]FileEntry, error) {
+	result, err := ExecListLimited(ctx, runner, botID, workDir, dirPath, recursive, ListLimits{})
+	if err != nil {
+		return nil, err
+	}
+	return result.Entries, nil
+}
+
+// ListLimits bounds a listing. Zero values disable the respective limit.
+type ListLimits struct {
+	MaxEntries int
+	Timeout    time.Duration
+}
+
+// ListResult is the outcome of ExecListLimited. Truncated is set when the
+// listing stopped early because a limit was reached.
+type ListResult struct {
+	Entries   []FileEntry
+	Truncated bool
+}
+
+// listExitMarker prefixes the find exit status the limited list script
+// reports on stderr, since the pipeline itself always exits with head's status.
+const listExitMarker = "__find_exit="
+
+// ExecListLimited lists directory entries like ExecList but stops after
+// limits.MaxEntries entries or once limits.Timeout elapses, returning what was
+// collected so far with Truncated set.
+func ExecListLimited(ctx context.Context, runner ExecRunner, botID, workDir, dirPath string, recursive bool, limits ListLimits) (ListResult, error) {
 	depthFlag := "-maxdepth 1"
 	if recursive {
 		depthFlag = ""
 	}
-	// Use find to get entries, skip the root dir itself, then stat each entry.
-	// busybox stat -c format: %n=name, %F=type, %s=size, %a=octal mode, %Y=mtime epoch
+	statFormat := `'%n|%F|%s|%a|%Y'`
+	if limits.MaxEntries <= 0 && limits.Timeout <= 0 {
+		// Use find to get entries, skip the root dir itself, then stat each entry.
+		// busybox stat -c format: %n=name, %F=type, %s=size, %a=octal mode, %Y=mtime epoch
+		script := fmt.Sprintf(
+			`find %s %s ! -path %s -exec stat -c %s {} \;`,
+			ShellQuote(dirPath), depthFlag, ShellQuote(dirPath), statFormat,
+		)
+		result, err := runner.ExecWithCapture(ctx, mcpgw.ExecRequest{
+			BotID:   botID,
+			Command: []string{"/bin/sh", "-c", script},
+			WorkDir: workDir,
+		})
+		if err != nil {
+			return ListResult{}, err
+		}
+		if result.ExitCode != 0 {
+			return ListResult{}, fmt.Errorf("%s", strings.TrimSpace(result.Stderr))
+		}
+		return ListResult{Entries: parseStatOutput(result.Stdout, dirPath)}, nil
+	}
+
+	// find prints paths into head, so it dies of SIGPIPE as soon as enough
+	// entries were collected instead of walking the rest of the tree; only
+	// the surviving paths are stat'ed. timeout bounds the walk itself.
+	find := fmt.Sprintf(`find %s %s ! -path %s -print`, ShellQuote(dirPath), depthFlag, ShellQuote(dirPath))
+	if limits.Timeout > 0 {
+		seconds := int(limits.Timeout.Round(time.Second) / time.Second)
+		if seconds < 1 {
+			seconds = 1
+		}
+		find = fmt.Sprintf(`if command -v timeout >/dev/null 2>&1; then timeout %d %s; else %s; fi`, seconds, find, find)
+	}
+	head := "cat"
+	if limits.MaxEntries > 0 {
+		head = fmt.Sprintf("head -n %d", limits.MaxEntries+1)
+	}
 	script := fmt.Sprintf(
-		`find %s %s ! -path %s -exec stat -c '%%n|%%F|%%s|%%a|%%Y' {} \;`,
-		ShellQuote(dirPath), depthFlag, ShellQuote(dirPath),
+		`{ %s; echo "%s$?" >&2; } | %s | while IFS= read -r f; do stat -c %s "$f"; done`,
+		find, listExitMarker, head, statFormat,
 	)
 	result, err := runner.ExecWithCapture(ctx, mcpgw.ExecRequest{
 		BotID:   botID,
@@ -78,12 +268,52 @@ func ExecList(ctx context.Context, runner ExecRunner, botID, workDir, dirPath st
 		WorkDir: workDir,
 	})
 	if err != nil {
-		return nil, err
+		return ListResult{}, err
 	}
-	if result.ExitCode != 0 {
-		return nil, fmt.Errorf("%s", strings.TrimSpace(result.Stderr))
+	stderr, findExit := splitListExit(result.Stderr)
+	if findExit < 0 {
+		findExit = int(result.ExitCode)
+	}
+	entries := parseStatOutput(result.Stdout, dirPath)
+	truncated := false
+	if limits.MaxEntries > 0 && len(entries) > limits.MaxEntries {
+		entries = entries[:limits.MaxEntries]
+		truncated = true
+	}
+	switch findExit {
+	case 0:
+	case 141: // SIGPIPE: head stopped reading after MaxEntries+1 paths.
+		truncated = true
+	case 124, 137, 143: // timeout (GNU exits 124, busybox reports the signal).
+		if limits.Timeout > 0 {
+			truncated = true
+			break
+		}
+		fallthrough
+	default:
+		if !truncated {
+			return ListResult{}, fmt.Errorf("%s", strings.TrimSpace(stderr))
+		}
+	}
+	return ListResult{Entries: entries, Truncated: truncated}, nil
+}
+
+// splitListExit removes the find exit marker from stderr and returns the
+// remaining output together with the reported status (-1 when missing).
+func splitListExit(stderr string) (string, int) {
+	code := -1
+	lines := strings.Split(stderr, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if rest, ok := strings.CutPrefix(strings.TrimSpace(line), listExitMarker); ok {
+			if v, err := strconv.Atoi(rest); err == nil {
+				code = v
+			}
+			continue
+		}
+		kept = append(kept, line)
 	}
-	return parseStatOutput(result.Stdout, dirPath), nil
+	return strings.Join(kept, "\n"), code
 }
 
 // parseStatOutput parses lines of "fullpath|type|size|mode|mtime" into FileEntry slices.
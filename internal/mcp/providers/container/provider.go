@@ -3,7 +3,9 @@ package container
 import (
 	"context"
 	"log/slog"
+	"path"
 	"strings"
+	"time"
 
 	mcpgw "github.com/memohai/memoh/internal/mcp"
 )
@@ -18,6 +20,13 @@ const (
 	defaultExecWorkDir = "/data"
 	shellCommandName   = "/bin/sh"
 	shellCommandFlag   = "-c"
+
+	// DefaultMaxFileSize caps how large a file read or edit may load (10 MiB).
+	DefaultMaxFileSize int64 = 10 << 20
+	// DefaultListMaxEntries caps how many entries a single list call returns.
+	DefaultListMaxEntries = 10000
+	// DefaultListTimeout bounds how long a list call may walk the tree.
+	DefaultListTimeout = 30 * time.Second
 )
 
 // ExecRunner runs a command in the bot container and returns stdout, stderr and exit code.
@@ -31,6 +40,12 @@ type ExecRunner interface {
 type Executor struct {
 	execRunner  ExecRunner
 	execWorkDir string
+	workDirFor  func(ctx context.Context, botID string) string
+	maxFileSize int64
+	listLimits  ListLimits
+	writePolicy *mcpgw.WritePolicy
+	writeAudit  mcpgw.WriteAuditor
+	readOnly    bool
 	logger      *slog.Logger
 }
 
@@ -48,16 +63,65 @@ func NewExecutor(log *slog.Logger, execRunner ExecRunner, execWorkDir string) *E
 	return &Executor{
 		execRunner:  execRunner,
 		execWorkDir: wd,
+		maxFileSize: DefaultMaxFileSize,
+		listLimits:  ListLimits{MaxEntries: DefaultListMaxEntries, Timeout: DefaultListTimeout},
 		logger:      log.With(slog.String("provider", "container_tool")),
 	}
 }
 
+// SetMaxFileSize sets the largest file, in bytes, that read and edit will
+// load. Zero or negative values disable the limit.
+func (p *Executor) SetMaxFileSize(maxBytes int64) {
+	p.maxFileSize = maxBytes
+}
+
+// SetListLimits sets the maximum entry count and traversal time for list.
+// Listings that hit a limit are returned truncated. Zero values disable the
+// respective limit.
+func (p *Executor) SetListLimits(maxEntries int, timeout time.Duration) {
+	p.listLimits = ListLimits{MaxEntries: maxEntries, Timeout: timeout}
+}
+
+// SetWritePolicy restricts what write and edit may store. A nil policy
+// allows all writes.
+func (p *Executor) SetWritePolicy(policy *mcpgw.WritePolicy) {
+	p.writePolicy = policy
+}
+
+// SetReadOnly refuses write, edit and exec for every session, as
+// ToolSessionContext.ReadOnlyFS does for one.
+func (p *Executor) SetReadOnly(readOnly bool) {
+	p.readOnly = readOnly
+}
+
+// SetWorkDirFunc makes the working directory depend on the bot, for bots
+// whose container mounts its data somewhere other than execWorkDir. An
+// empty result falls back to execWorkDir.
+func (p *Executor) SetWorkDirFunc(fn func(ctx context.Context, botID string) string) {
+	p.workDirFor = fn
+}
+
+func (p *Executor) workDir(ctx context.Context, botID string) string {
+	if p.workDirFor != nil {
+		if wd := strings.TrimSpace(p.workDirFor(ctx, botID)); wd != "" {
+			return wd
+		}
+	}
+	return p.execWorkDir
+}
+
+// SetWriteAuditor records successful write and edit calls, including the
+// optional "metadata" argument, for change attribution.
+func (p *Executor) SetWriteAuditor(auditor mcpgw.WriteAuditor) {
+	p.writeAudit = auditor
+}
+
 // ListTools returns read, write, list, edit, and exec tool descriptors.
 func (p *Executor) ListTools(ctx context.Context, session mcpgw.ToolSessionContext) ([]mcpgw.ToolDescriptor, error) {
 	return []mcpgw.ToolDescriptor{
 		{
 			Name:        toolRead,
-			Description: "Read file content inside the bot container.",
+			Description: "Read file content inside the bot container. Also returns the content's md5 hash for use as expected_hash in write or edit, and its sha256.",
 			InputSchema: map[string]any{
 				"type": "object",
 				"properties": map[string]any{
@@ -72,8 +136,10 @@ func (p *Executor) ListTools(ctx context.Context, session mcpgw.ToolSessionConte
 			InputSchema: map[string]any{
 				"type": "object",
 				"properties": map[string]any{
-					"path":    map[string]any{"type": "string", "description": "file path (relative to /data or absolute inside container)"},
-					"content": map[string]any{"type": "string", "description": "file content"},
+					"path":          map[string]any{"type": "string", "description": "file path (relative to /data or absolute inside container)"},
+					"content":       map[string]any{"type": "string", "description": "file content"},
+					"expected_hash": expectedHashSchema,
+					"metadata":      auditMetadataSchema,
 				},
 				"required": []string{"path", "content"},
 			},
@@ -96,9 +162,11 @@ func (p *Executor) ListTools(ctx context.Context, session mcpgw.ToolSessionConte
 			InputSchema: map[string]any{
 				"type": "object",
 				"properties": map[string]any{
-					"path":     map[string]any{"type": "string", "description": "file path (relative to /data or absolute inside container)"},
-					"old_text": map[string]any{"type": "string", "description": "exact text to find"},
-					"new_text": map[string]any{"type": "string", "description": "replacement text"},
+					"path":          map[string]any{"type": "string", "description": "file path (relative to /data or absolute inside container)"},
+					"old_text":      map[string]any{"type": "string", "description": "exact text to find"},
+					"new_text":      map[string]any{"type": "string", "description": "replacement text"},
+					"expected_hash": expectedHashSchema,
+					"metadata":      auditMetadataSchema,
 				},
 				"required": []string{"path", "old_text", "new_text"},
 			},
@@ -124,6 +192,49 @@ func (p *Executor) ListTools(ctx context.Context, session mcpgw.ToolSessionConte
 	}, nil
 }
 
+var expectedHashSchema = map[string]any{
+	"type":        "string",
+	"description": "optional md5 hash from read; the change is refused if the file was modified since",
+}
+
+var auditMetadataSchema = map[string]any{
+	"type":        "object",
+	"description": "optional attribution recorded in the write audit log, e.g. agent, run_id, reason",
+}
+
+// recordWrite stores an audit entry for a completed write. The chat and
+// platform of the session are added unless the caller already set them.
+// Failures are logged; the write itself already succeeded.
+func (p *Executor) recordWrite(ctx context.Context, session mcpgw.ToolSessionContext, action, workDir, filePath string, arguments map[string]any) {
+	if p.writeAudit == nil {
+		return
+	}
+	metadata := map[string]any{}
+	if raw, ok := arguments["metadata"].(map[string]any); ok {
+		for k, v := range raw {
+			metadata[k] = v
+		}
+	}
+	setDefault := func(key, value string) {
+		if _, ok := metadata[key]; !ok && strings.TrimSpace(value) != "" {
+			metadata[key] = value
+		}
+	}
+	setDefault("chat_id", session.ChatID)
+	setDefault("platform", session.CurrentPlatform)
+	if !path.IsAbs(filePath) {
+		filePath = path.Join(workDir, filePath)
+	}
+	if err := p.writeAudit.RecordWrite(ctx, mcpgw.WriteAuditEntry{
+		BotID:    session.BotID,
+		Path:     path.Clean(filePath),
+		Action:   action,
+		Metadata: metadata,
+	}); err != nil {
+		p.logger.Warn("record write audit failed", slog.String("bot_id", session.BotID), slog.String("path", filePath), slog.Any("error", err))
+	}
+}
+
 // normalizePath converts paths that the LLM may send as /data/... into relative
 // paths under the working directory. e.g. /data/test.txt -> test.txt, /data -> .
 func normalizePath(path string) string {
@@ -141,12 +252,26 @@ func normalizePath(path string) string {
 	return path
 }
 
+// mutatesContainer reports whether a tool can change the bot's container.
+// exec runs arbitrary commands, so it counts as a write.
+func mutatesContainer(toolName string) bool {
+	switch toolName {
+	case toolWrite, toolEdit, toolExec:
+		return true
+	}
+	return false
+}
+
 // CallTool dispatches to the appropriate container-exec backed implementation.
 func (p *Executor) CallTool(ctx context.Context, session mcpgw.ToolSessionContext, toolName string, arguments map[string]any) (map[string]any, error) {
 	botID := strings.TrimSpace(session.BotID)
 	if botID == "" {
 		return mcpgw.BuildToolErrorResult("bot_id is required"), nil
 	}
+	if (p.readOnly || session.ReadOnlyFS) && mutatesContainer(toolName) {
+		return mcpgw.BuildToolErrorResult("container filesystem is read-only"), nil
+	}
+	dataDir := p.workDir(ctx, botID)
 
 	switch toolName {
 	case toolRead:
@@ -154,11 +279,11 @@ func (p *Executor) CallTool(ctx context.Context, session mcpgw.ToolSessionContex
 		if filePath == "" {
 			return mcpgw.BuildToolErrorResult("path is required"), nil
 		}
-		content, err := ExecRead(ctx, p.execRunner, botID, p.execWorkDir, filePath)
+		content, err := ExecReadLimited(ctx, p.execRunner, botID, dataDir, filePath, p.maxFileSize)
 		if err != nil {
 			return mcpgw.BuildToolErrorResult(err.Error()), nil
 		}
-		return mcpgw.BuildToolSuccessResult(map[string]any{"content": content}), nil
+		return mcpgw.BuildToolSuccessResult(map[string]any{"content": content, "hash": ContentHash(content), "sha256": ContentSHA256(content)}), nil
 
 	case toolWrite:
 		filePath := normalizePath(mcpgw.StringArg(arguments, "path"))
@@ -166,9 +291,14 @@ func (p *Executor) CallTool(ctx context.Context, session mcpgw.ToolSessionContex
 		if filePath == "" {
 			return mcpgw.BuildToolErrorResult("path is required"), nil
 		}
-		if err := ExecWrite(ctx, p.execRunner, botID, p.execWorkDir, filePath, content); err != nil {
+		if err := p.writePolicy.Check(filePath, []byte(content), int64(len(content))); err != nil {
 			return mcpgw.BuildToolErrorResult(err.Error()), nil
 		}
+		expectedHash := strings.TrimSpace(mcpgw.StringArg(arguments, "expected_hash"))
+		if err := ExecWriteIfMatch(ctx, p.execRunner, botID, dataDir, filePath, content, expectedHash); err != nil {
+			return mcpgw.BuildToolErrorResult(err.Error()), nil
+		}
+		p.recordWrite(ctx, session, mcpgw.WriteAuditWrite, dataDir, filePath, arguments)
 		return mcpgw.BuildToolSuccessResult(map[string]any{"ok": true}), nil
 
 	case toolList:
@@ -177,12 +307,12 @@ func (p *Executor) CallTool(ctx context.Context, session mcpgw.ToolSessionContex
 			dirPath = "."
 		}
 		recursive, _, _ := mcpgw.BoolArg(arguments, "recursive")
-		entries, err := ExecList(ctx, p.execRunner, botID, p.execWorkDir, dirPath, recursive)
+		listed, err := ExecListLimited(ctx, p.execRunner, botID, dataDir, dirPath, recursive, p.listLimits)
 		if err != nil {
 			return mcpgw.BuildToolErrorResult(err.Error()), nil
 		}
-		entriesMaps := make([]map[string]any, len(entries))
-		for i, e := range entries {
+		entriesMaps := make([]map[string]any, len(listed.Entries))
+		for i, e := range listed.Entries {
 			entriesMaps[i] = map[string]any{
 				"path":     e.Path,
 				"is_dir":   e.IsDir,
@@ -191,7 +321,7 @@ func (p *Executor) CallTool(ctx context.Context, session mcpgw.ToolSessionContex
 				"mod_time": e.ModTime,
 			}
 		}
-		return mcpgw.BuildToolSuccessResult(map[string]any{"path": dirPath, "entries": entriesMaps}), nil
+		return mcpgw.BuildToolSuccessResult(map[string]any{"path": dirPath, "entries": entriesMaps, "truncated": listed.Truncated}), nil
 
 	case toolEdit:
 		filePath := normalizePath(mcpgw.StringArg(arguments, "path"))
@@ -201,19 +331,27 @@ func (p *Executor) CallTool(ctx context.Context, session mcpgw.ToolSessionContex
 			return mcpgw.BuildToolErrorResult("path, old_text and new_text are required"), nil
 		}
 		// Step 1: read via exec
-		raw, err := ExecRead(ctx, p.execRunner, botID, p.execWorkDir, filePath)
+		raw, err := ExecReadLimited(ctx, p.execRunner, botID, dataDir, filePath, p.maxFileSize)
 		if err != nil {
 			return mcpgw.BuildToolErrorResult(err.Error()), nil
 		}
+		readHash := ContentHash(raw)
+		if expected := strings.TrimSpace(mcpgw.StringArg(arguments, "expected_hash")); expected != "" && expected != readHash {
+			return mcpgw.BuildToolErrorResult((&HashMismatchError{Path: filePath, Expected: expected, Actual: readHash}).Error()), nil
+		}
 		// Step 2: fuzzy match in Go
 		updated, err := applyEdit(raw, filePath, oldText, newText)
 		if err != nil {
 			return mcpgw.BuildToolErrorResult(err.Error()), nil
 		}
-		// Step 3: write back via exec
-		if err := ExecWrite(ctx, p.execRunner, botID, p.execWorkDir, filePath, updated); err != nil {
+		if err := p.writePolicy.Check(filePath, []byte(updated), int64(len(updated))); err != nil {
+			return mcpgw.BuildToolErrorResult(err.Error()), nil
+		}
+		// Step 3: write back via exec, unless the file changed since step 1
+		if err := ExecWriteIfMatch(ctx, p.execRunner, botID, dataDir, filePath, updated, readHash); err != nil {
 			return mcpgw.BuildToolErrorResult(err.Error()), nil
 		}
+		p.recordWrite(ctx, session, mcpgw.WriteAuditEdit, dataDir, filePath, arguments)
 		return mcpgw.BuildToolSuccessResult(map[string]any{"ok": true}), nil
 
 	case toolExec:
@@ -223,7 +361,7 @@ func (p *Executor) CallTool(ctx context.Context, session mcpgw.ToolSessionContex
 		}
 		workDir := strings.TrimSpace(mcpgw.StringArg(arguments, "work_dir"))
 		if workDir == "" {
-			workDir = p.execWorkDir
+			workDir = dataDir
 		}
 		result, err := p.execRunner.ExecWithCapture(ctx, mcpgw.ExecRequest{
 			BotID:   botID,
@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/memohai/memoh/internal/config"
 	mcpgw "github.com/memohai/memoh/internal/mcp"
 )
 
@@ -128,6 +130,39 @@ func TestExecutor_CallTool_List(t *testing.T) {
 	}
 }
 
+func TestExecutor_CallTool_ListTruncated(t *testing.T) {
+	runner := &fakeExecRunner{
+		result: &mcpgw.ExecWithCaptureResult{
+			Stdout: "./a|regular file|1|644|1700000000\n./b|regular file|1|644|1700000000\n./c|regular file|1|644|1700000000\n",
+			Stderr: listExitMarker + "141\n",
+		},
+	}
+	exec := NewExecutor(nil, runner, "/data")
+	exec.SetListLimits(2, time.Second)
+	ctx := context.Background()
+	session := mcpgw.ToolSessionContext{BotID: "bot1"}
+
+	result, err := exec.CallTool(ctx, session, "list", map[string]any{"path": ".", "recursive": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mcpgw.PayloadError(result); err != nil {
+		t.Fatal(err)
+	}
+	content, _ := result["structuredContent"].(map[string]any)
+	entries, _ := content["entries"].([]map[string]any)
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+	if truncated, _ := content["truncated"].(bool); !truncated {
+		t.Error("expected truncated listing")
+	}
+	cmd := strings.Join(runner.lastReq.Command, " ")
+	if !strings.Contains(cmd, "head -n 3") || !strings.Contains(cmd, "timeout 1") {
+		t.Errorf("expected entry and time limits in script, got %q", cmd)
+	}
+}
+
 func TestExecutor_CallTool_Edit(t *testing.T) {
 	callCount := 0
 	runner := &fakeExecRunner{
@@ -201,6 +236,215 @@ func TestExecutor_CallTool_Exec(t *testing.T) {
 	}
 }
 
+func TestExecutor_CallTool_ExecPerBotWorkDir(t *testing.T) {
+	runner := &fakeExecRunner{result: &mcpgw.ExecWithCaptureResult{}}
+	exec := NewExecutor(nil, runner, "/data")
+	exec.SetWorkDirFunc(func(_ context.Context, botID string) string {
+		if botID == "bot1" {
+			return "/workspace"
+		}
+		return ""
+	})
+	ctx := context.Background()
+	for botID, want := range map[string]string{"bot1": "/workspace", "bot2": "/data"} {
+		session := mcpgw.ToolSessionContext{BotID: botID}
+		if _, err := exec.CallTool(ctx, session, toolExec, map[string]any{"command": "pwd"}); err != nil {
+			t.Fatal(err)
+		}
+		if runner.lastReq.WorkDir != want {
+			t.Errorf("%s: work dir = %q, want %q", botID, runner.lastReq.WorkDir, want)
+		}
+	}
+}
+
+func TestExecutor_CallTool_ReadTooLarge(t *testing.T) {
+	runner := &fakeExecRunner{
+		result: &mcpgw.ExecWithCaptureResult{Stderr: "52428800\n", ExitCode: exitFileTooLarge},
+	}
+	exec := NewExecutor(nil, runner, "/data")
+	ctx := context.Background()
+	session := mcpgw.ToolSessionContext{BotID: "bot1"}
+
+	result, err := exec.CallTool(ctx, session, "read", map[string]any{"path": "big.log"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isErr, _ := result["isError"].(bool); !isErr {
+		t.Fatal("expected error for oversized file")
+	}
+	content, _ := result["content"].([]map[string]any)
+	if len(content) == 0 {
+		t.Fatal("expected error content")
+	}
+	if msg, _ := content[0]["text"].(string); !strings.Contains(msg, "52428800 bytes") {
+		t.Errorf("error should report actual size, got %q", msg)
+	}
+	cmd := strings.Join(runner.lastReq.Command, " ")
+	if !strings.Contains(cmd, "stat -c %s") {
+		t.Errorf("expected size check before cat, got %q", cmd)
+	}
+}
+
+func TestExecutor_CallTool_WriteSpecialFile(t *testing.T) {
+	runner := &fakeExecRunner{
+		result: &mcpgw.ExecWithCaptureResult{Stderr: "fifo\n", ExitCode: exitSpecialFile},
+	}
+	exec := NewExecutor(nil, runner, "/data")
+	ctx := context.Background()
+	session := mcpgw.ToolSessionContext{BotID: "bot1"}
+
+	result, err := exec.CallTool(ctx, session, "write", map[string]any{
+		"path": "pipe", "content": "data",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isErr, _ := result["isError"].(bool); !isErr {
+		t.Fatal("expected error for special file target")
+	}
+	content, _ := result["content"].([]map[string]any)
+	if len(content) == 0 {
+		t.Fatal("expected error content")
+	}
+	if msg, _ := content[0]["text"].(string); !strings.Contains(msg, "not a regular file (fifo)") {
+		t.Errorf("unexpected error message %q", msg)
+	}
+	cmd := strings.Join(runner.lastReq.Command, " ")
+	guard := strings.Index(cmd, "[ -L")
+	if guard < 0 || guard > strings.Index(cmd, "base64 -d") {
+		t.Errorf("expected lstat guard before write, got %q", cmd)
+	}
+}
+
+func TestExecutor_CallTool_WriteDeniedByPolicy(t *testing.T) {
+	runner := &fakeExecRunner{result: &mcpgw.ExecWithCaptureResult{}}
+	exec := NewExecutor(nil, runner, "/data")
+	exec.SetWritePolicy(mcpgw.NewWritePolicy(config.WritePolicyConfig{DeniedExtensions: []string{".exe"}}))
+	ctx := context.Background()
+	session := mcpgw.ToolSessionContext{BotID: "bot1"}
+
+	result, err := exec.CallTool(ctx, session, "write", map[string]any{
+		"path": "tool.exe", "content": "MZ",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isErr, _ := result["isError"].(bool); !isErr {
+		t.Fatal("expected error for denied extension")
+	}
+	if runner.lastReq.Command != nil {
+		t.Errorf("denied write should not reach the container, ran %v", runner.lastReq.Command)
+	}
+}
+
+type recordingAuditor struct {
+	entries []mcpgw.WriteAuditEntry
+}
+
+func (r *recordingAuditor) RecordWrite(ctx context.Context, entry mcpgw.WriteAuditEntry) error {
+	r.entries = append(r.entries, entry)
+	return nil
+}
+
+func TestExecutor_CallTool_WriteRecordsAudit(t *testing.T) {
+	runner := &fakeExecRunner{result: &mcpgw.ExecWithCaptureResult{}}
+	exec := NewExecutor(nil, runner, "/data")
+	auditor := &recordingAuditor{}
+	exec.SetWriteAuditor(auditor)
+	ctx := context.Background()
+	session := mcpgw.ToolSessionContext{BotID: "bot1", ChatID: "chat1"}
+
+	result, err := exec.CallTool(ctx, session, "write", map[string]any{
+		"path": "notes/../hello.txt", "content": "world",
+		"metadata": map[string]any{"agent": "planner", "chat_id": "override"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mcpgw.PayloadError(result); err != nil {
+		t.Fatal(err)
+	}
+	if len(auditor.entries) != 1 {
+		t.Fatalf("got %d audit entries, want 1", len(auditor.entries))
+	}
+	entry := auditor.entries[0]
+	if entry.BotID != "bot1" || entry.Path != "/data/hello.txt" || entry.Action != mcpgw.WriteAuditWrite {
+		t.Errorf("entry = %+v", entry)
+	}
+	if entry.Metadata["agent"] != "planner" || entry.Metadata["chat_id"] != "override" {
+		t.Errorf("metadata = %v", entry.Metadata)
+	}
+
+	runner.result = &mcpgw.ExecWithCaptureResult{ExitCode: 1, Stderr: "boom"}
+	if _, err := exec.CallTool(ctx, session, "write", map[string]any{"path": "x", "content": "y"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(auditor.entries) != 1 {
+		t.Errorf("failed write should not be audited, got %d entries", len(auditor.entries))
+	}
+}
+
+func TestExecutor_CallTool_WriteHashMismatch(t *testing.T) {
+	runner := &fakeExecRunner{
+		handler: func(req mcpgw.ExecRequest) (*mcpgw.ExecWithCaptureResult, error) {
+			cmd := strings.Join(req.Command, " ")
+			if !strings.Contains(cmd, "md5sum") || !strings.Contains(cmd, "'abc'") {
+				return nil, fmt.Errorf("expected a hash guard, got %q", cmd)
+			}
+			return &mcpgw.ExecWithCaptureResult{ExitCode: exitHashMismatch, Stderr: "def"}, nil
+		},
+	}
+	exec := NewExecutor(nil, runner, "/data")
+	result, err := exec.CallTool(context.Background(), mcpgw.ToolSessionContext{BotID: "bot1"}, "write", map[string]any{
+		"path": "notes.md", "content": "new", "expected_hash": "abc",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isErr, _ := result["isError"].(bool); !isErr || !strings.Contains(fmt.Sprint(result["content"]), "changed since it was read") {
+		t.Fatalf("expected a hash mismatch error, got %v", result)
+	}
+}
+
+func TestExecutor_CallTool_EditGuardsConcurrentChange(t *testing.T) {
+	var writeCmd string
+	runner := &fakeExecRunner{
+		handler: func(req mcpgw.ExecRequest) (*mcpgw.ExecWithCaptureResult, error) {
+			cmd := strings.Join(req.Command, " ")
+			if strings.Contains(cmd, "base64 -d") {
+				writeCmd = cmd
+				return &mcpgw.ExecWithCaptureResult{}, nil
+			}
+			return &mcpgw.ExecWithCaptureResult{Stdout: "hello world"}, nil
+		},
+	}
+	exec := NewExecutor(nil, runner, "/data")
+	session := mcpgw.ToolSessionContext{BotID: "bot1"}
+	result, err := exec.CallTool(context.Background(), session, "edit", map[string]any{
+		"path": "test.txt", "old_text": "hello", "new_text": "goodbye",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mcpgw.PayloadError(result); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(writeCmd, ShellQuote(ContentHash("hello world"))) {
+		t.Fatalf("write-back should be conditional on the content read, got %q", writeCmd)
+	}
+
+	writeCmd = ""
+	result, err = exec.CallTool(context.Background(), session, "edit", map[string]any{
+		"path": "test.txt", "old_text": "hello", "new_text": "goodbye", "expected_hash": "stale",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isErr, _ := result["isError"].(bool); !isErr || writeCmd != "" {
+		t.Fatal("a stale expected_hash must refuse the edit without writing")
+	}
+}
+
 func TestExecutor_CallTool_NoBotID(t *testing.T) {
 	runner := &fakeExecRunner{result: &mcpgw.ExecWithCaptureResult{}}
 	exec := NewExecutor(nil, runner, "/data")
@@ -234,3 +478,31 @@ func TestNormalizePath(t *testing.T) {
 		}
 	}
 }
+
+func TestExecutor_CallTool_ReadOnly(t *testing.T) {
+	runner := &fakeExecRunner{result: &mcpgw.ExecWithCaptureResult{Stdout: "hello"}}
+	exec := NewExecutor(nil, runner, "/data")
+	exec.SetReadOnly(true)
+	ctx := context.Background()
+	session := mcpgw.ToolSessionContext{BotID: "bot1"}
+
+	for _, tool := range []string{"write", "edit", "exec"} {
+		result, err := exec.CallTool(ctx, session, tool, map[string]any{"path": "a.txt", "content": "x", "old_text": "x", "command": "true"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if isErr, _ := result["isError"].(bool); !isErr {
+			t.Errorf("%s succeeded in read-only mode", tool)
+		}
+	}
+	if runner.lastReq.BotID != "" {
+		t.Fatalf("read-only executor ran %v", runner.lastReq.Command)
+	}
+	result, err := exec.CallTool(ctx, session, "read", map[string]any{"path": "a.txt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isErr, _ := result["isError"].(bool); isErr {
+		t.Fatalf("read failed in read-only mode: %v", result)
+	}
+}
@@ -114,6 +114,10 @@ func (s *Source) CallTool(ctx context.Context, session mcpgw.ToolSessionContext,
 	case "sse":
 		payload, err = s.gateway.CallSSEConnectionTool(ctx, route.connection, route.originalName, arguments)
 	case "stdio":
+		// Stdio servers run inside the bot container and may write to it.
+		if session.ReadOnlyFS {
+			return mcpgw.BuildToolErrorResult("container filesystem is read-only"), nil
+		}
 		payload, err = s.gateway.CallStdioConnectionTool(ctx, botID, route.connection, route.originalName, arguments)
 	default:
 		return mcpgw.BuildToolErrorResult("unsupported federated source"), nil
@@ -0,0 +1,181 @@
+package mcp
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	containerd "github.com/containerd/containerd/v2/client"
+	"github.com/containerd/containerd/v2/pkg/namespaces"
+
+	ctr "github.com/memohai/memoh/internal/containerd"
+)
+
+const (
+	defaultRestartBackoff = time.Second
+	maxRestartBackoff     = 5 * time.Minute
+	// restartStableAfter is how long a restarted task has to keep running
+	// before its restart count starts over.
+	restartStableAfter = 10 * time.Minute
+)
+
+// taskSupervisors tracks the running supervisor of each container.
+type taskSupervisors struct {
+	mu      sync.Mutex
+	running map[string]*taskSupervisor
+}
+
+type taskSupervisor struct {
+	cancel context.CancelFunc
+}
+
+// Supervise watches the task of containerID and restarts it when it exits
+// without Unsupervise having been called first, up to MCPConfig.MaxRestarts
+// times in a row with exponential backoff. It does nothing when MaxRestarts
+// is zero. Supervising an already supervised container replaces the watcher.
+func (m *Manager) Supervise(containerID string) {
+	if m.cfg.MaxRestarts <= 0 || containerID == "" {
+		return
+	}
+	ctx, cancel := context.WithCancel(namespaces.WithNamespace(context.Background(), m.namespace))
+	sup := &taskSupervisor{cancel: cancel}
+
+	m.supervisors.mu.Lock()
+	if m.supervisors.running == nil {
+		m.supervisors.running = map[string]*taskSupervisor{}
+	}
+	if prev, ok := m.supervisors.running[containerID]; ok {
+		prev.cancel()
+	}
+	m.supervisors.running[containerID] = sup
+	m.supervisors.mu.Unlock()
+
+	go func() {
+		defer m.dropSupervisor(containerID, sup)
+		m.supervise(ctx, containerID)
+	}()
+}
+
+// Unsupervise stops watching containerID. Call it before stopping or deleting
+// a task on purpose, so the exit is not taken for a crash.
+func (m *Manager) Unsupervise(containerID string) {
+	m.supervisors.mu.Lock()
+	defer m.supervisors.mu.Unlock()
+	if sup, ok := m.supervisors.running[containerID]; ok {
+		sup.cancel()
+		delete(m.supervisors.running, containerID)
+	}
+}
+
+func (m *Manager) dropSupervisor(containerID string, sup *taskSupervisor) {
+	sup.cancel()
+	m.supervisors.mu.Lock()
+	defer m.supervisors.mu.Unlock()
+	if m.supervisors.running[containerID] == sup {
+		delete(m.supervisors.running, containerID)
+	}
+}
+
+func (m *Manager) supervise(ctx context.Context, containerID string) {
+	logger := m.logger.With(slog.String("container_id", containerID))
+	botID, err := m.BotForContainer(ctx, containerID)
+	if err != nil {
+		logger.Warn("supervisor: resolve bot failed", slog.Any("error", err))
+	} else {
+		logger = logger.With(slog.String("bot_id", botID))
+	}
+	task, err := m.service.GetTask(ctx, containerID)
+	if err != nil {
+		logger.Warn("supervisor: task not found", slog.Any("error", err))
+		return
+	}
+	restarts := 0
+	startedAt := time.Now()
+	for {
+		statusC, err := task.Wait(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				logger.Warn("supervisor: wait for task failed", slog.Any("error", err))
+			}
+			return
+		}
+		var status containerd.ExitStatus
+		select {
+		case <-ctx.Done():
+			return
+		case status = <-statusC:
+		}
+		if ctx.Err() != nil {
+			// Unsupervised while the task was exiting: a deliberate stop.
+			return
+		}
+		if time.Since(startedAt) >= restartStableAfter {
+			restarts = 0
+		}
+
+		for {
+			if restarts >= m.cfg.MaxRestarts {
+				logger.Error("supervisor: task keeps exiting, giving up",
+					slog.Int("restarts", restarts), slog.Any("exit_code", status.ExitCode()))
+				return
+			}
+			restarts++
+			backoff := restartBackoff(m.restartBackoffBase(), restarts)
+			logger.Warn("supervisor: task exited unexpectedly, restarting",
+				slog.Any("exit_code", status.ExitCode()),
+				slog.Int("attempt", restarts),
+				slog.Duration("backoff", backoff))
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			next, err := m.restartTask(ctx, containerID, task)
+			if err == nil {
+				task, startedAt = next, time.Now()
+				// A fresh task gets a full idle timeout before the reaper
+				// may stop it again.
+				m.Touch(botID)
+				break
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			logger.Error("supervisor: restart failed", slog.Int("attempt", restarts), slog.Any("error", err))
+		}
+	}
+}
+
+// restartTask replaces the dead task with a fresh one and reattaches its
+// network.
+func (m *Manager) restartTask(ctx context.Context, containerID string, dead containerd.Task) (containerd.Task, error) {
+	if err := ctr.RemoveNetwork(ctx, dead, containerID); err != nil {
+		m.logger.Warn("cleanup: remove network failed", slog.String("container_id", containerID), slog.Any("error", err))
+	}
+	task, err := m.service.RestartTask(ctx, containerID)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctr.SetupNetwork(ctx, task, containerID); err != nil {
+		m.logger.Warn("network setup failed, task kept running", slog.String("container_id", containerID), slog.Any("error", err))
+	}
+	return task, nil
+}
+
+func (m *Manager) restartBackoffBase() time.Duration {
+	if m.cfg.RestartBackoffSeconds > 0 {
+		return time.Duration(m.cfg.RestartBackoffSeconds) * time.Second
+	}
+	return defaultRestartBackoff
+}
+
+// restartBackoff doubles base for every restart after the first, up to
+// maxRestartBackoff.
+func restartBackoff(base time.Duration, attempt int) time.Duration {
+	backoff := base
+	for i := 1; i < attempt && backoff < maxRestartBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, maxRestartBackoff)
+}
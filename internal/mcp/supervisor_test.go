@@ -0,0 +1,143 @@
+package mcp
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	containerd "github.com/containerd/containerd/v2/client"
+	"github.com/containerd/containerd/v2/core/containers"
+
+	"github.com/memohai/memoh/internal/config"
+	ctr "github.com/memohai/memoh/internal/containerd"
+)
+
+func TestRestartBackoff(t *testing.T) {
+	cases := []struct {
+		base    time.Duration
+		attempt int
+		want    time.Duration
+	}{
+		{base: time.Second, attempt: 1, want: time.Second},
+		{base: time.Second, attempt: 2, want: 2 * time.Second},
+		{base: time.Second, attempt: 4, want: 8 * time.Second},
+		{base: time.Minute, attempt: 4, want: maxRestartBackoff},
+		{base: time.Second, attempt: 1000, want: maxRestartBackoff},
+	}
+	for _, tc := range cases {
+		if got := restartBackoff(tc.base, tc.attempt); got != tc.want {
+			t.Errorf("restartBackoff(%v, %d) = %v, want %v", tc.base, tc.attempt, got, tc.want)
+		}
+	}
+}
+
+func TestSuperviseDisabledWithoutMaxRestarts(t *testing.T) {
+	m := &Manager{}
+	m.Supervise("mcp-bot")
+	if len(m.supervisors.running) != 0 {
+		t.Fatal("supervisor started with MaxRestarts unset")
+	}
+	m.Unsupervise("mcp-bot")
+}
+
+// stoppableTask is a task that exits once stopped.
+type stoppableTask struct {
+	containerd.Task
+	waiting  chan struct{}
+	exited   chan struct{}
+	waitOnce sync.Once
+	exitOnce sync.Once
+}
+
+func newStoppableTask() *stoppableTask {
+	return &stoppableTask{waiting: make(chan struct{}), exited: make(chan struct{})}
+}
+
+func (t *stoppableTask) Pid() uint32 { return 0 }
+
+func (t *stoppableTask) Wait(ctx context.Context) (<-chan containerd.ExitStatus, error) {
+	t.waitOnce.Do(func() { close(t.waiting) })
+	ch := make(chan containerd.ExitStatus, 1)
+	go func() {
+		select {
+		case <-t.exited:
+			ch <- *containerd.NewExitStatus(137, time.Now(), nil)
+		case <-ctx.Done():
+		}
+	}()
+	return ch, nil
+}
+
+func (t *stoppableTask) exit() { t.exitOnce.Do(func() { close(t.exited) }) }
+
+// commitService fakes the containerd calls made while committing a version.
+type commitService struct {
+	ctr.Service
+	task     *stoppableTask
+	restarts atomic.Int32
+}
+
+func (s *commitService) GetTask(context.Context, string) (containerd.Task, error) {
+	return s.task, nil
+}
+
+func (s *commitService) StopTask(context.Context, string, *ctr.StopTaskOptions) (ctr.StopTaskResult, error) {
+	s.task.exit()
+	return ctr.StopTaskResult{}, nil
+}
+
+func (s *commitService) DeleteTask(context.Context, string, *ctr.DeleteTaskOptions) error {
+	return nil
+}
+
+func (s *commitService) RestartTask(context.Context, string) (containerd.Task, error) {
+	s.restarts.Add(1)
+	return s.task, nil
+}
+
+func (s *commitService) CommitSnapshot(context.Context, string, string, string, map[string]string) error {
+	return nil
+}
+
+func (s *commitService) PrepareSnapshot(context.Context, string, string, string) error {
+	return nil
+}
+
+func (s *commitService) DeleteContainer(context.Context, string, *ctr.DeleteContainerOptions) error {
+	return nil
+}
+
+func (s *commitService) CreateContainerFromSnapshot(context.Context, ctr.CreateContainerRequest) (containerd.Container, error) {
+	return nil, nil
+}
+
+func TestCommitContainerDoesNotRestartSupervisedTask(t *testing.T) {
+	svc := &commitService{task: newStoppableTask()}
+	m := &Manager{
+		service: svc,
+		cfg:     config.MCPConfig{MaxRestarts: 3, DataRoot: t.TempDir()},
+		logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	const botID, containerID = "bot", "mcp-bot"
+	m.bots.replace(map[string]string{containerID: botID})
+	m.Supervise(containerID)
+	select {
+	case <-svc.task.waiting:
+	case <-time.After(5 * time.Second):
+		t.Fatal("supervisor never waited on the task")
+	}
+
+	info := containers.Container{ID: containerID, Snapshotter: "overlayfs", SnapshotKey: "active"}
+	if err := m.commitContainer(context.Background(), botID, info, "mcp-bot-v1", nil); err != nil {
+		t.Fatalf("commitContainer: %v", err)
+	}
+	// Outlast the first restart backoff.
+	time.Sleep(defaultRestartBackoff + 500*time.Millisecond)
+	if n := svc.restarts.Load(); n != 0 {
+		t.Fatalf("supervisor restarted the task %d times during the commit", n)
+	}
+}
@@ -16,6 +16,9 @@ type ToolSessionContext struct {
 	SessionToken      string
 	CurrentPlatform   string
 	ReplyTarget       string
+	// ReadOnlyFS refuses tools that change the bot's container, for
+	// mcp.read_only_fs and fs:readonly tokens.
+	ReadOnlyFS bool
 }
 
 // ToolDescriptor is the MCP tools/list item shape used by the gateway.
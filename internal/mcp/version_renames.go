@@ -0,0 +1,124 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	ctr "github.com/memohai/memoh/internal/containerd"
+	dbsqlc "github.com/memohai/memoh/internal/db/sqlc"
+)
+
+// renameEventType is the lifecycle event recorded for moves in the data mount.
+const renameEventType = "fs_rename"
+
+// RecordRename notes that from was moved to to (both container paths) so the
+// next version lists the move as a rename instead of a delete and an add.
+func (m *Manager) RecordRename(ctx context.Context, userID, from, to string) error {
+	if m.queries == nil {
+		return nil
+	}
+	if err := validateBotID(userID); err != nil {
+		return err
+	}
+	return m.insertEvent(ctx, m.containerID(userID), renameEventType, map[string]any{
+		"from": from,
+		"to":   to,
+	})
+}
+
+// lastVersionTime returns when the container's latest version was created, or
+// the zero time when it has none yet.
+func (m *Manager) lastVersionTime(ctx context.Context, containerID string) pgtype.Timestamptz {
+	since := pgtype.Timestamptz{Time: time.Time{}, Valid: true}
+	versions, err := m.queries.ListVersionsByContainerID(ctx, containerID)
+	if err != nil {
+		m.logger.Warn("list versions for rename tracking failed",
+			slog.String("container_id", containerID), slog.Any("error", err))
+		return since
+	}
+	if n := len(versions); n > 0 && versions[n-1].CreatedAt.Valid {
+		since = versions[n-1].CreatedAt
+	}
+	return since
+}
+
+// recordedRenames returns the renames recorded since the given time. Chains
+// are collapsed (a→b, b→c becomes a→c) and moves back to the origin dropped.
+func (m *Manager) recordedRenames(ctx context.Context, containerID string, since pgtype.Timestamptz) []ctr.PathChange {
+	events, err := m.queries.ListLifecycleEventsSince(ctx, dbsqlc.ListLifecycleEventsSinceParams{
+		ContainerID: containerID,
+		EventType:   renameEventType,
+		Since:       since,
+	})
+	if err != nil {
+		m.logger.Warn("load recorded renames failed",
+			slog.String("container_id", containerID), slog.Any("error", err))
+		return nil
+	}
+	moves := make([][2]string, 0, len(events))
+	for _, ev := range events {
+		var payload struct {
+			From string `json:"from"`
+			To   string `json:"to"`
+		}
+		if err := json.Unmarshal(ev.Payload, &payload); err != nil || payload.From == "" || payload.To == "" {
+			continue
+		}
+		moves = append(moves, [2]string{payload.From, payload.To})
+	}
+	return collapseRenames(moves)
+}
+
+func collapseRenames(moves [][2]string) []ctr.PathChange {
+	origin := map[string]string{} // current path -> original path
+	order := []string{}
+	for _, mv := range moves {
+		from, to := mv[0], mv[1]
+		orig, ok := origin[from]
+		if ok {
+			delete(origin, from)
+		} else {
+			orig = from
+		}
+		if _, seen := origin[to]; !seen {
+			order = append(order, to)
+		}
+		origin[to] = orig
+	}
+	out := make([]ctr.PathChange, 0, len(origin))
+	for _, to := range order {
+		from, ok := origin[to]
+		if !ok || from == to {
+			continue
+		}
+		delete(origin, to)
+		out = append(out, ctr.PathChange{Kind: ctr.PathChangeRenamed, Path: to, From: from})
+	}
+	return out
+}
+
+// mergeRenames adds the recorded renames to changes, replacing any delete of
+// the source and add of the destination that describe the same move.
+func mergeRenames(changes, renames []ctr.PathChange) []ctr.PathChange {
+	if len(renames) == 0 {
+		return changes
+	}
+	covered := map[string]bool{}
+	for _, r := range renames {
+		covered["delete:"+r.From] = true
+		covered["add:"+r.Path] = true
+		covered[ctr.PathChangeRenamed+":"+r.Path] = true
+	}
+	out := make([]ctr.PathChange, 0, len(changes)+len(renames))
+	for _, ch := range changes {
+		if covered[ch.Kind+":"+ch.Path] {
+			continue
+		}
+		out = append(out, ch)
+	}
+	return append(out, renames...)
+}
@@ -0,0 +1,38 @@
+package mcp
+
+import (
+	"reflect"
+	"testing"
+
+	ctr "github.com/memohai/memoh/internal/containerd"
+)
+
+func TestCollapseRenames(t *testing.T) {
+	got := collapseRenames([][2]string{
+		{"/data/a.md", "/data/b.md"},
+		{"/data/b.md", "/data/c.md"},
+		{"/data/x.md", "/data/y.md"},
+		{"/data/y.md", "/data/x.md"},
+	})
+	want := []ctr.PathChange{{Kind: ctr.PathChangeRenamed, Path: "/data/c.md", From: "/data/a.md"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("collapseRenames = %+v, want %+v", got, want)
+	}
+}
+
+func TestMergeRenames(t *testing.T) {
+	changes := []ctr.PathChange{
+		{Kind: "delete", Path: "/data/old.md"},
+		{Kind: "add", Path: "/data/new.md"},
+		{Kind: "modify", Path: "/etc/hosts"},
+	}
+	renames := []ctr.PathChange{{Kind: ctr.PathChangeRenamed, Path: "/data/new.md", From: "/data/old.md"}}
+	got := mergeRenames(changes, renames)
+	want := []ctr.PathChange{
+		{Kind: "modify", Path: "/etc/hosts"},
+		{Kind: ctr.PathChangeRenamed, Path: "/data/new.md", From: "/data/old.md"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("mergeRenames = %+v, want %+v", got, want)
+	}
+}
@@ -3,15 +3,18 @@ package mcp
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
 	"time"
+	"unicode"
 
-	"github.com/containerd/containerd/v2/pkg/oci"
+	"github.com/containerd/containerd/v2/core/containers"
 	"github.com/containerd/errdefs"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
-	"github.com/opencontainers/runtime-spec/specs-go"
-
-	"github.com/memohai/memoh/internal/config"
 
 	ctr "github.com/memohai/memoh/internal/containerd"
 	"github.com/memohai/memoh/internal/db"
@@ -22,18 +25,61 @@ type VersionInfo struct {
 	ID         string
 	Version    int
 	SnapshotID string
-	CreatedAt  time.Time
+	// Label is the name the version was given when it was created, if any.
+	Label     string
+	CreatedAt time.Time
+	// ChangedPaths lists paths added, modified or deleted relative to the
+	// previous version (or the base image for the first version).
+	ChangedPaths []ctr.PathChange
 }
 
-func (m *Manager) CreateVersion(ctx context.Context, userID string) (*VersionInfo, error) {
+// maxVersionChangedPaths caps how many changed paths are stored per version.
+const maxVersionChangedPaths = 1000
+
+// ErrVersionInUse is returned by DeleteVersion when the version's snapshot is
+// still the parent of the container's active snapshot or of another snapshot.
+var ErrVersionInUse = errors.New("version snapshot is in use")
+
+// ErrVersionLabelTaken is returned by CreateVersion when another version of
+// the container already has the label.
+var ErrVersionLabelTaken = errors.New("version label is already in use")
+
+// VersionLabelKey is the snapshot label that carries a version's label.
+const VersionLabelKey = "mcp.version_label"
+
+// maxVersionLabelLength caps version labels, in bytes.
+const maxVersionLabelLength = 128
+
+// ErrBotLabelMismatch is returned when a bot's container carries another
+// bot's label, so that versions are never restored into the wrong container.
+var ErrBotLabelMismatch = errors.New("container belongs to a different bot")
+
+// CreateVersion commits the container's filesystem as a new version, named
+// label if it is not empty. Labels are unique per container, so they can
+// stand in for version numbers; see VersionByLabel. A task that was running
+// before the commit is started again afterwards.
+func (m *Manager) CreateVersion(ctx context.Context, userID, label string) (*VersionInfo, error) {
 	if m.db == nil || m.queries == nil {
 		return nil, fmt.Errorf("db is not configured")
 	}
 	if err := validateBotID(userID); err != nil {
 		return nil, err
 	}
+	label = strings.TrimSpace(label)
+	if err := validateVersionLabel(label); err != nil {
+		return nil, err
+	}
 
 	containerID := m.containerID(userID)
+	if label != "" {
+		// Checked up front so a taken label fails before the task is
+		// stopped; the unique index still guards against races.
+		if _, err := m.VersionByLabel(ctx, userID, label); err == nil {
+			return nil, fmt.Errorf("%w: %q", ErrVersionLabelTaken, label)
+		} else if !errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+	}
 	container, err := m.service.GetContainer(ctx, containerID)
 	if err != nil {
 		return nil, err
@@ -48,52 +94,92 @@ func (m *Manager) CreateVersion(ctx context.Context, userID string) (*VersionInf
 		return nil, err
 	}
 
-	if err := m.safeStopTask(ctx, containerID); err != nil {
+	versionSnapshotID := fmt.Sprintf("%s-v%d", containerID, time.Now().UnixNano())
+	var snapshotLabels map[string]string
+	if label != "" {
+		snapshotLabels = map[string]string{VersionLabelKey: label}
+	}
+	wasRunning := m.taskRunning(ctx, containerID)
+	if err := m.commitContainer(ctx, userID, info, versionSnapshotID, snapshotLabels); err != nil {
 		return nil, err
 	}
+	if wasRunning {
+		if err := m.Start(ctx, userID); err != nil {
+			return nil, fmt.Errorf("restart after commit: %w", err)
+		}
+	}
 
-	versionSnapshotID := fmt.Sprintf("%s-v%d", containerID, time.Now().UnixNano())
-	if err := m.service.CommitSnapshot(ctx, info.Snapshotter, versionSnapshotID, info.SnapshotKey); err != nil {
+	since := m.lastVersionTime(ctx, containerID)
+	versionID, versionNumber, createdAt, err := m.insertVersion(ctx, containerID, versionSnapshotID, info.Snapshotter, label)
+	if err != nil {
+		if db.IsUniqueViolation(err) {
+			return nil, fmt.Errorf("%w: %q", ErrVersionLabelTaken, label)
+		}
+		return nil, err
+	}
+
+	changedPaths := m.recordChangedPaths(ctx, containerID, versionID, info.Snapshotter, versionSnapshotID, since)
+
+	if err := m.insertEvent(ctx, containerID, "version_create", map[string]any{
+		"snapshot_id":   versionSnapshotID,
+		"version":       versionNumber,
+		"changed_paths": len(changedPaths),
+		"label":         label,
+	}); err != nil {
 		return nil, err
 	}
+	m.autoPruneVersions(ctx, userID)
+
+	return &VersionInfo{
+		ID:           versionID,
+		Version:      versionNumber,
+		SnapshotID:   versionSnapshotID,
+		Label:        label,
+		CreatedAt:    createdAt,
+		ChangedPaths: changedPaths,
+	}, nil
+}
+
+// commitContainer stops the container's task, commits its active snapshot as
+// versionSnapshotID and recreates the container on a fresh active snapshot
+// prepared from it. The task is unsupervised first, so the stop is not taken
+// for a crash and restarted under the container being replaced.
+func (m *Manager) commitContainer(ctx context.Context, botID string, info containers.Container, versionSnapshotID string, labels map[string]string) error {
+	containerID := info.ID
+	m.Unsupervise(containerID)
+	if task, err := m.service.GetTask(ctx, containerID); err == nil {
+		if err := ctr.RemoveNetwork(ctx, task, containerID); err != nil {
+			m.logger.Warn("cleanup: remove network failed", slog.String("container_id", containerID), slog.Any("error", err))
+		}
+	}
+	if err := m.safeStopTask(ctx, containerID); err != nil {
+		return err
+	}
+	// A stopped task still blocks deleting the container.
+	if err := m.service.DeleteTask(ctx, containerID, &ctr.DeleteTaskOptions{Force: true}); err != nil && !errdefs.IsNotFound(err) {
+		return err
+	}
+
+	if err := m.service.CommitSnapshot(ctx, info.Snapshotter, versionSnapshotID, info.SnapshotKey, labels); err != nil {
+		return err
+	}
 
 	activeSnapshotID := fmt.Sprintf("%s-active-%d", containerID, time.Now().UnixNano())
 	if err := m.service.PrepareSnapshot(ctx, info.Snapshotter, activeSnapshotID, versionSnapshotID); err != nil {
-		return nil, err
+		return err
 	}
 
 	if err := m.service.DeleteContainer(ctx, containerID, &ctr.DeleteContainerOptions{CleanupSnapshot: false}); err != nil {
-		return nil, err
+		return err
 	}
 
-	dataDir, err := m.ensureBotDir(userID)
+	dataDir, err := m.ensureBotDir(botID)
 	if err != nil {
-		return nil, err
-	}
-	dataMount := m.cfg.DataMount
-	if dataMount == "" {
-		dataMount = config.DefaultDataMount
+		return err
 	}
-	resolvPath, err := ctr.ResolveConfSource(dataDir)
+	specOpts, err := botMountSpecOpts(dataDir, m.cfg.DataMountFor(info.Image))
 	if err != nil {
-		return nil, err
-	}
-
-	specOpts := []oci.SpecOpts{
-		oci.WithMounts([]specs.Mount{
-			{
-				Destination: dataMount,
-				Type:        "bind",
-				Source:      dataDir,
-				Options:     []string{"rbind", "rw"},
-			},
-			{
-				Destination: "/etc/resolv.conf",
-				Type:        "bind",
-				Source:      resolvPath,
-				Options:     []string{"rbind", "ro"},
-			},
-		}),
+		return err
 	}
 
 	_, err = m.service.CreateContainerFromSnapshot(ctx, ctr.CreateContainerRequest{
@@ -103,29 +189,38 @@ func (m *Manager) CreateVersion(ctx context.Context, userID string) (*VersionInf
 		Snapshotter: info.Snapshotter,
 		Labels:      info.Labels,
 		SpecOpts:    specOpts,
-	})
+	}.WithResourceLimits(m.cfg))
+	return err
+}
+
+// recordChangedPaths computes the paths changed by a committed version
+// snapshot, merges in renames recorded since the previous version, and stores
+// them with the version. Failures are logged only: the version itself is
+// valid without this metadata.
+func (m *Manager) recordChangedPaths(ctx context.Context, containerID, versionID, snapshotter, snapshotID string, since pgtype.Timestamptz) []ctr.PathChange {
+	changes, truncated, err := ctr.SnapshotChanges(ctx, m.service, snapshotter, snapshotID, maxVersionChangedPaths)
 	if err != nil {
-		return nil, err
+		m.logger.Warn("compute version changed paths failed",
+			slog.String("version_id", versionID), slog.Any("error", err))
+		return nil
 	}
-
-	versionID, versionNumber, createdAt, err := m.insertVersion(ctx, containerID, versionSnapshotID, info.Snapshotter)
+	changes = mergeRenames(changes, m.recordedRenames(ctx, containerID, since))
+	if truncated {
+		m.logger.Info("version changed paths truncated",
+			slog.String("version_id", versionID), slog.Int("limit", maxVersionChangedPaths))
+	}
+	payload, err := json.Marshal(changes)
 	if err != nil {
-		return nil, err
+		return changes
 	}
-
-	if err := m.insertEvent(ctx, containerID, "version_create", map[string]any{
-		"snapshot_id": versionSnapshotID,
-		"version":     versionNumber,
+	if err := m.queries.SetVersionChangedPaths(ctx, dbsqlc.SetVersionChangedPathsParams{
+		ChangedPaths: payload,
+		ID:           versionID,
 	}); err != nil {
-		return nil, err
+		m.logger.Warn("store version changed paths failed",
+			slog.String("version_id", versionID), slog.Any("error", err))
 	}
-
-	return &VersionInfo{
-		ID:         versionID,
-		Version:    versionNumber,
-		SnapshotID: versionSnapshotID,
-		CreatedAt:  createdAt,
-	}, nil
+	return changes
 }
 
 func (m *Manager) ListVersions(ctx context.Context, userID string) ([]VersionInfo, error) {
@@ -148,11 +243,20 @@ func (m *Manager) ListVersions(ctx context.Context, userID string) ([]VersionInf
 		if row.CreatedAt.Valid {
 			createdAt = row.CreatedAt.Time
 		}
+		var changedPaths []ctr.PathChange
+		if len(row.ChangedPaths) > 0 {
+			if err := json.Unmarshal(row.ChangedPaths, &changedPaths); err != nil {
+				m.logger.Warn("decode version changed paths failed",
+					slog.String("version_id", row.ID), slog.Any("error", err))
+			}
+		}
 		out = append(out, VersionInfo{
-			ID:         row.ID,
-			Version:    int(row.Version),
-			SnapshotID: row.SnapshotID,
-			CreatedAt:  createdAt,
+			ID:           row.ID,
+			Version:      int(row.Version),
+			SnapshotID:   row.SnapshotID,
+			Label:        row.Label.String,
+			CreatedAt:    createdAt,
+			ChangedPaths: changedPaths,
 		})
 	}
 	return out, nil
@@ -183,10 +287,23 @@ func (m *Manager) RollbackVersion(ctx context.Context, userID string, version in
 	if err != nil {
 		return err
 	}
+	if err := checkBotLabel(info.Labels, userID); err != nil {
+		return err
+	}
 
+	m.Unsupervise(containerID)
+	if task, err := m.service.GetTask(ctx, containerID); err == nil {
+		if err := ctr.RemoveNetwork(ctx, task, containerID); err != nil {
+			m.logger.Warn("cleanup: remove network failed", slog.String("container_id", containerID), slog.Any("error", err))
+		}
+	}
 	if err := m.safeStopTask(ctx, containerID); err != nil {
 		return err
 	}
+	// A stopped task still blocks deleting the container.
+	if err := m.service.DeleteTask(ctx, containerID, &ctr.DeleteTaskOptions{Force: true}); err != nil && !errdefs.IsNotFound(err) {
+		return err
+	}
 
 	activeSnapshotID := fmt.Sprintf("%s-rollback-%d", containerID, time.Now().UnixNano())
 	if err := m.service.PrepareSnapshot(ctx, info.Snapshotter, activeSnapshotID, snapshotID); err != nil {
@@ -201,30 +318,10 @@ func (m *Manager) RollbackVersion(ctx context.Context, userID string, version in
 	if err != nil {
 		return err
 	}
-	dataMount := m.cfg.DataMount
-	if dataMount == "" {
-		dataMount = config.DefaultDataMount
-	}
-	resolvPath, err := ctr.ResolveConfSource(dataDir)
+	specOpts, err := botMountSpecOpts(dataDir, m.cfg.DataMountFor(info.Image))
 	if err != nil {
 		return err
 	}
-	specOpts := []oci.SpecOpts{
-		oci.WithMounts([]specs.Mount{
-			{
-				Destination: dataMount,
-				Type:        "bind",
-				Source:      dataDir,
-				Options:     []string{"rbind", "rw"},
-			},
-			{
-				Destination: "/etc/resolv.conf",
-				Type:        "bind",
-				Source:      resolvPath,
-				Options:     []string{"rbind", "ro"},
-			},
-		}),
-	}
 
 	_, err = m.service.CreateContainerFromSnapshot(ctx, ctr.CreateContainerRequest{
 		ID:          containerID,
@@ -233,7 +330,7 @@ func (m *Manager) RollbackVersion(ctx context.Context, userID string, version in
 		Snapshotter: info.Snapshotter,
 		Labels:      info.Labels,
 		SpecOpts:    specOpts,
-	})
+	}.WithResourceLimits(m.cfg))
 	if err != nil {
 		return err
 	}
@@ -244,6 +341,337 @@ func (m *Manager) RollbackVersion(ctx context.Context, userID string, version in
 	})
 }
 
+// Rollback restores the container to version and returns that version. The
+// returned bool is false when the active snapshot was prepared directly from
+// the version's snapshot, in which case the container is left untouched. A
+// task that was running before the rollback is started again afterwards.
+func (m *Manager) Rollback(ctx context.Context, userID string, version int) (*VersionInfo, bool, error) {
+	target, info, err := m.rollbackTarget(ctx, userID, version)
+	if err != nil {
+		return nil, false, err
+	}
+	if info.SnapshotKey != "" {
+		active, err := m.service.StatSnapshot(ctx, info.Snapshotter, info.SnapshotKey)
+		if err != nil && !errdefs.IsNotFound(err) {
+			return nil, false, err
+		}
+		if err == nil && active.Parent == target.SnapshotID {
+			return target, false, nil
+		}
+	}
+
+	wasRunning := m.taskRunning(ctx, info.ID)
+	if err := m.RollbackVersion(ctx, userID, version); err != nil {
+		return nil, false, err
+	}
+	if wasRunning {
+		if err := m.Start(ctx, userID); err != nil {
+			return nil, false, fmt.Errorf("restart after rollback: %w", err)
+		}
+	}
+	return target, true, nil
+}
+
+// RollbackPreview reports what rolling back to version would change in the
+// container's root filesystem, without changing anything. At most
+// maxVersionChangedPaths changes are listed; truncated is set beyond that.
+func (m *Manager) RollbackPreview(ctx context.Context, userID string, version int) (target *VersionInfo, changes []ctr.PathChange, truncated bool, err error) {
+	target, info, err := m.rollbackTarget(ctx, userID, version)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	current, err := ctr.MountContainerSnapshot(ctx, m.service, info.ID)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	defer current.Unmount()
+	versionDir, cleanup, err := ctr.MountSnapshotView(ctx, m.service, info.Snapshotter, target.SnapshotID)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	defer cleanup()
+	changes, truncated, err = ctr.DirChanges(ctx, current.Dir, versionDir, maxVersionChangedPaths)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	return target, changes, truncated, nil
+}
+
+// rollbackTarget looks up version and the bot's container, refusing
+// containers labelled for another bot.
+func (m *Manager) rollbackTarget(ctx context.Context, userID string, version int) (*VersionInfo, containers.Container, error) {
+	versions, err := m.ListVersions(ctx, userID)
+	if err != nil {
+		return nil, containers.Container{}, err
+	}
+	var target *VersionInfo
+	for i := range versions {
+		if versions[i].Version == version {
+			target = &versions[i]
+			break
+		}
+	}
+	if target == nil {
+		return nil, containers.Container{}, pgx.ErrNoRows
+	}
+
+	container, err := m.service.GetContainer(ctx, m.containerID(userID))
+	if err != nil {
+		return nil, containers.Container{}, err
+	}
+	info, err := container.Info(ctx)
+	if err != nil {
+		return nil, containers.Container{}, err
+	}
+	if err := checkBotLabel(info.Labels, userID); err != nil {
+		return nil, containers.Container{}, err
+	}
+	return target, info, nil
+}
+
+func checkBotLabel(labels map[string]string, botID string) error {
+	if labels[BotLabelKey] != botID {
+		return fmt.Errorf("%w: labelled %q, want %q", ErrBotLabelMismatch, labels[BotLabelKey], botID)
+	}
+	return nil
+}
+
+// DeleteVersion removes a single version: its committed snapshot and the
+// version and snapshot records. Versions the active snapshot derives from, or
+// that other snapshots were prepared from, are refused with ErrVersionInUse.
+func (m *Manager) DeleteVersion(ctx context.Context, userID string, version int) error {
+	if m.db == nil || m.queries == nil {
+		return fmt.Errorf("db is not configured")
+	}
+	if err := validateBotID(userID); err != nil {
+		return err
+	}
+
+	containerID := m.containerID(userID)
+	snapshotID, err := m.queries.GetVersionSnapshotID(ctx, dbsqlc.GetVersionSnapshotIDParams{
+		ContainerID: containerID,
+		Version:     int32(version),
+	})
+	if err != nil {
+		return err
+	}
+
+	container, err := m.service.GetContainer(ctx, containerID)
+	if err != nil {
+		return err
+	}
+	info, err := container.Info(ctx)
+	if err != nil {
+		return err
+	}
+
+	inUse, err := m.snapshotIsAncestor(ctx, info.Snapshotter, info.SnapshotKey, snapshotID)
+	if err != nil {
+		return err
+	}
+	if inUse {
+		return fmt.Errorf("%w: active snapshot derives from version %d", ErrVersionInUse, version)
+	}
+
+	if err := m.service.RemoveSnapshot(ctx, info.Snapshotter, snapshotID); err != nil {
+		switch {
+		case errdefs.IsFailedPrecondition(err):
+			return fmt.Errorf("%w: %v", ErrVersionInUse, err)
+		case !errdefs.IsNotFound(err):
+			return err
+		}
+	}
+
+	tx, err := m.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := m.queries.WithTx(tx)
+	if err := qtx.DeleteVersion(ctx, dbsqlc.DeleteVersionParams{
+		ContainerID: containerID,
+		Version:     int32(version),
+	}); err != nil {
+		return err
+	}
+	if err := qtx.DeleteSnapshot(ctx, snapshotID); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	return m.insertEvent(ctx, containerID, "version_delete", map[string]any{
+		"snapshot_id": snapshotID,
+		"version":     version,
+	})
+}
+
+// PruneResult reports what PruneVersions did.
+type PruneResult struct {
+	// Deleted counts the versions removed.
+	Deleted int
+	// InUse lists, newest first, versions older than the newest keep that
+	// could not be removed because the active snapshot derives from them or
+	// other snapshots were prepared from them. Committed snapshots cannot be
+	// reparented, so in a history without rollbacks every version is in use
+	// and their space is only reclaimed once the container is recreated.
+	InUse []int
+}
+
+// PruneVersions deletes all but the newest keep versions. Labeled versions
+// are always kept; versions still in use are kept and reported in
+// PruneResult.InUse. Newer versions are tried first, so a branch left behind
+// by a rollback goes in one call.
+func (m *Manager) PruneVersions(ctx context.Context, userID string, keep int) (PruneResult, error) {
+	if keep < 0 {
+		return PruneResult{}, fmt.Errorf("%w: keep must not be negative", ctr.ErrInvalidArgument)
+	}
+	versions, err := m.ListVersions(ctx, userID)
+	if err != nil || len(versions) <= keep {
+		return PruneResult{}, err
+	}
+	container, err := m.service.GetContainer(ctx, m.containerID(userID))
+	if err != nil {
+		return PruneResult{}, err
+	}
+	info, err := container.Info(ctx)
+	if err != nil {
+		return PruneResult{}, err
+	}
+	// Skipping the active chain up front saves a walk of it per version.
+	active, err := m.snapshotAncestors(ctx, info.Snapshotter, info.SnapshotKey)
+	if err != nil {
+		return PruneResult{}, err
+	}
+	return pruneVersions(versions, keep, active, func(version int) error {
+		return m.DeleteVersion(ctx, userID, version)
+	})
+}
+
+// pruneVersions calls del for every version older than the newest keep,
+// newest first, skipping labeled ones. Versions whose snapshot is in active,
+// or that del reports as in use, are skipped and listed in InUse.
+func pruneVersions(versions []VersionInfo, keep int, active map[string]struct{}, del func(version int) error) (PruneResult, error) {
+	var result PruneResult
+	for i := len(versions) - keep - 1; i >= 0; i-- {
+		if versions[i].Label != "" {
+			continue
+		}
+		if _, ok := active[versions[i].SnapshotID]; ok {
+			result.InUse = append(result.InUse, versions[i].Version)
+			continue
+		}
+		if err := del(versions[i].Version); err != nil {
+			if errors.Is(err, ErrVersionInUse) {
+				result.InUse = append(result.InUse, versions[i].Version)
+				continue
+			}
+			return result, err
+		}
+		result.Deleted++
+	}
+	return result, nil
+}
+
+// autoPruneVersions applies MCPConfig.KeepVersions after a new version.
+// Failures are logged only, since the new version is already recorded.
+func (m *Manager) autoPruneVersions(ctx context.Context, userID string) {
+	if m.cfg.KeepVersions <= 0 {
+		return
+	}
+	result, err := m.PruneVersions(ctx, userID, m.cfg.KeepVersions)
+	if err != nil {
+		m.logger.Warn("auto-prune versions failed", slog.String("bot_id", userID), slog.Any("error", err))
+	}
+	if result.Deleted > 0 {
+		m.logger.Info("pruned old versions", slog.String("bot_id", userID), slog.Int("deleted", result.Deleted))
+	}
+	if len(result.InUse) > 0 {
+		m.logger.Info("old versions kept in use", slog.String("bot_id", userID), slog.Any("versions", result.InUse))
+	}
+}
+
+// snapshotAncestors returns key and every snapshot in its parent chain.
+func (m *Manager) snapshotAncestors(ctx context.Context, snapshotter, key string) (map[string]struct{}, error) {
+	chain := map[string]struct{}{}
+	for key != "" {
+		if _, ok := chain[key]; ok {
+			break
+		}
+		chain[key] = struct{}{}
+		info, err := m.service.StatSnapshot(ctx, snapshotter, key)
+		if err != nil {
+			if errdefs.IsNotFound(err) {
+				break
+			}
+			return nil, err
+		}
+		key = info.Parent
+	}
+	return chain, nil
+}
+
+// snapshotIsAncestor reports whether target appears in the parent chain of key.
+func (m *Manager) snapshotIsAncestor(ctx context.Context, snapshotter, key, target string) (bool, error) {
+	seen := map[string]struct{}{}
+	for key != "" {
+		if key == target {
+			return true, nil
+		}
+		if _, ok := seen[key]; ok {
+			return false, nil
+		}
+		seen[key] = struct{}{}
+		info, err := m.service.StatSnapshot(ctx, snapshotter, key)
+		if err != nil {
+			if errdefs.IsNotFound(err) {
+				return false, nil
+			}
+			return false, err
+		}
+		key = info.Parent
+	}
+	return false, nil
+}
+
+// VersionByLabel returns the number of the version labeled label, or
+// pgx.ErrNoRows if there is none.
+func (m *Manager) VersionByLabel(ctx context.Context, userID, label string) (int, error) {
+	if m.db == nil || m.queries == nil {
+		return 0, fmt.Errorf("db is not configured")
+	}
+	if err := validateBotID(userID); err != nil {
+		return 0, err
+	}
+	version, err := m.queries.GetVersionByLabel(ctx, dbsqlc.GetVersionByLabelParams{
+		ContainerID: m.containerID(userID),
+		Label:       pgtype.Text{String: strings.TrimSpace(label), Valid: true},
+	})
+	return int(version), err
+}
+
+// validateVersionLabel accepts the empty label and short printable names
+// that cannot be mistaken for a version number.
+func validateVersionLabel(label string) error {
+	if label == "" {
+		return nil
+	}
+	if len(label) > maxVersionLabelLength {
+		return fmt.Errorf("%w: version label is longer than %d bytes", ctr.ErrInvalidArgument, maxVersionLabelLength)
+	}
+	if _, err := strconv.Atoi(label); err == nil {
+		return fmt.Errorf("%w: version label must not be a number", ctr.ErrInvalidArgument)
+	}
+	for _, r := range label {
+		if !unicode.IsPrint(r) || r == '/' {
+			return fmt.Errorf("%w: version label must be printable and must not contain '/'", ctr.ErrInvalidArgument)
+		}
+	}
+	return nil
+}
+
 func (m *Manager) VersionSnapshotID(ctx context.Context, userID string, version int) (string, error) {
 	if m.db == nil || m.queries == nil {
 		return "", fmt.Errorf("db is not configured")
@@ -260,7 +688,7 @@ func (m *Manager) VersionSnapshotID(ctx context.Context, userID string, version
 }
 
 func (m *Manager) safeStopTask(ctx context.Context, containerID string) error {
-	err := m.service.StopTask(ctx, containerID, &ctr.StopTaskOptions{
+	_, err := m.service.StopTask(ctx, containerID, &ctr.StopTaskOptions{
 		Timeout: 10 * time.Second,
 		Force:   true,
 	})
@@ -286,10 +714,7 @@ func (m *Manager) ensureDBRecords(ctx context.Context, botID, containerID, runti
 		return pgtype.UUID{}, err
 	}
 
-	containerPath := m.cfg.DataMount
-	if containerPath == "" {
-		containerPath = config.DefaultDataMount
-	}
+	containerPath := m.cfg.DataMountFor(imageRef)
 
 	if err := m.queries.UpsertContainer(ctx, dbsqlc.UpsertContainerParams{
 		BotID:         botUUID,
@@ -310,7 +735,7 @@ func (m *Manager) ensureDBRecords(ctx context.Context, botID, containerID, runti
 	return botUUID, nil
 }
 
-func (m *Manager) insertVersion(ctx context.Context, containerID, snapshotID, snapshotter string) (string, int, time.Time, error) {
+func (m *Manager) insertVersion(ctx context.Context, containerID, snapshotID, snapshotter, label string) (string, int, time.Time, error) {
 	tx, err := m.db.Begin(ctx)
 	if err != nil {
 		return "", 0, time.Time{}, err
@@ -340,6 +765,7 @@ func (m *Manager) insertVersion(ctx context.Context, containerID, snapshotID, sn
 		ContainerID: containerID,
 		SnapshotID:  snapshotID,
 		Version:     version,
+		Label:       pgtype.Text{String: label, Valid: label != ""},
 	})
 	if err != nil {
 		return "", 0, time.Time{}, err
@@ -369,4 +795,3 @@ func (m *Manager) insertEvent(ctx context.Context, containerID, eventType string
 		Payload:     b,
 	})
 }
-
@@ -0,0 +1,204 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+
+	containerd "github.com/containerd/containerd/v2/client"
+	"github.com/containerd/containerd/v2/core/containers"
+	"github.com/containerd/containerd/v2/core/snapshots"
+	"github.com/containerd/errdefs"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	ctr "github.com/memohai/memoh/internal/containerd"
+	dbsqlc "github.com/memohai/memoh/internal/db/sqlc"
+)
+
+func TestValidateVersionLabel(t *testing.T) {
+	for _, label := range []string{"", "before refactor", "v1.2", "release-2026"} {
+		if err := validateVersionLabel(label); err != nil {
+			t.Errorf("validateVersionLabel(%q) = %v, want nil", label, err)
+		}
+	}
+	for _, label := range []string{"12", "a/b", "tab\there", strings.Repeat("x", maxVersionLabelLength+1)} {
+		if err := validateVersionLabel(label); !errors.Is(err, ctr.ErrInvalidArgument) {
+			t.Errorf("validateVersionLabel(%q) = %v, want ErrInvalidArgument", label, err)
+		}
+	}
+}
+
+func TestPruneVersions(t *testing.T) {
+	versions := []VersionInfo{
+		{Version: 1, SnapshotID: "s1"},
+		{Version: 2, SnapshotID: "s2"},
+		{Version: 3, SnapshotID: "s3"},
+		{Version: 4, SnapshotID: "s4"},
+		{Version: 5, SnapshotID: "s5"},
+	}
+	labeled := slices.Clone(versions)
+	labeled[2].Label = "before refactor"
+	failure := errors.New("boom")
+	cases := []struct {
+		name        string
+		versions    []VersionInfo
+		keep        int
+		active      []string
+		errs        map[int]error
+		wantDeleted []int
+		wantInUse   []int
+		wantErr     error
+	}{
+		{name: "keep newest", keep: 2, wantDeleted: []int{3, 2, 1}},
+		{name: "keep all", keep: 5},
+		{name: "keep more than exist", keep: 10},
+		{name: "keep none", keep: 0, wantDeleted: []int{5, 4, 3, 2, 1}},
+		{name: "active chain", keep: 1, active: []string{"s1", "s3"}, wantDeleted: []int{4, 2}, wantInUse: []int{3, 1}},
+		{name: "in use", keep: 1, errs: map[int]error{3: ErrVersionInUse}, wantDeleted: []int{4, 2, 1}, wantInUse: []int{3}},
+		{name: "failure stops", keep: 1, errs: map[int]error{3: failure}, wantDeleted: []int{4}, wantErr: failure},
+		{name: "labeled kept", versions: labeled, keep: 1, wantDeleted: []int{4, 2, 1}},
+		{name: "labeled kept with keep none", versions: labeled, keep: 0, wantDeleted: []int{5, 4, 2, 1}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			active := map[string]struct{}{}
+			for _, id := range tc.active {
+				active[id] = struct{}{}
+			}
+			if tc.versions == nil {
+				tc.versions = versions
+			}
+			var deleted []int
+			result, err := pruneVersions(tc.versions, tc.keep, active, func(version int) error {
+				if err := tc.errs[version]; err != nil {
+					return err
+				}
+				deleted = append(deleted, version)
+				return nil
+			})
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tc.wantErr)
+			}
+			if result.Deleted != len(tc.wantDeleted) || !slices.Equal(deleted, tc.wantDeleted) {
+				t.Errorf("deleted %d %v, want %v", result.Deleted, deleted, tc.wantDeleted)
+			}
+			if !slices.Equal(result.InUse, tc.wantInUse) {
+				t.Errorf("in use %v, want %v", result.InUse, tc.wantInUse)
+			}
+		})
+	}
+}
+
+// versionRows yields container_versions rows for the given snapshot IDs,
+// numbered from 1.
+type versionRows struct {
+	pgx.Rows
+	snapshots []string
+	pos       int
+}
+
+func (r *versionRows) Next() bool {
+	r.pos++
+	return r.pos <= len(r.snapshots)
+}
+
+func (r *versionRows) Scan(dest ...any) error {
+	*dest[2].(*string) = r.snapshots[r.pos-1]
+	*dest[3].(*int32) = int32(r.pos)
+	return nil
+}
+
+func (r *versionRows) Close()     {}
+func (r *versionRows) Err() error { return nil }
+
+type versionsDB struct {
+	snapshots []string
+}
+
+func (d *versionsDB) Exec(context.Context, string, ...any) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, errors.New("not implemented")
+}
+
+func (d *versionsDB) Query(context.Context, string, ...any) (pgx.Rows, error) {
+	return &versionRows{snapshots: d.snapshots}, nil
+}
+
+func (d *versionsDB) QueryRow(context.Context, string, ...any) pgx.Row {
+	return nil
+}
+
+type snapshotContainer struct {
+	containerd.Container
+	key string
+}
+
+func (c *snapshotContainer) Info(context.Context, ...containerd.InfoOpts) (containers.Container, error) {
+	return containers.Container{Snapshotter: "overlayfs", SnapshotKey: c.key}, nil
+}
+
+// chainService serves a snapshot parent chain and records removals.
+type chainService struct {
+	ctr.Service
+	active  string
+	parents map[string]string
+	removed []string
+}
+
+func (s *chainService) GetContainer(context.Context, string) (containerd.Container, error) {
+	return &snapshotContainer{key: s.active}, nil
+}
+
+func (s *chainService) StatSnapshot(_ context.Context, _, key string) (snapshots.Info, error) {
+	parent, ok := s.parents[key]
+	if !ok {
+		return snapshots.Info{}, errdefs.ErrNotFound
+	}
+	return snapshots.Info{Name: key, Parent: parent}, nil
+}
+
+func (s *chainService) RemoveSnapshot(_ context.Context, _, key string) error {
+	s.removed = append(s.removed, key)
+	return nil
+}
+
+func TestPruneVersionsReportsActiveChain(t *testing.T) {
+	const botID = "00000000-0000-0000-0000-000000000001"
+	// The pool is never connected to; it only marks the DB as configured.
+	pool, err := pgxpool.New(context.Background(), "postgres://memoh@127.0.0.1:1/memoh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	svc := &chainService{
+		active: "active",
+		parents: map[string]string{
+			"active": "s5", "s5": "s4", "s4": "s3", "s3": "s2", "s2": "s1", "s1": "image",
+			"image": "",
+		},
+	}
+	m := &Manager{
+		service:     svc,
+		db:          pool,
+		queries:     dbsqlc.New(&versionsDB{snapshots: []string{"s1", "s2", "s3", "s4", "s5"}}),
+		logger:      slog.New(slog.NewTextHandler(io.Discard, nil)),
+		containerID: func(id string) string { return ContainerPrefix + id },
+	}
+
+	result, err := m.PruneVersions(context.Background(), botID, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Deleted != 0 || len(svc.removed) != 0 {
+		t.Errorf("deleted %d, removed snapshots %v; want none", result.Deleted, svc.removed)
+	}
+	if want := []int{3, 2, 1}; !slices.Equal(result.InUse, want) {
+		t.Errorf("in use %v, want %v", result.InUse, want)
+	}
+}
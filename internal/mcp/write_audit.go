@@ -0,0 +1,109 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/memohai/memoh/internal/db"
+	dbsqlc "github.com/memohai/memoh/internal/db/sqlc"
+)
+
+// Write audit actions.
+const (
+	WriteAuditWrite  = "write"
+	WriteAuditEdit   = "edit"
+	WriteAuditUpload = "upload"
+)
+
+// WriteAuditEntry attributes a change to a file in a bot's data mount.
+// Metadata is whatever the caller passed along (agent, run id, reason, ...).
+type WriteAuditEntry struct {
+	BotID    string
+	Path     string
+	Action   string
+	Metadata map[string]any
+}
+
+// WriteAuditRecord is a stored WriteAuditEntry.
+type WriteAuditRecord struct {
+	ID        string         `json:"id"`
+	Path      string         `json:"path"`
+	Action    string         `json:"action"`
+	Metadata  map[string]any `json:"metadata"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// WriteAuditor records file changes for later review.
+type WriteAuditor interface {
+	RecordWrite(ctx context.Context, entry WriteAuditEntry) error
+}
+
+// WriteAuditLog is the Postgres-backed WriteAuditor. A nil log records nothing.
+type WriteAuditLog struct {
+	queries *dbsqlc.Queries
+}
+
+// NewWriteAuditLog returns nil when queries is nil.
+func NewWriteAuditLog(queries *dbsqlc.Queries) *WriteAuditLog {
+	if queries == nil {
+		return nil
+	}
+	return &WriteAuditLog{queries: queries}
+}
+
+func (l *WriteAuditLog) RecordWrite(ctx context.Context, entry WriteAuditEntry) error {
+	if l == nil {
+		return nil
+	}
+	metadata := entry.Metadata
+	if metadata == nil {
+		metadata = map[string]any{}
+	}
+	payload, err := json.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("encode write audit metadata: %w", err)
+	}
+	botUUID, err := db.ParseUUID(entry.BotID)
+	if err != nil {
+		return err
+	}
+	return l.queries.InsertFSWriteAudit(ctx, dbsqlc.InsertFSWriteAuditParams{
+		BotID:    botUUID,
+		Path:     entry.Path,
+		Action:   entry.Action,
+		Metadata: payload,
+	})
+}
+
+// List returns the newest audit records for a bot, optionally for one path.
+func (l *WriteAuditLog) List(ctx context.Context, botID, path string, limit int) ([]WriteAuditRecord, error) {
+	if l == nil {
+		return nil, fmt.Errorf("write audit log is not configured")
+	}
+	botUUID, err := db.ParseUUID(botID)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := l.queries.ListFSWriteAudit(ctx, dbsqlc.ListFSWriteAuditParams{
+		BotID:      botUUID,
+		Path:       path,
+		MaxResults: int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]WriteAuditRecord, 0, len(rows))
+	for _, row := range rows {
+		record := WriteAuditRecord{ID: row.ID.String(), Path: row.Path, Action: row.Action}
+		if len(row.Metadata) > 0 {
+			_ = json.Unmarshal(row.Metadata, &record.Metadata)
+		}
+		if row.CreatedAt.Valid {
+			record.CreatedAt = row.CreatedAt.Time
+		}
+		out = append(out, record)
+	}
+	return out, nil
+}
@@ -0,0 +1,168 @@
+package mcp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/memohai/memoh/internal/config"
+)
+
+// ErrWriteNotAllowed is matched by errors returned from WritePolicy.Check.
+var ErrWriteNotAllowed = errors.New("write not allowed by policy")
+
+// WritePolicyError describes why a write into a bot's data mount was refused.
+type WritePolicyError struct {
+	Path        string
+	Extension   string
+	ContentType string
+	Reason      string
+}
+
+func (e *WritePolicyError) Error() string {
+	return fmt.Sprintf("write to %s not allowed: %s", e.Path, e.Reason)
+}
+
+func (e *WritePolicyError) Is(target error) bool {
+	return target == ErrWriteNotAllowed
+}
+
+// WritePolicy restricts which files may be written into bot containers by
+// extension, sniffed content type and size. A nil policy allows everything.
+type WritePolicy struct {
+	allowed      map[string]struct{}
+	denied       map[string]struct{}
+	deniedTypes  []string
+	maxSizeByExt map[string]int64
+}
+
+// NewWritePolicy builds a policy from config. It returns nil when the config
+// sets no restrictions, which keeps writes permissive.
+func NewWritePolicy(cfg config.WritePolicyConfig) *WritePolicy {
+	p := &WritePolicy{
+		allowed:      extensionSet(cfg.AllowedExtensions),
+		denied:       extensionSet(cfg.DeniedExtensions),
+		maxSizeByExt: map[string]int64{},
+	}
+	for _, t := range cfg.DeniedContentTypes {
+		if t = strings.ToLower(strings.TrimSpace(t)); t != "" {
+			p.deniedTypes = append(p.deniedTypes, t)
+		}
+	}
+	for ext, size := range cfg.MaxSizeByExtension {
+		if size <= 0 {
+			continue
+		}
+		if strings.TrimSpace(ext) == "*" {
+			p.maxSizeByExt["*"] = size
+			continue
+		}
+		p.maxSizeByExt[normalizeExtension(ext)] = size
+	}
+	if len(p.allowed) == 0 && len(p.denied) == 0 && len(p.deniedTypes) == 0 && len(p.maxSizeByExt) == 0 {
+		return nil
+	}
+	return p
+}
+
+// Check validates a write of size bytes to filePath. head should hold the
+// first bytes of the content (up to 512 are used) so the content type can be
+// sniffed instead of trusting the extension.
+func (p *WritePolicy) Check(filePath string, head []byte, size int64) error {
+	if p == nil {
+		return nil
+	}
+	ext := normalizeExtension(path.Ext(filePath))
+	deny := func(contentType, reason string) error {
+		return &WritePolicyError{Path: filePath, Extension: ext, ContentType: contentType, Reason: reason}
+	}
+
+	if len(p.allowed) > 0 {
+		if _, ok := p.allowed[ext]; !ok {
+			return deny("", fmt.Sprintf("extension %q is not allowed", ext))
+		}
+	}
+	if _, ok := p.denied[ext]; ok {
+		return deny("", fmt.Sprintf("extension %q is denied", ext))
+	}
+
+	limit, ok := p.maxSizeByExt[ext]
+	if !ok {
+		limit = p.maxSizeByExt["*"]
+	}
+	if limit > 0 && size > limit {
+		return deny("", fmt.Sprintf("size %d exceeds the %d byte limit for %q files", size, limit, ext))
+	}
+
+	if len(p.deniedTypes) > 0 && len(head) > 0 {
+		contentType := SniffContentType(head)
+		for _, denied := range p.deniedTypes {
+			if contentTypeMatches(contentType, denied) {
+				return deny(contentType, fmt.Sprintf("content type %s is denied", contentType))
+			}
+		}
+	}
+	return nil
+}
+
+// SniffContentType detects the MIME type of content from its first bytes.
+// On top of http.DetectContentType it recognizes executables and scripts,
+// which the standard sniffer reports as generic binary or text.
+func SniffContentType(head []byte) string {
+	if len(head) > 512 {
+		head = head[:512]
+	}
+	switch {
+	case bytes.HasPrefix(head, []byte("\x7fELF")):
+		return "application/x-executable"
+	case bytes.HasPrefix(head, []byte("MZ")):
+		return "application/vnd.microsoft.portable-executable"
+	case bytes.HasPrefix(head, []byte{0xcf, 0xfa, 0xed, 0xfe}),
+		bytes.HasPrefix(head, []byte{0xce, 0xfa, 0xed, 0xfe}),
+		bytes.HasPrefix(head, []byte{0xfe, 0xed, 0xfa, 0xcf}),
+		bytes.HasPrefix(head, []byte{0xfe, 0xed, 0xfa, 0xce}):
+		return "application/x-mach-binary"
+	case bytes.HasPrefix(head, []byte("#!")):
+		return "text/x-shellscript"
+	}
+	return http.DetectContentType(head)
+}
+
+// contentTypeMatches reports whether contentType matches pattern, which is a
+// full MIME type ("application/x-executable") or a prefix ending in "/"
+// ("video/"). Parameters such as charset are ignored.
+func contentTypeMatches(contentType, pattern string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.TrimSpace(mediaType)
+	if strings.HasSuffix(pattern, "/") {
+		return strings.HasPrefix(mediaType, pattern)
+	}
+	return mediaType == pattern
+}
+
+func extensionSet(exts []string) map[string]struct{} {
+	set := map[string]struct{}{}
+	for _, ext := range exts {
+		if strings.TrimSpace(ext) == "" {
+			continue
+		}
+		set[normalizeExtension(ext)] = struct{}{}
+	}
+	return set
+}
+
+// normalizeExtension lower-cases ext and ensures a leading dot. Files without
+// an extension map to "".
+func normalizeExtension(ext string) string {
+	ext = strings.ToLower(strings.TrimSpace(ext))
+	if ext == "" || ext == "." {
+		return ""
+	}
+	if !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	return ext
+}
@@ -0,0 +1,55 @@
+package mcp
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/memohai/memoh/internal/config"
+)
+
+func TestNewWritePolicy_EmptyIsPermissive(t *testing.T) {
+	policy := NewWritePolicy(config.WritePolicyConfig{})
+	if policy != nil {
+		t.Fatal("empty config should not build a policy")
+	}
+	if err := policy.Check("run.exe", []byte("MZ\x90\x00"), 4); err != nil {
+		t.Fatalf("nil policy should allow writes: %v", err)
+	}
+}
+
+func TestWritePolicy_Check(t *testing.T) {
+	policy := NewWritePolicy(config.WritePolicyConfig{
+		AllowedExtensions:  []string{"md", ".TXT", ".bin", "."},
+		DeniedExtensions:   []string{".bin"},
+		DeniedContentTypes: []string{"application/x-executable", "image/"},
+		MaxSizeByExtension: map[string]int64{".md": 10, "*": 100},
+	})
+
+	cases := []struct {
+		name    string
+		path    string
+		content string
+		allowed bool
+	}{
+		{name: "allowed extension", path: "notes/readme.md", content: "# hi", allowed: true},
+		{name: "case insensitive", path: "LOG.txt", content: "ok", allowed: true},
+		{name: "no extension", path: "Makefile", content: "all:", allowed: true},
+		{name: "not allowed", path: "a.sh", content: "echo", allowed: false},
+		{name: "denied wins", path: "a.bin", content: "x", allowed: false},
+		{name: "per extension size", path: "big.md", content: "01234567890", allowed: false},
+		{name: "default size", path: "big.txt", content: string(make([]byte, 101)), allowed: false},
+		{name: "sniffed executable", path: "innocent.txt", content: "\x7fELF\x02\x01\x01", allowed: false},
+		{name: "sniffed type prefix", path: "pic.txt", content: "\x89PNG\r\n\x1a\n", allowed: false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := policy.Check(tc.path, []byte(tc.content), int64(len(tc.content)))
+			if tc.allowed && err != nil {
+				t.Fatalf("expected allowed, got %v", err)
+			}
+			if !tc.allowed && !errors.Is(err, ErrWriteNotAllowed) {
+				t.Fatalf("expected ErrWriteNotAllowed, got %v", err)
+			}
+		})
+	}
+}
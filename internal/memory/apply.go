@@ -0,0 +1,197 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// defaultAddActionBackoff is the wait before the first retry of a failed
+// action, doubled for each further one up to maxAddActionBackoff.
+const (
+	defaultAddActionBackoff = 200 * time.Millisecond
+	maxAddActionBackoff     = 5 * time.Second
+)
+
+// errUnknownAction marks decided actions with an unsupported event; they are
+// not retried.
+var errUnknownAction = errors.New("unknown action")
+
+// ActionError describes a single decided action that could not be applied.
+type ActionError struct {
+	Index int
+	Event string
+	ID    string
+	Err   error
+}
+
+func (e ActionError) Error() string {
+	if e.ID != "" {
+		return fmt.Sprintf("action %d (%s %s): %v", e.Index, e.Event, e.ID, e.Err)
+	}
+	return fmt.Sprintf("action %d (%s): %v", e.Index, e.Event, e.Err)
+}
+
+// PartialApplyError is returned by Add when some decided actions failed while
+// others were applied. The applied ones are persisted and returned alongside.
+type PartialApplyError struct {
+	Total  int
+	Failed []ActionError
+}
+
+func (e *PartialApplyError) Error() string {
+	parts := make([]string, 0, len(e.Failed))
+	for _, failed := range e.Failed {
+		parts = append(parts, failed.Error())
+	}
+	return fmt.Sprintf("applied %d of %d memory actions; failed: %s",
+		e.Total-len(e.Failed), e.Total, strings.Join(parts, "; "))
+}
+
+func (e *PartialApplyError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Failed))
+	for _, failed := range e.Failed {
+		errs = append(errs, failed.Err)
+	}
+	return errs
+}
+
+// SetAddActionRetries sets how many times Add retries a failed action before
+// recording it as failed. Negative values are treated as zero.
+func (s *Service) SetAddActionRetries(retries int) {
+	if retries < 0 {
+		retries = 0
+	}
+	s.addActionRetries = retries
+}
+
+// applyActions applies every action, retrying failures up to the configured
+// count with a doubling backoff between attempts. A failing action does not stop the rest; failures are collected
+// into a PartialApplyError returned with the successfully applied items.
+func (s *Service) applyActions(ctx context.Context, actions []DecisionAction, apply func(context.Context, DecisionAction) (MemoryItem, error)) ([]MemoryItem, error) {
+	results := make([]MemoryItem, 0, len(actions))
+	var failed []ActionError
+	for idx, action := range actions {
+		var (
+			item MemoryItem
+			err  error
+		)
+		for attempt := 0; attempt <= s.addActionRetries; attempt++ {
+			if attempt > 0 && !sleepContext(ctx, s.addActionBackoffFor(attempt)) {
+				break
+			}
+			item, err = apply(ctx, action)
+			if err == nil || errors.Is(err, errUnknownAction) {
+				break
+			}
+		}
+		if err != nil {
+			s.logger.WarnContext(ctx, "apply memory action failed",
+				slog.Int("index", idx),
+				slog.String("event", action.Event),
+				slog.String("id", action.ID),
+				slog.Any("error", err),
+			)
+			failed = append(failed, ActionError{Index: idx, Event: strings.ToUpper(action.Event), ID: action.ID, Err: err})
+			continue
+		}
+		results = append(results, item)
+	}
+	if len(failed) > 0 {
+		return results, &PartialApplyError{Total: len(actions), Failed: failed}
+	}
+	return results, nil
+}
+
+// addActionBackoffFor returns the wait before retry attempt (1-based).
+func (s *Service) addActionBackoffFor(attempt int) time.Duration {
+	backoff := s.addActionBackoff
+	if backoff <= 0 {
+		backoff = defaultAddActionBackoff
+	}
+	for i := 1; i < attempt && backoff < maxAddActionBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, maxAddActionBackoff)
+}
+
+// sleepContext waits for d and reports whether ctx is still live.
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// applyAction applies a single decided action and tags the result with its event.
+func (s *Service) applyAction(ctx context.Context, action DecisionAction, filters map[string]any, metadata map[string]any, origin *MemoryOrigin, embeddingEnabled bool) (MemoryItem, error) {
+	switch strings.ToUpper(action.Event) {
+	case "ADD":
+		item, err := s.applyAdd(ctx, action.Text, filters, metadata, origin, embeddingEnabled)
+		if err != nil {
+			return MemoryItem{}, err
+		}
+		item.Metadata = mergeMetadata(item.Metadata, map[string]any{
+			"event": "ADD",
+		})
+		return item, nil
+	case "UPDATE":
+		item, err := s.applyUpdate(ctx, action.ID, action.Text, filters, metadata, origin, embeddingEnabled)
+		if err != nil {
+			return MemoryItem{}, err
+		}
+		item.Metadata = mergeMetadata(item.Metadata, map[string]any{
+			"event":           "UPDATE",
+			"previous_memory": action.OldMemory,
+		})
+		return item, nil
+	case "DELETE":
+		item, err := s.applyDelete(ctx, action.ID)
+		if err != nil {
+			return MemoryItem{}, err
+		}
+		item.Metadata = mergeMetadata(item.Metadata, map[string]any{
+			"event": "DELETE",
+		})
+		return item, nil
+	default:
+		return MemoryItem{}, fmt.Errorf("%w: %s", errUnknownAction, action.Event)
+	}
+}
+
+// AddSummary counts the memory changes made by one Add call.
+type AddSummary struct {
+	Added   int `json:"added"`
+	Updated int `json:"updated"`
+	Deleted int `json:"deleted"`
+	Failed  int `json:"failed,omitempty"`
+}
+
+// SummarizeAdd counts the events tagged on Add results. When err is a
+// PartialApplyError its failed actions are counted too.
+func SummarizeAdd(resp SearchResponse, err error) AddSummary {
+	var summary AddSummary
+	for _, item := range resp.Results {
+		event, _ := item.Metadata["event"].(string)
+		switch event {
+		case "ADD":
+			summary.Added++
+		case "UPDATE":
+			summary.Updated++
+		case "DELETE":
+			summary.Deleted++
+		}
+	}
+	var partial *PartialApplyError
+	if errors.As(err, &partial) {
+		summary.Failed = len(partial.Failed)
+	}
+	return summary
+}
@@ -0,0 +1,154 @@
+package memory
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/memohai/memoh/internal/embeddings"
+)
+
+// modelNamer is implemented by embedders that can report the model they use.
+type modelNamer interface {
+	Model() string
+}
+
+type embeddingCacheKey struct {
+	model string
+	text  string
+}
+
+type embeddingCacheEntry struct {
+	key    embeddingCacheKey
+	vector []float32
+}
+
+// CachedEmbedder is an LRU cache in front of an Embedder, keyed by model and
+// input text. When the inner embedder reports a different model than before
+// the cache is flushed, so stale vectors are never served.
+type CachedEmbedder struct {
+	inner embeddings.Embedder
+	size  int
+
+	mu      sync.Mutex
+	model   string
+	entries map[embeddingCacheKey]*list.Element
+	order   *list.List
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+// NewCachedEmbedder wraps inner with an LRU cache holding up to size vectors.
+// A size <= 0 returns inner unchanged.
+func NewCachedEmbedder(inner embeddings.Embedder, size int) embeddings.Embedder {
+	if inner == nil || size <= 0 {
+		return inner
+	}
+	return &CachedEmbedder{
+		inner:   inner,
+		size:    size,
+		entries: make(map[embeddingCacheKey]*list.Element, size),
+		order:   list.New(),
+	}
+}
+
+func (c *CachedEmbedder) Dimensions() int {
+	return c.inner.Dimensions()
+}
+
+func (c *CachedEmbedder) Embed(ctx context.Context, input string) ([]float32, error) {
+	key := embeddingCacheKey{model: c.innerModel(), text: input}
+	if vector, ok := c.get(key); ok {
+		c.hits.Add(1)
+		return vector, nil
+	}
+	c.misses.Add(1)
+	vector, err := c.inner.Embed(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	c.put(key, vector)
+	return cloneVector(vector), nil
+}
+
+// Stats returns the number of cache hits and misses so far.
+func (c *CachedEmbedder) Stats() (hits, misses uint64) {
+	return c.hits.Load(), c.misses.Load()
+}
+
+// HitRate returns the fraction of Embed calls served from the cache.
+func (c *CachedEmbedder) HitRate() float64 {
+	hits, misses := c.Stats()
+	if hits+misses == 0 {
+		return 0
+	}
+	return float64(hits) / float64(hits+misses)
+}
+
+// Len returns the number of cached vectors.
+func (c *CachedEmbedder) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+func (c *CachedEmbedder) innerModel() string {
+	if namer, ok := c.inner.(modelNamer); ok {
+		return namer.Model()
+	}
+	return ""
+}
+
+func (c *CachedEmbedder) get(key embeddingCacheKey) ([]float32, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.syncModelLocked(key.model)
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return cloneVector(elem.Value.(*embeddingCacheEntry).vector), true
+}
+
+func (c *CachedEmbedder) put(key embeddingCacheKey, vector []float32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.syncModelLocked(key.model)
+	if key.model != c.model {
+		// The model changed while the vector was being computed.
+		return
+	}
+	if elem, ok := c.entries[key]; ok {
+		elem.Value.(*embeddingCacheEntry).vector = cloneVector(vector)
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&embeddingCacheEntry{key: key, vector: cloneVector(vector)})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*embeddingCacheEntry).key)
+	}
+}
+
+// syncModelLocked flushes the cache when the inner model changed.
+func (c *CachedEmbedder) syncModelLocked(model string) {
+	if model == c.model {
+		return
+	}
+	c.model = model
+	c.entries = make(map[embeddingCacheKey]*list.Element, c.size)
+	c.order.Init()
+}
+
+func cloneVector(vector []float32) []float32 {
+	if vector == nil {
+		return nil
+	}
+	out := make([]float32, len(vector))
+	copy(out, vector)
+	return out
+}
@@ -0,0 +1,70 @@
+package memory
+
+import (
+	"context"
+	"testing"
+)
+
+type countingEmbedder struct {
+	model string
+	calls int
+}
+
+func (e *countingEmbedder) Embed(ctx context.Context, input string) ([]float32, error) {
+	e.calls++
+	return []float32{float32(len(input)), float32(e.calls)}, nil
+}
+
+func (e *countingEmbedder) Dimensions() int { return 2 }
+
+func (e *countingEmbedder) Model() string { return e.model }
+
+func TestCachedEmbedder_HitsAndEviction(t *testing.T) {
+	ctx := context.Background()
+	inner := &countingEmbedder{model: "m1"}
+	cached := NewCachedEmbedder(inner, 2).(*CachedEmbedder)
+
+	first, _ := cached.Embed(ctx, "a")
+	again, _ := cached.Embed(ctx, "a")
+	if inner.calls != 1 || first[1] != again[1] {
+		t.Fatalf("expected cached vector, inner calls = %d", inner.calls)
+	}
+	again[0] = 42
+	if v, _ := cached.Embed(ctx, "a"); v[0] == 42 {
+		t.Fatal("cached vector must not be shared with callers")
+	}
+
+	cached.Embed(ctx, "bb")
+	cached.Embed(ctx, "ccc") // evicts "a"
+	if cached.Len() != 2 {
+		t.Fatalf("expected 2 cached entries, got %d", cached.Len())
+	}
+	cached.Embed(ctx, "a")
+	if inner.calls != 4 {
+		t.Fatalf("expected evicted entry to be re-embedded, inner calls = %d", inner.calls)
+	}
+
+	hits, misses := cached.Stats()
+	if hits != 2 || misses != 4 {
+		t.Fatalf("unexpected stats hits=%d misses=%d", hits, misses)
+	}
+	if rate := cached.HitRate(); rate < 0.33 || rate > 0.34 {
+		t.Fatalf("unexpected hit rate %f", rate)
+	}
+}
+
+func TestCachedEmbedder_ModelChangeInvalidates(t *testing.T) {
+	ctx := context.Background()
+	inner := &countingEmbedder{model: "m1"}
+	cached := NewCachedEmbedder(inner, 4)
+
+	cached.Embed(ctx, "a")
+	inner.model = "m2"
+	cached.Embed(ctx, "a")
+	if inner.calls != 2 {
+		t.Fatalf("expected re-embed after model change, inner calls = %d", inner.calls)
+	}
+	if cached.(*CachedEmbedder).Len() != 1 {
+		t.Fatal("expected cache to be flushed on model change")
+	}
+}
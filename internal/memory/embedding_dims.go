@@ -0,0 +1,116 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// embeddingDimKey is the payload key recording the dense vector dimension a
+// memory was embedded with.
+const embeddingDimKey = "embedding_dim"
+
+// EmbeddingDimensionError reports an attempt to mix embedding dimensions
+// within one bot's memories.
+type EmbeddingDimensionError struct {
+	BotID    string
+	Existing int
+	Incoming int
+}
+
+func (e *EmbeddingDimensionError) Error() string {
+	return fmt.Sprintf("bot %s memories are indexed with %d-dimension embeddings but the current embedding model produces %d; reindex the bot's memory (delete all memories, then POST /bots/%s/memory/rebuild) before switching models", e.BotID, e.Existing, e.Incoming, e.BotID)
+}
+
+// checkEmbeddingDimension verifies that dim matches the dimension already
+// used for the bot's memories. Bots without dense vectors accept any dimension.
+func (s *Service) checkEmbeddingDimension(ctx context.Context, filters map[string]any, dim int) error {
+	botID := filterBotID(filters)
+	if botID == "" || dim <= 0 {
+		return nil
+	}
+	existing, err := s.botEmbeddingDimension(ctx, botID)
+	if err != nil {
+		return err
+	}
+	if existing != 0 && existing != dim {
+		return &EmbeddingDimensionError{BotID: botID, Existing: existing, Incoming: dim}
+	}
+	return nil
+}
+
+// rememberEmbeddingDimension records the dimension a bot's memories were
+// written with.
+func (s *Service) rememberEmbeddingDimension(filters map[string]any, dim int) {
+	botID := filterBotID(filters)
+	if botID == "" || dim <= 0 {
+		return
+	}
+	s.dimMu.Lock()
+	if s.botDims == nil {
+		s.botDims = map[string]int{}
+	}
+	s.botDims[botID] = dim
+	s.dimMu.Unlock()
+}
+
+// botEmbeddingDimension returns the recorded dimension for a bot, loading it
+// from the store on first use. Zero means the bot has no dense vectors yet.
+func (s *Service) botEmbeddingDimension(ctx context.Context, botID string) (int, error) {
+	s.dimMu.Lock()
+	dim, ok := s.botDims[botID]
+	s.dimMu.Unlock()
+	if ok {
+		return dim, nil
+	}
+	if s.store == nil {
+		return 0, nil
+	}
+	points, err := s.store.List(ctx, 1, map[string]any{
+		"bot_id":        botID,
+		embeddingDimKey: map[string]any{"gte": 1},
+	}, false)
+	if err != nil {
+		return 0, err
+	}
+	if len(points) == 0 {
+		return 0, nil
+	}
+	dim = payloadInt(points[0].Payload[embeddingDimKey])
+	s.rememberEmbeddingDimension(map[string]any{"bot_id": botID}, dim)
+	return dim, nil
+}
+
+// forgetEmbeddingDimension drops the cached dimension so the next write can
+// establish a new one (e.g. after a bot's memories were wiped for reindex).
+func (s *Service) forgetEmbeddingDimension(botID string) {
+	botID = strings.TrimSpace(botID)
+	if botID == "" {
+		return
+	}
+	s.dimMu.Lock()
+	delete(s.botDims, botID)
+	s.dimMu.Unlock()
+}
+
+func filterBotID(filters map[string]any) string {
+	if filters == nil {
+		return ""
+	}
+	botID, _ := filters["bot_id"].(string)
+	return strings.TrimSpace(botID)
+}
+
+func payloadInt(value any) int {
+	switch typed := value.(type) {
+	case int:
+		return typed
+	case int64:
+		return int(typed)
+	case float64:
+		return int(typed)
+	case float32:
+		return int(typed)
+	}
+	return 0
+}
@@ -0,0 +1,149 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/qdrant/go-client/qdrant"
+)
+
+// embeddingPendingKey marks memories stored without a dense vector because
+// the embedding provider failed; ReembedPending fills them in later.
+const embeddingPendingKey = "embedding_pending"
+
+// EmbeddingFallback decides what happens when the text embedder fails.
+type EmbeddingFallback string
+
+const (
+	// EmbeddingFallbackFail returns the embedding error to the caller.
+	EmbeddingFallbackFail EmbeddingFallback = "fail"
+	// EmbeddingFallbackDegrade answers searches with keyword (BM25) results
+	// and stores adds with only their sparse vector, marked for re-embedding.
+	EmbeddingFallbackDegrade EmbeddingFallback = "degrade"
+)
+
+// ParseEmbeddingFallback parses a config value; empty means fail.
+func ParseEmbeddingFallback(raw string) (EmbeddingFallback, error) {
+	switch EmbeddingFallback(strings.ToLower(strings.TrimSpace(raw))) {
+	case "", EmbeddingFallbackFail:
+		return EmbeddingFallbackFail, nil
+	case EmbeddingFallbackDegrade:
+		return EmbeddingFallbackDegrade, nil
+	default:
+		return "", fmt.Errorf("unknown embedding fallback %q (want %q or %q)", raw, EmbeddingFallbackFail, EmbeddingFallbackDegrade)
+	}
+}
+
+// SetEmbeddingFallback sets the policy applied when the text embedder fails.
+func (s *Service) SetEmbeddingFallback(policy EmbeddingFallback) {
+	s.embeddingFallback = policy
+}
+
+// degradeOnEmbeddingError logs err and reports whether the caller should
+// continue without a dense vector.
+func (s *Service) degradeOnEmbeddingError(ctx context.Context, op string, err error) bool {
+	if s.embeddingFallback != EmbeddingFallbackDegrade {
+		return false
+	}
+	s.logger.WarnContext(ctx, "embedding unavailable, degrading",
+		slog.String("op", op),
+		slog.Any("error", err),
+	)
+	return true
+}
+
+// embedPointText sets the dense vector of point from text. When embedding
+// fails and the fallback policy allows it, the point is kept sparse-only and
+// flagged as pending instead.
+func (s *Service) embedPointText(ctx context.Context, op string, point *qdrantPoint, text string) error {
+	if s.embedder == nil {
+		return fmt.Errorf("embedder not configured")
+	}
+	vector, err := s.embedder.Embed(ctx, text)
+	if err != nil {
+		if !s.degradeOnEmbeddingError(ctx, op, err) {
+			return err
+		}
+		point.Payload[embeddingPendingKey] = true
+		return nil
+	}
+	if err := s.checkEmbeddingDimension(ctx, point.Payload, len(vector)); err != nil {
+		return err
+	}
+	point.Payload[embeddingDimKey] = len(vector)
+	delete(point.Payload, embeddingPendingKey)
+	s.rememberEmbeddingDimension(point.Payload, len(vector))
+	point.Vector = vector
+	point.VectorName = s.vectorNameForText()
+	return nil
+}
+
+// ReembedPending embeds memories that were stored while the embedder was
+// unavailable. It stops at the first embedding failure, since the provider
+// is most likely still down, and returns how many memories were repaired.
+func (s *Service) ReembedPending(ctx context.Context, batchSize int) (int, error) {
+	if s.store == nil || s.embedder == nil {
+		return 0, nil
+	}
+	filters := map[string]any{embeddingPendingKey: true}
+	repaired := 0
+	var offset *qdrant.PointId
+	for {
+		points, next, err := s.store.Scroll(ctx, batchSize, filters, offset)
+		if err != nil {
+			return repaired, err
+		}
+		for _, point := range points {
+			text := fmt.Sprint(point.Payload["data"])
+			if strings.TrimSpace(text) == "" {
+				continue
+			}
+			vector, err := s.embedder.Embed(ctx, text)
+			if err != nil {
+				return repaired, err
+			}
+			if err := s.checkEmbeddingDimension(ctx, point.Payload, len(vector)); err != nil {
+				s.logger.WarnContext(ctx, "re-embed skipped", slog.String("id", point.ID), slog.Any("error", err))
+				continue
+			}
+			if err := s.store.UpdateDenseVector(ctx, point.ID, s.vectorNameForText(), vector); err != nil {
+				return repaired, err
+			}
+			if err := s.store.SetPayload(ctx, point.ID, map[string]any{
+				embeddingPendingKey: false,
+				embeddingDimKey:     len(vector),
+			}); err != nil {
+				return repaired, err
+			}
+			s.rememberEmbeddingDimension(point.Payload, len(vector))
+			repaired++
+		}
+		if next == nil {
+			return repaired, nil
+		}
+		offset = next
+	}
+}
+
+// RunPendingEmbeddings calls ReembedPending every interval until ctx ends.
+func (s *Service) RunPendingEmbeddings(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		repaired, err := s.ReembedPending(ctx, 100)
+		if repaired > 0 {
+			s.logger.InfoContext(ctx, "re-embedded pending memories", slog.Int("count", repaired))
+		}
+		if err != nil && ctx.Err() == nil {
+			s.logger.WarnContext(ctx, "re-embed pending memories failed", slog.Any("error", err))
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package memory
+
+import (
+	"context"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultExtractMaxTokens bounds the approximate token size of a single
+// Extract call when the service has not been configured otherwise.
+const DefaultExtractMaxTokens = 8000
+
+// SetExtractMaxTokens configures the approximate token budget per Extract
+// window. Non-positive values restore the default.
+func (s *Service) SetExtractMaxTokens(maxTokens int) {
+	s.extractMaxTokens = maxTokens
+}
+
+func (s *Service) extractWindowTokens() int {
+	if s.extractMaxTokens > 0 {
+		return s.extractMaxTokens
+	}
+	return DefaultExtractMaxTokens
+}
+
+// extractFacts runs Extract over token-bounded windows of messages so long
+// transcripts stay within the model context, merging facts across windows.
+func (s *Service) extractFacts(ctx context.Context, messages []Message, filters map[string]any, metadata map[string]any) (ExtractResponse, error) {
+	windows := chunkMessages(messages, s.extractWindowTokens())
+	if len(windows) > 1 {
+		s.logger.DebugContext(ctx, "extracting memory in windows", "messages", len(messages), "windows", len(windows))
+	}
+	facts := make([]string, 0)
+	seen := make(map[string]struct{})
+	for _, window := range windows {
+		resp, err := s.llm.Extract(ctx, ExtractRequest{
+			Messages: window,
+			Filters:  filters,
+			Metadata: metadata,
+		})
+		if err != nil {
+			return ExtractResponse{}, err
+		}
+		for _, fact := range resp.Facts {
+			key := strings.ToLower(strings.TrimSpace(fact))
+			if key == "" {
+				continue
+			}
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			facts = append(facts, strings.TrimSpace(fact))
+		}
+	}
+	return ExtractResponse{Facts: facts}, nil
+}
+
+// chunkMessages splits messages into consecutive windows whose estimated
+// token count stays within maxTokens. A single message larger than the
+// budget is kept whole in its own window.
+func chunkMessages(messages []Message, maxTokens int) [][]Message {
+	if len(messages) == 0 {
+		return [][]Message{messages}
+	}
+	if maxTokens <= 0 {
+		return [][]Message{messages}
+	}
+	windows := make([][]Message, 0, 1)
+	current := make([]Message, 0, len(messages))
+	currentTokens := 0
+	for _, msg := range messages {
+		tokens := estimateTokens(msg.Role) + estimateTokens(msg.Content)
+		if len(current) > 0 && currentTokens+tokens > maxTokens {
+			windows = append(windows, current)
+			current = make([]Message, 0)
+			currentTokens = 0
+		}
+		current = append(current, msg)
+		currentTokens += tokens
+	}
+	if len(current) > 0 {
+		windows = append(windows, current)
+	}
+	return windows
+}
+
+// estimateTokens approximates the token count using the common
+// four-characters-per-token heuristic.
+func estimateTokens(text string) int {
+	n := utf8.RuneCountInString(text)
+	if n == 0 {
+		return 0
+	}
+	return (n + 3) / 4
+}
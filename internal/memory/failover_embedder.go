@@ -0,0 +1,135 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/memohai/memoh/internal/embeddings"
+)
+
+const (
+	defaultEmbedderFailureThreshold = 3
+	defaultEmbedderCooldown         = 30 * time.Second
+)
+
+// FailoverOptions tunes the circuit breaker of a FailoverEmbedder. Zero
+// values use the defaults (3 failures, 30s cooldown).
+type FailoverOptions struct {
+	// FailureThreshold is how many primary failures in a row open the
+	// circuit, sending requests straight to the secondary.
+	FailureThreshold int
+	// Cooldown is how long the circuit stays open before the primary is
+	// tried again.
+	Cooldown time.Duration
+	Logger   *slog.Logger
+}
+
+// FailoverEmbedder embeds with a primary embedder and falls back to a
+// secondary one when the primary fails. After repeated failures the primary
+// is skipped for a cooldown, then probed again by the next request.
+//
+// Both embedders must produce vectors of the same size. They should also be
+// the same model served by different providers: vectors of different models
+// share a collection but do not compare meaningfully.
+type FailoverEmbedder struct {
+	primary   embeddings.Embedder
+	secondary embeddings.Embedder
+	threshold int
+	cooldown  time.Duration
+	logger    *slog.Logger
+	now       func() time.Time
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+}
+
+// NewFailoverEmbedder returns an embedder that fails over from primary to
+// secondary. A nil secondary returns primary unchanged.
+func NewFailoverEmbedder(primary, secondary embeddings.Embedder, opts FailoverOptions) (embeddings.Embedder, error) {
+	if primary == nil {
+		return nil, errors.New("primary embedder is required")
+	}
+	if secondary == nil {
+		return primary, nil
+	}
+	if primary.Dimensions() != secondary.Dimensions() {
+		return nil, fmt.Errorf("fallback embedder has %d dimensions, primary has %d", secondary.Dimensions(), primary.Dimensions())
+	}
+	e := &FailoverEmbedder{
+		primary:   primary,
+		secondary: secondary,
+		threshold: opts.FailureThreshold,
+		cooldown:  opts.Cooldown,
+		logger:    opts.Logger,
+		now:       time.Now,
+	}
+	if e.threshold <= 0 {
+		e.threshold = defaultEmbedderFailureThreshold
+	}
+	if e.cooldown <= 0 {
+		e.cooldown = defaultEmbedderCooldown
+	}
+	if e.logger == nil {
+		e.logger = slog.Default()
+	}
+	return e, nil
+}
+
+func (e *FailoverEmbedder) Dimensions() int {
+	return e.primary.Dimensions()
+}
+
+func (e *FailoverEmbedder) Embed(ctx context.Context, input string) ([]float32, error) {
+	if e.primaryAvailable() {
+		vector, err := e.primary.Embed(ctx, input)
+		if err == nil {
+			e.recordSuccess()
+			return vector, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		e.recordFailure(err)
+	}
+	vector, err := e.secondary.Embed(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("fallback embedder: %w", err)
+	}
+	return vector, nil
+}
+
+// primaryAvailable reports whether the circuit is closed, or open but past
+// its cooldown so the primary may be probed.
+func (e *FailoverEmbedder) primaryAvailable() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return !e.now().Before(e.openUntil)
+}
+
+func (e *FailoverEmbedder) recordSuccess() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.failures >= e.threshold {
+		e.logger.Info("primary embedder recovered")
+	}
+	e.failures = 0
+	e.openUntil = time.Time{}
+}
+
+func (e *FailoverEmbedder) recordFailure(err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.failures++
+	if e.failures >= e.threshold {
+		e.openUntil = e.now().Add(e.cooldown)
+		e.logger.Warn("primary embedder failing, using fallback",
+			slog.Int("failures", e.failures),
+			slog.Duration("cooldown", e.cooldown),
+			slog.Any("error", err))
+	}
+}
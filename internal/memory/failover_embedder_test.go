@@ -0,0 +1,63 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type flakyEmbedder struct {
+	dims  int
+	fail  bool
+	calls int
+}
+
+func (e *flakyEmbedder) Embed(context.Context, string) ([]float32, error) {
+	e.calls++
+	if e.fail {
+		return nil, errors.New("provider down")
+	}
+	return make([]float32, e.dims), nil
+}
+
+func (e *flakyEmbedder) Dimensions() int { return e.dims }
+
+func TestFailoverEmbedder_CircuitBreaker(t *testing.T) {
+	ctx := context.Background()
+	if _, err := NewFailoverEmbedder(&flakyEmbedder{dims: 3}, &flakyEmbedder{dims: 4}, FailoverOptions{}); err == nil {
+		t.Fatal("expected mismatched dimensions to be rejected")
+	}
+
+	primary, secondary := &flakyEmbedder{dims: 3, fail: true}, &flakyEmbedder{dims: 3}
+	embedder, err := NewFailoverEmbedder(primary, secondary, FailoverOptions{FailureThreshold: 2, Cooldown: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	failover := embedder.(*FailoverEmbedder)
+	now := time.Unix(1000, 0)
+	failover.now = func() time.Time { return now }
+
+	for i := 0; i < 4; i++ {
+		if _, err := failover.Embed(ctx, "x"); err != nil {
+			t.Fatalf("embed %d: %v", i, err)
+		}
+	}
+	if primary.calls != 2 || secondary.calls != 4 {
+		t.Fatalf("expected the open circuit to skip the primary: primary %d, secondary %d calls", primary.calls, secondary.calls)
+	}
+
+	now = now.Add(time.Minute)
+	primary.fail = false
+	if _, err := failover.Embed(ctx, "x"); err != nil {
+		t.Fatal(err)
+	}
+	if primary.calls != 3 || secondary.calls != 4 {
+		t.Fatalf("expected the primary to be probed after the cooldown: primary %d, secondary %d calls", primary.calls, secondary.calls)
+	}
+
+	primary.fail, secondary.fail = true, true
+	if _, err := failover.Embed(ctx, "x"); err == nil {
+		t.Fatal("expected an error when both embedders fail")
+	}
+}
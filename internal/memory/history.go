@@ -0,0 +1,109 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/memohai/memoh/internal/db/sqlc"
+	"github.com/memohai/memoh/internal/metrics"
+)
+
+const (
+	HistoryEventAdd    = "ADD"
+	HistoryEventUpdate = "UPDATE"
+	HistoryEventDelete = "DELETE"
+)
+
+// HistoryStore persists memory revisions.
+type HistoryStore interface {
+	AppendRevision(ctx context.Context, rev MemoryRevision) error
+	ListRevisions(ctx context.Context, memoryID string) ([]MemoryRevision, error)
+}
+
+// PostgresHistoryStore stores revisions in the memory_history table.
+type PostgresHistoryStore struct {
+	queries *sqlc.Queries
+}
+
+func NewPostgresHistoryStore(queries *sqlc.Queries) *PostgresHistoryStore {
+	return &PostgresHistoryStore{queries: queries}
+}
+
+func (h *PostgresHistoryStore) AppendRevision(ctx context.Context, rev MemoryRevision) error {
+	if rev.ID == "" {
+		rev.ID = uuid.NewString()
+	}
+	return h.queries.InsertMemoryHistory(ctx, sqlc.InsertMemoryHistoryParams{
+		ID:       rev.ID,
+		MemoryID: rev.MemoryID,
+		Event:    rev.Event,
+		Memory:   rev.Memory,
+		Hash:     rev.Hash,
+	})
+}
+
+func (h *PostgresHistoryStore) ListRevisions(ctx context.Context, memoryID string) ([]MemoryRevision, error) {
+	rows, err := h.queries.ListMemoryHistory(ctx, memoryID)
+	if err != nil {
+		return nil, err
+	}
+	revisions := make([]MemoryRevision, 0, len(rows))
+	for _, row := range rows {
+		rev := MemoryRevision{
+			ID:       row.ID,
+			MemoryID: row.MemoryID,
+			Event:    row.Event,
+			Memory:   row.Memory,
+			Hash:     row.Hash,
+		}
+		if row.CreatedAt.Valid {
+			rev.CreatedAt = row.CreatedAt.Time.UTC().Format(time.RFC3339)
+		}
+		revisions = append(revisions, rev)
+	}
+	return revisions, nil
+}
+
+// SetHistoryStore enables revision tracking for memory writes.
+func (s *Service) SetHistoryStore(history HistoryStore) {
+	s.history = history
+}
+
+// History returns the revisions recorded for a memory, oldest first.
+func (s *Service) History(ctx context.Context, memoryID string) ([]MemoryRevision, error) {
+	memoryID = strings.TrimSpace(memoryID)
+	if memoryID == "" {
+		return nil, fmt.Errorf("memory_id is required")
+	}
+	if s.history == nil {
+		return nil, fmt.Errorf("memory history not configured")
+	}
+	return s.history.ListRevisions(ctx, memoryID)
+}
+
+// recordRevision counts the write and appends a revision; failures are
+// logged so history never blocks the memory write itself.
+func (s *Service) recordRevision(ctx context.Context, event string, item MemoryItem) {
+	metrics.MemoryOperations.WithLabelValues(strings.ToLower(event)).Inc()
+	if s.history == nil || item.Memory == "" {
+		return
+	}
+	hash := item.Hash
+	if hash == "" {
+		hash = hashMemory(item.Memory)
+	}
+	err := s.history.AppendRevision(ctx, MemoryRevision{
+		MemoryID: item.ID,
+		Event:    event,
+		Memory:   item.Memory,
+		Hash:     hash,
+	})
+	if err != nil {
+		s.logger.WarnContext(ctx, "record memory revision failed", slog.String("memory_id", item.ID), slog.String("event", event), slog.Any("error", err))
+	}
+}
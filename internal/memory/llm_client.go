@@ -10,6 +10,8 @@ import (
 	"net/http"
 	"strings"
 	"time"
+
+	"github.com/memohai/memoh/internal/logger"
 )
 
 type LLMClient struct {
@@ -18,6 +20,7 @@ type LLMClient struct {
 	model   string
 	logger  *slog.Logger
 	http    *http.Client
+	prompts *Prompts
 }
 
 func NewLLMClient(log *slog.Logger, baseURL, apiKey, model string, timeout time.Duration) (*LLMClient, error) {
@@ -47,12 +50,20 @@ func NewLLMClient(log *slog.Logger, baseURL, apiKey, model string, timeout time.
 	}, nil
 }
 
+// SetPrompts overrides the extract and decide prompts. nil restores the defaults.
+func (c *LLMClient) SetPrompts(prompts *Prompts) {
+	c.prompts = prompts
+}
+
 func (c *LLMClient) Extract(ctx context.Context, req ExtractRequest) (ExtractResponse, error) {
 	if len(req.Messages) == 0 {
 		return ExtractResponse{}, fmt.Errorf("messages is required")
 	}
 	parsedMessages := strings.Join(formatMessages(req.Messages), "\n")
-	systemPrompt, userPrompt := getFactRetrievalMessages(parsedMessages)
+	systemPrompt, userPrompt, err := c.prompts.extractPrompts(parsedMessages, req)
+	if err != nil {
+		return ExtractResponse{}, err
+	}
 	content, err := c.callChat(ctx, []chatMessage{
 		{Role: "system", Content: systemPrompt},
 		{Role: "user", Content: userPrompt},
@@ -79,7 +90,10 @@ func (c *LLMClient) Decide(ctx context.Context, req DecideRequest) (DecideRespon
 			"text": candidate.Memory,
 		})
 	}
-	prompt := getUpdateMemoryMessages(retrieved, req.Facts)
+	prompt, err := c.prompts.decidePrompt(retrieved, req)
+	if err != nil {
+		return DecideResponse{}, err
+	}
 	content, err := c.callChat(ctx, []chatMessage{
 		{Role: "user", Content: prompt},
 	})
@@ -224,6 +238,7 @@ func (c *LLMClient) callChat(ctx context.Context, messages []chatMessage) (strin
 	}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	logger.SetOutgoingRequestID(req)
 
 	resp, err := c.http.Do(req)
 	if err != nil {
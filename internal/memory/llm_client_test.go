@@ -2,6 +2,7 @@ package memory
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -34,3 +35,51 @@ func TestLLMClientExtract(t *testing.T) {
 		t.Fatalf("unexpected response: %+v", resp)
 	}
 }
+
+func TestLLMClientExtractCustomPrompt(t *testing.T) {
+	t.Parallel()
+
+	var system string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []chatMessage `json:"messages"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err == nil && len(body.Messages) > 0 {
+			system = body.Messages[0].Content
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"choices":[{"message":{"content":"{\"facts\":[]}"}}]}`))
+	}))
+	defer server.Close()
+
+	prompts, err := NewPrompts(PromptTemplates{Extract: "bot={{.Filters}} conv={{.Messages}}"})
+	if err != nil {
+		t.Fatalf("new prompts: %v", err)
+	}
+	client, err := NewLLMClient(nil, server.URL, "test-key", "gpt-4.1-nano-2025-04-14", 0)
+	if err != nil {
+		t.Fatalf("new llm client: %v", err)
+	}
+	client.SetPrompts(prompts)
+	if _, err := client.Extract(context.Background(), ExtractRequest{
+		Messages: []Message{{Role: "user", Content: "hi"}},
+		Filters:  map[string]any{"bot_id": "b1"},
+	}); err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	if want := `bot={"bot_id":"b1"} conv=user: hi`; system != want {
+		t.Fatalf("system prompt = %q, want %q", system, want)
+	}
+}
+
+func TestNewPromptsRejectsUnknownPlaceholder(t *testing.T) {
+	t.Parallel()
+
+	if _, err := NewPrompts(PromptTemplates{Decide: "{{.Nope}}"}); err == nil {
+		t.Fatal("expected error for unknown placeholder")
+	}
+	prompts, err := NewPrompts(PromptTemplates{})
+	if err != nil || prompts != nil {
+		t.Fatalf("empty templates = %v, %v; want nil, nil", prompts, err)
+	}
+}
@@ -23,6 +23,7 @@ const (
 type MemoryFS struct {
 	execRunner container.ExecRunner
 	workDir    string // e.g. "/data"
+	workDirFor func(ctx context.Context, botID string) string
 	logger     *slog.Logger
 	mu         sync.Mutex // serialize manifest updates
 }
@@ -57,6 +58,22 @@ func NewMemoryFS(log *slog.Logger, runner container.ExecRunner, workDir string)
 	}
 }
 
+// SetWorkDirFunc makes the working directory depend on the bot, for bots
+// whose container mounts its data somewhere other than workDir. An empty
+// result falls back to workDir.
+func (fs *MemoryFS) SetWorkDirFunc(fn func(ctx context.Context, botID string) string) {
+	fs.workDirFor = fn
+}
+
+func (fs *MemoryFS) dir(ctx context.Context, botID string) string {
+	if fs.workDirFor != nil {
+		if wd := strings.TrimSpace(fs.workDirFor(ctx, botID)); wd != "" {
+			return wd
+		}
+	}
+	return fs.workDir
+}
+
 // ----- write operations -----
 
 // PersistMemories writes .md files for new items and incrementally updates the manifest.
@@ -181,7 +198,7 @@ func (fs *MemoryFS) ReadManifest(ctx context.Context, botID string) (*Manifest,
 }
 
 func (fs *MemoryFS) readManifestLocked(ctx context.Context, botID string) (*Manifest, error) {
-	content, err := container.ExecRead(ctx, fs.execRunner, botID, fs.workDir, manifestPath)
+	content, err := container.ExecRead(ctx, fs.execRunner, botID, fs.dir(ctx, botID), manifestPath)
 	if err != nil {
 		return nil, err
 	}
@@ -194,7 +211,7 @@ func (fs *MemoryFS) readManifestLocked(ctx context.Context, botID string) (*Mani
 
 // ReadAllMemoryFiles lists and reads all .md files under memory/ and parses their frontmatter.
 func (fs *MemoryFS) ReadAllMemoryFiles(ctx context.Context, botID string) ([]MemoryItem, error) {
-	entries, err := container.ExecList(ctx, fs.execRunner, botID, fs.workDir, memoryDirPath, false)
+	entries, err := container.ExecList(ctx, fs.execRunner, botID, fs.dir(ctx, botID), memoryDirPath, false)
 	if err != nil {
 		return nil, fmt.Errorf("list memory dir: %w", err)
 	}
@@ -205,7 +222,7 @@ func (fs *MemoryFS) ReadAllMemoryFiles(ctx context.Context, botID string) ([]Mem
 			continue
 		}
 		filePath := memoryDirPath + "/" + entry.Path
-		content, err := container.ExecRead(ctx, fs.execRunner, botID, fs.workDir, filePath)
+		content, err := container.ExecRead(ctx, fs.execRunner, botID, fs.dir(ctx, botID), filePath)
 		if err != nil {
 			fs.logger.Warn("read memory file failed", slog.String("path", filePath), slog.Any("error", err))
 			continue
@@ -225,7 +242,7 @@ func (fs *MemoryFS) ReadAllMemoryFiles(ctx context.Context, botID string) ([]Mem
 func (fs *MemoryFS) writeMemoryFile(ctx context.Context, botID string, item MemoryItem) error {
 	content := formatMemoryMD(item)
 	filePath := fmt.Sprintf("%s/%s.md", memoryDirPath, item.ID)
-	return container.ExecWrite(ctx, fs.execRunner, botID, fs.workDir, filePath, content)
+	return container.ExecWrite(ctx, fs.execRunner, botID, fs.dir(ctx, botID), filePath, content)
 }
 
 func (fs *MemoryFS) writeManifest(ctx context.Context, botID string, manifest *Manifest) error {
@@ -233,7 +250,7 @@ func (fs *MemoryFS) writeManifest(ctx context.Context, botID string, manifest *M
 	if err != nil {
 		return fmt.Errorf("marshal manifest: %w", err)
 	}
-	return container.ExecWrite(ctx, fs.execRunner, botID, fs.workDir, manifestPath, string(data))
+	return container.ExecWrite(ctx, fs.execRunner, botID, fs.dir(ctx, botID), manifestPath, string(data))
 }
 
 // execDeleteDir removes all files inside a directory (but keeps the directory itself).
@@ -243,7 +260,7 @@ func (fs *MemoryFS) execDeleteDir(ctx context.Context, botID, dirPath string) {
 	_, err := fs.execRunner.ExecWithCapture(ctx, mcpgw.ExecRequest{
 		BotID:   botID,
 		Command: []string{"/bin/sh", "-c", script},
-		WorkDir: fs.workDir,
+		WorkDir: fs.dir(ctx, botID),
 	})
 	if err != nil {
 		fs.logger.Warn("exec delete dir failed", slog.String("path", dirPath), slog.Any("error", err))
@@ -256,7 +273,7 @@ func (fs *MemoryFS) execDeleteFile(ctx context.Context, botID, filePath string)
 	_, err := fs.execRunner.ExecWithCapture(ctx, mcpgw.ExecRequest{
 		BotID:   botID,
 		Command: []string{"/bin/sh", "-c", script},
-		WorkDir: fs.workDir,
+		WorkDir: fs.dir(ctx, botID),
 	})
 	if err != nil {
 		fs.logger.Warn("exec delete file failed", slog.String("path", filePath), slog.Any("error", err))
@@ -0,0 +1,94 @@
+package memory
+
+import (
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// MetadataSourceRole is the metadata key holding the role of the message a
+// memory was derived from, e.g. to search only assistant-derived memories
+// with the filter "metadata.source_role".
+const MetadataSourceRole = "source_role"
+
+// messageMetadata returns the metadata to store for a memory taken verbatim
+// from msg: its own metadata plus its role.
+func messageMetadata(msg Message) map[string]any {
+	meta := make(map[string]any, len(msg.Metadata)+1)
+	for k, v := range msg.Metadata {
+		meta[k] = v
+	}
+	if role := strings.TrimSpace(msg.Role); role != "" {
+		meta[MetadataSourceRole] = role
+	}
+	return meta
+}
+
+// factMetadata attributes an extracted fact to the source message sharing
+// the most words with it and returns that message's metadata. When no
+// message can be attributed, only metadata common to all messages is kept.
+// It returns nil when there is nothing to propagate.
+func factMetadata(fact string, messages []Message) map[string]any {
+	if len(messages) == 0 {
+		return nil
+	}
+	if best := attributeFact(fact, messages); best != nil {
+		return messageMetadata(*best)
+	}
+	meta := sharedMessageMetadata(messages)
+	if len(meta) == 0 {
+		return nil
+	}
+	return meta
+}
+
+func attributeFact(fact string, messages []Message) *Message {
+	factWords := wordSet(fact)
+	if len(factWords) == 0 {
+		return nil
+	}
+	var best *Message
+	bestOverlap := 0
+	for i := range messages {
+		overlap := 0
+		for word := range wordSet(messages[i].Content) {
+			if _, ok := factWords[word]; ok {
+				overlap++
+			}
+		}
+		if overlap > bestOverlap {
+			best = &messages[i]
+			bestOverlap = overlap
+		}
+	}
+	return best
+}
+
+// sharedMessageMetadata keeps keys whose values are identical across all
+// messages, including the role when every message has the same one.
+func sharedMessageMetadata(messages []Message) map[string]any {
+	shared := messageMetadata(messages[0])
+	for _, msg := range messages[1:] {
+		other := messageMetadata(msg)
+		for k, v := range shared {
+			if ov, ok := other[k]; !ok || !reflect.DeepEqual(v, ov) {
+				delete(shared, k)
+			}
+		}
+	}
+	return shared
+}
+
+func wordSet(text string) map[string]struct{} {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	set := make(map[string]struct{}, len(words))
+	for _, word := range words {
+		if len([]rune(word)) < 3 {
+			continue
+		}
+		set[word] = struct{}{}
+	}
+	return set
+}
@@ -0,0 +1,98 @@
+package memory
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// originPayloadKey stores the source reference of a memory. It is separate
+// from "source", which already holds the ingestion source name.
+const originPayloadKey = "origin"
+
+// OriginOptions asks Add to record where the memories came from. Storing the
+// full messages can grow payloads considerably, so it is opt-in separately.
+type OriginOptions struct {
+	// Ref is a caller-supplied id of the originating turn (e.g. a message id).
+	Ref string `json:"ref,omitempty"`
+	// IncludeMessages stores the source messages themselves, not only their hash.
+	IncludeMessages bool `json:"include_messages,omitempty"`
+}
+
+// MemoryOrigin links a memory back to the conversation that produced it.
+type MemoryOrigin struct {
+	Ref      string    `json:"ref,omitempty"`
+	Hash     string    `json:"hash"`
+	Messages []Message `json:"messages,omitempty"`
+}
+
+// buildOrigin returns the origin to store for messages, or nil when no
+// origin tracking was requested.
+func buildOrigin(opts *OriginOptions, messages []Message) *MemoryOrigin {
+	if opts == nil {
+		return nil
+	}
+	origin := &MemoryOrigin{
+		Ref:  strings.TrimSpace(opts.Ref),
+		Hash: hashMessages(messages),
+	}
+	if opts.IncludeMessages {
+		origin.Messages = append([]Message(nil), messages...)
+	}
+	return origin
+}
+
+// hashMessages returns a stable digest of the role/content sequence.
+func hashMessages(messages []Message) string {
+	h := sha256.New()
+	for _, msg := range messages {
+		fmt.Fprintf(h, "%s\x00%s\x00", msg.Role, msg.Content)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// applyOriginToPayload stores origin in a Qdrant-compatible shape.
+func applyOriginToPayload(payload map[string]any, origin *MemoryOrigin) {
+	if origin == nil {
+		return
+	}
+	value := map[string]any{"hash": origin.Hash}
+	if origin.Ref != "" {
+		value["ref"] = origin.Ref
+	}
+	if len(origin.Messages) > 0 {
+		messages := make([]any, 0, len(origin.Messages))
+		for _, msg := range origin.Messages {
+			messages = append(messages, map[string]any{"role": msg.Role, "content": msg.Content})
+		}
+		value["messages"] = messages
+	}
+	payload[originPayloadKey] = value
+}
+
+// originFromPayload reads an origin written by applyOriginToPayload.
+func originFromPayload(payload map[string]any) *MemoryOrigin {
+	raw, ok := payload[originPayloadKey].(map[string]any)
+	if !ok {
+		return nil
+	}
+	origin := &MemoryOrigin{}
+	origin.Hash, _ = raw["hash"].(string)
+	origin.Ref, _ = raw["ref"].(string)
+	if messages, ok := raw["messages"].([]any); ok {
+		for _, entry := range messages {
+			msg, ok := entry.(map[string]any)
+			if !ok {
+				continue
+			}
+			role, _ := msg["role"].(string)
+			content, _ := msg["content"].(string)
+			origin.Messages = append(origin.Messages, Message{Role: role, Content: content})
+		}
+	}
+	if origin.Hash == "" && origin.Ref == "" && len(origin.Messages) == 0 {
+		return nil
+	}
+	return origin
+}
@@ -0,0 +1,92 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// pingBotID scopes Ping's probe point so it never shows up in a real bot's
+// memories, even if cleanup fails.
+const pingBotID = "__memoh_ping__"
+
+// Ping runs a small end-to-end round trip through the memory stack: it
+// embeds a probe text, upserts it as a scratch point, searches for it with
+// the same filters real queries use, and deletes it again. It catches
+// problems the per-component checks miss, such as an embedding dimension that
+// does not match the collection or a rejected embedding API key.
+func (s *Service) Ping(ctx context.Context) error {
+	if s.store == nil {
+		return errors.New("qdrant store not configured")
+	}
+	// A unique text keeps cached embedders from answering for the provider.
+	probe := "memoh readiness probe " + uuid.NewString()
+	filters := map[string]any{"bot_id": pingBotID}
+	point := qdrantPoint{
+		ID:      uuid.NewString(),
+		Payload: buildPayload(probe, filters, nil, ""),
+	}
+
+	var (
+		queryIndices []uint32
+		queryValues  []float32
+	)
+	if s.bm25 != nil {
+		lang := fallbackLanguageCode(probe)
+		termFreq, _, err := s.bm25.TermFrequencies(lang, probe)
+		if err != nil {
+			return fmt.Errorf("ping bm25: %w", err)
+		}
+		// BuildQueryVector leaves the corpus statistics untouched, unlike
+		// AddDocument, so the probe does not skew real scores.
+		queryIndices, queryValues = s.bm25.BuildQueryVector(lang, termFreq)
+		point.SparseIndices, point.SparseValues = queryIndices, queryValues
+		point.SparseVectorName = s.store.sparseVectorName
+	}
+	if s.embedder != nil {
+		vector, err := s.embedder.Embed(ctx, probe)
+		if err != nil {
+			return fmt.Errorf("ping embed: %w", err)
+		}
+		if want := s.embedder.Dimensions(); want > 0 && len(vector) != want {
+			return fmt.Errorf("ping embed: got %d dimensions, configured %d", len(vector), want)
+		}
+		point.Vector = vector
+		point.VectorName = s.vectorNameForText()
+	}
+	if point.Vector == nil && point.SparseIndices == nil {
+		return errors.New("ping: neither embedder nor bm25 indexer configured")
+	}
+
+	if err := s.store.Upsert(ctx, []qdrantPoint{point}); err != nil {
+		return fmt.Errorf("ping upsert: %w", err)
+	}
+	defer func() {
+		cleanupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+		if err := s.store.Delete(cleanupCtx, point.ID); err != nil {
+			s.logger.WarnContext(ctx, "ping cleanup failed", slog.String("id", point.ID), slog.Any("error", err))
+		}
+	}()
+
+	var (
+		found []qdrantPoint
+		err   error
+	)
+	if point.Vector != nil {
+		found, _, err = s.store.Search(ctx, point.Vector, 1, filters, point.VectorName)
+	} else {
+		found, _, err = s.store.SearchSparse(ctx, queryIndices, queryValues, 1, filters, false)
+	}
+	if err != nil {
+		return fmt.Errorf("ping search: %w", err)
+	}
+	if len(found) == 0 || found[0].ID != point.ID {
+		return errors.New("ping search: probe point not found")
+	}
+	return nil
+}
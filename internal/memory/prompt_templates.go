@@ -0,0 +1,128 @@
+package memory
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// PromptTemplates overrides the built-in extract and decide prompts with
+// text/template sources. An empty field keeps the default prompt.
+//
+// The extract template replaces the fact-extraction system prompt; the
+// conversation is still sent as the user message. It can use:
+//
+//	{{.Date}}      today's date (YYYY-MM-DD, UTC)
+//	{{.Messages}}  the conversation, one "role: content" line per message
+//	{{.Filters}}   the request filters as JSON
+//	{{.Metadata}}  the request metadata as JSON
+//
+// The decide template replaces the whole update-memory prompt. It can use
+// {{.Date}}, {{.Filters}} and {{.Metadata}} as above, plus:
+//
+//	{{.ExistingMemories}}  candidate memories as a JSON array of {"id","text"}
+//	{{.Facts}}             the newly extracted facts as a JSON array of strings
+//
+// Both prompts must still instruct the model to answer in the JSON shape the
+// client parses ({"facts": [...]} and {"memory": [...]} respectively).
+type PromptTemplates struct {
+	Extract string
+	Decide  string
+}
+
+// Prompts holds parsed prompt templates; a nil *Prompts uses the defaults.
+type Prompts struct {
+	extract *template.Template
+	decide  *template.Template
+}
+
+type extractPromptData struct {
+	Date     string
+	Messages string
+	Filters  string
+	Metadata string
+}
+
+type decidePromptData struct {
+	Date             string
+	ExistingMemories string
+	Facts            string
+	Filters          string
+	Metadata         string
+}
+
+// NewPrompts parses the given templates. It returns nil when neither is set.
+func NewPrompts(t PromptTemplates) (*Prompts, error) {
+	var (
+		p   Prompts
+		err error
+	)
+	if strings.TrimSpace(t.Extract) != "" {
+		if p.extract, err = parsePromptTemplate("extract", t.Extract, extractPromptData{}); err != nil {
+			return nil, err
+		}
+	}
+	if strings.TrimSpace(t.Decide) != "" {
+		if p.decide, err = parsePromptTemplate("decide", t.Decide, decidePromptData{}); err != nil {
+			return nil, err
+		}
+	}
+	if p.extract == nil && p.decide == nil {
+		return nil, nil
+	}
+	return &p, nil
+}
+
+// parsePromptTemplate parses src and test-renders it against an empty data
+// value so unknown placeholders fail at startup rather than on first use.
+func parsePromptTemplate(name, src string, sample any) (*template.Template, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(src)
+	if err != nil {
+		return nil, fmt.Errorf("%s prompt template: %w", name, err)
+	}
+	if err := tmpl.Execute(&strings.Builder{}, sample); err != nil {
+		return nil, fmt.Errorf("%s prompt template: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+// extractPrompts returns the fact-extraction system and user prompts for parsedMessages.
+func (p *Prompts) extractPrompts(parsedMessages string, req ExtractRequest) (string, string, error) {
+	systemPrompt, userPrompt := getFactRetrievalMessages(parsedMessages)
+	if p == nil || p.extract == nil {
+		return systemPrompt, userPrompt, nil
+	}
+	var b strings.Builder
+	if err := p.extract.Execute(&b, extractPromptData{
+		Date:     promptDate(),
+		Messages: parsedMessages,
+		Filters:  toJSON(req.Filters),
+		Metadata: toJSON(req.Metadata),
+	}); err != nil {
+		return "", "", fmt.Errorf("render extract prompt: %w", err)
+	}
+	return b.String(), userPrompt, nil
+}
+
+// decidePrompt returns the update-memory prompt for the given memories and facts.
+func (p *Prompts) decidePrompt(existing []map[string]string, req DecideRequest) (string, error) {
+	if p == nil || p.decide == nil {
+		return getUpdateMemoryMessages(existing, req.Facts), nil
+	}
+	var b strings.Builder
+	if err := p.decide.Execute(&b, decidePromptData{
+		Date:             promptDate(),
+		ExistingMemories: toJSON(existing),
+		Facts:            toJSON(req.Facts),
+		Filters:          toJSON(req.Filters),
+		Metadata:         toJSON(req.Metadata),
+	}); err != nil {
+		return "", fmt.Errorf("render decide prompt: %w", err)
+	}
+	return b.String(), nil
+}
+
+func promptDate() string {
+	return time.Now().UTC().Format("2006-01-02")
+}
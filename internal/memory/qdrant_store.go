@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"log/slog"
 	"net/url"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/qdrant/go-client/qdrant"
@@ -17,6 +19,9 @@ import (
 const (
 	sparseHashVectorName  = "sparse_hash"
 	sparseVocabVectorName = "sparse_vocab"
+
+	// deleteBeforeBatchSize bounds how many point IDs DeleteBefore removes per request.
+	deleteBeforeBatchSize = 256
 )
 
 type QdrantStore struct {
@@ -31,6 +36,13 @@ type QdrantStore struct {
 	usesNamedVectors  bool
 	sparseVectorName  string
 	usesSparseVectors bool
+	// vectorDistances records the distance metric per dense vector name ("" for
+	// an unnamed vector). Missing entries mean cosine, which is what
+	// ensureCollection creates.
+	vectorDistances map[string]qdrant.Distance
+	// backfillOnce starts backfillEpochPayloads on the first
+	// EnsureCollection of an existing collection only.
+	backfillOnce sync.Once
 }
 
 type qdrantPoint struct {
@@ -44,6 +56,19 @@ type qdrantPoint struct {
 }
 
 func NewQdrantStore(log *slog.Logger, baseURL, apiKey, collection string, dimension int, sparseVectorName string, timeout time.Duration) (*QdrantStore, error) {
+	store, err := newQdrantStore(log, baseURL, apiKey, collection, dimension, sparseVectorName, timeout)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), store.timeout)
+	defer cancel()
+	if err := store.EnsureCollection(ctx); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+func newQdrantStore(log *slog.Logger, baseURL, apiKey, collection string, dimension int, sparseVectorName string, timeout time.Duration) (*QdrantStore, error) {
 	host, port, useTLS, err := parseQdrantEndpoint(baseURL)
 	if err != nil {
 		return nil, err
@@ -59,10 +84,11 @@ func NewQdrantStore(log *slog.Logger, baseURL, apiKey, collection string, dimens
 	}
 
 	cfg := &qdrant.Config{
-		Host:   host,
-		Port:   port,
-		APIKey: apiKey,
-		UseTLS: useTLS,
+		Host:        host,
+		Port:        port,
+		APIKey:      apiKey,
+		UseTLS:      useTLS,
+		GrpcOptions: qdrantGrpcOptions(),
 	}
 	client, err := qdrant.NewClient(cfg)
 	if err != nil {
@@ -80,20 +106,37 @@ func NewQdrantStore(log *slog.Logger, baseURL, apiKey, collection string, dimens
 		sparseVectorName:  strings.TrimSpace(sparseVectorName),
 		usesSparseVectors: strings.TrimSpace(sparseVectorName) != "",
 	}
+	return store, nil
+}
+
+func (s *QdrantStore) NewSibling(collection string, dimension int) (*QdrantStore, error) {
+	return NewQdrantStore(s.logger, s.baseURL, s.apiKey, collection, dimension, s.sparseVectorName, s.timeout)
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), timeoutOrDefault(timeout))
+func NewQdrantStoreWithVectors(log *slog.Logger, baseURL, apiKey, collection string, vectors map[string]int, sparseVectorName string, timeout time.Duration) (*QdrantStore, error) {
+	store, err := newQdrantStoreWithVectors(log, baseURL, apiKey, collection, vectors, sparseVectorName, timeout)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), store.timeout)
 	defer cancel()
-	if err := store.ensureCollection(ctx, nil); err != nil {
+	if err := store.EnsureCollection(ctx); err != nil {
 		return nil, err
 	}
 	return store, nil
 }
 
-func (s *QdrantStore) NewSibling(collection string, dimension int) (*QdrantStore, error) {
-	return NewQdrantStore(s.logger, s.baseURL, s.apiKey, collection, dimension, s.sparseVectorName, s.timeout)
+// NewQdrantStoreDeferred builds the same store as NewQdrantStoreWithVectors
+// (or NewQdrantStore when vectors is empty) without contacting Qdrant. It is
+// used for degraded startup; call EnsureCollection once Qdrant is reachable.
+func NewQdrantStoreDeferred(log *slog.Logger, baseURL, apiKey, collection string, vectors map[string]int, dimension int, sparseVectorName string, timeout time.Duration) (*QdrantStore, error) {
+	if len(vectors) > 0 {
+		return newQdrantStoreWithVectors(log, baseURL, apiKey, collection, vectors, sparseVectorName, timeout)
+	}
+	return newQdrantStore(log, baseURL, apiKey, collection, dimension, sparseVectorName, timeout)
 }
 
-func NewQdrantStoreWithVectors(log *slog.Logger, baseURL, apiKey, collection string, vectors map[string]int, sparseVectorName string, timeout time.Duration) (*QdrantStore, error) {
+func newQdrantStoreWithVectors(log *slog.Logger, baseURL, apiKey, collection string, vectors map[string]int, sparseVectorName string, timeout time.Duration) (*QdrantStore, error) {
 	host, port, useTLS, err := parseQdrantEndpoint(baseURL)
 	if err != nil {
 		return nil, err
@@ -109,10 +152,11 @@ func NewQdrantStoreWithVectors(log *slog.Logger, baseURL, apiKey, collection str
 	}
 
 	cfg := &qdrant.Config{
-		Host:   host,
-		Port:   port,
-		APIKey: apiKey,
-		UseTLS: useTLS,
+		Host:        host,
+		Port:        port,
+		APIKey:      apiKey,
+		UseTLS:      useTLS,
+		GrpcOptions: qdrantGrpcOptions(),
 	}
 	client, err := qdrant.NewClient(cfg)
 	if err != nil {
@@ -131,15 +175,21 @@ func NewQdrantStoreWithVectors(log *slog.Logger, baseURL, apiKey, collection str
 		sparseVectorName:  strings.TrimSpace(sparseVectorName),
 		usesSparseVectors: strings.TrimSpace(sparseVectorName) != "",
 	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), timeoutOrDefault(timeout))
-	defer cancel()
-	if err := store.ensureCollection(ctx, vectors); err != nil {
-		return nil, err
-	}
 	return store, nil
 }
 
+// EnsureCollection creates the collection if needed and reconciles its
+// schema and payload indexes with the store's configuration.
+func (s *QdrantStore) EnsureCollection(ctx context.Context) error {
+	return s.ensureCollection(ctx, s.vectorNames)
+}
+
+// HealthCheck reports whether Qdrant is reachable.
+func (s *QdrantStore) HealthCheck(ctx context.Context) error {
+	_, err := s.client.HealthCheck(ctx)
+	return err
+}
+
 func (s *QdrantStore) Upsert(ctx context.Context, points []qdrantPoint) error {
 	if len(points) == 0 {
 		return nil
@@ -309,12 +359,19 @@ func (s *QdrantStore) SearchSparseBySources(ctx context.Context, indices []uint3
 	return pointsBySource, scoresBySource, nil
 }
 
-func (s *QdrantStore) Get(ctx context.Context, id string) (*qdrantPoint, error) {
-	result, err := s.client.Get(ctx, &qdrant.GetPoints{
+// Get loads a point by ID. With withVectors set, the point's dense vector is
+// returned too: the one named vectorName when the collection uses named
+// vectors, falling back to any other dense vector the point has.
+func (s *QdrantStore) Get(ctx context.Context, id string, withVectors bool, vectorName string) (*qdrantPoint, error) {
+	req := &qdrant.GetPoints{
 		CollectionName: s.collection,
 		Ids:            []*qdrant.PointId{qdrant.NewIDUUID(id)},
 		WithPayload:    qdrant.NewWithPayload(true),
-	})
+	}
+	if withVectors {
+		req.WithVectors = qdrant.NewWithVectors(true)
+	}
+	result, err := s.client.Get(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -322,10 +379,90 @@ func (s *QdrantStore) Get(ctx context.Context, id string) (*qdrantPoint, error)
 		return nil, nil
 	}
 	point := result[0]
-	return &qdrantPoint{
+	out := &qdrantPoint{
 		ID:      pointIDToString(point.GetId()),
 		Payload: valueMapToInterface(point.GetPayload()),
-	}, nil
+	}
+	if withVectors {
+		out.VectorName, out.Vector = s.extractDenseVector(point.GetVectors(), vectorName)
+	}
+	return out, nil
+}
+
+// extractDenseVector picks a dense vector from a point's vectors, preferring
+// the named one. The returned name is "" for unnamed collections.
+func (s *QdrantStore) extractDenseVector(vectors *qdrant.VectorsOutput, preferred string) (string, []float32) {
+	if vectors == nil {
+		return "", nil
+	}
+	if vecOut := vectors.GetVector(); vecOut != nil {
+		return "", denseFromVectorOutput(vecOut)
+	}
+	named := vectors.GetVectors().GetVectors()
+	if vecOut, ok := named[preferred]; ok && preferred != "" {
+		if data := denseFromVectorOutput(vecOut); len(data) > 0 {
+			return preferred, data
+		}
+	}
+	names := make([]string, 0, len(named))
+	for name := range named {
+		if name != s.sparseVectorName {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if data := denseFromVectorOutput(named[name]); len(data) > 0 {
+			return name, data
+		}
+	}
+	return "", nil
+}
+
+func denseFromVectorOutput(vecOut *qdrant.VectorOutput) []float32 {
+	if vecOut == nil {
+		return nil
+	}
+	if dense := vecOut.GetDense(); dense != nil {
+		return dense.GetData()
+	}
+	// Deprecated flat field used by older Qdrant servers; sparse vectors also
+	// carry indices there and are skipped.
+	if vecOut.GetIndices() == nil {
+		return vecOut.GetData()
+	}
+	return nil
+}
+
+// SetPayload updates the given payload keys of a point, leaving its vectors
+// and any other payload keys untouched.
+func (s *QdrantStore) SetPayload(ctx context.Context, id string, payload map[string]any) error {
+	values, err := qdrant.TryValueMap(payload)
+	if err != nil {
+		return err
+	}
+	_, err = s.client.SetPayload(ctx, &qdrant.SetPayloadPoints{
+		CollectionName: s.collection,
+		Wait:           qdrant.PtrOf(true),
+		Payload:        values,
+		PointsSelector: qdrant.NewPointsSelector(qdrant.NewIDUUID(id)),
+	})
+	return err
+}
+
+// UpdateDenseVector sets the dense vector of an existing point, leaving its
+// sparse vector and payload intact.
+func (s *QdrantStore) UpdateDenseVector(ctx context.Context, id, vectorName string, vector []float32) error {
+	vectors := qdrant.NewVectorsDense(vector)
+	if s.usesNamedVectors && vectorName != "" {
+		vectors = qdrant.NewVectorsMap(map[string]*qdrant.Vector{vectorName: qdrant.NewVectorDense(vector)})
+	}
+	_, err := s.client.UpdateVectors(ctx, &qdrant.UpdatePointVectors{
+		CollectionName: s.collection,
+		Wait:           qdrant.PtrOf(true),
+		Points:         []*qdrant.PointVectors{{Id: qdrant.NewIDUUID(id), Vectors: vectors}},
+	})
+	return err
 }
 
 func (s *QdrantStore) Delete(ctx context.Context, id string) error {
@@ -479,7 +616,13 @@ func (s *QdrantStore) ensureCollection(ctx context.Context, vectors map[string]i
 		if err := s.refreshCollectionSchema(ctx, vectors); err != nil {
 			return err
 		}
-		return s.ensurePayloadIndexes(ctx)
+		if err := s.ensurePayloadIndexes(ctx); err != nil {
+			return err
+		}
+		s.backfillOnce.Do(func() {
+			go s.backfillEpochPayloads(context.WithoutCancel(ctx))
+		})
+		return nil
 	}
 	var vectorsConfig *qdrant.VectorsConfig
 	if len(vectors) > 0 {
@@ -528,9 +671,11 @@ func (s *QdrantStore) refreshCollectionSchema(ctx context.Context, vectors map[s
 	if vectorsConfig != nil && vectorsConfig.GetParamsMap() != nil {
 		s.usesNamedVectors = true
 		s.vectorNames = map[string]int{}
+		s.vectorDistances = map[string]qdrant.Distance{}
 		for name, vec := range vectorsConfig.GetParamsMap().GetMap() {
 			if vec != nil {
 				s.vectorNames[name] = int(vec.GetSize())
+				s.vectorDistances[name] = vec.GetDistance()
 			}
 		}
 		if len(vectors) > 0 {
@@ -544,6 +689,10 @@ func (s *QdrantStore) refreshCollectionSchema(ctx context.Context, vectors map[s
 	} else {
 		s.usesNamedVectors = false
 		s.vectorNames = nil
+		s.vectorDistances = nil
+		if vectorsConfig != nil && vectorsConfig.GetParams() != nil {
+			s.vectorDistances = map[string]qdrant.Distance{"": vectorsConfig.GetParams().GetDistance()}
+		}
 	}
 
 	sparseConfig := params.GetSparseVectorsConfig()
@@ -577,17 +726,136 @@ func (s *QdrantStore) refreshCollectionSchema(ctx context.Context, vectors map[s
 	return nil
 }
 
+// DeleteBefore removes points matching filters whose created_at is older than
+// before. Points are scrolled and deleted in batches so large scopes do not
+// produce a single oversized request. It returns the number of deleted points.
+func (s *QdrantStore) DeleteBefore(ctx context.Context, filters map[string]any, before time.Time) (int, error) {
+	filter := buildDeleteBeforeFilter(filters, before)
+	if filter == nil {
+		return 0, fmt.Errorf("delete before requires filters")
+	}
+	deleted := 0
+	for {
+		// Always read the first page: deleted points drop out of the filter,
+		// so the next batch starts at the head again.
+		points, err := s.client.Scroll(ctx, &qdrant.ScrollPoints{
+			CollectionName: s.collection,
+			Limit:          qdrant.PtrOf(uint32(deleteBeforeBatchSize)),
+			Filter:         filter,
+			WithPayload:    qdrant.NewWithPayload(false),
+		})
+		if err != nil {
+			return deleted, err
+		}
+		if len(points) == 0 {
+			return deleted, nil
+		}
+		ids := make([]string, 0, len(points))
+		for _, point := range points {
+			ids = append(ids, pointIDToString(point.GetId()))
+		}
+		if err := s.DeleteBatch(ctx, ids); err != nil {
+			return deleted, err
+		}
+		deleted += len(ids)
+		if len(points) < deleteBeforeBatchSize {
+			return deleted, nil
+		}
+	}
+}
+
+// buildDeleteBeforeFilter combines the scope filters with a range condition
+// on the created_at epoch. It returns nil when no scope filter is present so
+// callers never wipe a whole collection by time alone.
+func buildDeleteBeforeFilter(filters map[string]any, before time.Time) *qdrant.Filter {
+	filter := buildQdrantFilter(filters)
+	if filter == nil {
+		return nil
+	}
+	filter.Must = append(filter.Must, buildQdrantCondition(createdAtEpochKey, epochRange{lt: &before}))
+	return filter
+}
+
+// backfillEpochPayloads adds the epoch timestamp fields to points written
+// before they existed, so range filters on them see every point. Points are
+// otherwise migrated only when rewritten. It runs in the background once per
+// process; failures are logged and whatever is left is picked up on the next
+// start.
+func (s *QdrantStore) backfillEpochPayloads(ctx context.Context) {
+	filter := epochBackfillFilter()
+	include := make([]string, 0, 2*len(timestampEpochKeys))
+	for key, epochKey := range timestampEpochKeys {
+		include = append(include, key, epochKey)
+	}
+	var offset *qdrant.PointId
+	migrated := 0
+	for {
+		points, next, err := s.client.ScrollAndOffset(ctx, &qdrant.ScrollPoints{
+			CollectionName: s.collection,
+			Limit:          qdrant.PtrOf(uint32(deleteBeforeBatchSize)),
+			Filter:         filter,
+			Offset:         offset,
+			WithPayload:    qdrant.NewWithPayloadInclude(include...),
+		})
+		if err != nil {
+			s.logger.Warn("backfill epoch timestamps failed", slog.Int("migrated", migrated), slog.Any("error", err))
+			return
+		}
+		for _, point := range points {
+			missing := missingTimestampEpochs(valueMapToInterface(point.GetPayload()))
+			if len(missing) == 0 {
+				continue
+			}
+			if err := s.SetPayload(ctx, pointIDToString(point.GetId()), missing); err != nil {
+				s.logger.Warn("backfill epoch timestamps failed", slog.Int("migrated", migrated), slog.Any("error", err))
+				return
+			}
+			migrated++
+		}
+		if next == nil || len(points) == 0 {
+			break
+		}
+		offset = next
+	}
+	if migrated > 0 {
+		s.logger.Info("backfilled epoch timestamps", slog.Int("points", migrated))
+	}
+}
+
+// epochBackfillFilter matches points with an RFC3339 timestamp but no epoch
+// twin. Points never updated have no updated_at, so they are not matched
+// once created_at_ts is set.
+func epochBackfillFilter() *qdrant.Filter {
+	should := make([]*qdrant.Condition, 0, len(timestampEpochKeys))
+	for key, epochKey := range timestampEpochKeys {
+		should = append(should, qdrant.NewFilterAsCondition(&qdrant.Filter{
+			Must:    []*qdrant.Condition{qdrant.NewIsEmpty(epochKey)},
+			MustNot: []*qdrant.Condition{qdrant.NewIsEmpty(key)},
+		}))
+	}
+	return &qdrant.Filter{Should: should}
+}
+
 func (s *QdrantStore) ensurePayloadIndexes(ctx context.Context) error {
 	if s.client == nil {
 		return nil
 	}
-	fields := []string{"bot_id", "run_id"}
+	fields := map[string]qdrant.FieldType{
+		"bot_id":     qdrant.FieldType_FieldTypeKeyword,
+		"run_id":     qdrant.FieldType_FieldTypeKeyword,
+		"created_at": qdrant.FieldType_FieldTypeDatetime,
+		"updated_at": qdrant.FieldType_FieldTypeDatetime,
+
+		createdAtEpochKey:   qdrant.FieldType_FieldTypeInteger,
+		updatedAtEpochKey:   qdrant.FieldType_FieldTypeInteger,
+		embeddingPendingKey: qdrant.FieldType_FieldTypeBool,
+	}
 	wait := true
-	for _, field := range fields {
+	for field, fieldType := range fields {
 		_, err := s.client.CreateFieldIndex(ctx, &qdrant.CreateFieldIndexCollection{
 			CollectionName: s.collection,
 			FieldName:      field,
-			FieldType:      qdrant.FieldType_FieldTypeKeyword.Enum(),
+			FieldType:      fieldType.Enum(),
 			Wait:           &wait,
 		})
 		if err == nil {
@@ -653,6 +921,25 @@ func timeoutOrDefault(timeout time.Duration) time.Duration {
 	return timeout
 }
 
+// vectorDistance returns the distance metric name for a dense vector, as
+// reported in MemoryItem.Distance.
+func (s *QdrantStore) vectorDistance(vectorName string) string {
+	distance, ok := s.vectorDistances[vectorName]
+	if !ok {
+		return DistanceCosine
+	}
+	switch distance {
+	case qdrant.Distance_Dot:
+		return DistanceDot
+	case qdrant.Distance_Euclid:
+		return DistanceEuclid
+	case qdrant.Distance_Manhattan:
+		return DistanceManhattan
+	default:
+		return DistanceCosine
+	}
+}
+
 func buildQdrantFilter(filters map[string]any) *qdrant.Filter {
 	if len(filters) == 0 {
 		return nil
@@ -671,6 +958,24 @@ func buildQdrantFilter(filters map[string]any) *qdrant.Filter {
 	}
 }
 
+func buildQdrantAnyCondition(key string, values []string) *qdrant.Condition {
+	should := make([]*qdrant.Condition, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		should = append(should, qdrant.NewMatch(key, value))
+	}
+	switch len(should) {
+	case 0:
+		return nil
+	case 1:
+		return should[0]
+	}
+	return qdrant.NewFilterAsCondition(&qdrant.Filter{Should: should})
+}
+
 func cloneFilters(filters map[string]any) map[string]any {
 	if len(filters) == 0 {
 		return map[string]any{}
@@ -682,10 +987,49 @@ func cloneFilters(filters map[string]any) map[string]any {
 	return clone
 }
 
+// epochRange is a filter value matching epoch payload timestamps (Unix
+// seconds) in [gte, lt). Either bound may be nil.
+type epochRange struct {
+	gte *time.Time
+	lt  *time.Time
+}
+
+// epochCeil is t in whole Unix seconds, rounded up. Stored epochs are
+// truncated to the second, so rounding bounds up matches what comparing
+// against the RFC3339 strings would.
+func epochCeil(t time.Time) float64 {
+	sec := t.Unix()
+	if t.Nanosecond() > 0 {
+		sec++
+	}
+	return float64(sec)
+}
+
+// buildQdrantCondition maps a single filter entry to a Qdrant condition.
+// Slice values ([]string or []any) become a nested filter whose Should
+// clauses match any of the listed values; the outer filter still ANDs it
+// with the remaining keys.
 func buildQdrantCondition(key string, value any) *qdrant.Condition {
 	switch typed := value.(type) {
 	case string:
 		return qdrant.NewMatch(key, typed)
+	case []string:
+		return buildQdrantAnyCondition(key, typed)
+	case []any:
+		values := make([]string, 0, len(typed))
+		for _, item := range typed {
+			values = append(values, fmt.Sprint(item))
+		}
+		return buildQdrantAnyCondition(key, values)
+	case epochRange:
+		r := &qdrant.Range{}
+		if typed.gte != nil {
+			r.Gte = qdrant.PtrOf(epochCeil(*typed.gte))
+		}
+		if typed.lt != nil {
+			r.Lt = qdrant.PtrOf(epochCeil(*typed.lt))
+		}
+		return qdrant.NewRange(key, r)
 	case bool:
 		return qdrant.NewMatchBool(key, typed)
 	case int:
@@ -1,6 +1,11 @@
 package memory
 
-import "testing"
+import (
+	"testing"
+	"time"
+
+	"github.com/qdrant/go-client/qdrant"
+)
 
 func TestBuildQdrantFilter(t *testing.T) {
 	t.Parallel()
@@ -16,3 +21,171 @@ func TestBuildQdrantFilter(t *testing.T) {
 		t.Fatalf("expected two conditions, got %d", len(filter.Must))
 	}
 }
+
+func TestBuildDeleteBeforeFilter(t *testing.T) {
+	t.Parallel()
+
+	before := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	if filter := buildDeleteBeforeFilter(nil, before); filter != nil {
+		t.Fatalf("expected nil filter without scope, got %v", filter)
+	}
+
+	filter := buildDeleteBeforeFilter(map[string]any{"bot_id": "bot-1"}, before)
+	if filter == nil {
+		t.Fatalf("expected filter")
+	}
+	if len(filter.Must) != 2 {
+		t.Fatalf("expected scope and range conditions, got %d", len(filter.Must))
+	}
+	field := filter.Must[1].GetField()
+	if field.GetKey() != createdAtEpochKey {
+		t.Fatalf("expected %s range, got %q", createdAtEpochKey, field.GetKey())
+	}
+	if got := field.GetRange().GetLt(); got != float64(before.Unix()) {
+		t.Fatalf("expected lt %d, got %v", before.Unix(), got)
+	}
+}
+
+func TestEpochCeil(t *testing.T) {
+	t.Parallel()
+
+	at := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	if got := epochCeil(at); got != float64(at.Unix()) {
+		t.Fatalf("whole second: got %v, want %d", got, at.Unix())
+	}
+	if got := epochCeil(at.Add(time.Millisecond)); got != float64(at.Unix()+1) {
+		t.Fatalf("fraction: got %v, want %d", got, at.Unix()+1)
+	}
+}
+
+func TestBuildQdrantFilter_MultiValueScope(t *testing.T) {
+	t.Parallel()
+
+	filters := buildSearchFilters(SearchRequest{
+		BotID:  "bot-1",
+		RunIDs: []string{"run-1", "run-2", "run-1"},
+	})
+	filter := buildQdrantFilter(filters)
+	if filter == nil {
+		t.Fatalf("expected filter")
+	}
+	if len(filter.Must) != 2 {
+		t.Fatalf("expected two AND-ed conditions, got %d", len(filter.Must))
+	}
+	var nested int
+	for _, cond := range filter.Must {
+		if inner := cond.GetFilter(); inner != nil {
+			nested++
+			if len(inner.Should) != 2 {
+				t.Fatalf("expected two should conditions, got %d", len(inner.Should))
+			}
+		}
+	}
+	if nested != 1 {
+		t.Fatalf("expected one nested should filter, got %d", nested)
+	}
+}
+
+func TestBuildQdrantFilter_TimeRange(t *testing.T) {
+	t.Parallel()
+
+	after := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	before := after.AddDate(0, 0, 7)
+	filters := buildSearchFilters(SearchRequest{
+		BotID:     "bot-1",
+		TimeRange: TimeRange{CreatedAfter: &after, UpdatedBefore: &before},
+	})
+	filter := buildQdrantFilter(filters)
+	if filter == nil || len(filter.Must) != 3 {
+		t.Fatalf("expected scope and two range conditions, got %v", filter)
+	}
+	ranges := map[string]*qdrant.Range{}
+	for _, cond := range filter.Must {
+		if r := cond.GetField().GetRange(); r != nil {
+			ranges[cond.GetField().GetKey()] = r
+		}
+	}
+	created, updated := ranges[createdAtEpochKey], ranges[updatedAtEpochKey]
+	if created == nil || created.GetGte() != float64(after.Unix()) || created.Lt != nil {
+		t.Fatalf("unexpected created_at range: %v", created)
+	}
+	if updated == nil || updated.GetLt() != float64(before.Unix()) || updated.Gte != nil {
+		t.Fatalf("unexpected updated_at range: %v", updated)
+	}
+}
+
+func TestNormalizeScore(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		score    float64
+		distance string
+		want     float64
+	}{
+		{1, DistanceCosine, 1},
+		{-1, DistanceCosine, 0},
+		{0, DistanceCosine, 0.5},
+		{0, DistanceEuclid, 1},
+		{3, DistanceManhattan, 0.25},
+		{42.5, DistanceDot, 42.5},
+		{0.03, DistanceRankFusion, 0.03},
+	}
+	for _, tt := range tests {
+		if got := normalizeScore(tt.score, tt.distance); got != tt.want {
+			t.Errorf("normalizeScore(%v, %q) = %v, want %v", tt.score, tt.distance, got, tt.want)
+		}
+	}
+}
+
+func TestExtractDenseVector(t *testing.T) {
+	t.Parallel()
+
+	store := &QdrantStore{sparseVectorName: sparseHashVectorName}
+	dense := func(data ...float32) *qdrant.VectorOutput {
+		return &qdrant.VectorOutput{Vector: &qdrant.VectorOutput_Dense{Dense: &qdrant.DenseVector{Data: data}}}
+	}
+	named := &qdrant.VectorsOutput{VectorsOptions: &qdrant.VectorsOutput_Vectors{Vectors: &qdrant.NamedVectorsOutput{
+		Vectors: map[string]*qdrant.VectorOutput{
+			"text-model": dense(1, 2),
+			"mm-model":   dense(3, 4),
+			sparseHashVectorName: {Vector: &qdrant.VectorOutput_Sparse{Sparse: &qdrant.SparseVector{
+				Indices: []uint32{1}, Values: []float32{0.5},
+			}}},
+		},
+	}}}
+
+	name, vec := store.extractDenseVector(named, "text-model")
+	if name != "text-model" || len(vec) != 2 || vec[0] != 1 {
+		t.Fatalf("expected preferred vector, got %q %v", name, vec)
+	}
+	name, vec = store.extractDenseVector(named, "missing")
+	if name != "mm-model" || len(vec) != 2 || vec[0] != 3 {
+		t.Fatalf("expected fallback to first dense vector, got %q %v", name, vec)
+	}
+
+	unnamed := &qdrant.VectorsOutput{VectorsOptions: &qdrant.VectorsOutput_Vector{Vector: dense(5, 6)}}
+	name, vec = store.extractDenseVector(unnamed, "text-model")
+	if name != "" || len(vec) != 2 || vec[0] != 5 {
+		t.Fatalf("expected unnamed vector, got %q %v", name, vec)
+	}
+}
+
+func TestEpochBackfillFilter(t *testing.T) {
+	t.Parallel()
+
+	filter := epochBackfillFilter()
+	if len(filter.Should) != len(timestampEpochKeys) {
+		t.Fatalf("expected one clause per timestamp, got %d", len(filter.Should))
+	}
+	for _, cond := range filter.Should {
+		nested := cond.GetFilter()
+		if len(nested.GetMust()) != 1 || len(nested.GetMustNot()) != 1 {
+			t.Fatalf("expected an empty epoch and a set timestamp, got %v", nested)
+		}
+		epochKey := nested.GetMust()[0].GetIsEmpty().GetKey()
+		key := nested.GetMustNot()[0].GetIsEmpty().GetKey()
+		if timestampEpochKeys[key] != epochKey {
+			t.Fatalf("clause pairs %q with %q", key, epochKey)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package memory
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	defaultRecencyHalfLifeDays = 30
+	// recencyOverfetchFactor widens the candidate pool when recency decay
+	// is on, so fresh memories just outside the top results can move up.
+	recencyOverfetchFactor = 3
+)
+
+// validateRecency checks the recency options of a search request.
+func validateRecency(req SearchRequest) error {
+	if req.RecencyWeight < 0 || req.RecencyWeight > 1 {
+		return fmt.Errorf("recency_weight must be between 0 and 1")
+	}
+	if req.RecencyHalfLifeDays < 0 {
+		return fmt.Errorf("recency_half_life_days must not be negative")
+	}
+	return nil
+}
+
+// applyRecencyDecay blends each item's relevance with an exponential decay
+// on the age of its last update (or creation):
+//
+//	score = relevance * ((1 - weight) + weight * 0.5^(age / halfLife))
+//
+// so at weight 1 a memory one half-life old scores half as much as an
+// equally similar new one. Items without a timestamp are not decayed.
+// Score and Relevance both take the blended value, Recency the decay, and
+// items are re-sorted by score.
+func applyRecencyDecay(items []MemoryItem, weight, halfLifeDays float64, now time.Time) {
+	if weight <= 0 || len(items) == 0 {
+		return
+	}
+	if halfLifeDays <= 0 {
+		halfLifeDays = defaultRecencyHalfLifeDays
+	}
+	halfLife := halfLifeDays * 24 * float64(time.Hour)
+	for i := range items {
+		decay := 1.0
+		if ts, ok := memoryTimestamp(items[i]); ok {
+			age := max(now.Sub(ts), 0)
+			decay = math.Pow(0.5, float64(age)/halfLife)
+		}
+		blended := items[i].Relevance * ((1 - weight) + weight*decay)
+		items[i].Score = blended
+		items[i].Relevance = blended
+		items[i].Recency = decay
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Score > items[j].Score
+	})
+}
+
+// memoryTimestamp returns when the memory last changed, from the epoch
+// payload fields. Points not yet backfilled fall back to the RFC3339 ones.
+func memoryTimestamp(item MemoryItem) (time.Time, bool) {
+	for _, ts := range []int64{item.UpdatedAtTs, item.CreatedAtTs} {
+		if ts > 0 {
+			return time.Unix(ts, 0), true
+		}
+	}
+	for _, raw := range []string{item.UpdatedAt, item.CreatedAt} {
+		if raw == "" {
+			continue
+		}
+		if t, err := time.Parse(time.RFC3339, raw); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,53 @@
+package memory
+
+// Distance names reported in MemoryItem.Distance.
+const (
+	DistanceCosine    = "cosine"
+	DistanceDot       = "dot"
+	DistanceEuclid    = "euclid"
+	DistanceManhattan = "manhattan"
+	// DistanceSparseDot is the dot product over BM25 sparse vectors.
+	DistanceSparseDot = "sparse_dot"
+	// DistanceRankFusion marks scores produced by reciprocal rank fusion.
+	DistanceRankFusion = "rrf"
+	// DistanceRerank marks scores assigned by the configured Reranker.
+	DistanceRerank = "rerank"
+)
+
+// annotateRelevance sets Distance and a normalized Relevance on each item.
+func annotateRelevance(items []MemoryItem, distance string) {
+	for i := range items {
+		items[i].Distance = distance
+		items[i].Relevance = normalizeScore(items[i].Score, distance)
+	}
+}
+
+// normalizeScore maps a raw Qdrant score to a relevance value.
+//
+//   - cosine: similarity in [-1, 1] is mapped linearly to [0, 1].
+//   - euclid / manhattan: distances (lower is closer) become 1 / (1 + d).
+//   - dot, sparse_dot, rrf, rerank: unbounded or model-defined, so the raw
+//     score is kept.
+func normalizeScore(score float64, distance string) float64 {
+	switch distance {
+	case DistanceCosine:
+		return clamp01((score + 1) / 2)
+	case DistanceEuclid, DistanceManhattan:
+		if score < 0 {
+			score = 0
+		}
+		return 1 / (1 + score)
+	default:
+		return score
+	}
+}
+
+func clamp01(v float64) float64 {
+	if v < 0 {
+		return 0
+	}
+	if v > 1 {
+		return 1
+	}
+	return v
+}
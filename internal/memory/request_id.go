@@ -0,0 +1,24 @@
+package memory
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/memohai/memoh/internal/logger"
+)
+
+// qdrantGrpcOptions forwards the request id of each call to Qdrant as
+// metadata when request id propagation is enabled.
+func qdrantGrpcOptions() []grpc.DialOption {
+	return []grpc.DialOption{grpc.WithChainUnaryInterceptor(requestIDUnaryInterceptor)}
+}
+
+func requestIDUnaryInterceptor(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if requestID := logger.OutgoingRequestID(ctx); requestID != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, strings.ToLower(logger.RequestIDHeader), requestID)
+	}
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
@@ -0,0 +1,94 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+const (
+	defaultSearchLimit = 10
+	// rerankOverfetchFactor controls how many candidates are pulled from
+	// Qdrant per requested result when a reranker is configured.
+	rerankOverfetchFactor = 3
+)
+
+// Reranker reorders search candidates by relevance to the query (e.g. with a
+// cross-encoder). Implementations should set MemoryItem.Score to their own
+// relevance score so downstream thresholds keep working.
+type Reranker interface {
+	Rerank(ctx context.Context, query string, items []MemoryItem) ([]MemoryItem, error)
+}
+
+// PassthroughReranker keeps the store ordering and scores untouched.
+type PassthroughReranker struct{}
+
+func (PassthroughReranker) Rerank(_ context.Context, _ string, items []MemoryItem) ([]MemoryItem, error) {
+	return items, nil
+}
+
+// SetReranker configures an optional reranker applied after vector search.
+func (s *Service) SetReranker(reranker Reranker) {
+	s.reranker = reranker
+}
+
+// RerankEnabled reports whether search results go through a reranker.
+func (s *Service) RerankEnabled() bool {
+	return s.reranker != nil
+}
+
+// SearchCandidates runs the store search without reranking. When a reranker
+// is configured it over-fetches so Rerank has a wider pool to pick from.
+func (s *Service) SearchCandidates(ctx context.Context, req SearchRequest) (SearchResponse, error) {
+	if s.reranker == nil {
+		return s.search(ctx, req)
+	}
+	candidateReq := req
+	candidateReq.Limit = searchLimit(req.Limit) * rerankOverfetchFactor
+	return s.search(ctx, candidateReq)
+}
+
+// Rerank applies the configured reranker to candidates, orders them by the
+// reranked score and trims to limit. Without a reranker the candidates are
+// only trimmed. Items whose score the reranker left unchanged, such as all
+// of them with PassthroughReranker, keep the store's Distance and Relevance.
+func (s *Service) Rerank(ctx context.Context, query string, candidates []MemoryItem, limit int) ([]MemoryItem, error) {
+	results := candidates
+	if s.reranker != nil {
+		storeScores := make(map[string]float64, len(candidates))
+		for _, item := range candidates {
+			storeScores[item.ID] = item.Score
+		}
+		reranked, err := s.reranker.Rerank(ctx, query, candidates)
+		if err != nil {
+			return nil, fmt.Errorf("rerank: %w", err)
+		}
+		results = reranked
+		for i := range results {
+			if score, ok := storeScores[results[i].ID]; ok && score == results[i].Score && results[i].Distance != "" {
+				continue
+			}
+			annotateRelevance(results[i:i+1], DistanceRerank)
+		}
+		sortByScore(results)
+	}
+	limit = searchLimit(limit)
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+func searchLimit(limit int) int {
+	if limit <= 0 {
+		return defaultSearchLimit
+	}
+	return limit
+}
+
+// sortByScore orders items by descending score, keeping ties stable.
+func sortByScore(items []MemoryItem) {
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Score > items[j].Score
+	})
+}
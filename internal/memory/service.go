@@ -9,12 +9,14 @@ import (
 	"math"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/qdrant/go-client/qdrant"
 
 	"github.com/memohai/memoh/internal/embeddings"
+	"github.com/memohai/memoh/internal/metrics"
 )
 
 type Service struct {
@@ -23,6 +25,15 @@ type Service struct {
 	store                    *QdrantStore
 	resolver                 *embeddings.Resolver
 	bm25                     *BM25Indexer
+	reranker                 Reranker
+	extractMaxTokens         int
+	addActionRetries         int
+	addActionBackoff         time.Duration
+	rawByDefault             bool
+	embeddingFallback        EmbeddingFallback
+	history                  HistoryStore
+	dimMu                    sync.Mutex
+	botDims                  map[string]int
 	logger                   *slog.Logger
 	defaultTextModelID       string
 	defaultMultimodalModelID string
@@ -41,6 +52,19 @@ func NewService(log *slog.Logger, llm LLM, embedder embeddings.Embedder, store *
 	}
 }
 
+// SetDefaultInfer sets whether Add extracts facts with the LLM when the
+// request leaves Infer unset. An explicit req.Infer always wins.
+func (s *Service) SetDefaultInfer(infer bool) {
+	s.rawByDefault = !infer
+}
+
+func (s *Service) shouldInfer(infer *bool) bool {
+	if infer != nil {
+		return *infer
+	}
+	return !s.rawByDefault
+}
+
 func (s *Service) Add(ctx context.Context, req AddRequest) (SearchResponse, error) {
 	if req.Message == "" && len(req.Messages) == 0 {
 		return SearchResponse{}, fmt.Errorf("message or messages is required")
@@ -53,15 +77,11 @@ func (s *Service) Add(ctx context.Context, req AddRequest) (SearchResponse, erro
 	filters := buildFilters(req)
 
 	embeddingEnabled := req.EmbeddingEnabled != nil && *req.EmbeddingEnabled
-	if req.Infer != nil && !*req.Infer {
-		return s.addRawMessages(ctx, messages, filters, req.Metadata, embeddingEnabled)
+	if !s.shouldInfer(req.Infer) {
+		return s.addRawMessages(ctx, messages, filters, req.Metadata, req.Origin, embeddingEnabled)
 	}
 
-	extractResp, err := s.llm.Extract(ctx, ExtractRequest{
-		Messages: messages,
-		Filters:  filters,
-		Metadata: req.Metadata,
-	})
+	extractResp, err := s.extractFacts(ctx, messages, filters, req.Metadata)
 	if err != nil {
 		return SearchResponse{}, err
 	}
@@ -95,46 +115,52 @@ func (s *Service) Add(ctx context.Context, req AddRequest) (SearchResponse, erro
 		}
 	}
 
-	results := make([]MemoryItem, 0, len(actions))
-	for _, action := range actions {
-		switch strings.ToUpper(action.Event) {
-		case "ADD":
-			item, err := s.applyAdd(ctx, action.Text, filters, req.Metadata, embeddingEnabled)
-			if err != nil {
-				return SearchResponse{}, err
-			}
-			item.Metadata = mergeMetadata(item.Metadata, map[string]any{
-				"event": "ADD",
-			})
-			results = append(results, item)
-		case "UPDATE":
-			item, err := s.applyUpdate(ctx, action.ID, action.Text, filters, req.Metadata, embeddingEnabled)
-			if err != nil {
-				return SearchResponse{}, err
-			}
-			item.Metadata = mergeMetadata(item.Metadata, map[string]any{
-				"event":           "UPDATE",
-				"previous_memory": action.OldMemory,
-			})
-			results = append(results, item)
-		case "DELETE":
-			item, err := s.applyDelete(ctx, action.ID)
-			if err != nil {
-				return SearchResponse{}, err
-			}
-			item.Metadata = mergeMetadata(item.Metadata, map[string]any{
-				"event": "DELETE",
-			})
-			results = append(results, item)
-		default:
-			return SearchResponse{}, fmt.Errorf("unknown action: %s", action.Event)
+	origin := buildOrigin(req.Origin, messages)
+	results, err := s.applyActions(ctx, actions, func(ctx context.Context, action DecisionAction) (MemoryItem, error) {
+		metadata := req.Metadata
+		if derived := factMetadata(action.Text, messages); derived != nil {
+			metadata = mergeMetadata(derived, req.Metadata)
 		}
+		return s.applyAction(ctx, action, filters, metadata, origin, embeddingEnabled)
+	})
+	if err != nil {
+		// Actions that succeeded are already persisted; return them with the
+		// aggregated error so callers can see what was applied.
+		return SearchResponse{Results: results}, err
 	}
-
 	return SearchResponse{Results: results}, nil
 }
 
 func (s *Service) Search(ctx context.Context, req SearchRequest) (SearchResponse, error) {
+	if err := validateRecency(req); err != nil {
+		return SearchResponse{}, err
+	}
+	limit := req.Limit
+	if req.RecencyWeight > 0 {
+		req.Limit = searchLimit(limit) * recencyOverfetchFactor
+	}
+	candidates, err := s.SearchCandidates(ctx, req)
+	if err != nil {
+		return SearchResponse{}, err
+	}
+	if s.reranker != nil {
+		results, err := s.Rerank(ctx, req.Query, candidates.Results, req.Limit)
+		if err != nil {
+			return SearchResponse{}, err
+		}
+		candidates = SearchResponse{Results: results, Relations: candidates.Relations}
+	}
+	if req.RecencyWeight > 0 {
+		// Decay is applied after reranking so it adjusts the final scores.
+		applyRecencyDecay(candidates.Results, req.RecencyWeight, req.RecencyHalfLifeDays, time.Now())
+		if limit = searchLimit(limit); len(candidates.Results) > limit {
+			candidates.Results = candidates.Results[:limit]
+		}
+	}
+	return candidates, nil
+}
+
+func (s *Service) search(ctx context.Context, req SearchRequest) (SearchResponse, error) {
 	if strings.TrimSpace(req.Query) == "" {
 		return SearchResponse{}, fmt.Errorf("query is required")
 	}
@@ -177,6 +203,7 @@ func (s *Service) Search(ctx context.Context, req SearchRequest) (SearchResponse
 				}
 				results = append(results, item)
 			}
+			annotateRelevance(results, s.store.vectorDistance(vectorName))
 			return SearchResponse{Results: results}, nil
 		}
 		pointsBySource, scoresBySource, err := s.store.SearchBySources(ctx, result.Embedding, req.Limit, filters, req.Sources, vectorName)
@@ -184,6 +211,7 @@ func (s *Service) Search(ctx context.Context, req SearchRequest) (SearchResponse
 			return SearchResponse{}, err
 		}
 		results := fuseByRankFusion(pointsBySource, scoresBySource)
+		annotateRelevance(results, DistanceRankFusion)
 		return SearchResponse{Results: results}, nil
 	}
 
@@ -193,6 +221,14 @@ func (s *Service) Search(ctx context.Context, req SearchRequest) (SearchResponse
 		}
 		vector, err := s.embedder.Embed(ctx, req.Query)
 		if err != nil {
+			if !s.degradeOnEmbeddingError(ctx, "search", err) {
+				return SearchResponse{}, err
+			}
+			resp, err := s.searchSparse(ctx, req, filters)
+			resp.Degraded = true
+			return resp, err
+		}
+		if err := s.checkEmbeddingDimension(ctx, filters, len(vector)); err != nil {
 			return SearchResponse{}, err
 		}
 		vectorName := s.vectorNameForText()
@@ -209,6 +245,7 @@ func (s *Service) Search(ctx context.Context, req SearchRequest) (SearchResponse
 				}
 				results = append(results, item)
 			}
+			annotateRelevance(results, s.store.vectorDistance(vectorName))
 			return SearchResponse{Results: results}, nil
 		}
 		pointsBySource, scoresBySource, err := s.store.SearchBySources(ctx, vector, req.Limit, filters, req.Sources, vectorName)
@@ -216,9 +253,14 @@ func (s *Service) Search(ctx context.Context, req SearchRequest) (SearchResponse
 			return SearchResponse{}, err
 		}
 		results := fuseByRankFusion(pointsBySource, scoresBySource)
+		annotateRelevance(results, DistanceRankFusion)
 		return SearchResponse{Results: results}, nil
 	}
+	return s.searchSparse(ctx, req, filters)
+}
 
+// searchSparse runs the keyword (BM25) search.
+func (s *Service) searchSparse(ctx context.Context, req SearchRequest, filters map[string]any) (SearchResponse, error) {
 	if s.bm25 == nil {
 		return SearchResponse{}, fmt.Errorf("bm25 indexer not configured")
 	}
@@ -248,6 +290,7 @@ func (s *Service) Search(ctx context.Context, req SearchRequest) (SearchResponse
 			}
 			results = append(results, item)
 		}
+		annotateRelevance(results, DistanceSparseDot)
 		return SearchResponse{Results: results}, nil
 	}
 	pointsBySource, scoresBySource, err := s.store.SearchSparseBySources(ctx, indices, values, req.Limit, filters, req.Sources, wantStats)
@@ -267,6 +310,7 @@ func (s *Service) Search(ctx context.Context, req SearchRequest) (SearchResponse
 		}
 	}
 	results := fuseByRankFusion(pointsBySource, scoresBySource)
+	annotateRelevance(results, DistanceRankFusion)
 	if wantStats {
 		for i := range results {
 			if p, ok := sparseByID[results[i].ID]; ok {
@@ -352,7 +396,7 @@ func (s *Service) Update(ctx context.Context, req UpdateRequest) (MemoryItem, er
 		return MemoryItem{}, fmt.Errorf("bm25 indexer not configured")
 	}
 
-	existing, err := s.store.Get(ctx, req.MemoryID)
+	existing, err := s.store.Get(ctx, req.MemoryID, false, "")
 	if err != nil {
 		return MemoryItem{}, err
 	}
@@ -367,13 +411,13 @@ func (s *Service) Update(ctx context.Context, req UpdateRequest) (MemoryItem, er
 		var detectErr error
 		oldLang, detectErr = s.detectLanguage(ctx, oldText)
 		if detectErr != nil {
-			s.logger.Warn("detect language failed for old text", slog.Any("error", detectErr))
+			s.logger.WarnContext(ctx, "detect language failed for old text", slog.Any("error", detectErr))
 		}
 	}
 	if strings.TrimSpace(oldText) != "" && strings.TrimSpace(oldLang) != "" {
 		oldFreq, oldLen, err := s.bm25.TermFrequencies(oldLang, oldText)
 		if err != nil {
-			s.logger.Warn("bm25 term frequencies failed", slog.String("lang", oldLang), slog.Any("error", err))
+			s.logger.WarnContext(ctx, "bm25 term frequencies failed", slog.String("lang", oldLang), slog.Any("error", err))
 		} else {
 			s.bm25.RemoveDocument(oldLang, oldFreq, oldLen)
 		}
@@ -391,7 +435,8 @@ func (s *Service) Update(ctx context.Context, req UpdateRequest) (MemoryItem, er
 
 	payload["data"] = req.Memory
 	payload["hash"] = hashMemory(req.Memory)
-	payload["updated_at"] = time.Now().UTC().Format(time.RFC3339)
+	setTimestamp(payload, "updated_at", time.Now())
+	backfillTimestampEpochs(payload)
 	payload["lang"] = newLang
 
 	embeddingEnabled := req.EmbeddingEnabled != nil && *req.EmbeddingEnabled
@@ -403,27 +448,64 @@ func (s *Service) Update(ctx context.Context, req UpdateRequest) (MemoryItem, er
 		Payload:          payload,
 	}
 	if embeddingEnabled {
-		if s.embedder == nil {
-			return MemoryItem{}, fmt.Errorf("embedder not configured")
-		}
-		vector, err := s.embedder.Embed(ctx, req.Memory)
-		if err != nil {
+		if err := s.embedPointText(ctx, "update", &point, req.Memory); err != nil {
 			return MemoryItem{}, err
 		}
-		point.Vector = vector
-		point.VectorName = s.vectorNameForText()
 	}
 	if err := s.store.Upsert(ctx, []qdrantPoint{point}); err != nil {
 		return MemoryItem{}, err
 	}
-	return payloadToMemoryItem(req.MemoryID, payload), nil
+	item := payloadToMemoryItem(req.MemoryID, payload)
+	s.recordRevision(ctx, HistoryEventUpdate, item)
+	return item, nil
+}
+
+// UpdateMetadata patches a memory's metadata without touching its text or
+// vectors. When merge is true the new keys are merged into the existing
+// metadata; otherwise the metadata is replaced.
+func (s *Service) UpdateMetadata(ctx context.Context, memoryID string, metadata map[string]any, merge bool) (MemoryItem, error) {
+	if strings.TrimSpace(memoryID) == "" {
+		return MemoryItem{}, fmt.Errorf("memory_id is required")
+	}
+	if s.store == nil {
+		return MemoryItem{}, fmt.Errorf("qdrant store not configured")
+	}
+	existing, err := s.store.Get(ctx, memoryID, false, "")
+	if err != nil {
+		return MemoryItem{}, err
+	}
+	if existing == nil {
+		return MemoryItem{}, fmt.Errorf("memory not found")
+	}
+
+	var nextMetadata map[string]any
+	if merge {
+		nextMetadata = mergeMetadata(existing.Payload["metadata"], metadata)
+	} else {
+		nextMetadata = mergeMetadata(nil, metadata)
+	}
+	patch := map[string]any{
+		"metadata": nextMetadata,
+	}
+	for key, value := range missingTimestampEpochs(existing.Payload) {
+		patch[key] = value
+	}
+	setTimestamp(patch, "updated_at", time.Now())
+	if err := s.store.SetPayload(ctx, memoryID, patch); err != nil {
+		return MemoryItem{}, err
+	}
+	payload := existing.Payload
+	for k, v := range patch {
+		payload[k] = v
+	}
+	return payloadToMemoryItem(memoryID, payload), nil
 }
 
 func (s *Service) Get(ctx context.Context, memoryID string) (MemoryItem, error) {
 	if strings.TrimSpace(memoryID) == "" {
 		return MemoryItem{}, fmt.Errorf("memory_id is required")
 	}
-	point, err := s.store.Get(ctx, memoryID)
+	point, err := s.store.Get(ctx, memoryID, false, "")
 	if err != nil {
 		return MemoryItem{}, err
 	}
@@ -433,6 +515,59 @@ func (s *Service) Get(ctx context.Context, memoryID string) (MemoryItem, error)
 	return payloadToMemoryItem(point.ID, point.Payload), nil
 }
 
+// Related returns up to limit memories closest to memoryID, using the
+// point's stored vector so the text is not re-embedded. Results are scoped to
+// the same bot/agent/run as the source memory and exclude it.
+func (s *Service) Related(ctx context.Context, memoryID string, limit int) ([]MemoryItem, error) {
+	if strings.TrimSpace(memoryID) == "" {
+		return nil, fmt.Errorf("memory_id is required")
+	}
+	if s.store == nil {
+		return nil, fmt.Errorf("qdrant store not configured")
+	}
+	if limit <= 0 {
+		limit = 10
+	}
+	point, err := s.store.Get(ctx, memoryID, true, s.vectorNameForText())
+	if err != nil {
+		return nil, err
+	}
+	if point == nil {
+		return nil, fmt.Errorf("memory not found")
+	}
+	if len(point.Vector) == 0 {
+		return nil, fmt.Errorf("memory %s has no stored vector", memoryID)
+	}
+	filters := map[string]any{}
+	for _, key := range []string{"bot_id", "agent_id", "run_id"} {
+		if v, ok := point.Payload[key].(string); ok && v != "" {
+			filters[key] = v
+		}
+	}
+	if len(filters) == 0 {
+		return nil, fmt.Errorf("memory %s has no scope", memoryID)
+	}
+
+	// Ask for one extra hit since the source point is its own nearest neighbor.
+	points, scores, err := s.store.Search(ctx, point.Vector, limit+1, filters, point.VectorName)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]MemoryItem, 0, limit)
+	for idx, p := range points {
+		if p.ID == point.ID || len(results) == limit {
+			continue
+		}
+		item := payloadToMemoryItem(p.ID, p.Payload)
+		if idx < len(scores) {
+			item.Score = scores[idx]
+		}
+		results = append(results, item)
+	}
+	annotateRelevance(results, s.store.vectorDistance(point.VectorName))
+	return results, nil
+}
+
 func (s *Service) GetAll(ctx context.Context, req GetAllRequest) (SearchResponse, error) {
 	filters := map[string]any{}
 	for k, v := range req.Filters {
@@ -450,6 +585,7 @@ func (s *Service) GetAll(ctx context.Context, req GetAllRequest) (SearchResponse
 	if len(filters) == 0 {
 		return SearchResponse{}, fmt.Errorf("bot_id, agent_id or run_id is required")
 	}
+	req.TimeRange.apply(filters)
 
 	wantStats := !req.NoStats
 	points, err := s.store.List(ctx, req.Limit, filters, wantStats)
@@ -471,9 +607,22 @@ func (s *Service) Delete(ctx context.Context, memoryID string) (DeleteResponse,
 	if strings.TrimSpace(memoryID) == "" {
 		return DeleteResponse{}, fmt.Errorf("memory_id is required")
 	}
+	var existing *qdrantPoint
+	if s.history != nil {
+		point, err := s.store.Get(ctx, memoryID, false, "")
+		if err != nil {
+			s.logger.WarnContext(ctx, "load memory before delete failed", slog.String("memory_id", memoryID), slog.Any("error", err))
+		}
+		existing = point
+	}
 	if err := s.store.Delete(ctx, memoryID); err != nil {
 		return DeleteResponse{}, err
 	}
+	item := MemoryItem{ID: memoryID}
+	if existing != nil {
+		item = payloadToMemoryItem(memoryID, existing.Payload)
+	}
+	s.recordRevision(ctx, HistoryEventDelete, item)
 	return DeleteResponse{Message: "Memory deleted successfully!"}, nil
 }
 
@@ -494,6 +643,7 @@ func (s *Service) DeleteBatch(ctx context.Context, memoryIDs []string) (DeleteRe
 	if err := s.store.DeleteBatch(ctx, cleaned); err != nil {
 		return DeleteResponse{}, err
 	}
+	metrics.MemoryOperations.WithLabelValues(metrics.MemoryOpDelete).Add(float64(len(cleaned)))
 	return DeleteResponse{Message: fmt.Sprintf("%d memories deleted successfully!", len(cleaned))}, nil
 }
 
@@ -517,9 +667,45 @@ func (s *Service) DeleteAll(ctx context.Context, req DeleteAllRequest) (DeleteRe
 	if err := s.store.DeleteAll(ctx, filters); err != nil {
 		return DeleteResponse{}, err
 	}
+	s.forgetEmbeddingDimension(filterBotID(filters))
 	return DeleteResponse{Message: "Memories deleted successfully!"}, nil
 }
 
+// DeleteBefore removes all memories in the given scope created before the
+// cutoff and returns how many were deleted. At least one scope filter
+// (bot_id, agent_id or run_id) is required.
+func (s *Service) DeleteBefore(ctx context.Context, req DeleteAllRequest, before time.Time) (int, error) {
+	filters := map[string]any{}
+	for k, v := range req.Filters {
+		filters[k] = v
+	}
+	if req.BotID != "" {
+		filters["bot_id"] = req.BotID
+	}
+	if req.AgentID != "" {
+		filters["agent_id"] = req.AgentID
+	}
+	if req.RunID != "" {
+		filters["run_id"] = req.RunID
+	}
+	if len(filters) == 0 {
+		return 0, fmt.Errorf("bot_id, agent_id or run_id is required")
+	}
+	if before.IsZero() {
+		return 0, fmt.Errorf("before is required")
+	}
+	deleted, err := s.store.DeleteBefore(ctx, filters, before)
+	if deleted > 0 {
+		metrics.MemoryOperations.WithLabelValues(metrics.MemoryOpDelete).Add(float64(deleted))
+		s.logger.InfoContext(ctx, "deleted memories before cutoff",
+			slog.Any("filters", filters),
+			slog.Time("before", before),
+			slog.Int("deleted", deleted),
+		)
+	}
+	return deleted, err
+}
+
 func (s *Service) Compact(ctx context.Context, filters map[string]any, ratio float64, decayDays int) (CompactResult, error) {
 	if s.llm == nil {
 		return CompactResult{}, fmt.Errorf("llm not configured")
@@ -605,7 +791,7 @@ func (s *Service) Compact(ctx context.Context, filters map[string]any, ratio flo
 		if strings.TrimSpace(fact) == "" {
 			continue
 		}
-		item, err := s.applyAdd(ctx, fact, filters, nil, false)
+		item, err := s.applyAdd(ctx, fact, filters, nil, nil, false)
 		if err != nil {
 			return CompactResult{}, fmt.Errorf("compact add failed: %w", err)
 		}
@@ -680,7 +866,7 @@ func (s *Service) WarmupBM25(ctx context.Context, batchSize int) error {
 			}
 			termFreq, docLen, err := s.bm25.TermFrequencies(lang, text)
 			if err != nil {
-				s.logger.Warn("bm25 warmup: term frequencies failed", slog.String("id", point.ID), slog.Any("error", err))
+				s.logger.WarnContext(ctx, "bm25 warmup: term frequencies failed", slog.String("id", point.ID), slog.Any("error", err))
 				continue
 			}
 			s.bm25.AddDocument(lang, termFreq, docLen)
@@ -693,10 +879,10 @@ func (s *Service) WarmupBM25(ctx context.Context, batchSize int) error {
 	return nil
 }
 
-func (s *Service) addRawMessages(ctx context.Context, messages []Message, filters map[string]any, metadata map[string]any, embeddingEnabled bool) (SearchResponse, error) {
+func (s *Service) addRawMessages(ctx context.Context, messages []Message, filters map[string]any, metadata map[string]any, origin *OriginOptions, embeddingEnabled bool) (SearchResponse, error) {
 	results := make([]MemoryItem, 0, len(messages))
 	for _, message := range messages {
-		item, err := s.applyAdd(ctx, message.Content, filters, metadata, embeddingEnabled)
+		item, err := s.applyAdd(ctx, message.Content, filters, mergeMetadata(messageMetadata(message), metadata), buildOrigin(origin, []Message{message}), embeddingEnabled)
 		if err != nil {
 			return SearchResponse{}, err
 		}
@@ -744,7 +930,7 @@ func (s *Service) collectCandidates(ctx context.Context, facts []string, filters
 	return candidates, nil
 }
 
-func (s *Service) applyAdd(ctx context.Context, text string, filters map[string]any, metadata map[string]any, embeddingEnabled bool) (MemoryItem, error) {
+func (s *Service) applyAdd(ctx context.Context, text string, filters map[string]any, metadata map[string]any, origin *MemoryOrigin, embeddingEnabled bool) (MemoryItem, error) {
 	if s.store == nil {
 		return MemoryItem{}, fmt.Errorf("qdrant store not configured")
 	}
@@ -760,9 +946,18 @@ func (s *Service) applyAdd(ctx context.Context, text string, filters map[string]
 		return MemoryItem{}, err
 	}
 	sparseIndices, sparseValues := s.bm25.AddDocument(lang, termFreq, docLen)
+	// The document only stays in the corpus stats once it is stored, so a
+	// retried action does not count it twice.
+	stored := false
+	defer func() {
+		if !stored {
+			s.bm25.RemoveDocument(lang, termFreq, docLen)
+		}
+	}()
 	id := uuid.NewString()
 	payload := buildPayload(text, filters, metadata, "")
 	payload["lang"] = lang
+	applyOriginToPayload(payload, origin)
 	point := qdrantPoint{
 		ID:               id,
 		SparseIndices:    sparseIndices,
@@ -771,20 +966,17 @@ func (s *Service) applyAdd(ctx context.Context, text string, filters map[string]
 		Payload:          payload,
 	}
 	if embeddingEnabled {
-		if s.embedder == nil {
-			return MemoryItem{}, fmt.Errorf("embedder not configured")
-		}
-		vector, err := s.embedder.Embed(ctx, text)
-		if err != nil {
+		if err := s.embedPointText(ctx, "add", &point, text); err != nil {
 			return MemoryItem{}, err
 		}
-		point.Vector = vector
-		point.VectorName = s.vectorNameForText()
 	}
 	if err := s.store.Upsert(ctx, []qdrantPoint{point}); err != nil {
 		return MemoryItem{}, err
 	}
-	return payloadToMemoryItem(id, payload), nil
+	stored = true
+	item := payloadToMemoryItem(id, payload)
+	s.recordRevision(ctx, HistoryEventAdd, item)
+	return item, nil
 }
 
 // RebuildAdd inserts a memory with a specific ID (from filesystem recovery).
@@ -823,11 +1015,11 @@ func (s *Service) RebuildAdd(ctx context.Context, id, text string, filters map[s
 	return payloadToMemoryItem(id, payload), nil
 }
 
-func (s *Service) applyUpdate(ctx context.Context, id, text string, filters map[string]any, metadata map[string]any, embeddingEnabled bool) (MemoryItem, error) {
+func (s *Service) applyUpdate(ctx context.Context, id, text string, filters map[string]any, metadata map[string]any, origin *MemoryOrigin, embeddingEnabled bool) (MemoryItem, error) {
 	if strings.TrimSpace(id) == "" {
 		return MemoryItem{}, fmt.Errorf("update action missing id")
 	}
-	existing, err := s.store.Get(ctx, id)
+	existing, err := s.store.Get(ctx, id, false, "")
 	if err != nil {
 		return MemoryItem{}, err
 	}
@@ -842,29 +1034,52 @@ func (s *Service) applyUpdate(ctx context.Context, id, text string, filters map[
 		var detectErr error
 		oldLang, detectErr = s.detectLanguage(ctx, oldText)
 		if detectErr != nil {
-			s.logger.Warn("detect language failed for old text", slog.Any("error", detectErr))
+			s.logger.WarnContext(ctx, "detect language failed for old text", slog.Any("error", detectErr))
 		}
 	}
+	// The corpus stats change is undone unless the point is stored, so a
+	// retried action does not apply it twice.
+	var (
+		oldFreq, newFreq     map[string]int
+		oldLen, newLen       int
+		newLang              string
+		removedOld, addedNew bool
+		stored               bool
+	)
+	defer func() {
+		if stored {
+			return
+		}
+		if addedNew {
+			s.bm25.RemoveDocument(newLang, newFreq, newLen)
+		}
+		if removedOld {
+			s.bm25.AddDocument(oldLang, oldFreq, oldLen)
+		}
+	}()
 	if strings.TrimSpace(oldText) != "" && strings.TrimSpace(oldLang) != "" {
-		oldFreq, oldLen, err := s.bm25.TermFrequencies(oldLang, oldText)
+		oldFreq, oldLen, err = s.bm25.TermFrequencies(oldLang, oldText)
 		if err != nil {
-			s.logger.Warn("bm25 term frequencies failed", slog.String("lang", oldLang), slog.Any("error", err))
+			s.logger.WarnContext(ctx, "bm25 term frequencies failed", slog.String("lang", oldLang), slog.Any("error", err))
 		} else {
 			s.bm25.RemoveDocument(oldLang, oldFreq, oldLen)
+			removedOld = true
 		}
 	}
-	newLang, err := s.detectLanguage(ctx, text)
+	newLang, err = s.detectLanguage(ctx, text)
 	if err != nil {
 		return MemoryItem{}, err
 	}
-	newFreq, newLen, err := s.bm25.TermFrequencies(newLang, text)
+	newFreq, newLen, err = s.bm25.TermFrequencies(newLang, text)
 	if err != nil {
 		return MemoryItem{}, err
 	}
 	sparseIndices, sparseValues := s.bm25.AddDocument(newLang, newFreq, newLen)
+	addedNew = true
 	payload["data"] = text
 	payload["hash"] = hashMemory(text)
-	payload["updated_at"] = time.Now().UTC().Format(time.RFC3339)
+	setTimestamp(payload, "updated_at", time.Now())
+	backfillTimestampEpochs(payload)
 	payload["lang"] = newLang
 	if metadata != nil {
 		payload["metadata"] = mergeMetadata(payload["metadata"], metadata)
@@ -872,6 +1087,7 @@ func (s *Service) applyUpdate(ctx context.Context, id, text string, filters map[
 	if filters != nil {
 		applyFiltersToPayload(payload, filters)
 	}
+	applyOriginToPayload(payload, origin)
 	point := qdrantPoint{
 		ID:               id,
 		SparseIndices:    sparseIndices,
@@ -880,27 +1096,24 @@ func (s *Service) applyUpdate(ctx context.Context, id, text string, filters map[
 		Payload:          payload,
 	}
 	if embeddingEnabled {
-		if s.embedder == nil {
-			return MemoryItem{}, fmt.Errorf("embedder not configured")
-		}
-		vector, err := s.embedder.Embed(ctx, text)
-		if err != nil {
+		if err := s.embedPointText(ctx, "update", &point, text); err != nil {
 			return MemoryItem{}, err
 		}
-		point.Vector = vector
-		point.VectorName = s.vectorNameForText()
 	}
 	if err := s.store.Upsert(ctx, []qdrantPoint{point}); err != nil {
 		return MemoryItem{}, err
 	}
-	return payloadToMemoryItem(id, payload), nil
+	stored = true
+	item := payloadToMemoryItem(id, payload)
+	s.recordRevision(ctx, HistoryEventUpdate, item)
+	return item, nil
 }
 
 func (s *Service) applyDelete(ctx context.Context, id string) (MemoryItem, error) {
 	if strings.TrimSpace(id) == "" {
 		return MemoryItem{}, fmt.Errorf("delete action missing id")
 	}
-	existing, err := s.store.Get(ctx, id)
+	existing, err := s.store.Get(ctx, id, false, "")
 	if err != nil {
 		return MemoryItem{}, err
 	}
@@ -908,28 +1121,41 @@ func (s *Service) applyDelete(ctx context.Context, id string) (MemoryItem, error
 		return MemoryItem{}, fmt.Errorf("memory not found")
 	}
 	item := payloadToMemoryItem(id, existing.Payload)
+	// Like applyUpdate, the corpus stats change is undone if the delete
+	// fails, so a retried action does not remove the document twice.
+	var (
+		oldFreq    map[string]int
+		oldLen     int
+		oldLang    string
+		removedOld bool
+	)
 	if s.bm25 != nil {
 		oldText := fmt.Sprint(existing.Payload["data"])
-		oldLang := fmt.Sprint(existing.Payload["lang"])
+		oldLang = fmt.Sprint(existing.Payload["lang"])
 		if oldLang == "" && strings.TrimSpace(oldText) != "" {
 			var detectErr error
 			oldLang, detectErr = s.detectLanguage(ctx, oldText)
 			if detectErr != nil {
-				s.logger.Warn("detect language failed for old text", slog.Any("error", detectErr))
+				s.logger.WarnContext(ctx, "detect language failed for old text", slog.Any("error", detectErr))
 			}
 		}
 		if strings.TrimSpace(oldText) != "" && strings.TrimSpace(oldLang) != "" {
-			oldFreq, oldLen, err := s.bm25.TermFrequencies(oldLang, oldText)
+			oldFreq, oldLen, err = s.bm25.TermFrequencies(oldLang, oldText)
 			if err != nil {
-				s.logger.Warn("bm25 term frequencies failed", slog.String("lang", oldLang), slog.Any("error", err))
+				s.logger.WarnContext(ctx, "bm25 term frequencies failed", slog.String("lang", oldLang), slog.Any("error", err))
 			} else {
 				s.bm25.RemoveDocument(oldLang, oldFreq, oldLen)
+				removedOld = true
 			}
 		}
 	}
 	if err := s.store.Delete(ctx, id); err != nil {
+		if removedOld {
+			s.bm25.AddDocument(oldLang, oldFreq, oldLen)
+		}
 		return MemoryItem{}, err
 	}
+	s.recordRevision(ctx, HistoryEventDelete, item)
 	return item, nil
 }
 
@@ -950,7 +1176,7 @@ func (s *Service) detectLanguage(ctx context.Context, text string) (string, erro
 	}
 	fallback := fallbackLanguageCode(text)
 	if s.logger != nil {
-		s.logger.Warn("language detection failed; using fallback", slog.Any("error", err), slog.String("fallback", fallback))
+		s.logger.WarnContext(ctx, "language detection failed; using fallback", slog.Any("error", err), slog.String("fallback", fallback))
 	}
 	return fallback, nil
 }
@@ -1012,16 +1238,49 @@ func buildSearchFilters(req SearchRequest) map[string]any {
 	for key, value := range req.Filters {
 		filters[key] = value
 	}
-	if req.BotID != "" {
-		filters["bot_id"] = req.BotID
+	setScopeFilter(filters, "bot_id", req.BotID, req.BotIDs)
+	setScopeFilter(filters, "agent_id", req.AgentID, req.AgentIDs)
+	setScopeFilter(filters, "run_id", req.RunID, req.RunIDs)
+	req.TimeRange.apply(filters)
+	return filters
+}
+
+// apply adds the range bounds to filters as numeric conditions on the
+// created_at/updated_at epoch payload fields.
+func (r TimeRange) apply(filters map[string]any) {
+	if r.CreatedAfter != nil || r.CreatedBefore != nil {
+		filters[createdAtEpochKey] = epochRange{gte: r.CreatedAfter, lt: r.CreatedBefore}
 	}
-	if req.AgentID != "" {
-		filters["agent_id"] = req.AgentID
+	if r.UpdatedAfter != nil || r.UpdatedBefore != nil {
+		filters[updatedAtEpochKey] = epochRange{gte: r.UpdatedAfter, lt: r.UpdatedBefore}
 	}
-	if req.RunID != "" {
-		filters["run_id"] = req.RunID
+}
+
+// setScopeFilter sets key to the union of single and many. One distinct
+// value is stored as a string (exact match); several as a []string, which
+// the store turns into an any-of condition.
+func setScopeFilter(filters map[string]any, key, single string, many []string) {
+	values := make([]string, 0, len(many)+1)
+	seen := map[string]struct{}{}
+	for _, value := range append([]string{single}, many...) {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		if _, ok := seen[value]; ok {
+			continue
+		}
+		seen[value] = struct{}{}
+		values = append(values, value)
+	}
+	switch len(values) {
+	case 0:
+		return
+	case 1:
+		filters[key] = values[0]
+	default:
+		filters[key] = values
 	}
-	return filters
 }
 
 func buildEmbedFilters(req EmbedUpsertRequest) map[string]any {
@@ -1095,6 +1354,7 @@ func buildPayload(text string, filters map[string]any, metadata map[string]any,
 		"hash":       hashMemory(text),
 		"created_at": createdAt,
 	}
+	backfillTimestampEpochs(payload)
 	if metadata != nil {
 		payload["metadata"] = metadata
 	}
@@ -1122,6 +1382,12 @@ func payloadToMemoryItem(id string, payload map[string]any) MemoryItem {
 	if v, ok := payload["updated_at"].(string); ok {
 		item.UpdatedAt = v
 	}
+	if v, ok := toFloat(payload[createdAtEpochKey]); ok {
+		item.CreatedAtTs = int64(v)
+	}
+	if v, ok := toFloat(payload[updatedAtEpochKey]); ok {
+		item.UpdatedAtTs = int64(v)
+	}
 	if v, ok := payload["bot_id"].(string); ok {
 		item.BotID = v
 	}
@@ -1131,6 +1397,7 @@ func payloadToMemoryItem(id string, payload map[string]any) MemoryItem {
 	if v, ok := payload["run_id"].(string); ok {
 		item.RunID = v
 	}
+	item.Origin = originFromPayload(payload)
 	if meta, ok := payload["metadata"].(map[string]any); ok {
 		item.Metadata = meta
 	} else if payload["metadata"] == nil {
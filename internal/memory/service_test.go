@@ -2,9 +2,13 @@ package memory
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"math"
+	"strings"
 	"testing"
+	"time"
 )
 
 // MockLLM mocks LLM for tests.
@@ -121,3 +125,447 @@ func TestRankFusion_Logic(t *testing.T) {
 		// Symmetric case: both get same RRF score (e.g. 1/(k+1)+1/(k+2) for k=60).
 	}
 }
+
+func TestChunkMessages_SplitsByTokenBudget(t *testing.T) {
+	long := strings.Repeat("a", 40) // ~10 tokens
+	messages := []Message{
+		{Role: "user", Content: long},
+		{Role: "assistant", Content: long},
+		{Role: "user", Content: long},
+	}
+	windows := chunkMessages(messages, 25)
+	if len(windows) != 2 {
+		t.Fatalf("expected 2 windows, got %d", len(windows))
+	}
+	if len(windows[0]) != 2 || len(windows[1]) != 1 {
+		t.Fatalf("unexpected window sizes: %d, %d", len(windows[0]), len(windows[1]))
+	}
+}
+
+func TestService_ExtractFacts_DedupesAcrossWindows(t *testing.T) {
+	calls := 0
+	s := &Service{
+		logger: slog.Default(),
+		llm: &MockLLM{
+			ExtractFunc: func(ctx context.Context, req ExtractRequest) (ExtractResponse, error) {
+				calls++
+				return ExtractResponse{Facts: []string{"User likes Go", fmt.Sprintf("Fact %d", calls)}}, nil
+			},
+		},
+	}
+	s.SetExtractMaxTokens(5)
+	messages := []Message{
+		{Role: "user", Content: strings.Repeat("x", 16)},
+		{Role: "user", Content: strings.Repeat("y", 16)},
+	}
+	resp, err := s.extractFacts(context.Background(), messages, nil, nil)
+	if err != nil {
+		t.Fatalf("extractFacts: %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("expected 2 extract calls, got %d", calls)
+	}
+	want := []string{"User likes Go", "Fact 1", "Fact 2"}
+	if len(resp.Facts) != len(want) {
+		t.Fatalf("expected facts %v, got %v", want, resp.Facts)
+	}
+	for i := range want {
+		if resp.Facts[i] != want[i] {
+			t.Fatalf("expected facts %v, got %v", want, resp.Facts)
+		}
+	}
+}
+
+type fakeHistoryStore struct {
+	revisions []MemoryRevision
+}
+
+func (f *fakeHistoryStore) AppendRevision(ctx context.Context, rev MemoryRevision) error {
+	f.revisions = append(f.revisions, rev)
+	return nil
+}
+
+func (f *fakeHistoryStore) ListRevisions(ctx context.Context, memoryID string) ([]MemoryRevision, error) {
+	out := make([]MemoryRevision, 0)
+	for _, rev := range f.revisions {
+		if rev.MemoryID == memoryID {
+			out = append(out, rev)
+		}
+	}
+	return out, nil
+}
+
+func TestService_History_RecordsRevisions(t *testing.T) {
+	history := &fakeHistoryStore{}
+	s := &Service{logger: slog.Default()}
+	if _, err := s.History(context.Background(), "mem-1"); err == nil {
+		t.Fatal("expected error when history is not configured")
+	}
+	s.SetHistoryStore(history)
+
+	s.recordRevision(context.Background(), HistoryEventAdd, MemoryItem{ID: "mem-1", Memory: "likes tea"})
+	s.recordRevision(context.Background(), HistoryEventUpdate, MemoryItem{ID: "mem-1", Memory: "likes green tea"})
+	s.recordRevision(context.Background(), HistoryEventAdd, MemoryItem{ID: "mem-2", Memory: "other"})
+
+	revisions, err := s.History(context.Background(), "mem-1")
+	if err != nil {
+		t.Fatalf("History: %v", err)
+	}
+	if len(revisions) != 2 {
+		t.Fatalf("expected 2 revisions, got %d", len(revisions))
+	}
+	if revisions[0].Event != HistoryEventAdd || revisions[1].Event != HistoryEventUpdate {
+		t.Fatalf("unexpected events: %s, %s", revisions[0].Event, revisions[1].Event)
+	}
+	if revisions[1].Hash != hashMemory("likes green tea") {
+		t.Fatalf("expected hash to be filled from memory text")
+	}
+}
+
+type reverseReranker struct{}
+
+func (reverseReranker) Rerank(ctx context.Context, query string, items []MemoryItem) ([]MemoryItem, error) {
+	out := make([]MemoryItem, len(items))
+	for i, item := range items {
+		item.Score = float64(i)
+		out[i] = item
+	}
+	return out, nil
+}
+
+func TestService_Rerank_ReplacesScoresAndTrims(t *testing.T) {
+	s := &Service{logger: slog.Default()}
+	items := []MemoryItem{{ID: "a", Score: 0.9}, {ID: "b", Score: 0.8}, {ID: "c", Score: 0.7}}
+
+	got, err := s.Rerank(context.Background(), "q", items, 2)
+	if err != nil {
+		t.Fatalf("Rerank without reranker: %v", err)
+	}
+	if len(got) != 2 || got[0].ID != "a" {
+		t.Fatalf("expected store order trimmed to 2, got %+v", got)
+	}
+
+	s.SetReranker(reverseReranker{})
+	got, err = s.Rerank(context.Background(), "q", items, 2)
+	if err != nil {
+		t.Fatalf("Rerank: %v", err)
+	}
+	if len(got) != 2 || got[0].ID != "c" || got[0].Score != 2 {
+		t.Fatalf("expected reranked order c,b with reranker scores, got %+v", got)
+	}
+}
+
+func TestService_Rerank_PassthroughKeepsStoreRelevance(t *testing.T) {
+	items := []MemoryItem{{ID: "a", Score: 0.6}, {ID: "b", Score: 0.2}}
+	annotateRelevance(items, DistanceCosine)
+
+	s := &Service{logger: slog.Default()}
+	s.SetReranker(PassthroughReranker{})
+	got, err := s.Rerank(context.Background(), "q", items, 2)
+	if err != nil {
+		t.Fatalf("Rerank: %v", err)
+	}
+	for _, item := range got {
+		if item.Distance != DistanceCosine || item.Relevance != normalizeScore(item.Score, DistanceCosine) {
+			t.Fatalf("passthrough changed store relevance: %+v", item)
+		}
+	}
+
+	s.SetReranker(reverseReranker{})
+	got, err = s.Rerank(context.Background(), "q", []MemoryItem{
+		{ID: "a", Score: 0.6, Distance: DistanceCosine, Relevance: 0.8},
+		{ID: "b", Score: 0.2, Distance: DistanceCosine, Relevance: 0.6},
+	}, 2)
+	if err != nil {
+		t.Fatalf("Rerank: %v", err)
+	}
+	for _, item := range got {
+		if item.Distance != DistanceRerank || item.Relevance != item.Score {
+			t.Fatalf("reranked item should carry reranker relevance: %+v", item)
+		}
+	}
+}
+
+func TestService_CheckEmbeddingDimension(t *testing.T) {
+	s := &Service{logger: slog.Default()}
+	filters := map[string]any{"bot_id": "bot-1"}
+	if err := s.checkEmbeddingDimension(context.Background(), filters, 768); err != nil {
+		t.Fatalf("first dimension should be accepted: %v", err)
+	}
+	s.rememberEmbeddingDimension(filters, 768)
+	if err := s.checkEmbeddingDimension(context.Background(), filters, 768); err != nil {
+		t.Fatalf("same dimension should be accepted: %v", err)
+	}
+	err := s.checkEmbeddingDimension(context.Background(), filters, 1536)
+	var dimErr *EmbeddingDimensionError
+	if !errors.As(err, &dimErr) {
+		t.Fatalf("expected EmbeddingDimensionError, got %v", err)
+	}
+	if dimErr.Existing != 768 || dimErr.Incoming != 1536 {
+		t.Fatalf("unexpected dimensions in error: %+v", dimErr)
+	}
+	s.forgetEmbeddingDimension("bot-1")
+	if err := s.checkEmbeddingDimension(context.Background(), filters, 1536); err != nil {
+		t.Fatalf("dimension should be accepted after reset: %v", err)
+	}
+}
+
+func TestService_ApplyActions_ContinuesPastFailure(t *testing.T) {
+	s := &Service{logger: slog.Default(), addActionBackoff: time.Millisecond}
+	s.SetAddActionRetries(1)
+
+	actions := []DecisionAction{
+		{Event: "ADD", Text: "first"},
+		{Event: "UPDATE", ID: "mem-2", Text: "second"},
+		{Event: "ADD", Text: "third"},
+	}
+	attempts := map[string]int{}
+	apply := func(ctx context.Context, action DecisionAction) (MemoryItem, error) {
+		attempts[action.Text]++
+		if action.Text == "second" {
+			return MemoryItem{}, errors.New("store unavailable")
+		}
+		return MemoryItem{Memory: action.Text}, nil
+	}
+
+	results, err := s.applyActions(context.Background(), actions, apply)
+	if len(results) != 2 || results[0].Memory != "first" || results[1].Memory != "third" {
+		t.Fatalf("expected first and third to be applied, got %+v", results)
+	}
+	var partial *PartialApplyError
+	if !errors.As(err, &partial) {
+		t.Fatalf("expected PartialApplyError, got %v", err)
+	}
+	if partial.Total != 3 || len(partial.Failed) != 1 {
+		t.Fatalf("unexpected partial error: %+v", partial)
+	}
+	failed := partial.Failed[0]
+	if failed.Index != 1 || failed.Event != "UPDATE" || failed.ID != "mem-2" {
+		t.Fatalf("unexpected failed action: %+v", failed)
+	}
+	if !strings.Contains(err.Error(), "applied 2 of 3") || !strings.Contains(err.Error(), "store unavailable") {
+		t.Fatalf("unexpected error message: %q", err.Error())
+	}
+	if attempts["second"] != 2 {
+		t.Fatalf("expected failed action to be retried once, got %d attempts", attempts["second"])
+	}
+	if attempts["first"] != 1 || attempts["third"] != 1 {
+		t.Fatalf("successful actions should be applied once, got %v", attempts)
+	}
+}
+
+func TestService_ApplyActions_RetriedAddKeepsBM25Stats(t *testing.T) {
+	s := &Service{
+		llm: &MockLLM{DetectLanguageFunc: func(ctx context.Context, text string) (string, error) {
+			return "en", nil
+		}},
+		bm25: NewBM25Indexer(nil),
+		// Without a sparse vector name every upsert fails before reaching
+		// Qdrant.
+		store:            &QdrantStore{},
+		logger:           slog.Default(),
+		addActionBackoff: time.Millisecond,
+	}
+	s.SetAddActionRetries(2)
+
+	actions := []DecisionAction{{Event: "ADD", Text: "User likes Go"}}
+	attempts := 0
+	_, err := s.applyActions(context.Background(), actions, func(ctx context.Context, action DecisionAction) (MemoryItem, error) {
+		attempts++
+		return s.applyAction(ctx, action, nil, nil, nil, false)
+	})
+	var partial *PartialApplyError
+	if !errors.As(err, &partial) || len(partial.Failed) != 1 {
+		t.Fatalf("expected the add to fail, got %v", err)
+	}
+	if attempts != 3 {
+		t.Fatalf("expected 3 attempts, got %d", attempts)
+	}
+	if stats := s.bm25.stats["en"]; stats != nil && (stats.DocCount != 0 || len(stats.DocFreq) != 0) {
+		t.Fatalf("failed adds left corpus stats behind: %+v", stats)
+	}
+}
+
+func TestAddActionBackoff(t *testing.T) {
+	s := &Service{}
+	want := []time.Duration{200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond}
+	for i, w := range want {
+		if got := s.addActionBackoffFor(i + 1); got != w {
+			t.Errorf("attempt %d: backoff %v, want %v", i+1, got, w)
+		}
+	}
+	if got := s.addActionBackoffFor(20); got != maxAddActionBackoff {
+		t.Errorf("backoff not capped: %v", got)
+	}
+}
+
+func TestSummarizeAdd(t *testing.T) {
+	resp := SearchResponse{Results: []MemoryItem{
+		{ID: "a", Metadata: map[string]any{"event": "ADD"}},
+		{ID: "b", Metadata: map[string]any{"event": "ADD"}},
+		{ID: "c", Metadata: map[string]any{"event": "UPDATE"}},
+		{ID: "d", Metadata: map[string]any{"event": "DELETE"}},
+	}}
+	err := &PartialApplyError{Total: 5, Failed: []ActionError{{Index: 4, Event: "ADD", Err: errors.New("boom")}}}
+
+	got := SummarizeAdd(resp, err)
+	want := AddSummary{Added: 2, Updated: 1, Deleted: 1, Failed: 1}
+	if got != want {
+		t.Fatalf("SummarizeAdd = %+v, want %+v", got, want)
+	}
+}
+
+func TestOrigin_PayloadRoundTrip(t *testing.T) {
+	messages := []Message{{Role: "user", Content: "I moved to Berlin"}, {Role: "assistant", Content: "Noted!"}}
+	if buildOrigin(nil, messages) != nil {
+		t.Fatal("origin must be opt-in")
+	}
+
+	hashOnly := buildOrigin(&OriginOptions{Ref: "msg-1"}, messages)
+	if hashOnly.Hash != hashMessages(messages) || len(hashOnly.Messages) != 0 {
+		t.Fatalf("unexpected hash-only origin: %+v", hashOnly)
+	}
+
+	payload := buildPayload("User lives in Berlin", map[string]any{"bot_id": "bot-1"}, nil, "")
+	applyOriginToPayload(payload, buildOrigin(&OriginOptions{Ref: "msg-1", IncludeMessages: true}, messages))
+	item := payloadToMemoryItem("mem-1", payload)
+	if item.Origin == nil {
+		t.Fatal("expected origin on memory item")
+	}
+	if item.Origin.Ref != "msg-1" || item.Origin.Hash != hashOnly.Hash {
+		t.Fatalf("unexpected origin: %+v", item.Origin)
+	}
+	if len(item.Origin.Messages) != 2 || item.Origin.Messages[0].Content != messages[0].Content {
+		t.Fatalf("expected source messages to round trip, got %+v", item.Origin.Messages)
+	}
+}
+
+func TestFactMetadata_AttributesSourceMessage(t *testing.T) {
+	messages := []Message{
+		{Role: "user", Content: "Can you recommend a restaurant?", Metadata: map[string]any{"platform": "telegram"}},
+		{Role: "assistant", Content: "Sushi Zen near the station serves great omakase.", Metadata: map[string]any{"platform": "telegram", "tool_calls": []any{"search"}}},
+	}
+
+	meta := factMetadata("Sushi Zen serves omakase near the station", messages)
+	if meta[MetadataSourceRole] != "assistant" {
+		t.Fatalf("expected assistant-derived fact, got %v", meta)
+	}
+	if meta["platform"] != "telegram" || meta["tool_calls"] == nil {
+		t.Fatalf("expected message metadata to propagate, got %v", meta)
+	}
+
+	shared := factMetadata("Prefers quiet places", messages)
+	if _, ok := shared[MetadataSourceRole]; ok {
+		t.Fatalf("unattributed fact must not claim a role across mixed messages, got %v", shared)
+	}
+	if shared["platform"] != "telegram" {
+		t.Fatalf("expected shared platform metadata, got %v", shared)
+	}
+}
+
+func TestService_ShouldInfer(t *testing.T) {
+	yes, no := true, false
+	s := &Service{logger: slog.Default()}
+	if !s.shouldInfer(nil) || s.shouldInfer(&no) {
+		t.Fatal("default service should infer unless the request opts out")
+	}
+	s.SetDefaultInfer(false)
+	if s.shouldInfer(nil) || !s.shouldInfer(&yes) {
+		t.Fatal("raw default should apply only when the request leaves infer unset")
+	}
+}
+
+func TestTimestampEpochs(t *testing.T) {
+	payload := buildPayload("likes tea", map[string]any{"bot_id": "b1"}, nil, "2026-01-02T03:04:05Z")
+	if got := payload[createdAtEpochKey]; got != int64(1767323045) {
+		t.Fatalf("created_at_ts = %v", got)
+	}
+
+	legacy := map[string]any{"created_at": "2026-01-02T03:04:05Z", "updated_at": "not a time"}
+	backfillTimestampEpochs(legacy)
+	if legacy[createdAtEpochKey] != int64(1767323045) {
+		t.Fatalf("expected created_at_ts to be backfilled, got %v", legacy[createdAtEpochKey])
+	}
+	if _, ok := legacy[updatedAtEpochKey]; ok {
+		t.Fatal("unparsable updated_at should not produce an epoch")
+	}
+
+	now := time.Unix(1767400000, 0)
+	setTimestamp(legacy, "updated_at", now)
+	if legacy["updated_at"] != "2026-01-03T00:26:40Z" || legacy[updatedAtEpochKey] != now.Unix() {
+		t.Fatalf("unexpected updated_at fields: %v %v", legacy["updated_at"], legacy[updatedAtEpochKey])
+	}
+}
+
+func TestApplyRecencyDecay(t *testing.T) {
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	items := []MemoryItem{
+		{ID: "old", Relevance: 0.9, CreatedAt: now.AddDate(0, 0, -60).Format(time.RFC3339)},
+		{ID: "new", Relevance: 0.8, CreatedAtTs: now.AddDate(0, 0, -90).Unix(), UpdatedAtTs: now.Unix(), Distance: DistanceCosine},
+		{ID: "undated", Relevance: 0.5},
+	}
+	applyRecencyDecay(items, 1, 30, now)
+	if items[0].ID != "new" || items[0].Score != 0.8 {
+		t.Fatalf("expected the freshly updated memory first, got %+v", items)
+	}
+	// Two half-lives old at full weight: a quarter of its relevance.
+	for _, item := range items {
+		if item.ID == "old" && math.Abs(item.Score-0.225) > 1e-9 {
+			t.Fatalf("old score = %v, want 0.225", item.Score)
+		}
+		if item.ID == "undated" && item.Score != 0.5 {
+			t.Fatalf("undated score = %v, want 0.5 kept", item.Score)
+		}
+	}
+
+	if items[0].Distance != DistanceCosine || items[0].Recency != 1 {
+		t.Fatalf("decay should keep the metric and report the factor, got %+v", items[0])
+	}
+
+	half := []MemoryItem{{ID: "a", Relevance: 1, CreatedAtTs: now.AddDate(0, 0, -30).Unix()}}
+	applyRecencyDecay(half, 0.5, 0, now)
+	if math.Abs(half[0].Score-0.75) > 1e-9 || half[0].Recency != 0.5 {
+		t.Fatalf("half weight = %+v, want score 0.75 and recency 0.5", half[0])
+	}
+	if err := validateRecency(SearchRequest{RecencyWeight: 1.5}); err == nil {
+		t.Fatal("expected recency_weight above 1 to be rejected")
+	}
+}
+
+type failingEmbedder struct{}
+
+func (failingEmbedder) Embed(context.Context, string) ([]float32, error) {
+	return nil, errors.New("rate limited")
+}
+
+func (failingEmbedder) Dimensions() int { return 3 }
+
+func TestEmbedPointText_Fallback(t *testing.T) {
+	s := NewService(slog.Default(), nil, failingEmbedder{}, nil, nil, nil, "", "")
+	point := qdrantPoint{ID: "m1", Payload: map[string]any{"data": "likes tea"}}
+	if err := s.embedPointText(context.Background(), "add", &point, "likes tea"); err == nil {
+		t.Fatal("expected the embedding error without a fallback policy")
+	}
+
+	s.SetEmbeddingFallback(EmbeddingFallbackDegrade)
+	if err := s.embedPointText(context.Background(), "add", &point, "likes tea"); err != nil {
+		t.Fatalf("degrade policy should swallow the error: %v", err)
+	}
+	if point.Payload[embeddingPendingKey] != true || point.Vector != nil {
+		t.Fatalf("expected a sparse-only point marked pending, got %+v", point)
+	}
+
+	if _, err := ParseEmbeddingFallback("retry"); err == nil {
+		t.Fatal("expected an unknown policy to be rejected")
+	}
+	if p, err := ParseEmbeddingFallback(""); err != nil || p != EmbeddingFallbackFail {
+		t.Fatalf("empty policy = %q, %v", p, err)
+	}
+}
+
+func TestPing_RequiresStore(t *testing.T) {
+	s := NewService(slog.Default(), nil, failingEmbedder{}, nil, nil, nil, "", "")
+	if err := s.Ping(context.Background()); err == nil || !strings.Contains(err.Error(), "qdrant") {
+		t.Fatalf("expected a missing store error, got %v", err)
+	}
+}
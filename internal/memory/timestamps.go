@@ -0,0 +1,54 @@
+package memory
+
+import "time"
+
+// Every RFC3339 timestamp field in a memory payload has a numeric twin
+// holding Unix seconds, so range conditions work on plain integer indexes.
+const (
+	createdAtEpochKey = "created_at_ts"
+	updatedAtEpochKey = "updated_at_ts"
+)
+
+var timestampEpochKeys = map[string]string{
+	"created_at": createdAtEpochKey,
+	"updated_at": updatedAtEpochKey,
+}
+
+// setTimestamp stores t under key as an RFC3339 string and under the key's
+// epoch twin as Unix seconds.
+func setTimestamp(payload map[string]any, key string, t time.Time) {
+	payload[key] = t.UTC().Format(time.RFC3339)
+	if epochKey, ok := timestampEpochKeys[key]; ok {
+		payload[epochKey] = t.Unix()
+	}
+}
+
+// backfillTimestampEpochs adds epoch fields missing from payloads written
+// before they existed. It is applied whenever a point is rewritten, so old
+// points migrate lazily.
+func backfillTimestampEpochs(payload map[string]any) {
+	for key, value := range missingTimestampEpochs(payload) {
+		payload[key] = value
+	}
+}
+
+// missingTimestampEpochs returns the epoch fields payload lacks, derived from
+// its RFC3339 timestamps.
+func missingTimestampEpochs(payload map[string]any) map[string]any {
+	missing := map[string]any{}
+	for key, epochKey := range timestampEpochKeys {
+		if _, ok := payload[epochKey]; ok {
+			continue
+		}
+		raw, ok := payload[key].(string)
+		if !ok {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			continue
+		}
+		missing[epochKey] = t.Unix()
+	}
+	return missing
+}
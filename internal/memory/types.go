@@ -1,6 +1,9 @@
 package memory
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 // LLM is the interface for LLM operations needed by memory service
 type LLM interface {
@@ -13,6 +16,9 @@ type LLM interface {
 type Message struct {
 	Role    string `json:"role"`
 	Content string `json:"content"`
+	// Metadata carries optional per-message context (platform, tool names,
+	// timestamps). It is propagated to memories derived from the message.
+	Metadata map[string]any `json:"metadata,omitempty"`
 }
 
 type AddRequest struct {
@@ -25,18 +31,47 @@ type AddRequest struct {
 	Filters          map[string]any `json:"filters,omitempty"`
 	Infer            *bool          `json:"infer,omitempty"`
 	EmbeddingEnabled *bool          `json:"embedding_enabled,omitempty"`
+	// Origin, when set, records a reference to the source messages on each
+	// stored memory.
+	Origin *OriginOptions `json:"origin,omitempty"`
 }
 
+// SearchRequest describes a memory search. Each scope dimension (bot, agent,
+// run) accepts a single ID and/or a list of IDs; both forms are unioned, so a
+// memory matches when it belongs to any of the given IDs. Different
+// dimensions are still ANDed together. Scope fields take precedence over the
+// same keys in Filters.
 type SearchRequest struct {
 	Query            string         `json:"query"`
 	BotID            string         `json:"bot_id,omitempty"`
 	AgentID          string         `json:"agent_id,omitempty"`
 	RunID            string         `json:"run_id,omitempty"`
+	BotIDs           []string       `json:"bot_ids,omitempty"`
+	AgentIDs         []string       `json:"agent_ids,omitempty"`
+	RunIDs           []string       `json:"run_ids,omitempty"`
 	Limit            int            `json:"limit,omitempty"`
 	Filters          map[string]any `json:"filters,omitempty"`
 	Sources          []string       `json:"sources,omitempty"`
 	EmbeddingEnabled *bool          `json:"embedding_enabled,omitempty"`
 	NoStats          bool           `json:"no_stats,omitempty"`
+	// RecencyWeight in [0, 1] blends an exponential decay on the age of each
+	// memory into its relevance, reported per item as Recency; 0 ranks by
+	// similarity alone.
+	RecencyWeight float64 `json:"recency_weight,omitempty"`
+	// RecencyHalfLifeDays is the age at which the decay halves; 0 uses 30.
+	RecencyHalfLifeDays float64 `json:"recency_half_life_days,omitempty"`
+	TimeRange
+}
+
+// TimeRange restricts results to memories created or updated in a window.
+// After bounds are inclusive, Before bounds exclusive; nil leaves a side open.
+// Memories that were never updated have no updated_at and do not match the
+// Updated bounds.
+type TimeRange struct {
+	CreatedAfter  *time.Time `json:"created_after,omitempty"`
+	CreatedBefore *time.Time `json:"created_before,omitempty"`
+	UpdatedAfter  *time.Time `json:"updated_after,omitempty"`
+	UpdatedBefore *time.Time `json:"updated_before,omitempty"`
 }
 
 type UpdateRequest struct {
@@ -52,6 +87,7 @@ type GetAllRequest struct {
 	Limit   int            `json:"limit,omitempty"`
 	Filters map[string]any `json:"filters,omitempty"`
 	NoStats bool           `json:"no_stats,omitempty"`
+	TimeRange
 }
 
 type DeleteAllRequest struct {
@@ -93,8 +129,14 @@ type MemoryItem struct {
 	Hash        string         `json:"hash,omitempty"`
 	CreatedAt   string         `json:"created_at,omitempty"`
 	UpdatedAt   string         `json:"updated_at,omitempty"`
+	CreatedAtTs int64          `json:"created_at_ts,omitempty"`
+	UpdatedAtTs int64          `json:"updated_at_ts,omitempty"`
 	Score       float64        `json:"score,omitempty"`
+	Relevance   float64        `json:"relevance,omitempty"`
+	Distance    string         `json:"distance,omitempty"`
+	Recency     float64        `json:"recency,omitempty"`
 	Metadata    map[string]any `json:"metadata,omitempty"`
+	Origin      *MemoryOrigin  `json:"origin,omitempty"`
 	BotID       string         `json:"bot_id,omitempty"`
 	AgentID     string         `json:"agent_id,omitempty"`
 	RunID       string         `json:"run_id,omitempty"`
@@ -102,6 +144,16 @@ type MemoryItem struct {
 	CDFCurve    []CDFPoint     `json:"cdf_curve,omitempty"`
 }
 
+// MemoryRevision is one entry in the edit history of a memory.
+type MemoryRevision struct {
+	ID        string `json:"id"`
+	MemoryID  string `json:"memory_id"`
+	Event     string `json:"event"`
+	Memory    string `json:"memory"`
+	Hash      string `json:"hash"`
+	CreatedAt string `json:"created_at"`
+}
+
 // TopKBucket represents one bar in the Top-K sparse dimension bar chart.
 type TopKBucket struct {
 	Index uint32  `json:"index"` // sparse dimension index (term hash)
@@ -117,6 +169,9 @@ type CDFPoint struct {
 type SearchResponse struct {
 	Results   []MemoryItem `json:"results"`
 	Relations []any        `json:"relations,omitempty"`
+	// Degraded is set when the embedder failed and keyword results were
+	// returned instead of semantic ones.
+	Degraded bool `json:"degraded,omitempty"`
 }
 
 type DeleteResponse struct {
@@ -0,0 +1,81 @@
+// Package metrics defines the Prometheus counters and histograms used across
+// the agent and the registry they are exposed from.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registry is the process-wide registry exposed on /metrics. It also carries
+// the standard Go runtime and process collectors.
+var Registry = prometheus.NewRegistry()
+
+var (
+	// HTTPRequests counts HTTP requests by method, route template and status code.
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "memoh_http_requests_total",
+		Help: "HTTP requests handled, by method, route and status.",
+	}, []string{"method", "route", "status"})
+	// HTTPRequestDuration observes HTTP request latency by method and route template.
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "memoh_http_request_duration_seconds",
+		Help:    "HTTP request latency in seconds, by method and route.",
+		Buckets: DefaultBuckets,
+	}, []string{"method", "route"})
+
+	// MemoryOperations counts memory writes by operation (add, update, delete).
+	MemoryOperations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "memoh_memory_operations_total",
+		Help: "Memory write operations, by operation.",
+	}, []string{"operation"})
+
+	// ChatRequests counts chat requests sent to the agent gateway by mode (sync, stream).
+	ChatRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "memoh_chat_requests_total",
+		Help: "Chat requests sent to the agent gateway, by mode.",
+	}, []string{"mode"})
+	// GatewayErrors counts failed agent gateway calls by mode.
+	GatewayErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "memoh_gateway_errors_total",
+		Help: "Agent gateway call failures, by mode.",
+	}, []string{"mode"})
+
+	// ContainerExecDuration observes command execution time inside bot containers.
+	ContainerExecDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "memoh_container_exec_duration_seconds",
+		Help:    "Duration of commands executed in bot containers, in seconds.",
+		Buckets: DefaultBuckets,
+	}, []string{"kind"})
+)
+
+// DefaultBuckets are latency buckets in seconds.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// Memory operation label values.
+const (
+	MemoryOpAdd    = "add"
+	MemoryOpUpdate = "update"
+	MemoryOpDelete = "delete"
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPRequestDuration,
+		MemoryOperations,
+		ChatRequests,
+		GatewayErrors,
+		ContainerExecDuration,
+	)
+}
+
+// Handler serves Registry in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,31 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	HTTPRequests.WithLabelValues("GET", "/bots/:bot_id", "200").Inc()
+	ContainerExecDuration.WithLabelValues("exec").Observe(0.02)
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	out := rec.Body.String()
+	for _, want := range []string{
+		"# TYPE memoh_http_requests_total counter",
+		`memoh_http_requests_total{method="GET",route="/bots/:bot_id",status="200"} 1`,
+		"# TYPE memoh_container_exec_duration_seconds histogram",
+		`memoh_container_exec_duration_seconds_bucket{kind="exec",le="0.025"} 1`,
+		"go_goroutines",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q", want)
+		}
+	}
+}
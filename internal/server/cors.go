@@ -0,0 +1,73 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+
+	"github.com/memohai/memoh/internal/config"
+	"github.com/memohai/memoh/internal/logger"
+)
+
+// corsPreflightMaxAge lets browsers cache preflight results for ten minutes.
+const corsPreflightMaxAge = 600
+
+var defaultCORSMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+}
+
+// corsMiddleware builds the CORS middleware from the server config. It
+// returns nil when no origins are configured, which keeps cross-origin
+// requests denied. It must run before the JWT middleware: preflight requests
+// carry no Authorization header and are answered here without reaching auth.
+func corsMiddleware(cfg config.ServerConfig) echo.MiddlewareFunc {
+	origins := trimNonEmpty(cfg.CORSAllowedOrigins)
+	if len(origins) == 0 {
+		return nil
+	}
+	methods := trimNonEmpty(cfg.CORSAllowedMethods)
+	for i, method := range methods {
+		methods[i] = strings.ToUpper(method)
+	}
+	if len(methods) == 0 {
+		methods = defaultCORSMethods
+	}
+	return middleware.CORSWithConfig(middleware.CORSConfig{
+		AllowOrigins:     origins,
+		AllowMethods:     methods,
+		AllowCredentials: cfg.CORSAllowCredentials,
+		// Authorization carries the JWT; Last-Event-ID is sent by EventSource
+		// when it reconnects to a stream.
+		AllowHeaders: []string{
+			echo.HeaderAuthorization,
+			echo.HeaderContentType,
+			echo.HeaderAccept,
+			"Last-Event-ID",
+			logger.RequestIDHeader,
+		},
+		ExposeHeaders: []string{
+			logger.RequestIDHeader,
+			echo.HeaderRetryAfter,
+			echo.HeaderContentDisposition,
+			echo.HeaderContentLength,
+		},
+		MaxAge: corsPreflightMaxAge,
+	})
+}
+
+func trimNonEmpty(values []string) []string {
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,115 @@
+package server
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"golang.org/x/time/rate"
+
+	"github.com/memohai/memoh/internal/auth"
+)
+
+// rateLimiterIdleTTL is how long an unused bucket is kept before it is evicted.
+const rateLimiterIdleTTL = 10 * time.Minute
+
+type rateBucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// userRateLimiter holds one in-memory token bucket per user and route limit.
+// It is meant for a single instance; replicas each enforce their own budget.
+type userRateLimiter struct {
+	perMinute int
+	routes    map[string]int
+
+	mu        sync.Mutex
+	buckets   map[string]*rateBucket
+	lastSweep time.Time
+}
+
+func newUserRateLimiter(perMinute int, routes map[string]int) *userRateLimiter {
+	return &userRateLimiter{
+		perMinute: perMinute,
+		routes:    routes,
+		buckets:   map[string]*rateBucket{},
+	}
+}
+
+// limitFor returns the per-minute limit for a route template, honoring
+// per-route overrides. A result <= 0 means the route is not limited.
+func (l *userRateLimiter) limitFor(route string) int {
+	if limit, ok := l.routes[route]; ok {
+		return limit
+	}
+	return l.perMinute
+}
+
+// reserve takes a token for the user and returns how long the caller must
+// wait before retrying; zero means the request is allowed.
+func (l *userRateLimiter) reserve(userID, route string, now time.Time) time.Duration {
+	limit := l.limitFor(route)
+	if limit <= 0 {
+		return 0
+	}
+	// Routes with an override get their own bucket; everything else shares
+	// the user's default bucket.
+	key := userID
+	if _, ok := l.routes[route]; ok {
+		key = userID + " " + route
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if now.Sub(l.lastSweep) > time.Minute {
+		for k, b := range l.buckets {
+			if now.Sub(b.lastSeen) > rateLimiterIdleTTL {
+				delete(l.buckets, k)
+			}
+		}
+		l.lastSweep = now
+	}
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &rateBucket{limiter: rate.NewLimiter(rate.Limit(float64(limit)/60), limit)}
+		l.buckets[key] = bucket
+	}
+	bucket.lastSeen = now
+
+	reservation := bucket.limiter.ReserveN(now, 1)
+	if !reservation.OK() {
+		return time.Minute
+	}
+	delay := reservation.DelayFrom(now)
+	if delay > 0 {
+		reservation.CancelAt(now)
+	}
+	return delay
+}
+
+// rateLimitMiddleware limits authenticated requests per JWT user id and
+// answers 429 with Retry-After once a user's bucket is empty. Requests
+// without a user (public routes) and /ping are never limited.
+func rateLimitMiddleware(limiter *userRateLimiter) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if c.Request().URL.Path == "/ping" {
+				return next(c)
+			}
+			userID, err := auth.UserIDFromContext(c)
+			if err != nil || userID == "" {
+				return next(c)
+			}
+			if delay := limiter.reserve(userID, c.Path(), time.Now()); delay > 0 {
+				retryAfter := int(math.Ceil(delay.Seconds()))
+				c.Response().Header().Set("Retry-After", strconv.Itoa(retryAfter))
+				return echo.NewHTTPError(http.StatusTooManyRequests, "rate limit exceeded")
+			}
+			return next(c)
+		}
+	}
+}
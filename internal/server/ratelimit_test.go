@@ -0,0 +1,99 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+)
+
+func TestUserRateLimiterBurst(t *testing.T) {
+	limiter := newUserRateLimiter(3, nil)
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		if delay := limiter.reserve("u1", "/bots", now); delay != 0 {
+			t.Fatalf("request %d: delay = %v, want 0 within the burst", i, delay)
+		}
+	}
+	delay := limiter.reserve("u1", "/bots", now)
+	if delay <= 0 || delay > 20*time.Second {
+		t.Fatalf("delay after the burst = %v, want one token interval (20s)", delay)
+	}
+	if delay := limiter.reserve("u1", "/bots", now.Add(20*time.Second)); delay != 0 {
+		t.Fatalf("delay after refill = %v, want 0", delay)
+	}
+}
+
+func TestUserRateLimiterIsolation(t *testing.T) {
+	limiter := newUserRateLimiter(1, map[string]int{"/upload": 1, "/health": 0})
+	now := time.Now()
+	if limiter.reserve("u1", "/bots", now) != 0 || limiter.reserve("u1", "/bots", now) == 0 {
+		t.Fatal("u1 should exhaust its single token")
+	}
+	if limiter.reserve("u2", "/bots", now) != 0 {
+		t.Fatal("u2 should not share u1's bucket")
+	}
+	if limiter.reserve("u1", "/upload", now) != 0 {
+		t.Fatal("a route override should have a bucket of its own")
+	}
+	if limiter.reserve("u1", "/other", now) == 0 {
+		t.Fatal("routes without an override should share the default bucket")
+	}
+	for i := 0; i < 5; i++ {
+		if limiter.reserve("u1", "/health", now) != 0 {
+			t.Fatal("a zero override should disable limiting for the route")
+		}
+	}
+}
+
+func TestUserRateLimiterEvictsIdle(t *testing.T) {
+	limiter := newUserRateLimiter(1, nil)
+	now := time.Now()
+	limiter.reserve("idle", "/bots", now)
+	limiter.reserve("busy", "/bots", now)
+	later := now.Add(rateLimiterIdleTTL)
+	limiter.reserve("busy", "/bots", later)
+
+	limiter.reserve("busy", "/bots", later.Add(2*time.Minute))
+	if _, ok := limiter.buckets["idle"]; ok {
+		t.Fatal("idle bucket should be evicted")
+	}
+	if _, ok := limiter.buckets["busy"]; !ok {
+		t.Fatal("recently used bucket should be kept")
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	e := echo.New()
+	handler := rateLimitMiddleware(newUserRateLimiter(1, nil))(func(c echo.Context) error {
+		return c.NoContent(http.StatusNoContent)
+	})
+	call := func(userID string) error {
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, "/bots", nil), httptest.NewRecorder())
+		c.SetPath("/bots")
+		if userID != "" {
+			c.Set("user", &jwt.Token{Valid: true, Claims: jwt.MapClaims{"sub": userID}})
+		}
+		return handler(c)
+	}
+
+	if err := call("u1"); err != nil {
+		t.Fatalf("first request: %v", err)
+	}
+	var httpErr *echo.HTTPError
+	if err := call("u1"); !errors.As(err, &httpErr) || httpErr.Code != http.StatusTooManyRequests {
+		t.Fatalf("second request: err = %v, want 429", err)
+	}
+	if err := call("u2"); err != nil {
+		t.Fatalf("other user: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := call(""); err != nil {
+			t.Fatalf("anonymous request should not be limited: %v", err)
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ProbeFunc checks whether a dependency is currently usable.
+type ProbeFunc func(ctx context.Context) error
+
+// ThrottledProbe wraps probe so it runs at most once per minInterval; calls
+// in between return the previous result. Use it for probes that are too
+// expensive to run on every readiness tick.
+func ThrottledProbe(probe ProbeFunc, minInterval time.Duration) ProbeFunc {
+	var (
+		mu      sync.Mutex
+		last    time.Time
+		lastErr error
+	)
+	return func(ctx context.Context) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if !last.IsZero() && time.Since(last) < minInterval {
+			return lastErr
+		}
+		lastErr = probe(ctx)
+		last = time.Now()
+		return lastErr
+	}
+}
+
+// Readiness tracks the availability of the agent's external dependencies.
+// A dependency registered with route prefixes gates those routes: while it is
+// unavailable, matching requests are answered with 503 instead of reaching
+// handlers that would fail anyway. The aggregated state is served on /readyz.
+type Readiness struct {
+	logger *slog.Logger
+
+	mu   sync.RWMutex
+	deps map[string]*dependency
+}
+
+type dependency struct {
+	probe    ProbeFunc
+	prefixes []string
+	ready    bool
+	err      string
+	since    time.Time
+}
+
+// DependencyStatus is the /readyz view of a single dependency.
+type DependencyStatus struct {
+	Ready bool      `json:"ready"`
+	Error string    `json:"error,omitempty"`
+	Since time.Time `json:"since"`
+}
+
+// ReadyzResponse is the body returned by /readyz.
+type ReadyzResponse struct {
+	Status       string                      `json:"status"`
+	Dependencies map[string]DependencyStatus `json:"dependencies"`
+}
+
+func NewReadiness(log *slog.Logger) *Readiness {
+	return &Readiness{
+		logger: log.With(slog.String("component", "readiness")),
+		deps:   map[string]*dependency{},
+	}
+}
+
+// Register adds a dependency. It starts out unavailable until a probe
+// succeeds. routePrefixes are echo route templates (e.g.
+// "/bots/:bot_id/container") that return 503 while it is unavailable.
+func (r *Readiness) Register(name string, probe ProbeFunc, routePrefixes ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.deps[name] = &dependency{
+		probe:    probe,
+		prefixes: routePrefixes,
+		err:      "not checked yet",
+		since:    time.Now(),
+	}
+}
+
+// Set records the latest probe result for name and logs state transitions.
+func (r *Readiness) Set(name string, err error) {
+	r.mu.Lock()
+	dep, ok := r.deps[name]
+	if !ok {
+		r.mu.Unlock()
+		return
+	}
+	ready := err == nil
+	changed := dep.ready != ready
+	dep.ready = ready
+	dep.err = ""
+	if err != nil {
+		dep.err = err.Error()
+	}
+	if changed {
+		dep.since = time.Now()
+	}
+	r.mu.Unlock()
+
+	if !changed {
+		return
+	}
+	if ready {
+		r.logger.Info("dependency available", slog.String("dependency", name))
+	} else {
+		r.logger.Warn("dependency unavailable", slog.String("dependency", name), slog.Any("error", err))
+	}
+}
+
+// Check probes name once and records the result.
+func (r *Readiness) Check(ctx context.Context, name string) error {
+	r.mu.RLock()
+	dep, ok := r.deps[name]
+	r.mu.RUnlock()
+	if !ok {
+		return errors.New("unknown dependency: " + name)
+	}
+	err := dep.probe(ctx)
+	r.Set(name, err)
+	return err
+}
+
+// Await probes name up to attempts times, waiting interval between tries,
+// and returns the last error if it never became available.
+func (r *Readiness) Await(ctx context.Context, name string, attempts int, interval time.Duration) error {
+	if attempts < 1 {
+		attempts = 1
+	}
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		probeCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		err = r.Check(probeCtx, name)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		if attempt == attempts {
+			break
+		}
+		r.logger.Warn("dependency not ready, retrying",
+			slog.String("dependency", name),
+			slog.Int("attempt", attempt),
+			slog.Int("max_attempts", attempts),
+			slog.Any("error", err))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+	return err
+}
+
+// Watch re-probes every dependency each interval until ctx is done, so
+// degraded subsystems recover once their dependency comes up.
+func (r *Readiness) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		for _, name := range r.names() {
+			probeCtx, cancel := context.WithTimeout(ctx, interval)
+			_ = r.Check(probeCtx, name)
+			cancel()
+		}
+	}
+}
+
+// Degraded returns the names of dependencies that are currently unavailable.
+func (r *Readiness) Degraded() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var out []string
+	for name, dep := range r.deps {
+		if !dep.ready {
+			out = append(out, name)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (r *Readiness) names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]string, 0, len(r.deps))
+	for name := range r.deps {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// unavailableFor returns the first unavailable dependency gating route.
+func (r *Readiness) unavailableFor(route string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for name, dep := range r.deps {
+		if dep.ready {
+			continue
+		}
+		for _, prefix := range dep.prefixes {
+			if route == prefix || strings.HasPrefix(route, prefix+"/") {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// Readyz reports 200 when every dependency is available and 503 otherwise.
+func (r *Readiness) Readyz(c echo.Context) error {
+	r.mu.RLock()
+	resp := ReadyzResponse{Status: "ok", Dependencies: make(map[string]DependencyStatus, len(r.deps))}
+	for name, dep := range r.deps {
+		if !dep.ready {
+			resp.Status = "degraded"
+		}
+		resp.Dependencies[name] = DependencyStatus{Ready: dep.ready, Error: dep.err, Since: dep.since}
+	}
+	r.mu.RUnlock()
+	if resp.Status != "ok" {
+		return c.JSON(http.StatusServiceUnavailable, resp)
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// readinessMiddleware answers 503 for routes gated by an unavailable dependency.
+func readinessMiddleware(r *Readiness) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if name := r.unavailableFor(c.Path()); name != "" {
+				c.Response().Header().Set("Retry-After", "10")
+				return echo.NewHTTPError(http.StatusServiceUnavailable, name+" is unavailable")
+			}
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package server
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/memohai/memoh/internal/handlers"
+	"github.com/memohai/memoh/internal/logger"
+)
+
+// recoverMiddleware turns handler panics into a JSON 500. The panic value and
+// stack are logged with the request id; clients only see a generic message.
+func recoverMiddleware(log *slog.Logger) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) (err error) {
+			defer func() {
+				r := recover()
+				if r == nil {
+					return
+				}
+				if r == http.ErrAbortHandler {
+					panic(r)
+				}
+				req := c.Request()
+				log.Error("panic recovered",
+					slog.String("request_id", logger.RequestIDFromContext(req.Context())),
+					slog.String("method", req.Method),
+					slog.String("path", c.Path()),
+					slog.String("panic", fmt.Sprint(r)),
+					slog.String("stack", string(debug.Stack())),
+				)
+				if c.Response().Committed {
+					// Headers (and maybe part of a stream) are already out;
+					// nothing sensible can be written anymore.
+					err = nil
+					return
+				}
+				err = c.JSON(http.StatusInternalServerError, handlers.ErrorResponse{Message: "internal error"})
+			}()
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package server
+
+import (
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+
+	"github.com/memohai/memoh/internal/logger"
+)
+
+// maxRequestIDLength bounds client-supplied request ids so they stay safe to log.
+const maxRequestIDLength = 128
+
+// requestIDMiddleware accepts a well-formed X-Request-ID from the caller or
+// generates one, echoes it on the response and stores it in the request
+// context for downstream logging and forwarding.
+func requestIDMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			requestID := req.Header.Get(logger.RequestIDHeader)
+			if !validRequestID(requestID) {
+				requestID = uuid.NewString()
+				req.Header.Set(logger.RequestIDHeader, requestID)
+			}
+			c.Response().Header().Set(logger.RequestIDHeader, requestID)
+			c.SetRequest(req.WithContext(logger.WithRequestID(req.Context(), requestID)))
+			return next(c)
+		}
+	}
+}
+
+func validRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range requestID {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-' || r == '_' || r == '.' || r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
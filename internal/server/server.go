@@ -9,6 +9,8 @@ import (
 	"github.com/labstack/echo/v4/middleware"
 
 	"github.com/memohai/memoh/internal/auth"
+	"github.com/memohai/memoh/internal/config"
+	"github.com/memohai/memoh/internal/metrics"
 )
 
 type Server struct {
@@ -21,7 +23,7 @@ type Handler interface {
 	Register(e *echo.Echo)
 }
 
-func NewServer(log *slog.Logger, addr string, jwtSecret string,
+func NewServer(log *slog.Logger, addr string, jwtSecret string, cfg config.ServerConfig, readiness *Readiness,
 	handlers ...Handler,
 ) *Server {
 	if addr == "" {
@@ -30,25 +32,49 @@ func NewServer(log *slog.Logger, addr string, jwtSecret string,
 
 	e := echo.New()
 	e.HideBanner = true
-	e.Use(middleware.Recover())
+	if cfg.MetricsEnabled {
+		e.Use(metricsMiddleware())
+		e.GET("/metrics", echo.WrapHandler(metrics.Handler()))
+	}
+	e.Use(requestIDMiddleware())
 	e.Use(middleware.RequestLoggerWithConfig(middleware.RequestLoggerConfig{
-		LogStatus: true,
-		LogURI:    true,
-		LogMethod: true,
+		LogStatus:        true,
+		LogURI:           true,
+		LogURIPath:       true,
+		LogMethod:        true,
+		LogLatency:       true,
+		LogRequestID:     true,
+		LogContentLength: true,
+		LogResponseSize:  true,
 		LogValuesFunc: func(c echo.Context, v middleware.RequestLoggerValues) error {
+			userID, _ := auth.UserIDFromContext(c)
 			log.Info("request",
+				slog.String("request_id", v.RequestID),
 				slog.String("method", v.Method),
+				slog.String("path", v.URIPath),
 				slog.String("uri", v.URI),
 				slog.Int("status", v.Status),
 				slog.Duration("latency", v.Latency),
+				slog.String("user_id", userID),
+				slog.String("request_bytes", v.ContentLength),
+				slog.Int64("response_bytes", v.ResponseSize),
 				slog.String("remote_ip", c.RealIP()),
 			)
 			return nil
 		},
 	}))
+	// Recovery runs inside the request logger so recovered panics are logged
+	// as 500s, and ahead of CORS and auth so their panics are caught too.
+	e.Use(recoverMiddleware(log))
+	if cors := corsMiddleware(cfg); cors != nil {
+		e.Use(cors)
+	}
 	e.Use(auth.JWTMiddleware(jwtSecret, func(c echo.Context) bool {
 		path := c.Request().URL.Path
-		if path == "/ping" || path == "/health" || path == "/api/swagger.json" || path == "/auth/login" {
+		if path == "/ping" || path == "/health" || path == "/readyz" || path == "/api/swagger.json" || path == "/auth/login" {
+			return true
+		}
+		if cfg.MetricsEnabled && path == "/metrics" {
 			return true
 		}
 		if strings.HasPrefix(path, "/api/docs") {
@@ -56,6 +82,13 @@ func NewServer(log *slog.Logger, addr string, jwtSecret string,
 		}
 		return false
 	}))
+	if cfg.RateLimitPerMinute > 0 || len(cfg.RateLimitRoutes) > 0 {
+		e.Use(rateLimitMiddleware(newUserRateLimiter(cfg.RateLimitPerMinute, cfg.RateLimitRoutes)))
+	}
+	if readiness != nil {
+		e.GET("/readyz", readiness.Readyz)
+		e.Use(readinessMiddleware(readiness))
+	}
 
 	for _, h := range handlers {
 		if h != nil {